@@ -0,0 +1,99 @@
+// Command imagerenditions generates the thumbnail, listing and zoom
+// renditions for product images uploaded before the image pipeline existed.
+//
+//	go run ./cmd/imagerenditions
+//	go run ./cmd/imagerenditions -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"go.uber.org/zap"
+)
+
+type imageRow struct {
+	ID       uint
+	Original string
+}
+
+var imageTables = []struct {
+	Table  string
+	Column string
+	Folder string
+}{
+	{"product_images", "product_images", storage.FolderProducts},
+	{"product_variants_images", "product_variants_images", storage.FolderProductVariants},
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "list the images that have no renditions without generating them")
+	flag.Parse()
+
+	logger.InitLogger()
+	config.LoadEnvFile()
+	storage.Init()
+	config.DBconnect()
+
+	ctx := context.Background()
+	var generated, failed int
+	for _, table := range imageTables {
+		var rows []imageRow
+		if err := config.DB.Table(table.Table).
+			Select("id, " + table.Column + " AS original").
+			Where("(listing_url IS NULL OR listing_url = '') AND deleted_at IS NULL").
+			Order("id").
+			Scan(&rows).Error; err != nil {
+			logger.Log.Fatal("Failed to list images", zap.String("table", table.Table), zap.Error(err))
+		}
+
+		for _, row := range rows {
+			if *dryRun {
+				fmt.Printf("%s %d: %s\n", table.Table, row.ID, row.Original)
+				continue
+			}
+			if err := generate(ctx, table.Table, table.Folder, row); err != nil {
+				logger.Log.Error("Failed to generate renditions",
+					zap.String("table", table.Table),
+					zap.Uint("id", row.ID),
+					zap.String("url", row.Original),
+					zap.Error(err))
+				failed++
+				continue
+			}
+			generated++
+		}
+	}
+
+	fmt.Printf("generated renditions for %d images, %d failures\n", generated, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func generate(ctx context.Context, table, folder string, row imageRow) error {
+	image, err := storage.Open(ctx, row.Original)
+	if err != nil {
+		return err
+	}
+	defer image.Close()
+
+	renditions, err := services.GenerateRenditions(ctx, folder, image)
+	if err != nil {
+		return err
+	}
+	return config.DB.Table(table).Where("id = ?", row.ID).Updates(map[string]interface{}{
+		"thumbnail_url":      renditions.ThumbnailURL,
+		"thumbnail_webp_url": renditions.ThumbnailWebPURL,
+		"listing_url":        renditions.ListingURL,
+		"listing_webp_url":   renditions.ListingWebPURL,
+		"zoom_url":           renditions.ZoomURL,
+		"zoom_webp_url":      renditions.ZoomWebPURL,
+	}).Error
+}
@@ -0,0 +1,145 @@
+// Command migrateimages copies the images referenced in the database from one
+// image store to the other and points the rows at the copies.
+//
+//	go run ./cmd/migrateimages -to local
+//	go run ./cmd/migrateimages -to cloudinary -dry-run
+//
+// Originals are left in place so the old store can be removed once the copy
+// has been checked.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
+	"go.uber.org/zap"
+)
+
+type imageColumn struct {
+	Table  string
+	Column string
+	Folder string
+}
+
+// Order items keep a copy of a variant image URL, so variants are copied
+// first and the order items pick up the same new URL.
+func imageColumns() []imageColumn {
+	columns := []imageColumn{{"product_images", "product_images", storage.FolderProducts}}
+	columns = append(columns, renditionColumns("product_images", storage.FolderProducts)...)
+	columns = append(columns, imageColumn{"product_variants_images", "product_variants_images", storage.FolderProductVariants})
+	columns = append(columns, renditionColumns("product_variants_images", storage.FolderProductVariants)...)
+	return append(columns,
+		imageColumn{"user_auths", "profile_pic", storage.FolderProfilePictures},
+		imageColumn{"order_items", "product_image", storage.FolderProductVariants},
+	)
+}
+
+func renditionColumns(table, folder string) []imageColumn {
+	var columns []imageColumn
+	for _, column := range []string{
+		"thumbnail_url", "thumbnail_webp_url",
+		"listing_url", "listing_webp_url",
+		"zoom_url", "zoom_webp_url",
+	} {
+		columns = append(columns, imageColumn{table, column, folder})
+	}
+	return columns
+}
+
+func main() {
+	to := flag.String("to", "", `store to copy images into: "local" or "cloudinary"`)
+	dryRun := flag.Bool("dry-run", false, "list the images that would be copied without copying them")
+	flag.Parse()
+
+	logger.InitLogger()
+	config.LoadEnvFile()
+
+	local := storage.NewLocalStore(config.IMAGE_STORE_DIR, config.IMAGE_STORE_URL)
+	cloud := storage.NewCloudinaryStore(config.InitCloudinary(), config.CLOUDINARY_CLOUD_NAME)
+
+	var target, source storage.ImageStore
+	switch *to {
+	case "local":
+		target, source = local, cloud
+	case "cloudinary":
+		target, source = cloud, local
+	default:
+		fmt.Fprintln(os.Stderr, `-to must be "local" or "cloudinary"`)
+		os.Exit(2)
+	}
+
+	config.DBconnect()
+
+	ctx := context.Background()
+	copied := map[string]string{}
+	var failed int
+	for _, col := range imageColumns() {
+		var urls []string
+		if err := config.DB.Table(col.Table).
+			Distinct(col.Column).
+			Where(col.Column+" <> '' AND "+col.Column+" IS NOT NULL AND "+col.Column+" <> ?", os.Getenv("DEFAULT_PROFILE_PIC")).
+			Pluck(col.Column, &urls).Error; err != nil {
+			logger.Log.Fatal("Failed to list images", zap.String("table", col.Table), zap.Error(err))
+		}
+
+		for _, oldURL := range urls {
+			if target.Owns(oldURL) {
+				continue
+			}
+			if *dryRun {
+				fmt.Printf("%s.%s: %s\n", col.Table, col.Column, oldURL)
+				continue
+			}
+
+			newURL, ok := copied[oldURL]
+			if !ok {
+				var err error
+				newURL, err = copyImage(ctx, source, target, col.Folder, oldURL)
+				if err != nil {
+					logger.Log.Error("Failed to copy image", zap.String("url", oldURL), zap.Error(err))
+					failed++
+					continue
+				}
+				copied[oldURL] = newURL
+			}
+
+			if err := config.DB.Table(col.Table).Where(col.Column+" = ?", oldURL).
+				Update(col.Column, newURL).Error; err != nil {
+				logger.Log.Error("Failed to update image URL", zap.String("table", col.Table), zap.String("url", oldURL), zap.Error(err))
+				failed++
+				continue
+			}
+			logger.Log.Info("Image migrated", zap.String("table", col.Table), zap.String("from", oldURL), zap.String("to", newURL))
+		}
+	}
+
+	fmt.Printf("copied %d images, %d failures\n", len(copied), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// copyImage reads the image from the store that holds it, or over HTTP for
+// any other absolute URL, and saves it in target.
+func copyImage(ctx context.Context, source, target storage.ImageStore, folder, url string) (string, error) {
+	var (
+		image io.ReadCloser
+		err   error
+	)
+	if source.Owns(url) {
+		image, err = source.Open(ctx, url)
+	} else {
+		image, err = storage.Fetch(ctx, url)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer image.Close()
+	return target.Save(ctx, folder, url, image)
+}
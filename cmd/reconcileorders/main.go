@@ -0,0 +1,46 @@
+// Command reconcileorders checks that stored order amounts add up to the
+// paisa, over the whole order history or the last few days, and lists the
+// orders that don't.
+//
+//	go run ./cmd/reconcileorders
+//	go run ./cmd/reconcileorders -days 30
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"go.uber.org/zap"
+)
+
+func main() {
+	days := flag.Int("days", 0, "only check orders placed in the last this many days; 0 checks every order")
+	flag.Parse()
+
+	logger.InitLogger()
+	config.LoadEnvFile()
+	config.DBconnect()
+
+	var since time.Time
+	if *days > 0 {
+		since = time.Now().AddDate(0, 0, -*days)
+	}
+
+	mismatches, err := services.ReconcileOrders(config.DB, since)
+	if err != nil {
+		logger.Log.Fatal("Failed to reconcile orders", zap.Error(err))
+	}
+	for _, mismatch := range mismatches {
+		fmt.Printf("order %d (%s): items %s, header %s, subtotals %s\n", mismatch.OrderID, mismatch.OrderUID,
+			mismatch.ItemsDifference, mismatch.HeaderDifference, mismatch.SubtotalDifference)
+	}
+	fmt.Printf("%d orders do not reconcile\n", len(mismatches))
+	if len(mismatches) > 0 {
+		os.Exit(1)
+	}
+}
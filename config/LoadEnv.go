@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
 	"github.com/joho/godotenv"
@@ -9,8 +10,31 @@ import (
 )
  
 var (
-	RAZORPAY_KEY_ID     string
-	RAZORPAY_KEY_SECRET string
+	RAZORPAY_KEY_ID           string
+	RAZORPAY_KEY_SECRET       string
+	PAYMENT_GATEWAY           string
+	PAYMENT_GATEWAY_STUB_FAIL bool
+	NOTIFIER                  string
+	NOTIFIER_FILE             string
+	MAILER                    string
+	SENDGRID_API_KEY          string
+	SMTP_HOST                 string
+	SMTP_PORT                 string
+	SMTP_USERNAME             string
+	SMTP_PASSWORD             string
+	MAIL_DIR                  string
+	MAIL_FROM                 string
+	MAIL_FROM_NAME            string
+	IMAGE_STORE               string
+	IMAGE_STORE_DIR           string
+	IMAGE_STORE_URL           string
+	CLOUDINARY_CLOUD_NAME     string
+	TWO_FACTOR_KEY            string
+	RATE_LIMIT_STORE          string
+	// TRUSTED_PROXIES are the proxies whose X-Forwarded-For gin believes.
+	// Empty means the server is not behind a proxy and the client IP is the
+	// address the request came from.
+	TRUSTED_PROXIES []string
 )
 
 func LoadEnvFile() {
@@ -24,6 +48,61 @@ func LoadEnvFile() {
 
 	RAZORPAY_KEY_ID = os.Getenv("RAZORPAY_KEY_ID")
 	RAZORPAY_KEY_SECRET = os.Getenv("RAZORPAY_KEY_SECRET")
+	PAYMENT_GATEWAY = os.Getenv("PAYMENT_GATEWAY")
+	if PAYMENT_GATEWAY == "" {
+		PAYMENT_GATEWAY = "razorpay"
+	}
+	PAYMENT_GATEWAY_STUB_FAIL = os.Getenv("PAYMENT_GATEWAY_STUB_FAIL") == "true"
+	NOTIFIER = os.Getenv("NOTIFIER")
+	if NOTIFIER == "" {
+		NOTIFIER = "email"
+	}
+	NOTIFIER_FILE = os.Getenv("NOTIFIER_FILE")
+	MAILER = os.Getenv("MAILER")
+	if MAILER == "" {
+		MAILER = "sendgrid"
+	}
+	SENDGRID_API_KEY = os.Getenv("SENDGRID_API_KEY")
+	SMTP_HOST = os.Getenv("SMTP_HOST")
+	SMTP_PORT = os.Getenv("SMTP_PORT")
+	SMTP_USERNAME = os.Getenv("SMTP_USERNAME")
+	SMTP_PASSWORD = os.Getenv("SMTP_PASSWORD")
+	MAIL_DIR = os.Getenv("MAIL_DIR")
+	MAIL_FROM = os.Getenv("MAIL_FROM")
+	if MAIL_FROM == "" {
+		MAIL_FROM = CompanyConfig.Email
+	}
+	MAIL_FROM_NAME = os.Getenv("MAIL_FROM_NAME")
+	if MAIL_FROM_NAME == "" {
+		MAIL_FROM_NAME = CompanyConfig.Name + " E-Commerce"
+	}
+	IMAGE_STORE = os.Getenv("IMAGE_STORE")
+	if IMAGE_STORE == "" {
+		IMAGE_STORE = "cloudinary"
+	}
+	IMAGE_STORE_DIR = os.Getenv("IMAGE_STORE_DIR")
+	if IMAGE_STORE_DIR == "" {
+		IMAGE_STORE_DIR = "uploads"
+	}
+	IMAGE_STORE_URL = os.Getenv("IMAGE_STORE_URL")
+	if IMAGE_STORE_URL == "" {
+		IMAGE_STORE_URL = "/uploads"
+	}
+	CLOUDINARY_CLOUD_NAME = os.Getenv("CLOUDINARY_CLOUD_NAME")
+	TWO_FACTOR_KEY = os.Getenv("TWO_FACTOR_KEY")
+	if TWO_FACTOR_KEY == "" {
+		TWO_FACTOR_KEY = os.Getenv("SECRETKEY")
+	}
+	RATE_LIMIT_STORE = os.Getenv("RATE_LIMIT_STORE")
+	if RATE_LIMIT_STORE == "" {
+		RATE_LIMIT_STORE = "memory"
+	}
+	TRUSTED_PROXIES = nil
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			TRUSTED_PROXIES = append(TRUSTED_PROXIES, proxy)
+		}
+	}
 	IsConfigErr = true
 	ConfigErr = nil
 }
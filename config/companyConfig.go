@@ -13,15 +13,23 @@ var CompanyConfig = struct {
 	Name         string
 	Address1     string
 	Address2     string
+	State        string
+	GSTIN        string
 	Email        string
+	Website      string
 	LogoURL      string
+	EmailLogoURL string
 	LogoFilePath string
 }{
 	Name:         "LAPTIX",
 	Address1:     "Laptix Ecom Pvt.Ltd.KINFRA SDF Building,",
 	Address2:     "Kakkanchery,Malapuram, 673634",
+	State:        "Kerala",
+	GSTIN:        "32AAACL0000A1Z5",
 	Email:        "laptixinfo@gmail.com",
+	Website:      "https://www.laptix.com",
 	LogoURL:      "https://res.cloudinary.com/dghzlcoco/image/upload/v1740498507/text-1740498489427_ir9mat.png",
+	EmailLogoURL: "https://res.cloudinary.com/dghzlcoco/image/upload/v1742683014/text-1742682998645_rtgwj1.png",
 	LogoFilePath: "company_logo.png",
 }
 
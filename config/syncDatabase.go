@@ -1,12 +1,19 @@
 package config
 
 import (
+	"fmt"
+
 	models "github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
 	"go.uber.org/zap"
 )
  
 func SyncDatabase() {
+	backfillShippingDiscount := !DB.Migrator().HasColumn(&models.Order{}, "shipping_discount")
+	backfillStatusHistory := !DB.Migrator().HasTable(&models.OrderStatusHistory{})
+	backfillItemQuantities := !DB.Migrator().HasColumn(&models.OrderItem{}, "active_quantity")
+	migrateMoneyColumns()
+
 	err := DB.AutoMigrate(
 		&models.AdminModel{}, &models.UserAuth{}, &models.Categories{}, &models.ProductDetail{}, &models.ProductImage{},
 		&models.ProductOffer{}, models.ProductDescription{}, &models.ProductVariantsImage{}, models.ProductVariantDetails{}, &models.ProductSpecification{},
@@ -14,6 +21,11 @@ func SyncDatabase() {
 		&models.Coupon{}, &models.OfferByCategory{}, &models.Order{}, &models.OrderItem{}, &models.Rating{},
 		&models.Review{}, &models.ShippingAddress{}, &models.Wallet{}, &models.WalletGiftCard{}, &models.Wishlist{},
 		&models.WishlistItem{}, &models.PaymentDetail{}, &models.WalletTransaction{}, &models.ReferralAccount{}, &models.ReferalHistory{}, &models.ReturnRequest{},
+		&models.ProductSearchDocument{}, &models.FilterableSpecification{}, &models.TaxRule{},
+		&models.ShippingZone{}, &models.ShippingSlab{}, &models.PinCodeServiceability{},
+		&models.OrderStatusHistory{}, &models.Refund{}, &models.ScheduledJob{}, &models.OutboxMessage{}, &models.UserSession{}, &models.AdminAuditLog{},
+		&models.TwoFactorAuth{}, &models.TwoFactorRecoveryCode{}, &models.TwoFactorChallenge{}, &models.RateLimitBucket{}, &models.AccountLockout{},
+		&models.CheckoutPayment{}, &models.ExchangeRate{},
 	)
 	if err != nil {
 		logger.Log.Error("Failed to migrate models", zap.Error(err))
@@ -21,8 +33,91 @@ func SyncDatabase() {
 		ConfigErr = err
 	}
 	DB.Exec("CREATE INDEX idx_order_items_created_at_product_variant_id ON order_items (created_at, product_variant_id)")
+	if backfillShippingDiscount {
+		// Orders placed before shipping rules got free shipping by waiving the flat 100.
+		DB.Exec("UPDATE orders SET shipping_discount = 100 WHERE shipping_charge = 0")
+	}
+	if backfillItemQuantities {
+		// Items were always cancelled or returned as a whole before partial quantities.
+		DB.Exec(`UPDATE order_items SET
+			active_quantity = CASE WHEN order_status IN ('Cancelled', 'Returned') THEN 0 ELSE quantity END,
+			cancelled_quantity = CASE WHEN order_status = 'Cancelled' THEN quantity ELSE 0 END,
+			returned_quantity = CASE WHEN order_status = 'Returned' THEN quantity ELSE 0 END`)
+	}
+	DB.Exec(`CREATE OR REPLACE FUNCTION reject_order_status_history_change() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'order_status_histories is append-only';
+		END;
+		$$ LANGUAGE plpgsql`)
+	DB.Exec("DROP TRIGGER IF EXISTS order_status_histories_append_only ON order_status_histories")
+	DB.Exec(`CREATE TRIGGER order_status_histories_append_only BEFORE UPDATE OR DELETE ON order_status_histories
+		FOR EACH ROW EXECUTE FUNCTION reject_order_status_history_change()`)
+	if backfillStatusHistory {
+		// Rebuild the history of existing items from the milestone dates kept on order_items.
+		DB.Exec(`INSERT INTO order_status_histories (order_item_id, from_status, to_status, actor_type, actor_id, note, created_at)
+			SELECT id, COALESCE(LAG(status) OVER (PARTITION BY id ORDER BY seq), ''), status, 'System', 0, 'Imported from order records', at
+			FROM (
+				SELECT id, 'Pending' AS status, created_at AS at, 1 AS seq FROM order_items
+				UNION ALL SELECT id, 'Confirmed', created_at, 2 FROM order_items
+					WHERE order_status NOT IN ('Pending', 'Order Not Placed', 'Failed')
+				UNION ALL SELECT id, 'Shipped', shipped_date, 3 FROM order_items WHERE shipped_date > '0001-01-02'
+				UNION ALL SELECT id, 'Out For Delivery', out_of_delivery_date, 4 FROM order_items WHERE out_of_delivery_date > '0001-01-02'
+				UNION ALL SELECT id, 'Delivered', delivery_date, 5 FROM order_items WHERE delivery_date > '0001-01-02'
+				UNION ALL SELECT id, order_status, CASE WHEN cancel_date > '0001-01-02' THEN cancel_date ELSE updated_at END, 6 FROM order_items
+					WHERE order_status IN ('Cancelled', 'Returned')
+				UNION ALL SELECT id, order_status, updated_at, 6 FROM order_items
+					WHERE order_status IN ('Order Not Placed', 'Failed')
+			) milestones`)
+	}
 	logger.Log.Info("Models migrated")
 	DownloadLogo()
 	IsConfigErr = true
 	ConfigErr = nil
 }
+
+// moneyColumns were double precision before amounts were kept to the paisa.
+var moneyColumns = [][2]string{
+	{"wallets", "balance"},
+	{"wallet_transactions", "amount"},
+	{"wallet_transactions", "last_balance"},
+	{"wallet_gift_cards", "gift_card_value"},
+	{"referral_accounts", "balance"},
+	{"referal_histories", "reward"},
+	{"coupons", "discount_value"},
+	{"coupons", "max_discount_value"},
+	{"reserved_coupons", "coupon_discount_amount"},
+	{"orders", "tax"},
+	{"orders", "coupon_value"},
+}
+
+// MoneyColumnsMigrated is set when this start converted a money column, so
+// the order history can be reconciled once afterwards.
+var MoneyColumnsMigrated bool
+
+// migrateMoneyColumns turns the remaining double precision money columns
+// into numeric(10,2), rounding what they hold to the paisa. It runs before
+// AutoMigrate so the rounding is explicit, and skips columns already done.
+func migrateMoneyColumns() {
+	for _, column := range moneyColumns {
+		table, name := column[0], column[1]
+		var current struct {
+			DataType     string
+			NumericScale *int
+		}
+		if err := DB.Raw(`SELECT data_type, numeric_scale FROM information_schema.columns
+			WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?`, table, name).
+			Scan(&current).Error; err != nil {
+			logger.Log.Error("Failed to read money column type", zap.String("table", table), zap.String("column", name), zap.Error(err))
+			continue
+		}
+		if current.DataType == "" || (current.DataType == "numeric" && current.NumericScale != nil && *current.NumericScale == 2) {
+			continue
+		}
+		if err := DB.Exec(fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s TYPE numeric(10,2) USING ROUND(%s::numeric, 2)`, table, name, name)).Error; err != nil {
+			logger.Log.Error("Failed to migrate money column", zap.String("table", table), zap.String("column", name), zap.Error(err))
+			continue
+		}
+		logger.Log.Info("Migrated money column to numeric", zap.String("table", table), zap.String("column", name))
+		MoneyColumnsMigrated = true
+	}
+}
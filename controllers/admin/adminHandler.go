@@ -1,35 +1,29 @@
 package controllers
 
 import (
+	"crypto/subtle"
 	"errors"
 	"net/http"
-	"time" 
+	"strings"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 var RoleAdmin = "Admin"
 
 func ShowLoginPage(c *gin.Context) {
-	tokenString, err := c.Cookie("jwtTokensAdmin")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			c.Redirect(http.StatusSeeOther, "/admin/dashboard")
-			return
-		}
+	if _, ok := middleware.Authenticate(c, RoleAdmin); ok {
+		c.Redirect(http.StatusSeeOther, "/admin/dashboard")
+		return
 	}
 
 	c.HTML(http.StatusOK, "adminLogin.html", nil)
@@ -56,6 +50,11 @@ func AdminLoginHandler(c *gin.Context) {
 		return
 	}
 
+	if helper.AccountLocked(c, ratelimit.ScopeAdminLogin, input.Email) {
+		logger.Log.Warn("Login attempt on locked admin account", zap.String("email", input.Email))
+		return
+	}
+
 	if err := config.DB.Where("email = ?", input.Email).First(&admin).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
             logger.Log.Error("Invalid Email", zap.Error(err))
@@ -67,37 +66,67 @@ func AdminLoginHandler(c *gin.Context) {
 		return
 	}
 
-	if admin.Password != input.Password {
+	if !checkAdminPassword(&admin, input.Password) {
         logger.Log.Error("Invalid Password")
+		if helper.RecordFailedAttempt(c, ratelimit.ScopeAdminLogin, input.Email) {
+			return
+		}
 		helper.RespondWithError(c, http.StatusUnauthorized, "Invalid Password", "Invalid Password", "")
 		return
 	}
 
-	token, err := middleware.GenerateJWT(admin.ID, admin.Email, RoleAdmin)
-	if err != nil {
-        logger.Log.Error("Failed to generate JWT tokens", zap.Error(err))
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to generate JWT tokens", "Failed to generate JWT tokens", "")
+	if !admin.IsActive {
+		logger.Log.Warn("Deactivated admin tried to log in", zap.String("email", admin.Email))
+		helper.RespondWithError(c, http.StatusForbidden, "Account deactivated", "Your admin account has been deactivated", "")
 		return
 	}
 
-	c.SetCookie("jwtTokensAdmin", token, int((time.Hour * 6).Seconds()), "/", "", false, true)
+	// Two-factor authentication is mandatory for admins. Admins who have not
+	// set it up yet are enrolled on the next page.
+	if err := middleware.BeginTwoFactor(c, admin.ID, admin.Email, RoleAdmin); err != nil {
+		logger.Log.Error("Failed to start two-factor challenge", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to start two-factor authentication", "Something Went Wrong", "")
+		return
+	}
 
-    logger.Log.Info("Admin Logined successfully")
+	logger.Log.Info("Admin password verified, waiting for two-factor code", zap.String("email", admin.Email))
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Login successful",
-		"token":   token,
-		"code":    http.StatusOK,
+		"status":   "two_factor_required",
+		"message":  "Enter the code from your authenticator app",
+		"redirect": "/admin/login/2fa",
+		"code":     http.StatusOK,
 	})
 }
 
+// checkAdminPassword compares against the bcrypt hash. Admins created before
+// passwords were hashed still have a plain text password, which is hashed on
+// their next successful login.
+func checkAdminPassword(admin *models.AdminModel, password string) bool {
+	if strings.HasPrefix(admin.Password, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)) == nil
+	}
+	if subtle.ConstantTimeCompare([]byte(admin.Password), []byte(password)) != 1 {
+		return false
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Log.Error("Failed to hash admin password", zap.Uint("adminID", admin.ID), zap.Error(err))
+		return true
+	}
+	if err := config.DB.Model(admin).Update("password", string(hashed)).Error; err != nil {
+		logger.Log.Error("Failed to store hashed admin password", zap.Uint("adminID", admin.ID), zap.Error(err))
+	}
+	return true
+}
+
 func ShowSettings(c *gin.Context) {
     logger.Log.Info("Admin Settings Open successfully")
 	c.HTML(http.StatusOK, "settings.html", nil)
 }
 
 func AdminLogoutHandler(c *gin.Context) {
-	c.SetCookie("jwtTokensAdmin", "", -1, "/", "", false, true)
+	middleware.EndSession(c, RoleAdmin)
     logger.Log.Info("Admin Logout successfully")
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/rbac"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const auditLogPageSize = 50
+
+type auditLogRow struct {
+	CreatedAt  string
+	AdminEmail string
+	AdminRole  string
+	Action     string
+	EntityType string
+	EntityID   uint
+	Changes    []string
+	IPAddress  string
+}
+
+// ShowAuditLog lists staff changes, newest first, filtered by entity type,
+// entity ID or admin.
+func ShowAuditLog(c *gin.Context) {
+	logger.Log.Info("Requested to show audit log")
+
+	entityType := c.Query("entity")
+	entityID, _ := strconv.ParseUint(c.Query("entity_id"), 10, 64)
+	adminID, _ := strconv.ParseUint(c.Query("admin_id"), 10, 64)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := config.DB.Model(&models.AdminAuditLog{})
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID > 0 {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if adminID > 0 {
+		query = query.Where("admin_id = ?", adminID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var entries []models.AdminAuditLog
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * auditLogPageSize).Limit(auditLogPageSize).Find(&entries).Error; err != nil {
+		logger.Log.Error("Failed to fetch audit log", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch audit log", "Something Went Wrong", "")
+		return
+	}
+
+	var admins []models.AdminModel
+	config.DB.Unscoped().Select("id", "email").Order("email").Find(&admins)
+
+	rows := make([]auditLogRow, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, auditLogRow{
+			CreatedAt:  entry.CreatedAt.Format("02 Jan 2006 15:04:05"),
+			AdminEmail: entry.AdminEmail,
+			AdminRole:  rbac.Label(entry.AdminRole),
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			Changes:    describeChanges(entry.Changes),
+			IPAddress:  entry.IPAddress,
+		})
+	}
+
+	totalPages := int((total + auditLogPageSize - 1) / auditLogPageSize)
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	logger.Log.Info("Audit log fetched successfully", zap.Int64("total", total))
+	c.HTML(http.StatusOK, "auditLog.html", gin.H{
+		"Entries":    rows,
+		"Entities":   audit.Entities,
+		"Admins":     admins,
+		"Entity":     entityType,
+		"EntityID":   entityID,
+		"AdminID":    uint(adminID),
+		"Total":      total,
+		"Page":       page,
+		"TotalPages": totalPages,
+		"PrevPage":   page - 1,
+		"NextPage":   page + 1,
+		"HasNext":    page < totalPages,
+	})
+}
+
+// describeChanges turns the stored JSON into "field: from → to" lines.
+func describeChanges(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	var changes audit.Changes
+	if err := json.Unmarshal([]byte(raw), &changes); err != nil {
+		return []string{raw}
+	}
+
+	lines := make([]string, 0, len(changes))
+	for _, field := range sortedKeys(changes) {
+		change := changes[field]
+		switch {
+		case change.From == nil:
+			lines = append(lines, fmt.Sprintf("%s: %v", field, change.To))
+		case change.To == nil:
+			lines = append(lines, fmt.Sprintf("%s: %v (removed)", field, change.From))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %v → %v", field, change.From, change.To))
+		}
+	}
+	return lines
+}
+
+func sortedKeys(changes audit.Changes) []string {
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
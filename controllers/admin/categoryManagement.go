@@ -8,6 +8,7 @@ import (
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -134,6 +135,9 @@ func EditCategory(c *gin.Context) {
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update category", "Something Went Wrong", "")
 		return
 	}
+	if err := services.RefreshCategorySearch(category.ID); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.String("CategoryID", categoryID), zap.Error(err))
+	}
 	logger.Log.Info("Categoty Updated Successfully")
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "OK",
@@ -8,6 +8,7 @@ import (
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
@@ -68,21 +69,23 @@ func ShowCoupon(c *gin.Context) {
 	})
 }
 
+type addCouponInput struct {
+	CouponCode        string `json:"code" binding:"required"`
+	Discription       string `json:"description" binding:"required"`
+	CouponType        string `json:"type" binding:"required"`
+	DiscountValue     string `json:"value" binding:"required"`
+	ApplicableProduct string `json:"appliedTo" binding:"required"`
+	MinOrdervalue     string `json:"minOrderValue" binding:"required"`
+	MaxDiscountValue  string `json:"maxDiscount" binding:"required"`
+	MaxUseCount       string `json:"usageLimit" binding:"required"`
+	ValidFrom         string `json:"validDate" binding:"required"`
+	ExpirationDate    string `json:"expiryDate" binding:"required"`
+}
+
 func AddCoupon(c *gin.Context) {
 	logger.Log.Info("Requested to Add Coupon")
 
-	var couponInput struct {
-		CouponCode        string `json:"code" binding:"required"`
-		Discription       string `json:"description" binding:"required"`
-		CouponType        string `json:"type" binding:"required"`
-		DiscountValue     string `json:"value" binding:"required"`
-		ApplicableProduct string `json:"appliedTo" binding:"required"`
-		MinOrdervalue     string `json:"minOrderValue" binding:"required"`
-		MaxDiscountValue  string `json:"maxDiscount" binding:"required"`
-		MaxUseCount       string `json:"usageLimit" binding:"required"`
-		ValidFrom         string `json:"validDate" binding:"required"`
-		ExpirationDate    string `json:"expiryDate" binding:"required"`
-	}
+	var couponInput addCouponInput
 
 	if err := c.ShouldBindJSON(&couponInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -169,12 +172,22 @@ func AddCoupon(c *gin.Context) {
 		Status:           status,
 	}
 
-	if err := config.DB.Create(&couponFixed).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Create(&couponFixed).Error; err != nil {
 		logger.Log.Error("Failed to create coupon", zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusBadRequest, "Failed to create coupon code already exist", "Failed to create coupon code already exist", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionCreate, audit.EntityCoupon, couponFixed.ID, couponChanges(models.Coupon{}, couponFixed)); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("couponCode", couponFixed.CouponCode), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create coupon", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Coupon added successfully", zap.String("couponCode", couponFixed.CouponCode))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -194,19 +207,33 @@ func DeleteCoupon(c *gin.Context) {
 		return
 	}
 
+	changes := audit.Changes{}
+	changes.Removed("code", coupon.CouponCode)
+	changes.Set("status", coupon.Status, "Deleted")
 	coupon.Status = "Deleted"
 
-	if err := config.DB.Save(&coupon).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Save(&coupon).Error; err != nil {
 		logger.Log.Error("Failed to update coupon status", zap.String("couponID", couponID), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete coupon", "Failed to delete coupon", "")
 		return
 	}
 
-	if err := config.DB.Delete(&coupon).Error; err != nil {
+	if err := tx.Delete(&coupon).Error; err != nil {
 		logger.Log.Error("Failed to delete coupon", zap.String("couponID", couponID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete coupon", "Failed to delete coupon", "")
+		return
+	}
+
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionDelete, audit.EntityCoupon, coupon.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("couponID", couponID), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete coupon", "Failed to delete coupon", "")
 		return
 	}
+	tx.Commit()
 
 	logger.Log.Info("Coupon deleted successfully", zap.String("couponID", couponID))
 	c.JSON(http.StatusOK, gin.H{
@@ -236,22 +263,24 @@ func CouponDetails(c *gin.Context) {
 	})
 }
 
+type updateCouponInput struct {
+	CouponCode       string `json:"code"`
+	Description      string `json:"description"`
+	CouponType       string `json:"type"`
+	DiscountValue    string `json:"value"`
+	AppliedTo        string `json:"appliedTo"`
+	MinOrderValue    string `json:"minOrderValue"`
+	MaxDiscountValue string `json:"maxDiscount"`
+	UsageLimit       string `json:"usageLimit"`
+	ValidDate        string `json:"validDate"`
+	ExpiryDate       string `json:"expiryDate"`
+}
+
 func UpdateCoupon(c *gin.Context) {
 	couponID := c.Param("id")
 	logger.Log.Info("Requested to Update Coupon", zap.String("couponID", couponID))
 
-	var request struct {
-		CouponCode       string `json:"code"`
-		Description      string `json:"description"`
-		CouponType       string `json:"type"`
-		DiscountValue    string `json:"value"`
-		AppliedTo        string `json:"appliedTo"`
-		MinOrderValue    string `json:"minOrderValue"`
-		MaxDiscountValue string `json:"maxDiscount"`
-		UsageLimit       string `json:"usageLimit"`
-		ValidDate        string `json:"validDate"`
-		ExpiryDate       string `json:"expiryDate"`
-	}
+	var request updateCouponInput
 
 	if err := c.ShouldBindJSON(&request); err != nil {
 		logger.Log.Error("Invalid request data", zap.String("couponID", couponID), zap.Error(err))
@@ -313,6 +342,7 @@ func UpdateCoupon(c *gin.Context) {
 		return
 	}
 
+	before := coupon
 	coupon.CouponCode = strings.ToUpper(request.CouponCode)
 	coupon.Discription = request.Description
 	coupon.CouponType = request.CouponType
@@ -346,12 +376,22 @@ func UpdateCoupon(c *gin.Context) {
 		coupon.Status = "Scheduled"
 	}
 
-	if err := config.DB.Save(&coupon).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Save(&coupon).Error; err != nil {
 		logger.Log.Error("Failed to update coupon", zap.String("couponID", couponID), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update coupon", "Something Went Wrong", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionUpdate, audit.EntityCoupon, coupon.ID, couponChanges(before, coupon)); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("couponID", couponID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update coupon", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Coupon updated successfully", zap.String("couponID", couponID))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -359,3 +399,24 @@ func UpdateCoupon(c *gin.Context) {
 		"code":    200,
 	})
 }
+
+// couponChanges lists the coupon fields that differ between before and
+// after, or every field when before is empty because the coupon is new.
+func couponChanges(before, after models.Coupon) audit.Changes {
+	changes := audit.Changes{}
+	set := changes.Set
+	if before.ID == 0 {
+		set = func(field string, _, to interface{}) { changes.Added(field, to) }
+	}
+	set("code", before.CouponCode, after.CouponCode)
+	set("type", before.CouponType, after.CouponType)
+	set("discount_value", before.DiscountValue, after.DiscountValue)
+	set("max_discount_value", before.MaxDiscountValue, after.MaxDiscountValue)
+	set("min_order_value", before.MinOrderValue, after.MinOrderValue)
+	set("max_use_count", before.MaxUseCount, after.MaxUseCount)
+	set("applicable_for", before.ApplicableFor, after.ApplicableFor)
+	set("valid_from", before.ValidFrom.Format("2006-01-02"), after.ValidFrom.Format("2006-01-02"))
+	set("expiration_date", before.ExpirationDate.Format("2006-01-02"), after.ExpirationDate.Format("2006-01-02"))
+	set("status", before.Status, after.Status)
+	return changes
+}
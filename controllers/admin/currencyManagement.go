@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const exchangeRateHistorySize = 50
+
+func ShowCurrencies(c *gin.Context) {
+	logger.Log.Info("Requested to show currencies")
+
+	latest, err := currency.CurrentRates(config.DB)
+	if err != nil {
+		logger.Log.Error("Failed to fetch exchange rates", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch exchange rates", "Something Went Wrong", "")
+		return
+	}
+
+	type currencyRow struct {
+		currency.Currency
+		HasRate   bool
+		Rate      float64
+		Source    string
+		UpdatedAt time.Time
+	}
+	var currencies []currencyRow
+	for _, supported := range currency.Supported {
+		if supported.Code == currency.Base {
+			continue
+		}
+		row := currencyRow{Currency: supported}
+		if rate, ok := latest[supported.Code]; ok {
+			row.HasRate = true
+			row.Rate = rate.Rate
+			row.Source = rate.Source
+			row.UpdatedAt = rate.CreatedAt
+		}
+		currencies = append(currencies, row)
+	}
+
+	var history []models.ExchangeRate
+	if err := config.DB.Order("created_at DESC, id DESC").Limit(exchangeRateHistorySize).Find(&history).Error; err != nil {
+		logger.Log.Error("Failed to fetch exchange rate history", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch exchange rates", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Currencies fetched successfully", zap.Int("rates", len(latest)))
+	c.HTML(http.StatusOK, "currencies.html", gin.H{
+		"Base":       currency.Base,
+		"Currencies": currencies,
+		"History":    history,
+	})
+}
+
+// saveExchangeRates saves the rates as the newest for their currencies and
+// records each change in the audit log, all in one transaction.
+func saveExchangeRates(c *gin.Context, inputs []currency.RateInput, source string) ([]models.ExchangeRate, error) {
+	actor := helper.AuditActor(c)
+
+	tx := config.DB.Begin()
+	previous, err := currency.CurrentRates(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	rates, err := currency.SetRates(tx, inputs, source, actor.AdminID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, rate := range rates {
+		changes := audit.Changes{}
+		action := audit.ActionCreate
+		if old, ok := previous[rate.CurrencyCode]; ok {
+			action = audit.ActionUpdate
+			changes.Set("rate", old.Rate, rate.Rate)
+		} else {
+			changes.Added("rate", rate.Rate)
+		}
+		changes.Added("currency", rate.CurrencyCode)
+		changes.Added("source", rate.Source)
+		if err := audit.Record(tx, actor, action, audit.EntityExchangeRate, rate.ID, changes); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func SetExchangeRate(c *gin.Context) {
+	logger.Log.Info("Requested to set exchange rate")
+
+	code := strings.TrimSpace(c.PostForm("currency"))
+	rate, err := strconv.ParseFloat(strings.TrimSpace(c.PostForm("rate")), 64)
+	if err != nil || rate <= 0 {
+		logger.Log.Error("Invalid exchange rate", zap.String("currency", code), zap.String("rate", c.PostForm("rate")))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid rate", "Rate must be a number above zero", "")
+		return
+	}
+
+	rates, err := saveExchangeRates(c, []currency.RateInput{{Code: code, Rate: rate}}, currency.SourceManual)
+	if errors.Is(err, currency.ErrUnsupported) {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid currency", "Choose a supported currency", "")
+		return
+	}
+	if err != nil || len(rates) == 0 {
+		logger.Log.Error("Failed to save exchange rate", zap.String("currency", code), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save exchange rate", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Exchange rate saved successfully",
+		zap.String("currency", rates[0].CurrencyCode),
+		zap.Float64("rate", rates[0].Rate))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Exchange rate saved successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+// ImportExchangeRates reads a CSV with the columns currency,rate, where rate
+// is the rupees one unit of the currency is worth. A header row is skipped.
+func ImportExchangeRates(c *gin.Context) {
+	logger.Log.Info("Requested to import exchange rates")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		logger.Log.Error("Exchange rate file missing", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "File is required", "Select a CSV file to import", "")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Log.Error("Failed to open exchange rate file", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid file", "Unable to read the uploaded file", "")
+		return
+	}
+	defer file.Close()
+
+	inputs, err := currency.ParseRates(file)
+	if err != nil {
+		logger.Log.Error("Failed to parse exchange rate file", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid file", err.Error(), "")
+		return
+	}
+	if len(inputs) == 0 {
+		helper.RespondWithError(c, http.StatusBadRequest, "Empty file", "The file has no exchange rates", "")
+		return
+	}
+
+	rates, err := saveExchangeRates(c, inputs, currency.SourceImport)
+	if err != nil {
+		logger.Log.Error("Failed to import exchange rates", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to import exchange rates", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Exchange rates imported successfully", zap.Int("count", len(rates)))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": fmt.Sprintf("%d exchange rates imported successfully", len(rates)),
+		"code":    http.StatusOK,
+	})
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type jobView struct {
+	Name         string
+	Description  string
+	Interval     string
+	IsPaused     bool
+	IsRunning    bool
+	IsTriggered  bool
+	LastStarted  string
+	LastDuration string
+	LastError    string
+	LastRunOn    string
+	RunCount     int64
+	FailureCount int64
+}
+
+func ShowJobs(c *gin.Context) {
+	logger.Log.Info("Requested to show background jobs")
+
+	rows, err := jobs.List(config.DB)
+	if err != nil {
+		logger.Log.Error("Failed to fetch jobs", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch jobs", "Something Went Wrong", "")
+		return
+	}
+
+	var views []jobView
+	for _, row := range rows {
+		view := jobView{
+			Name:         row.Name,
+			Description:  row.Description,
+			Interval:     (time.Duration(row.IntervalSeconds) * time.Second).String(),
+			IsPaused:     row.IsPaused,
+			LastStarted:  "Never",
+			LastError:    row.LastError,
+			LastRunOn:    row.LastRunOn,
+			RunCount:     row.RunCount,
+			FailureCount: row.FailureCount,
+		}
+		if row.LastStartedAt != nil {
+			view.LastStarted = row.LastStartedAt.Format("02 Jan 2006 03:04:05 PM")
+			view.IsRunning = row.LastFinishedAt == nil || row.LastFinishedAt.Before(*row.LastStartedAt)
+			view.IsTriggered = row.RunRequestedAt != nil && row.RunRequestedAt.After(*row.LastStartedAt)
+		} else {
+			view.IsTriggered = row.RunRequestedAt != nil
+		}
+		if row.LastFinishedAt != nil {
+			view.LastDuration = (time.Duration(row.LastDurationMs) * time.Millisecond).String()
+		}
+		views = append(views, view)
+	}
+
+	c.HTML(http.StatusOK, "jobs.html", gin.H{
+		"Jobs": views,
+	})
+}
+
+func respondJobError(c *gin.Context, name string, err error) {
+	if errors.Is(err, jobs.ErrUnknownJob) {
+		helper.RespondWithError(c, http.StatusNotFound, "Job not found", "Job not found", "")
+		return
+	}
+	logger.Log.Error("Failed to update job", zap.String("job", name), zap.Error(err))
+	helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update job", "Something Went Wrong", "")
+}
+
+func TriggerJob(c *gin.Context) {
+	name := c.Param("name")
+	logger.Log.Info("Requested to trigger job", zap.String("job", name))
+
+	if err := jobs.Trigger(config.DB, name); err != nil {
+		respondJobError(c, name, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Job will run within a few seconds",
+		"code":    http.StatusOK,
+	})
+}
+
+func ToggleJobPause(c *gin.Context) {
+	name := c.Param("name")
+	paused := c.PostForm("paused") == "true"
+	logger.Log.Info("Requested to change job pause", zap.String("job", name), zap.Bool("paused", paused))
+
+	if err := jobs.SetPaused(config.DB, name, paused); err != nil {
+		respondJobError(c, name, err)
+		return
+	}
+
+	message := "Job resumed"
+	if paused {
+		message = "Job paused"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": message,
+		"code":    http.StatusOK,
+	})
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/rbac"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type lockedAccountRow struct {
+	models.AccountLockout
+	ScopeLabel string
+	CanUnlock  bool
+}
+
+// ShowLockedAccounts lists the accounts locked after repeated failed logins,
+// OTPs or gift card codes.
+func ShowLockedAccounts(c *gin.Context) {
+	logger.Log.Info("Requested to show locked accounts")
+
+	lockouts, err := ratelimit.Locked(config.DB)
+	if err != nil {
+		logger.Log.Error("Failed to fetch locked accounts", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch locked accounts", "Something Went Wrong", "")
+		return
+	}
+
+	canUnlockAdmins := false
+	if admin, err := middleware.CurrentAdmin(c); err == nil {
+		canUnlockAdmins = rbac.Can(admin.Role, rbac.PermStaff)
+	}
+
+	rows := make([]lockedAccountRow, 0, len(lockouts))
+	for _, lockout := range lockouts {
+		rows = append(rows, lockedAccountRow{
+			AccountLockout: lockout,
+			ScopeLabel:     ratelimit.Policies[lockout.Scope].Label,
+			CanUnlock:      lockout.Scope != ratelimit.ScopeAdminLogin || canUnlockAdmins,
+		})
+	}
+
+	logger.Log.Info("Locked accounts fetched successfully", zap.Int("count", len(rows)))
+	c.HTML(http.StatusOK, "lockedAccounts.html", gin.H{
+		"Lockouts": rows,
+	})
+}
+
+func UnlockAccount(c *gin.Context) {
+	logger.Log.Info("Requested to unlock account")
+
+	lockoutID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid lockout", "Invalid lockout ID", "")
+		return
+	}
+
+	tx := config.DB.Begin()
+	lockout, err := ratelimit.Unlock(tx, uint(lockoutID))
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			helper.RespondWithError(c, http.StatusNotFound, "Lockout not found", "This account is no longer locked", "")
+			return
+		}
+		logger.Log.Error("Failed to unlock account", zap.Uint64("lockoutID", lockoutID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to unlock account", "Something Went Wrong", "")
+		return
+	}
+
+	actor := helper.AuditActor(c)
+	if lockout.Scope == ratelimit.ScopeAdminLogin && !rbac.Can(actor.Role, rbac.PermStaff) {
+		tx.Rollback()
+		logger.Log.Warn("Admin tried to unlock an admin account without permission", zap.Uint("adminID", actor.AdminID))
+		helper.RespondWithError(c, http.StatusForbidden, "Permission denied", "Only super admins can unlock admin accounts", "")
+		return
+	}
+
+	changes := audit.Changes{}
+	changes.Removed("scope", lockout.Scope)
+	changes.Removed("account", lockout.Account)
+	changes.Removed("locked_until", lockout.LockedUntil)
+	if err := audit.Record(tx, actor, audit.ActionUnlock, audit.EntityAccountLockout, lockout.ID, changes); err != nil {
+		tx.Rollback()
+		logger.Log.Error("Failed to record audit entry", zap.Uint64("lockoutID", lockoutID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to unlock account", "Something Went Wrong", "")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Log.Error("Failed to commit unlock", zap.Uint64("lockoutID", lockoutID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to unlock account", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Account unlocked",
+		zap.String("scope", lockout.Scope),
+		zap.String("account", lockout.Account))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Account unlocked",
+		"code":    http.StatusOK,
+	})
+}
@@ -1,15 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/imageproc"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
 	"github.com/anfastk/E-Commerce-Website/services"
-	"github.com/anfastk/E-Commerce-Website/utils"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -39,13 +41,13 @@ func ShowProductsAdmin(c *gin.Context) {
         Preload("Product")
         
     if searchQuery != "" {
-        query = query.Where("product_name ILIKE ?", "%"+searchQuery+"%")
+        query = services.OrderBySearchRank(services.ApplyProductSearch(query, searchQuery), searchQuery)
     }
     if categoryID != "" {
-        query = query.Where("category_id = ?", categoryID)
+        query = query.Where("product_variant_details.category_id = ?", categoryID)
     }
     
-    result := query.Order("created_at DESC").Find(&variants)
+    result := query.Order("product_variant_details.created_at DESC").Find(&variants)
     if result.Error != nil {
         logger.Log.Error("Failed to fetch product variants", zap.Error(result.Error))
         helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch product variants", "Failed to fetch product variants", "")
@@ -142,12 +144,34 @@ func AddMainProductDetails(c *gin.Context) {
 		return
 	}
 
+	weight, _ := strconv.ParseFloat(c.PostForm("weight"), 64)
+	shippingSurcharge, _ := strconv.ParseFloat(c.PostForm("shipping_surcharge"), 64)
+	dispatchDays, err := strconv.Atoi(c.DefaultPostForm("dispatch_days", "1"))
+	if err != nil || dispatchDays < 1 {
+		logger.Log.Error("Invalid dispatch days", zap.String("dispatchDays", c.PostForm("dispatch_days")))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid dispatch days", "Dispatch days must be at least 1", "")
+		return
+	}
+	if weight < 0 || shippingSurcharge < 0 {
+		logger.Log.Error("Invalid shipping details",
+			zap.Float64("weight", weight),
+			zap.Float64("shippingSurcharge", shippingSurcharge))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid shipping details", "Weight and surcharge cannot be negative", "")
+		return
+	}
+
 	product := models.ProductDetail{
-		ProductName:    c.PostForm("product_name"),
-		CategoryID:     uint(categoryID),
-		BrandName:      c.PostForm("brand_name"),
-		IsCODAvailable: c.PostForm("cod_available") == "YES",
-		IsReturnable:   c.PostForm("return_available") == "YES",
+		ProductName:       c.PostForm("product_name"),
+		CategoryID:        uint(categoryID),
+		BrandName:         c.PostForm("brand_name"),
+		HSNCode:           c.PostForm("hsn_code"),
+		Weight:            weight,
+		ShippingSurcharge: shippingSurcharge,
+		DispatchDays:      dispatchDays,
+		IsCODAvailable:    c.PostForm("cod_available") == "YES",
+		IsReturnable:      c.PostForm("return_available") == "YES",
 	}
 
 	if err := tx.Create(&product).Error; err != nil {
@@ -157,25 +181,21 @@ func AddMainProductDetails(c *gin.Context) {
 		return
 	}
 
-	cld := config.InitCloudinary()
 	form, _ := c.MultipartForm()
 	if form != nil {
 		if productImage, ok := form.File["product_image"]; ok && len(productImage) > 0 {
-			fileHeader := productImage[0]
-			file, _ := fileHeader.Open()
-			defer file.Close()
-
-			url, err := utils.UploadImageToCloudinary(file, fileHeader, cld, "products", "")
+			url, renditions, err := services.StoreProductImage(c.Request.Context(), storage.FolderProducts, productImage[0])
 			if err != nil {
-				logger.Log.Error("Failed to upload product image to Cloudinary", zap.Error(err))
+				logger.Log.Error("Failed to upload product image", zap.Error(err))
 				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to upload product image", "Failed to upload product image", "")
+				respondWithImageError(c, err, "Failed to upload product image")
 				return
 			}
 
 			image := models.ProductImage{
-				ProductImages: url,
-				ProductID:     product.ID,
+				ProductImages:   url,
+				ProductID:       product.ID,
+				ImageRenditions: renditions,
 			}
 			if err := tx.Create(&image).Error; err != nil {
 				logger.Log.Error("Failed to save product image", zap.Error(err))
@@ -296,11 +316,15 @@ func ShowEditMainProduct(c *gin.Context) {
 }
 
 type updateProduct struct {
-	ProductName    string `json:"productname"`
-	CategoryID     string `json:"categoryid"`
-	BrandName      string `json:"brandname"`
-	IsCodAvailable bool   `json:"iscodavailable"`
-	IsReturnable   bool   `json:"isreturnable"`
+	ProductName       string  `json:"productname"`
+	CategoryID        string  `json:"categoryid"`
+	BrandName         string  `json:"brandname"`
+	HSNCode           string  `json:"hsncode"`
+	Weight            float64 `json:"weight"`
+	ShippingSurcharge float64 `json:"shippingsurcharge"`
+	DispatchDays      int     `json:"dispatchdays"`
+	IsCodAvailable    bool    `json:"iscodavailable"`
+	IsReturnable      bool    `json:"isreturnable"`
 }
 
 func EditMainProduct(c *gin.Context) {
@@ -332,10 +356,37 @@ func EditMainProduct(c *gin.Context) {
 		return
 	}
 
+	if updateData.Weight < 0 || updateData.ShippingSurcharge < 0 {
+		logger.Log.Error("Invalid shipping details",
+			zap.Float64("weight", updateData.Weight),
+			zap.Float64("shippingSurcharge", updateData.ShippingSurcharge))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid shipping details", "Weight and surcharge cannot be negative", "")
+		return
+	}
+	if updateData.DispatchDays < 1 {
+		logger.Log.Error("Invalid dispatch days", zap.Int("dispatchDays", updateData.DispatchDays))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid dispatch days", "Dispatch days must be at least 1", "")
+		return
+	}
+
+	if err := tx.Model(&existingProduct).Updates(map[string]interface{}{
+		"weight":             updateData.Weight,
+		"shipping_surcharge": updateData.ShippingSurcharge,
+		"dispatch_days":      updateData.DispatchDays,
+	}).Error; err != nil {
+		logger.Log.Error("Failed to update product shipping details", zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, err.Error(), err.Error(), "")
+		return
+	}
+
 	if err := tx.Model(&existingProduct).Updates(models.ProductDetail{
 		ProductName:    updateData.ProductName,
 		CategoryID:     uint(categoryID),
 		BrandName:      updateData.BrandName,
+		HSNCode:        updateData.HSNCode,
 		IsCODAvailable: updateData.IsCodAvailable,
 		IsReturnable:   updateData.IsReturnable,
 	}).Error; err != nil {
@@ -361,6 +412,9 @@ func EditMainProduct(c *gin.Context) {
 	}
 
 	tx.Commit()
+	if err := services.RefreshProductSearch(existingProduct.ID); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.String("productID", productID), zap.Error(err))
+	}
 	logger.Log.Info("Main product updated successfully", zap.String("productID", productID))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "OK",
@@ -488,6 +542,7 @@ func ReplaceMainProductImage(c *gin.Context) {
 	}
 
 	oldImage := productImage.ProductImages
+	oldRenditions := productImage.ImageRenditions
 
 	form, err := c.FormFile("product_image")
 	if err != nil {
@@ -497,35 +552,25 @@ func ReplaceMainProductImage(c *gin.Context) {
 		return
 	}
 
-	cld := config.InitCloudinary()
-	file, _ := form.Open()
-	url, uploadErr := utils.UploadImageToCloudinary(file, form, cld, "products", "")
+	url, renditions, uploadErr := services.StoreProductImage(c.Request.Context(), storage.FolderProducts, form)
 	if uploadErr != nil {
-		logger.Log.Error("Failed to upload new image to Cloudinary", zap.Error(uploadErr))
+		logger.Log.Error("Failed to upload new image", zap.Error(uploadErr))
 		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to upload product image", "Failed to upload product image", "")
+		respondWithImageError(c, uploadErr, "Failed to upload product image")
 		return
 	}
 
-	if err := tx.Model(&productImage).Update("product_images", url).Error; err != nil {
+	if err := tx.Model(&productImage).Updates(models.ProductImage{ProductImages: url, ImageRenditions: renditions}).Error; err != nil {
 		logger.Log.Error("Failed to update image in database", zap.Error(err))
 		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update image", "Failed to update image", "")
 		return
 	}
 
-	publicID, err := helper.ExtractCloudinaryPublicID(oldImage)
-	if err != nil {
-		logger.Log.Error("Failed to extract Cloudinary public ID", zap.Error(err))
+	if err := services.DeleteProductImage(c.Request.Context(), oldImage, oldRenditions); err != nil {
+		logger.Log.Error("Failed to delete old image", zap.Error(err))
 		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to extract Cloudinary public ID", "Failed to extract Cloudinary public ID", "")
-		return
-	}
-
-	if err := utils.DeleteCloudinaryImage(cld, publicID, c); err != nil {
-		logger.Log.Error("Failed to delete old image from Cloudinary", zap.Error(err))
-		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete image from Cloudinary", "Failed to delete image from Cloudinary", "")
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete old image", "Failed to delete old image", "")
 		return
 	}
 
@@ -536,4 +581,14 @@ func ReplaceMainProductImage(c *gin.Context) {
 		"filename": url,
 		"code":     http.StatusOK,
 	})
-}
\ No newline at end of file
+}
+
+// respondWithImageError reports uploads rejected by the image pipeline as bad
+// requests and anything else as a server error.
+func respondWithImageError(c *gin.Context, err error, message string) {
+	if errors.Is(err, imageproc.ErrTooLarge) || errors.Is(err, imageproc.ErrUnsupportedType) || errors.Is(err, imageproc.ErrDimensions) {
+		helper.RespondWithError(c, http.StatusBadRequest, err.Error(), message, "")
+		return
+	}
+	helper.RespondWithError(c, http.StatusInternalServerError, message, message, "")
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
@@ -103,12 +104,22 @@ func AddProductOffer(c *gin.Context) {
 		Status:          status,
 	}
 
-	if err := config.DB.Create(&productOffer).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Create(&productOffer).Error; err != nil {
 		logger.Log.Error("Failed to save product offer", zap.Int("productID", productID), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save product", "Failed to save product", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionCreate, audit.EntityProductOffer, productOffer.ID, productOfferChanges(models.ProductOffer{}, productOffer)); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("productID", productID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save product", "Failed to save product", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Product offer added successfully", zap.Int("productID", productID), zap.String("offerName", offerName))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
@@ -117,18 +128,20 @@ func AddProductOffer(c *gin.Context) {
 	})
 }
 
+type editProductOfferInput struct {
+	Id              string `json:"offerId"`
+	ProductId       string `json:"productId"`
+	OfferName       string `json:"offerName"`
+	OfferDetails    string `json:"offerDetails"`
+	OfferPercentage string `json:"percentage"`
+	StartDate       string `json:"startDate"`
+	EndDate         string `json:"endDate"`
+}
+
 func UpdateProductOffer(c *gin.Context) {
 	logger.Log.Info("Requested to update product offer")
 
-	var editOfferInput struct {
-		Id              string `json:"offerId"`
-		ProductId       string `json:"productId"`
-		OfferName       string `json:"offerName"`
-		OfferDetails    string `json:"offerDetails"`
-		OfferPercentage string `json:"percentage"`
-		StartDate       string `json:"startDate"`
-		EndDate         string `json:"endDate"`
-	}
+	var editOfferInput editProductOfferInput
 
 	if err := c.ShouldBindJSON(&editOfferInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -209,6 +222,7 @@ func UpdateProductOffer(c *gin.Context) {
 		return
 	}
 
+	before := offer
 	offer.OfferName = editOfferInput.OfferName
 	offer.OfferDetails = editOfferInput.OfferDetails
 	offer.OfferPercentage = offerPercentage
@@ -221,11 +235,21 @@ func UpdateProductOffer(c *gin.Context) {
 		offer.Status = "Active"
 	}
 
-	if err := config.DB.Save(&offer).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Save(&offer).Error; err != nil {
 		logger.Log.Error("Failed to update offer", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update offer", "Something Went Wrong", "")
+		return
+	}
+
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionUpdate, audit.EntityProductOffer, offer.ID, productOfferChanges(before, offer)); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update offer", "Something Went Wrong", "")
 		return
 	}
+	tx.Commit()
 
 	logger.Log.Info("Product offer updated successfully", zap.Int("offerId", offerId), zap.Int("productId", productId))
 	c.JSON(http.StatusOK, gin.H{
@@ -235,12 +259,14 @@ func UpdateProductOffer(c *gin.Context) {
 	})
 }
 
+type deleteProductOfferInput struct {
+	Id string `json:"productId"`
+}
+
 func DeleteProductOffer(c *gin.Context) {
 	logger.Log.Info("Requested to delete product offer")
 
-	var deleteOfferInput struct {
-		Id string `json:"productId"`
-	}
+	var deleteOfferInput deleteProductOfferInput
 
 	if err := c.ShouldBindJSON(&deleteOfferInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -262,12 +288,27 @@ func DeleteProductOffer(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Unscoped().Delete(&Offer).Error; err != nil {
+	changes := audit.Changes{}
+	changes.Removed("product_id", Offer.ProductID)
+	changes.Removed("offer_name", Offer.OfferName)
+	changes.Removed("offer_percentage", Offer.OfferPercentage)
+
+	tx := config.DB.Begin()
+	if err := tx.Unscoped().Delete(&Offer).Error; err != nil {
 		logger.Log.Error("Failed to delete offer", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed To Delete Offer", "Delete Offer Failed", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionDelete, audit.EntityProductOffer, Offer.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed To Delete Offer", "Delete Offer Failed", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Product offer deleted successfully", zap.Int("offerId", offerId))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -276,17 +317,19 @@ func DeleteProductOffer(c *gin.Context) {
 	})
 }
 
+type categoryOfferInput struct {
+	Id               string `json:"categoryId"`
+	OfferName        string `json:"offerName"`
+	OfferDescription string `json:"offerDescription"`
+	OfferValue       string `json:"discount"`
+	StartDate        string `json:"startDate"`
+	EndDate          string `json:"endDate"`
+}
+
 func AddCategoryOffer(c *gin.Context) {
 	logger.Log.Info("Requested to add category offer")
 
-	var addOfferInput struct {
-		Id               string `json:"categoryId"`
-		OfferName        string `json:"offerName"`
-		OfferDescription string `json:"offerDescription"`
-		OfferValue       string `json:"discount"`
-		StartDate        string `json:"startDate"`
-		EndDate          string `json:"endDate"`
-	}
+	var addOfferInput categoryOfferInput
 
 	if err := c.ShouldBindJSON(&addOfferInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -358,12 +401,22 @@ func AddCategoryOffer(c *gin.Context) {
 		EndDate:                 endDate,
 	}
 
-	if err := config.DB.Create(&addOffer).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Create(&addOffer).Error; err != nil {
 		logger.Log.Error("Failed to create category offer", zap.Int("categoryId", categoryId), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create offer", "Failed to create offer", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionCreate, audit.EntityCategoryOffer, addOffer.ID, categoryOfferChanges(models.OfferByCategory{}, addOffer)); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("categoryId", categoryId), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create offer", "Failed to create offer", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Category offer added successfully", zap.Int("categoryId", categoryId), zap.String("offerName", addOfferInput.OfferName))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
@@ -372,17 +425,19 @@ func AddCategoryOffer(c *gin.Context) {
 	})
 }
 
+type editCategoryOfferInput struct {
+	Id               string `json:"offerId"`
+	OfferName        string `json:"offerName"`
+	OfferDescription string `json:"offerDescription"`
+	OfferValue       string `json:"discount"`
+	StartDate        string `json:"startDate"`
+	EndDate          string `json:"endDate"`
+}
+
 func UpdateCategoryOffer(c *gin.Context) {
 	logger.Log.Info("Requested to update category offer")
 
-	var editOfferInput struct {
-		Id               string `json:"offerId"`
-		OfferName        string `json:"offerName"`
-		OfferDescription string `json:"offerDescription"`
-		OfferValue       string `json:"discount"`
-		StartDate        string `json:"startDate"`
-		EndDate          string `json:"endDate"`
-	}
+	var editOfferInput editCategoryOfferInput
 
 	if err := c.ShouldBindJSON(&editOfferInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -444,6 +499,7 @@ func UpdateCategoryOffer(c *gin.Context) {
 		return
 	}
 
+	before := offer
 	offer.CategoryOfferName = editOfferInput.OfferName
 	offer.CategoryOfferPercentage = offerValue
 	offer.OfferDescription = editOfferInput.OfferDescription
@@ -455,12 +511,22 @@ func UpdateCategoryOffer(c *gin.Context) {
 		offer.OfferStatus = "Active"
 	}
 
-	if err := config.DB.Save(&offer).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Save(&offer).Error; err != nil {
 		logger.Log.Error("Failed to update category offer", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update offer", "Something Went Wrong", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionUpdate, audit.EntityCategoryOffer, offer.ID, categoryOfferChanges(before, offer)); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update offer", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Category offer updated successfully", zap.Int("offerId", offerId))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
@@ -469,12 +535,14 @@ func UpdateCategoryOffer(c *gin.Context) {
 	})
 }
 
+type deleteCategoryOfferInput struct {
+	OfferId string `json:"offerId"`
+}
+
 func DeleteCategoryOffer(c *gin.Context) {
 	logger.Log.Info("Requested to delete category offer")
 
-	var DeleteOfferInput struct {
-		OfferId string `json:"offerId"`
-	}
+	var DeleteOfferInput deleteCategoryOfferInput
 
 	if err := c.ShouldBindJSON(&DeleteOfferInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -496,12 +564,27 @@ func DeleteCategoryOffer(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Unscoped().Delete(&Offer).Error; err != nil {
+	changes := audit.Changes{}
+	changes.Removed("category_id", Offer.CategoryID)
+	changes.Removed("offer_name", Offer.CategoryOfferName)
+	changes.Removed("offer_percentage", Offer.CategoryOfferPercentage)
+
+	tx := config.DB.Begin()
+	if err := tx.Unscoped().Delete(&Offer).Error; err != nil {
 		logger.Log.Error("Failed to delete category offer", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed To Delete Offer", "Delete Offer Failed", "")
 		return
 	}
 
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionDelete, audit.EntityCategoryOffer, Offer.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("offerId", offerId), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed To Delete Offer", "Delete Offer Failed", "")
+		return
+	}
+	tx.Commit()
+
 	logger.Log.Info("Category offer deleted successfully", zap.Int("offerId", offerId))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -509,3 +592,35 @@ func DeleteCategoryOffer(c *gin.Context) {
 		"code":    200,
 	})
 }
+
+// productOfferChanges lists the offer fields that differ between before and
+// after, or every field when before is empty because the offer is new.
+func productOfferChanges(before, after models.ProductOffer) audit.Changes {
+	changes := audit.Changes{}
+	set := changes.Set
+	if before.ID == 0 {
+		set = func(field string, _, to interface{}) { changes.Added(field, to) }
+	}
+	set("product_id", before.ProductID, after.ProductID)
+	set("offer_name", before.OfferName, after.OfferName)
+	set("offer_percentage", before.OfferPercentage, after.OfferPercentage)
+	set("start_date", before.StartDate.Format("2006-01-02"), after.StartDate.Format("2006-01-02"))
+	set("end_date", before.EndDate.Format("2006-01-02"), after.EndDate.Format("2006-01-02"))
+	set("status", before.Status, after.Status)
+	return changes
+}
+
+func categoryOfferChanges(before, after models.OfferByCategory) audit.Changes {
+	changes := audit.Changes{}
+	set := changes.Set
+	if before.ID == 0 {
+		set = func(field string, _, to interface{}) { changes.Added(field, to) }
+	}
+	set("category_id", before.CategoryID, after.CategoryID)
+	set("offer_name", before.CategoryOfferName, after.CategoryOfferName)
+	set("offer_percentage", before.CategoryOfferPercentage, after.CategoryOfferPercentage)
+	set("start_date", before.StartDate.Format("2006-01-02"), after.StartDate.Format("2006-01-02"))
+	set("end_date", before.EndDate.Format("2006-01-02"), after.EndDate.Format("2006-01-02"))
+	set("status", before.OfferStatus, after.OfferStatus)
+	return changes
+}
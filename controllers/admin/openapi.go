@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/openapi"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+)
+
+// Operations documents the admin handlers in the OpenAPI document.
+// Request must be the type the handler binds.
+var Operations = []openapi.Description{
+	// Sign in
+	openapi.Describe(AdminLoginHandler, openapi.Operation{Summary: "Sign in to the admin panel", Request: AdminInput{}, Error: helper.ErrorResponse{}}),
+	openapi.Describe(VerifyAdminTwoFactor, openapi.Operation{Summary: "Finish signing in with an authenticator or recovery code", Request: twoFactorCodeInput{}, Error: helper.ErrorResponse{}}),
+
+	// Catalog
+	openapi.Describe(EditMainProduct, openapi.Operation{Summary: "Edit a product", Request: updateProduct{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(EditProductVariant, openapi.Operation{Summary: "Edit a product variant", Request: updateProductVariants{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(UpdateProductSpecification, openapi.Operation{Summary: "Edit a variant's specifications", Request: UpdateSpecification{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(UpdateProductOffer, openapi.Operation{Summary: "Edit a product offer", Request: editProductOfferInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(DeleteProductOffer, openapi.Operation{Summary: "Delete a product offer", Request: deleteProductOfferInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(AddCategory, openapi.Operation{Summary: "Add a category", Request: models.Categories{}, Form: true, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(EditCategory, openapi.Operation{Summary: "Edit a category", Request: models.Categories{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(AddCategoryOffer, openapi.Operation{Summary: "Add a category offer", Request: categoryOfferInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(UpdateCategoryOffer, openapi.Operation{Summary: "Edit a category offer", Request: editCategoryOfferInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(DeleteCategoryOffer, openapi.Operation{Summary: "Delete a category offer", Request: deleteCategoryOfferInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+
+	// Orders
+	openapi.Describe(ChangeOrderStatus, openapi.Operation{Summary: "Move an order item to another status", Request: orderManageData{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(ApproveReturn, openapi.Operation{Summary: "Approve or reject a return request", Request: returnDecisionInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+
+	// Coupons
+	openapi.Describe(AddCoupon, openapi.Operation{Summary: "Add a coupon", Request: addCouponInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(UpdateCoupon, openapi.Operation{Summary: "Edit a coupon", Request: updateCouponInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+
+	// Sales
+	openapi.Describe(GetSalesDashboard, openapi.Operation{Summary: "Sales report page", HTML: true, Security: openapi.AdminCookie}),
+	openapi.Describe(GetSalesData, openapi.Operation{Summary: "Sales figures for a period", Response: SalesDashboardDTO{}, Security: openapi.AdminCookie}),
+
+	// Staff
+	openapi.Describe(AddAdminUser, openapi.Operation{Summary: "Add a staff member", Request: adminUserInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+	openapi.Describe(ChangeAdminRole, openapi.Operation{Summary: "Change a staff member's role", Request: adminRoleInput{}, Error: helper.ErrorResponse{}, Security: openapi.AdminCookie}),
+}
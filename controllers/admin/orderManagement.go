@@ -1,8 +1,8 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,12 +10,13 @@ import (
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/orderlifecycle"
+	"github.com/anfastk/E-Commerce-Website/services"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 func ShowOrderManagement(c *gin.Context) {
@@ -182,6 +183,14 @@ func ordinalSuffix(day int) string {
 	}
 }
 
+// adminStatusActions maps an order status to the action name the order
+// detail page sends to ChangeOrderStatus.
+var adminStatusActions = map[string]string{
+	orderlifecycle.StatusOutForDelivery: "Out for Delivery",
+	orderlifecycle.StatusReturned:       "Return",
+	orderlifecycle.StatusCancelled:      "Cancel",
+}
+
 func ShowOrderDetailManagement(c *gin.Context) {
 	orderItemID := c.Param("id")
 	logger.Log.Info("Requested to show order detail management", zap.String("orderItemID", orderItemID))
@@ -222,17 +231,25 @@ func ShowOrderDetailManagement(c *gin.Context) {
 	}
 
 	productDiscount := (orderItemDetails.ProductRegularPrice - orderItemDetails.ProductSalePrice) * float64(orderItemDetails.Quantity)
-	totalDiscount := productDiscount + orderDetails.CouponDiscountAmount
-	if orderDetails.ShippingCharge == 0 {
-		totalDiscount += 100
-	}
+	totalDiscount := productDiscount + orderDetails.CouponDiscountAmount + orderDetails.ShippingDiscount
 
 	IsReurnRequested := false
 	var returnRequest models.ReturnRequest
-	if err := config.DB.First(&returnRequest, "user_id = ? AND order_item_id = ? AND product_variant_id = ?", UserDetails.ID, orderItemDetails.ID, orderItemDetails.ProductVariantID).Error; err == nil {
+	if err := config.DB.Order("created_at DESC").First(&returnRequest, "user_id = ? AND order_item_id = ? AND product_variant_id = ?", UserDetails.ID, orderItemDetails.ID, orderItemDetails.ProductVariantID).Error; err == nil {
 		IsReurnRequested = true
 	}
 
+	returnQuantity := returnRequest.Quantity
+	if returnQuantity == 0 {
+		returnQuantity = orderItemDetails.ActiveQuantity
+	}
+	var returnRefund float64
+	if IsReurnRequested && returnRequest.Status == "Pending" {
+		if release, err := services.CalculateItemRelease(config.DB, &orderDetails, &orderItemDetails, returnQuantity); err == nil {
+			returnRefund = release.RefundAmount
+		}
+	}
+
 	logger.Log.Info("Order details fetched successfully", zap.String("orderItemID", orderItemID))
 	var allOrderDetails []models.OrderItem
 	if err := config.DB.Find(&allOrderDetails, "order_id = ?", orderDetails.ID).Error; err != nil {
@@ -243,20 +260,34 @@ func ShowOrderDetailManagement(c *gin.Context) {
 	var (
 		allProductDiscount      float64
 		allProductTotalDiscount float64
-		shipCharge              float64
 	)
 
 	allProductDiscount = orderDetails.TotalProductDiscount
-	if orderDetails.ShippingCharge == 0.0 {
-		shipCharge = 100
+	allProductTotalDiscount = allProductDiscount + orderDetails.ShippingCharge + orderDetails.ShippingDiscount + orderDetails.CouponDiscountAmount
+
+	var nextActions []string
+	for _, status := range orderlifecycle.AllowedStatuses(orderlifecycle.AdminActor(c.GetUint("userid")), orderItemDetails.OrderStatus) {
+		if action, ok := adminStatusActions[status]; ok {
+			status = action
+		}
+		nextActions = append(nextActions, status)
 	}
-	allProductTotalDiscount = allProductDiscount + orderDetails.ShippingCharge + shipCharge + orderDetails.CouponDiscountAmount
+
+	history, err := orderlifecycle.FetchHistory(orderItemDetails.ID)
+	if err != nil {
+		logger.Log.Error("Failed to fetch order status history", zap.Uint("orderItemID", orderItemDetails.ID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch order history", "Something Went Wrong", "")
+		return
+	}
+
 	c.HTML(http.StatusOK, "orderDetailsManagement.html", gin.H{
 		"status":                  "success",
 		"message":                 "Order details fetched successfully",
 		"IsReurnRequested":        IsReurnRequested,
 		"ReturnRequest":           returnRequest,
 		"ReturnRequestDate":       returnRequest.CreatedAt.Format("Jan 02, 2006 - 03:04 PM"),
+		"ReturnQuantity":          returnQuantity,
+		"ReturnRefund":            returnRefund,
 		"OrderItem":               orderItemDetails,
 		"OrderDate":               orderItemDetails.CreatedAt.Format("02 Jan 2006"),
 		"Order":                   orderDetails,
@@ -268,20 +299,23 @@ func ShowOrderDetailManagement(c *gin.Context) {
 		"AllProduct":              allOrderDetails,
 		"AllProductDiscount":      allProductDiscount,
 		"AllProductTotalDiscount": allProductTotalDiscount,
+		"NextActions":             nextActions,
+		"StatusHistory":           history,
 	})
 }
 
+type orderManageData struct {
+	OrderId       string `json:"orderId"`
+	NewStatus     string `json:"status"`
+	CurrentStatus string `json:"previousStatus"`
+	CancelReason  string `json:"cancelReason"`
+	OtherReason   string `json:"otherReason"`
+	Note          string `json:"note"`
+}
+
 func ChangeOrderStatus(c *gin.Context) {
 	logger.Log.Info("Requested to change order status")
 
-	type orderManageData struct {
-		OrderId       string `json:"orderId"`
-		NewStatus     string `json:"status"`
-		CurrentStatus string `json:"previousStatus"`
-		CancelReason  string `json:"cancelReason"`
-		OtherReason   string `json:"otherReason"`
-	}
-
 	var updateOrderStatus orderManageData
 	if err := c.ShouldBindJSON(&updateOrderStatus); err != nil {
 		logger.Log.Error("Invalid request data", zap.Error(err))
@@ -313,91 +347,74 @@ func ChangeOrderStatus(c *gin.Context) {
 		return
 	}
 
+	currentTime := time.Now()
+	var newStatus string
+	updates := map[string]interface{}{}
+	note := strings.TrimSpace(updateOrderStatus.Note)
 	switch updateOrderStatus.NewStatus {
 	case "Confirmed":
-		if err := tx.Model(&orderItemDetails).Update("order_status", "Confirmed").Error; err != nil {
-			logger.Log.Error("Failed to update order status to Confirmed", zap.Int("orderItemID", orderItemID), zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
-			return
-		}
+		newStatus = orderlifecycle.StatusConfirmed
 	case "Shipped":
-		if err := tx.Model(&orderItemDetails).Updates(map[string]interface{}{
-			"order_status": "Shipped",
-			"shipped_date": time.Now(),
-		}).Error; err != nil {
-			logger.Log.Error("Failed to update order status to Shipped", zap.Int("orderItemID", orderItemID), zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
-			return
-		}
+		newStatus = orderlifecycle.StatusShipped
+		updates["shipped_date"] = currentTime
 	case "Out for Delivery":
-		if err := tx.Model(&orderItemDetails).Updates(map[string]interface{}{
-			"order_status":         "Out For Delivery",
-			"out_of_delivery_date": time.Now(),
-		}).Error; err != nil {
-			logger.Log.Error("Failed to update order status to Out for Delivery", zap.Int("orderItemID", orderItemID), zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
-			return
-		}
+		newStatus = orderlifecycle.StatusOutForDelivery
+		updates["out_of_delivery_date"] = currentTime
 	case "Delivered":
-		currentTime := time.Now()
-		if err := tx.Model(&orderItemDetails).Updates(map[string]interface{}{
-			"order_status":  "Delivered",
-			"delivery_date": currentTime,
-			"return_date":   currentTime.AddDate(0, 0, 7),
-		}).Error; err != nil {
-			logger.Log.Error("Failed to update order status to Delivered", zap.Int("orderItemID", orderItemID), zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
+		newStatus = orderlifecycle.StatusDelivered
+		updates["delivery_date"] = currentTime
+		updates["return_date"] = currentTime.AddDate(0, 0, 7)
+	case "Return":
+		newStatus = orderlifecycle.StatusReturned
+		updates["return_date"] = currentTime
+	case "Cancel":
+		newStatus = orderlifecycle.StatusCancelled
+		updates["cancel_date"] = currentTime
+		if updateOrderStatus.CancelReason == "other" {
+			updates["reason"] = updateOrderStatus.OtherReason
+			note = updateOrderStatus.OtherReason
+		} else if note == "" {
+			note = updateOrderStatus.CancelReason
+		}
+	default:
+		logger.Log.Error("Unknown order status", zap.String("status", updateOrderStatus.NewStatus))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid Status", "Invalid Status", "")
+		return
+	}
+
+	previousStatus := orderItemDetails.OrderStatus
+	if err := orderlifecycle.Transition(tx, &orderItemDetails, newStatus, orderlifecycle.AdminActor(c.GetUint("userid")), note, updates); err != nil {
+		logger.Log.Error("Failed to update order status",
+			zap.Int("orderItemID", orderItemID),
+			zap.String("currentStatus", orderItemDetails.OrderStatus),
+			zap.String("newStatus", newStatus),
+			zap.Error(err))
+		tx.Rollback()
+		if errors.Is(err, orderlifecycle.ErrInvalidTransition) || errors.Is(err, orderlifecycle.ErrNotPermitted) || errors.Is(err, orderlifecycle.ErrStatusChanged) {
+			helper.RespondWithError(c, http.StatusConflict, "Invalid status change", fmt.Sprintf("Order cannot be moved from %s to %s", orderItemDetails.OrderStatus, newStatus), "")
 			return
 		}
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
+		return
+	}
+
+	switch newStatus {
+	case orderlifecycle.StatusDelivered:
 		if err := tx.Model(&paymentDetails).Update("payment_status", "Completed").Error; err != nil {
 			logger.Log.Error("Failed to update payment status to Completed", zap.Int("orderItemID", orderItemID), zap.Error(err))
 			tx.Rollback()
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update payment status ", "Something Went Wrong", "")
 			return
 		}
-	case "Return":
-		if err := tx.Model(&orderItemDetails).Updates(map[string]interface{}{
-			"order_status": "Returned",
-			"return_date":  time.Now(),
-		}).Error; err != nil {
-			logger.Log.Error("Failed to update order status to Returned", zap.Int("orderItemID", orderItemID), zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
-			return
-		}
+	case orderlifecycle.StatusReturned:
 		if err := tx.Model(&paymentDetails).Update("payment_status", "Refunded").Error; err != nil {
 			logger.Log.Error("Failed to update payment status to Refunded", zap.Int("orderItemID", orderItemID), zap.Error(err))
 			tx.Rollback()
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update payment status ", "Something Went Wrong", "")
 			return
 		}
-	case "Cancel":
-		if updateOrderStatus.CancelReason == "other" {
-			if err := tx.Model(&orderItemDetails).Updates(map[string]interface{}{
-				"order_status": "Cancelled",
-				"reason":       updateOrderStatus.OtherReason,
-				"cancel_date":  time.Now(),
-			}).Error; err != nil {
-				logger.Log.Error("Failed to update order status to Cancelled with other reason", zap.Int("orderItemID", orderItemID), zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
-				return
-			}
-		} else {
-			if err := tx.Model(&orderItemDetails).Updates(map[string]interface{}{
-				"order_status": "Cancelled",
-				"cancel_date":  time.Now(),
-			}).Error; err != nil {
-				logger.Log.Error("Failed to update order status to Cancelled", zap.Int("orderItemID", orderItemID), zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
-				return
-			}
-		}
+	case orderlifecycle.StatusCancelled:
 		if paymentDetails.PaymentMethod == "Cash On Delivery" && paymentDetails.PaymentStatus == "Paid" {
 			if err := tx.Model(&paymentDetails).Update("payment_status", "Refunded").Error; err != nil {
 				logger.Log.Error("Failed to update COD payment status to Refunded", zap.Int("orderItemID", orderItemID), zap.Error(err))
@@ -415,6 +432,18 @@ func ChangeOrderStatus(c *gin.Context) {
 		}
 	}
 
+	changes := audit.Changes{}
+	changes.Set("order_status", previousStatus, newStatus)
+	if note != "" {
+		changes.Added("note", note)
+	}
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionStatusChange, audit.EntityOrderItem, orderItemDetails.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Int("orderItemID", orderItemID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order status ", "Something Went Wrong", "")
+		return
+	}
+
 	tx.Commit()
 	logger.Log.Info("Order status updated successfully",
 		zap.Int("orderItemID", orderItemID),
@@ -427,15 +456,17 @@ func ChangeOrderStatus(c *gin.Context) {
 	})
 }
 
+type returnDecisionInput struct {
+	ReturnRequestID string `json:"requestUID"`
+	OrderID         string `json:"orderId"`
+	Status          string `json:"action"`
+	AdminNotes      string `json:"adminNotes"`
+}
+
 func ApproveReturn(c *gin.Context) {
 	logger.Log.Info("Requested to approve return")
 
-	var input struct {
-		ReturnRequestID string `json:"requestUID"`
-		OrderID         string `json:"orderId"`
-		Status          string `json:"action"`
-		AdminNotes      string `json:"adminNotes"`
-	}
+	var input returnDecisionInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -467,6 +498,8 @@ func ApproveReturn(c *gin.Context) {
 		return
 	}
 
+	previousStatus := returnRequest.Status
+	var refund *models.Refund
 	if input.Status == "Approved" {
 		var orderItems models.OrderItem
 		if err := tx.First(&orderItems, "id = ? AND user_id = ?", ordid, returnRequest.UserID).Error; err != nil {
@@ -476,85 +509,36 @@ func ApproveReturn(c *gin.Context) {
 			return
 		}
 
-		var order models.Order
-		if err := tx.First(&order, "user_id = ? AND id = ?", returnRequest.UserID, orderItems.OrderID).Error; err != nil {
-			logger.Log.Error("Order not found", zap.Uint("orderID", orderItems.OrderID), zap.Error(err))
+		adminActor := orderlifecycle.AdminActor(c.GetUint("userid"))
+		if err := orderlifecycle.Validate(adminActor, orderItems.OrderStatus, orderlifecycle.StatusReturned); err != nil {
+			logger.Log.Warn("Order item cannot be returned",
+				zap.Uint64("orderItemID", ordid),
+				zap.String("status", orderItems.OrderStatus),
+				zap.Error(err))
 			tx.Rollback()
-			helper.RespondWithError(c, http.StatusNotFound, "Order Not Found", "Something Went Wrong", "")
+			helper.RespondWithError(c, http.StatusConflict, "Invalid status change", fmt.Sprintf("A %s order cannot be returned", orderItems.OrderStatus), "")
 			return
 		}
 
-		var allItems []models.OrderItem
-		if err := tx.Find(&allItems, "order_id = ? AND order_status != ? AND order_status != ?", order.ID, "Cancelled", "Returned").Error; err != nil {
-			logger.Log.Error("Order item not found",
-				zap.Error(err))
+		var order models.Order
+		if err := tx.First(&order, "user_id = ? AND id = ?", returnRequest.UserID, orderItems.OrderID).Error; err != nil {
+			logger.Log.Error("Order not found", zap.Uint("orderID", orderItems.OrderID), zap.Error(err))
 			tx.Rollback()
-			helper.RespondWithError(c, http.StatusNotFound, "Order Items Not Found", "Something Went Wrong", "")
+			helper.RespondWithError(c, http.StatusNotFound, "Order Not Found", "Something Went Wrong", "")
 			return
 		}
 
-		var refundAmount float64
-		var total float64
-		for _, val := range allItems {
-			total += val.ProductSalePrice
+		quantity := returnRequest.Quantity
+		if quantity == 0 {
+			quantity = orderItems.ActiveQuantity
 		}
-		productTotal := orderItems.ProductSalePrice * float64(orderItems.Quantity)
-		isCouponRemoved := false
-		IsMinusAmount := false
-		couponAmt := 0.0
-
-		if order.IsCouponApplied {
-			var couponDetails models.Coupon
-			if err := tx.Unscoped().First(&couponDetails, "coupon_code = ?", order.CouponCode).Error; err != nil {
-				logger.Log.Error("Coupon not found",
-					zap.String("couponCode", order.CouponCode),
-					zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusNotFound, "Coupon Not Found", "Something Went Wrong", "")
-				return
-			}
-			if !order.IsCouponFixed {
-				couponAmt = (order.CouponValue * productTotal) / 100
-				if couponAmt > couponDetails.MaxDiscountValue {
-					couponAmt = couponDetails.MaxDiscountValue
-				}
-			}
-			if (total - productTotal) < couponDetails.MinOrderValue {
-				if order.IsCouponFixed {
-					couponAmt = order.CouponValue
-				}
-				refundAmount = orderItems.Total  - couponAmt
-				if refundAmount <= 0 {
-					IsMinusAmount = true
-				}
-				couponDetails.UsersUsedCount -= 1
-				if err := tx.Unscoped().Save(&couponDetails).Error; err != nil {
-					logger.Log.Error("Failed to update coupon",
-						zap.String("couponCode", order.CouponCode),
-						zap.Error(err))
-					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Update Coupon", "Something Went Wrong", "")
-					return
-				} else {
-					isCouponRemoved = true
-				}
-			}
-		} else {
-			refundAmount = orderItems.Total - couponAmt
-		}
-
-		var product models.ProductVariantDetails
-		if err := tx.First(&product, orderItems.ProductVariantID).Error; err != nil {
-			logger.Log.Error("Product not found", zap.Uint("productVariantID", orderItems.ProductVariantID), zap.Error(err))
+		if quantity < 1 || quantity > orderItems.ActiveQuantity {
+			logger.Log.Warn("Return quantity no longer available",
+				zap.Uint64("orderItemID", ordid),
+				zap.Int("quantity", quantity),
+				zap.Int("activeQuantity", orderItems.ActiveQuantity))
 			tx.Rollback()
-			helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Something Went Wrong", "")
-			return
-		}
-		if err := tx.Model(&product).Where("id = ?", orderItems.ProductVariantID).
-			Update("stock_quantity", product.StockQuantity+orderItems.Quantity).Error; err != nil {
-			logger.Log.Error("Failed to update stock quantity", zap.Uint("productVariantID", orderItems.ProductVariantID), zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Stock Reverse Failed", "Stock Update Failed", "/checkout")
+			helper.RespondWithError(c, http.StatusConflict, "Invalid return quantity", fmt.Sprintf("Only %d of this item can be returned", orderItems.ActiveQuantity), "")
 			return
 		}
 
@@ -566,173 +550,52 @@ func ApproveReturn(c *gin.Context) {
 			return
 		}
 
+		release, err := services.ReleaseItemQuantity(tx, &order, &orderItems, quantity, services.ReleaseReturned)
+		if err != nil {
+			logger.Log.Error("Failed to release returned quantity", zap.Uint64("orderItemID", ordid), zap.Int("quantity", quantity), zap.Error(err))
+			tx.Rollback()
+			helper.RespondWithError(c, http.StatusInternalServerError, "Return Failed", "Something Went Wrong", "")
+			return
+		}
+
 		if payment.PaymentStatus == "Completed" {
-			var wallet models.Wallet
-			if err := tx.First(&wallet, "user_id = ?", returnRequest.UserID).Error; err != nil {
-				logger.Log.Error("Wallet not found", zap.Uint("userID", returnRequest.UserID), zap.Error(err))
+			refund, err = services.RecordRefund(tx, &payment, &orderItems, release, services.ReleaseReturned)
+			if err != nil {
+				logger.Log.Error("Failed to record refund", zap.Uint("userID", returnRequest.UserID), zap.Uint64("orderItemID", ordid), zap.Error(err))
 				tx.Rollback()
-				helper.RespondWithError(c, http.StatusNotFound, "User Wallet Not Found", "Something Went Wrong", "")
+				helper.RespondWithError(c, http.StatusInternalServerError, "Refund Failed", "Something Went Wrong", "")
 				return
 			}
-			if IsMinusAmount {
-				lastBalance := wallet.Balance
-				wallet.Balance += -order.CouponDiscountAmount
-				if err := tx.Save(&wallet).Error; err != nil {
-					logger.Log.Error("Failed to update wallet",
-						zap.Uint("userID", returnRequest.UserID),
-						zap.Error(err))
-					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Update Wallet", "Something Went Wrong", "")
-					return
-				}
-
-				receiptID := "rcpt_" + uuid.New().String()
-				rand.Seed(time.Now().UnixNano())
-				transactionID := fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Intn(10000))
-
-				walletTransaction := models.WalletTransaction{
-					UserID:        returnRequest.UserID,
-					WalletID:      wallet.ID,
-					Amount:        order.CouponDiscountAmount,
-					Description:   fmt.Sprintf("Coupon discount adjustment due to partial order cancellation. ₹%.2f deducted from wallet as per refund policy. ORD ID %s", order.CouponDiscountAmount, orderItems.OrderUID),
-					Type:          "Deduct",
-					Receipt:       receiptID,
-					OrderId:       orderItems.OrderUID,
-					LastBalance:   lastBalance,
-					TransactionID: strings.ToUpper(transactionID),
-					PaymentMethod: payment.PaymentMethod,
-				}
-				if err := tx.Create(&walletTransaction).Error; err != nil {
-					logger.Log.Error("Failed to create wallet transaction",
-						zap.Uint("userID", returnRequest.UserID),
-						zap.Error(err))
-					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Create Transaction History", "Something Went Wrong", "")
-					return
-				}
-
-				lastBalance = wallet.Balance
-				wallet.Balance += orderItems.ProductSalePrice + orderItems.Tax
-				if err := tx.Save(&wallet).Error; err != nil {
-					logger.Log.Error("Failed to update wallet",
-						zap.Uint("userID", returnRequest.UserID),
-						zap.Error(err))
-					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Update Wallet", "Something Went Wrong", "")
-					return
-				}
-
-				receiptID = "rcpt_" + uuid.New().String()
-				rand.Seed(time.Now().UnixNano())
-				transactionID = fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Intn(10000))
-
-				walletTransaction = models.WalletTransaction{
-					UserID:        returnRequest.UserID,
-					WalletID:      wallet.ID,
-					Amount:        orderItems.Total,
-					Description:   fmt.Sprintf("Order Refund ORD ID " + orderItems.OrderUID),
-					Type:          "Refund",
-					Receipt:       receiptID,
-					OrderId:       orderItems.OrderUID,
-					LastBalance:   lastBalance,
-					TransactionID: strings.ToUpper(transactionID),
-					PaymentMethod: payment.PaymentMethod,
-				}
-				if err := tx.Create(&walletTransaction).Error; err != nil {
-					logger.Log.Error("Failed to create wallet transaction",
-						zap.Uint("userID", returnRequest.UserID),
-						zap.Error(err))
-					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Create Transaction History", "Something Went Wrong", "")
-					return
-				}
-
-			} else {
-				lastBalance := wallet.Balance
-				wallet.Balance += refundAmount
-				if err := tx.Save(&wallet).Error; err != nil {
-					logger.Log.Error("Failed to update wallet",
-						zap.Uint("userID", returnRequest.UserID),
-						zap.Error(err))
-					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Update Wallet", "Something Went Wrong", "")
-					return
-				}
+		}
 
-				receiptID := "rcpt_" + uuid.New().String()
-				rand.Seed(time.Now().UnixNano())
-				transactionID := fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Intn(10000))
-
-				walletTransaction := models.WalletTransaction{
-					UserID:        returnRequest.UserID,
-					WalletID:      wallet.ID,
-					Amount:        refundAmount,
-					Description:   fmt.Sprintf("Order Refund ORD ID " + orderItems.OrderUID),
-					Type:          "Refund",
-					Receipt:       receiptID,
-					OrderId:       orderItems.OrderUID,
-					LastBalance:   lastBalance,
-					TransactionID: strings.ToUpper(transactionID),
-					PaymentMethod: payment.PaymentMethod,
-				}
-				if err := tx.Create(&walletTransaction).Error; err != nil {
-					logger.Log.Error("Failed to create wallet transaction",
-						zap.Uint("userID", returnRequest.UserID),
-						zap.Error(err))
+		note := fmt.Sprintf("%s (Qty %d of %d)", returnRequest.Reason, quantity, orderItems.Quantity)
+		if input.AdminNotes != "" {
+			note += ": " + input.AdminNotes
+		}
+		if release.IsFullItem {
+			if payment.PaymentStatus == "Completed" {
+				if err := tx.Model(&payment).Where("user_id = ? AND order_item_id = ?", returnRequest.UserID, orderItems.ID).
+					Update("payment_status", "Refunded").Error; err != nil {
+					logger.Log.Error("Failed to update payment status to Refunded", zap.Uint("orderItemID", orderItems.ID), zap.Error(err))
 					tx.Rollback()
-					helper.RespondWithError(c, http.StatusNotFound, "Failed to Create Transaction History", "Something Went Wrong", "")
+					helper.RespondWithError(c, http.StatusInternalServerError, "Payment Status Update Failed", "Payment Status Update Failed", "/checkout")
 					return
 				}
 			}
-			if err := tx.Model(&payment).Where("user_id = ? AND order_item_id = ?", returnRequest.UserID, orderItems.ID).
-				Update("payment_status", "Refunded").Error; err != nil {
-				logger.Log.Error("Failed to update payment status to Refunded", zap.Uint("orderItemID", orderItems.ID), zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Payment Status Update Failed", "Payment Status Update Failed", "/checkout")
-				return
-			}
-		}
-
-		shipCharge := 100
-		if order.SubTotal-orderItems.SubTotal > 1000 {
-			shipCharge = 0
-		}
-
-		if isCouponRemoved {
-			if err := tx.Model(&order).Where("user_id = ? AND id = ?", returnRequest.UserID, order.ID).
-				Updates(map[string]interface{}{
-					"coupon_code":            gorm.Expr("NULL"),
-					"shipping_charge":        shipCharge,
-					"coupon_discount_amount": gorm.Expr("NULL"),
-					"is_coupon_applied":      false,
-				}).Error; err != nil {
-				logger.Log.Error("Failed to update order with coupon removal", zap.Uint("orderID", order.ID), zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Order Status Update Failed", "Order Status Update Failed", "/checkout")
-				return
-			}
-		} else {
-			if err := tx.Model(&order).Where("user_id = ? AND id = ?", returnRequest.UserID, order.ID).
-				Updates(map[string]interface{}{
-					"shipping_charge":        shipCharge,
-					"coupon_discount_amount": order.CouponDiscountAmount-couponAmt,
-				}).Error; err != nil {
-				logger.Log.Error("Failed to update order shipping", zap.Uint("orderID", order.ID), zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Order Status Update Failed", "Order Status Update Failed", "/checkout")
-				return
-			}
-		}
 
-		if err := tx.Model(&orderItems).Where("user_id = ? AND order_id = ?", returnRequest.UserID, order.ID).
-			Updates(map[string]interface{}{
-				"order_status":           "Returned",
+			if err := orderlifecycle.Transition(tx, &orderItems, orderlifecycle.StatusReturned, adminActor, note, map[string]interface{}{
 				"reason":                 returnRequest.Reason,
 				"cancel_date":            time.Now(),
 				"expected_delivery_date": time.Now(),
 				"return_date":            time.Now(),
-			}).Error; err != nil {
-			logger.Log.Error("Failed to update order item to Returned", zap.Uint64("orderItemID", ordid), zap.Error(err))
+			}); err != nil {
+				logger.Log.Error("Failed to update order item to Returned", zap.Uint64("orderItemID", ordid), zap.Error(err))
+				tx.Rollback()
+				helper.RespondWithError(c, http.StatusInternalServerError, "Order Status Update Failed", "Order Status Update Failed", "/checkout")
+				return
+			}
+		} else if err := orderlifecycle.Record(tx, orderItems.ID, orderItems.OrderStatus, orderItems.OrderStatus, adminActor, "Returned "+note); err != nil {
+			logger.Log.Error("Failed to record partial return", zap.Uint64("orderItemID", ordid), zap.Error(err))
 			tx.Rollback()
 			helper.RespondWithError(c, http.StatusInternalServerError, "Order Status Update Failed", "Order Status Update Failed", "/checkout")
 			return
@@ -749,11 +612,29 @@ func ApproveReturn(c *gin.Context) {
 		return
 	}
 
+	action := audit.ActionReject
+	if input.Status == "Approved" {
+		action = audit.ActionApprove
+	}
+	changes := audit.Changes{}
+	changes.Set("status", previousStatus, input.Status)
+	changes.Added("order_item_id", returnRequest.OrderItemID)
+	if input.AdminNotes != "" {
+		changes.Added("admin_notes", input.AdminNotes)
+	}
+	if err := audit.Record(tx, helper.AuditActor(c), action, audit.EntityReturnRequest, returnRequest.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("requestUID", input.ReturnRequestID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update status", "Something Went Wrong", "")
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		logger.Log.Error("Failed to commit transaction", zap.Error(err))
 		helper.RespondWithError(c, http.StatusInternalServerError, "Transaction Failed", "Order cancellation failed", "")
 		return
 	}
+	services.ProcessRefunds(config.DB, []*models.Refund{refund})
 	logger.Log.Info("Return request processed successfully",
 		zap.String("requestUID", input.ReturnRequestID),
 		zap.String("status", input.Status))
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"github.com/anfastk/E-Commerce-Website/pkg/outbox"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const outboxPageSize = 50
+
+// ShowOutbox lists outbox messages, dead letters by default.
+func ShowOutbox(c *gin.Context) {
+	logger.Log.Info("Requested to show outbox")
+
+	status := c.DefaultQuery("status", outbox.StatusDead)
+	if status == "All" {
+		status = ""
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := config.DB.Model(&models.OutboxMessage{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var messages []models.OutboxMessage
+	if err := query.Order("created_at DESC").Offset((page - 1) * outboxPageSize).Limit(outboxPageSize).Find(&messages).Error; err != nil {
+		logger.Log.Error("Failed to fetch outbox messages", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch outbox", "Something Went Wrong", "")
+		return
+	}
+
+	// Payloads hold OTPs and gift card codes; admins see everything else.
+	for i := range messages {
+		messages[i].Payload = notifier.Redact(messages[i].EventType, messages[i].Payload)
+	}
+
+	var pendingCount, deadCount int64
+	config.DB.Model(&models.OutboxMessage{}).Where("status = ?", outbox.StatusPending).Count(&pendingCount)
+	config.DB.Model(&models.OutboxMessage{}).Where("status = ?", outbox.StatusDead).Count(&deadCount)
+
+	totalPages := int((total + outboxPageSize - 1) / outboxPageSize)
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if status == "" {
+		status = "All"
+	}
+
+	logger.Log.Info("Outbox messages fetched successfully", zap.Int64("total", total))
+	c.HTML(http.StatusOK, "outbox.html", gin.H{
+		"Messages":     messages,
+		"Status":       status,
+		"Statuses":     []string{outbox.StatusDead, outbox.StatusPending, outbox.StatusSent, "All"},
+		"PendingCount": pendingCount,
+		"DeadCount":    deadCount,
+		"Total":        total,
+		"Page":         page,
+		"TotalPages":   totalPages,
+		"PrevPage":     page - 1,
+		"NextPage":     page + 1,
+		"HasNext":      page < totalPages,
+	})
+}
+
+func RequeueOutboxMessage(c *gin.Context) {
+	logger.Log.Info("Requested to requeue outbox message")
+
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid message", "Invalid message ID", "")
+		return
+	}
+
+	if err := outbox.Requeue(config.DB, uint(messageID)); err != nil {
+		if errors.Is(err, outbox.ErrNotRequeueable) {
+			helper.RespondWithError(c, http.StatusConflict, "Message cannot be requeued", "Only dead messages can be requeued", "")
+			return
+		}
+		logger.Log.Error("Failed to requeue outbox message", zap.Uint64("messageID", messageID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to requeue message", "Something Went Wrong", "")
+		return
+	}
+	outbox.Kick(config.DB)
+
+	logger.Log.Info("Outbox message requeued", zap.Uint64("messageID", messageID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Message queued for delivery",
+		"code":    http.StatusOK,
+	})
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+const pinCodePageSize = 100
+
+func ShowPinCodes(c *gin.Context) {
+	logger.Log.Info("Requested to show pin codes")
+
+	search := strings.TrimSpace(c.Query("search"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := config.DB.Model(&models.PinCodeServiceability{})
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where("pin_code LIKE ? OR city ILIKE ? OR state ILIKE ?", search+"%", like, like)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var pinCodes []models.PinCodeServiceability
+	if err := query.Order("pin_code ASC").Offset((page - 1) * pinCodePageSize).Limit(pinCodePageSize).Find(&pinCodes).Error; err != nil {
+		logger.Log.Error("Failed to fetch pin codes", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch pin codes", "Something Went Wrong", "")
+		return
+	}
+
+	totalPages := int((total + pinCodePageSize - 1) / pinCodePageSize)
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	logger.Log.Info("Pin codes fetched successfully", zap.Int64("total", total))
+	c.HTML(http.StatusOK, "pinCodes.html", gin.H{
+		"PinCodes":           pinCodes,
+		"Search":             search,
+		"Total":              total,
+		"Page":               page,
+		"TotalPages":         totalPages,
+		"PrevPage":           page - 1,
+		"NextPage":           page + 1,
+		"HasNext":            page < totalPages,
+		"DefaultTransitDays": services.DefaultTransitDays,
+	})
+}
+
+func parseServiceFlag(value string, fallback bool) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return fallback, nil
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid flag %q", value)
+}
+
+func parsePinCodeRow(pinCode, serviceable, cod, transitDays, city, state string) (models.PinCodeServiceability, error) {
+	pinCode = strings.TrimSpace(pinCode)
+	if len(pinCode) != 6 {
+		return models.PinCodeServiceability{}, errors.New("pin code must be 6 digits")
+	}
+	if _, err := strconv.Atoi(pinCode); err != nil {
+		return models.PinCodeServiceability{}, errors.New("pin code must be 6 digits")
+	}
+
+	isServiceable, err := parseServiceFlag(serviceable, true)
+	if err != nil {
+		return models.PinCodeServiceability{}, err
+	}
+	isCODAvailable, err := parseServiceFlag(cod, true)
+	if err != nil {
+		return models.PinCodeServiceability{}, err
+	}
+
+	days := services.DefaultTransitDays
+	if strings.TrimSpace(transitDays) != "" {
+		days, err = strconv.Atoi(strings.TrimSpace(transitDays))
+		if err != nil || days < 0 {
+			return models.PinCodeServiceability{}, errors.New("transit days must be a positive number")
+		}
+	}
+
+	return models.PinCodeServiceability{
+		PinCode:        pinCode,
+		City:           strings.TrimSpace(city),
+		State:          strings.TrimSpace(state),
+		IsServiceable:  isServiceable,
+		IsCODAvailable: isCODAvailable,
+		TransitDays:    days,
+	}, nil
+}
+
+// upsertPinCodes writes explicit false values too, which a plain Create would
+// replace with the column defaults.
+func upsertPinCodes(pinCodes []models.PinCodeServiceability) error {
+	return config.DB.Select("*").Omit("id").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pin_code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"city", "state", "is_serviceable", "is_cod_available", "transit_days", "updated_at"}),
+	}).CreateInBatches(&pinCodes, 500).Error
+}
+
+func AddPinCode(c *gin.Context) {
+	logger.Log.Info("Requested to add pin code")
+
+	pinCode, err := parsePinCodeRow(c.PostForm("pinCode"), c.PostForm("isServiceable"), c.PostForm("isCodAvailable"),
+		c.PostForm("transitDays"), c.PostForm("city"), c.PostForm("state"))
+	if err != nil {
+		logger.Log.Error("Invalid pin code details", zap.String("pinCode", c.PostForm("pinCode")), zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid pin code", err.Error(), "")
+		return
+	}
+
+	if err := upsertPinCodes([]models.PinCodeServiceability{pinCode}); err != nil {
+		logger.Log.Error("Failed to save pin code", zap.String("pinCode", pinCode.PinCode), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save pin code", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Pin code saved successfully", zap.String("pinCode", pinCode.PinCode))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Pin code saved successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+// ImportPinCodes reads a CSV with the columns
+// pincode,serviceable,cod,transit_days[,city,state]. A header row is skipped
+// and existing pin codes are overwritten.
+func ImportPinCodes(c *gin.Context) {
+	logger.Log.Info("Requested to import pin codes")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		logger.Log.Error("Pin code file missing", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "File is required", "Select a CSV file to import", "")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Log.Error("Failed to open pin code file", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid file", "Unable to read the uploaded file", "")
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var pinCodes []models.PinCodeServiceability
+	seen := make(map[string]int)
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			logger.Log.Error("Failed to parse pin code file", zap.Int("line", line), zap.Error(err))
+			helper.RespondWithError(c, http.StatusBadRequest, "Invalid file", fmt.Sprintf("Line %d: %v", line, err), "")
+			return
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "pincode") {
+			continue
+		}
+		if len(record) < 4 {
+			helper.RespondWithError(c, http.StatusBadRequest, "Invalid file", fmt.Sprintf("Line %d: expected pincode,serviceable,cod,transit_days", line), "")
+			return
+		}
+		for len(record) < 6 {
+			record = append(record, "")
+		}
+
+		pinCode, err := parsePinCodeRow(record[0], record[1], record[2], record[3], record[4], record[5])
+		if err != nil {
+			logger.Log.Error("Invalid pin code row", zap.Int("line", line), zap.Error(err))
+			helper.RespondWithError(c, http.StatusBadRequest, "Invalid file", fmt.Sprintf("Line %d: %v", line, err), "")
+			return
+		}
+		if index, ok := seen[pinCode.PinCode]; ok {
+			pinCodes[index] = pinCode
+			continue
+		}
+		seen[pinCode.PinCode] = len(pinCodes)
+		pinCodes = append(pinCodes, pinCode)
+	}
+
+	if len(pinCodes) == 0 {
+		helper.RespondWithError(c, http.StatusBadRequest, "Empty file", "The file has no pin codes", "")
+		return
+	}
+
+	if err := upsertPinCodes(pinCodes); err != nil {
+		logger.Log.Error("Failed to import pin codes", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to import pin codes", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Pin codes imported successfully", zap.Int("count", len(pinCodes)))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": fmt.Sprintf("%d pin codes imported successfully", len(pinCodes)),
+		"code":    http.StatusOK,
+	})
+}
+
+func DeletePinCode(c *gin.Context) {
+	logger.Log.Info("Requested to delete pin code")
+
+	id := c.Param("id")
+	if err := config.DB.Unscoped().Delete(&models.PinCodeServiceability{}, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Failed to delete pin code", zap.String("pinCodeID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete pin code", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Pin code deleted successfully", zap.String("pinCodeID", id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Pin code removed successfully",
+		"code":    http.StatusOK,
+	})
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func ShowProductFilters(c *gin.Context) {
+	logger.Log.Info("Requested to show product filters")
+
+	var filters []models.FilterableSpecification
+	if err := config.DB.Order("sort_order ASC, specification_key ASC").Find(&filters).Error; err != nil {
+		logger.Log.Error("Failed to fetch filterable specifications", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch filters", "Something Went Wrong", "")
+		return
+	}
+
+	var specificationKeys []string
+	if err := config.DB.Model(&models.ProductSpecification{}).
+		Where("is_deleted = ? AND specification_key NOT IN (?)", false,
+			config.DB.Model(&models.FilterableSpecification{}).Select("specification_key")).
+		Distinct("specification_key").
+		Order("specification_key").
+		Pluck("specification_key", &specificationKeys).Error; err != nil {
+		logger.Log.Error("Failed to fetch specification keys", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch specification keys", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Product filters fetched successfully", zap.Int("filterCount", len(filters)))
+	c.HTML(http.StatusOK, "productFilters.html", gin.H{
+		"Filters":           filters,
+		"SpecificationKeys": specificationKeys,
+	})
+}
+
+func AddProductFilter(c *gin.Context) {
+	logger.Log.Info("Requested to add product filter")
+
+	key := strings.TrimSpace(c.PostForm("specificationKey"))
+	label := strings.TrimSpace(c.PostForm("label"))
+	if key == "" {
+		logger.Log.Error("Specification key is missing")
+		helper.RespondWithError(c, http.StatusBadRequest, "Specification key is required", "Specification key is required", "")
+		return
+	}
+	if label == "" {
+		label = key
+	}
+
+	var count int64
+	config.DB.Model(&models.FilterableSpecification{}).Where("specification_key = ?", key).Count(&count)
+	if count > 0 {
+		logger.Log.Warn("Specification key already filterable", zap.String("specificationKey", key))
+		helper.RespondWithError(c, http.StatusConflict, "Filter already exists", "Filter already exists", "")
+		return
+	}
+
+	filter := models.FilterableSpecification{
+		SpecificationKey: key,
+		Label:            label,
+	}
+	if err := config.DB.Create(&filter).Error; err != nil {
+		logger.Log.Error("Failed to create product filter", zap.String("specificationKey", key), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add filter", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Product filter added successfully", zap.String("specificationKey", key))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Filter added successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func DeleteProductFilter(c *gin.Context) {
+	logger.Log.Info("Requested to delete product filter")
+
+	id := c.Param("id")
+	var filter models.FilterableSpecification
+	if err := config.DB.First(&filter, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Product filter not found", zap.String("filterID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Filter not found", "Filter not found", "")
+		return
+	}
+
+	if err := config.DB.Unscoped().Delete(&filter).Error; err != nil {
+		logger.Log.Error("Failed to delete product filter", zap.String("filterID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete filter", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Product filter deleted successfully", zap.String("filterID", id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Filter removed successfully",
+		"code":    http.StatusOK,
+	})
+}
@@ -8,9 +8,10 @@ import (
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
 	"github.com/anfastk/E-Commerce-Website/services"
-	"github.com/anfastk/E-Commerce-Website/utils"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -74,7 +75,6 @@ func AddProductVariants(c *gin.Context) {
 	}
 
 	tx := config.DB.Begin()
-	cld := config.InitCloudinary()
 
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -83,6 +83,7 @@ func AddProductVariants(c *gin.Context) {
 		return
 	}
 
+	var variantIDs []uint
 	for i := 0; i < formLength; i++ {
 		regularPrice, err := strconv.ParseFloat(regularPrices[i], 64)
 		salePrice, err2 := strconv.ParseFloat(salePrices[i], 64)
@@ -119,6 +120,7 @@ func AddProductVariants(c *gin.Context) {
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save product variant", "Database Error", "")
 			return
 		}
+		variantIDs = append(variantIDs, productVariant.ID)
 
 		files := form.File[fmt.Sprintf("product_images[%d][]", i)]
 		logger.Log.Info("Processing variant", zap.Int("index", i), zap.Int("fileCount", len(files)))
@@ -128,26 +130,18 @@ func AddProductVariants(c *gin.Context) {
 		}
 
 		for j, fileHeader := range files {
-			file, err := fileHeader.Open()
-			if err != nil {
-				logger.Log.Error("Failed to open file", zap.Int("variantIndex", i), zap.Int("fileIndex", j), zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to process image", "File Error", "")
-				return
-			}
-			defer file.Close()
-
-			url, err := utils.UploadImageToCloudinary(file, fileHeader, cld, "ProductVariants", "")
+			url, renditions, err := services.StoreProductImage(c.Request.Context(), storage.FolderProductVariants, fileHeader)
 			if err != nil {
 				logger.Log.Error("Failed to upload product image", zap.Int("variantIndex", i), zap.Int("imageIndex", j), zap.Error(err))
 				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to upload product image", "Upload Error", "")
+				respondWithImageError(c, err, "Failed to upload product image")
 				return
 			}
 
 			variantImage := models.ProductVariantsImage{
 				ProductVariantsImages: url,
 				ProductVariantID:      productVariant.ID,
+				ImageRenditions:       renditions,
 			}
 			if err := tx.Create(&variantImage).Error; err != nil {
 				logger.Log.Error("Failed to save product image", zap.Uint("variantID", productVariant.ID), zap.Error(err))
@@ -159,6 +153,9 @@ func AddProductVariants(c *gin.Context) {
 	}
 
 	tx.Commit()
+	if err := services.RefreshVariantSearch(variantIDs...); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.Int("productID", productID), zap.Error(err))
+	}
 	redirectURL := "/admin/products/variant/details?product_id=" + strconv.Itoa(int(productID))
 	logger.Log.Info("Product variants added successfully", zap.Int("productID", productID), zap.Int("variantCount", formLength))
 	c.JSON(http.StatusOK, gin.H{
@@ -227,6 +224,10 @@ func AddProductSpecification(c *gin.Context) {
 		}
 	}
 
+	if err := services.RefreshVariantSearch(uint(variantID)); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.Int("variantID", variantID), zap.Error(err))
+	}
+
 	logger.Log.Info("Product specification added successfully", zap.Int("variantID", variantID), zap.Int("count", len(headings)))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "OK",
@@ -297,17 +298,9 @@ func DeleteVariantImage(c *gin.Context) {
 		return
 	}
 
-	publicID, err := helper.ExtractCloudinaryPublicID(variantImage.ProductVariantsImages)
-	if err != nil {
-		logger.Log.Error("Failed to extract Cloudinary public ID", zap.String("imageID", imageID), zap.Error(err))
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to extract Cloudinary public ID", "Processing Error", "")
-		return
-	}
-
-	cld := config.InitCloudinary()
-	if err := utils.DeleteCloudinaryImage(cld, publicID, c); err != nil {
-		logger.Log.Error("Failed to delete image from Cloudinary", zap.String("publicID", publicID), zap.Error(err))
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete image from Cloudinary", "Upload Error", "")
+	if err := services.DeleteProductImage(c.Request.Context(), variantImage.ProductVariantsImages, variantImage.ImageRenditions); err != nil {
+		logger.Log.Error("Failed to delete image", zap.String("imageID", imageID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete image", "Delete Error", "")
 		return
 	}
 
@@ -378,7 +371,15 @@ func EditProductVariant(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Model(&existingVariant).Updates(map[string]interface{}{
+	changes := audit.Changes{}
+	changes.Set("regular_price", existingVariant.RegularPrice, updateData.RegularPrice)
+	changes.Set("sale_price", existingVariant.SalePrice, updateData.SalePrice)
+	changes.Set("stock_quantity", existingVariant.StockQuantity, updateData.StockQuantity)
+	changes.Set("sku", existingVariant.SKU, updateData.SKU)
+	changes.Set("product_name", existingVariant.ProductName, updateData.ProductName)
+
+	tx := config.DB.Begin()
+	if err := tx.Model(&existingVariant).Updates(map[string]interface{}{
 		"product_name":    updateData.ProductName,
 		"product_summary": updateData.ProductSummary,
 		"size":            updateData.Size,
@@ -391,10 +392,25 @@ func EditProductVariant(c *gin.Context) {
 		"sku":             updateData.SKU,
 	}).Error; err != nil {
 		logger.Log.Error("Failed to save variant updates", zap.String("variantID", variantID), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save data", "Database Error", "")
 		return
 	}
 
+	if len(changes) > 0 {
+		if err := audit.Record(tx, helper.AuditActor(c), audit.ActionUpdate, audit.EntityProductVariant, existingVariant.ID, changes); err != nil {
+			logger.Log.Error("Failed to record audit entry", zap.String("variantID", variantID), zap.Error(err))
+			tx.Rollback()
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save data", "Database Error", "")
+			return
+		}
+	}
+	tx.Commit()
+
+	if err := services.RefreshVariantSearch(existingVariant.ID); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.String("variantID", variantID), zap.Error(err))
+	}
+
 	logger.Log.Info("Product variant updated successfully", zap.String("variantID", variantID))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "OK",
@@ -420,6 +436,10 @@ func DeleteSpecification(c *gin.Context) {
 		return
 	}
 
+	if err := services.RefreshVariantSearch(specification.ProductVariantID); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.Uint("variantID", specification.ProductVariantID), zap.Error(err))
+	}
+
 	logger.Log.Info("Specification deleted successfully", zap.String("specificationID", specificationID))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -428,15 +448,15 @@ func DeleteSpecification(c *gin.Context) {
 	})
 }
 
+type UpdateSpecification struct {
+	SpecificationIDs []string `json:"specification_id"`
+	SpecificationKey []string `json:"specification_key"`
+	Specification    []string `json:"specification"`
+}
+
 func UpdateProductSpecification(c *gin.Context) {
 	logger.Log.Info("Requested to update product specification")
 
-	type UpdateSpecification struct {
-		SpecificationIDs []string `json:"specification_id"`
-		SpecificationKey []string `json:"specification_key"`
-		Specification    []string `json:"specification"`
-	}
-
 	var updateData UpdateSpecification
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -490,6 +510,10 @@ func UpdateProductSpecification(c *gin.Context) {
 		}
 	}
 
+	if err := services.RefreshVariantSearch(uint(productID)); err != nil {
+		logger.Log.Warn("Failed to refresh search documents", zap.Int("variantID", productID), zap.Error(err))
+	}
+
 	logger.Log.Info("Product specification updated successfully", zap.Int("productID", productID), zap.Int("count", len(updateData.SpecificationIDs)))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -519,6 +543,7 @@ func ReplaceVariantProductImage(c *gin.Context) {
 	}
 
 	oldImage := variantImage.ProductVariantsImages
+	oldRenditions := variantImage.ImageRenditions
 	form, err := c.FormFile("product_image")
 	if err != nil {
 		logger.Log.Error("No file uploaded", zap.Error(err))
@@ -527,38 +552,28 @@ func ReplaceVariantProductImage(c *gin.Context) {
 		return
 	}
 
-	cld := config.InitCloudinary()
-	file, _ := form.Open()
-	url, uploadErr := utils.UploadImageToCloudinary(file, form, cld, "ProductVariants", "")
+	url, renditions, uploadErr := services.StoreProductImage(c.Request.Context(), storage.FolderProductVariants, form)
 	if uploadErr != nil {
 		logger.Log.Error("Failed to upload new image", zap.Error(uploadErr))
 		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to upload product image", "Replace Image Failed", "")
+		respondWithImageError(c, uploadErr, "Replace Image Failed")
 		return
 	}
 
-	if err := tx.Model(&variantImage).Update("product_variants_images", url).Error; err != nil {
+	if err := tx.Model(&variantImage).Updates(models.ProductVariantsImage{ProductVariantsImages: url, ImageRenditions: renditions}).Error; err != nil {
 		logger.Log.Error("Failed to update image in database", zap.Int("imageID", imageID), zap.Error(err))
 		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update image", "Replace Image Failed", "")
 		return
 	}
 
-	publicID, err := helper.ExtractCloudinaryPublicID(oldImage)
-	if err != nil {
-		logger.Log.Error("Failed to extract Cloudinary public ID", zap.String("oldImage", oldImage), zap.Error(err))
+	if err := services.DeleteProductImage(c.Request.Context(), oldImage, oldRenditions); err != nil {
+		logger.Log.Error("Failed to delete old image", zap.String("oldImage", oldImage), zap.Error(err))
 		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to extract Cloudinary public ID", "Replace Image Failed", "")
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete old image", "Replace Image Failed", "")
 		return
 	}
 
-	if err := utils.DeleteCloudinaryImage(cld, publicID, c); err != nil {
-		logger.Log.Error("Failed to delete old image from Cloudinary", zap.String("publicID", publicID), zap.Error(err))
-		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete image from Cloudinary", "Replace Image Failed", "")
-		return
-	} 
-
 	tx.Commit()
 	logger.Log.Info("Variant product image replaced successfully", zap.Int("imageID", imageID))
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const refundPageSize = 50
+
+func ShowRefunds(c *gin.Context) {
+	logger.Log.Info("Requested to show refunds")
+
+	status := c.Query("status")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	query := config.DB.Model(&models.Refund{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var refunds []models.Refund
+	if err := query.Preload("OrderItem").Preload("UserAuth", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Order("created_at DESC").Offset((page - 1) * refundPageSize).Limit(refundPageSize).Find(&refunds).Error; err != nil {
+		logger.Log.Error("Failed to fetch refunds", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch refunds", "Something Went Wrong", "")
+		return
+	}
+
+	retryable := make(map[uint]bool, len(refunds))
+	reconcilable := make(map[uint]bool, len(refunds))
+	for i := range refunds {
+		retryable[refunds[i].ID] = services.RefundRetryable(&refunds[i])
+		reconcilable[refunds[i].ID] = services.RefundReconcilable(&refunds[i])
+	}
+
+	var failedCount, reconcileCount int64
+	config.DB.Model(&models.Refund{}).Where("status = ?", services.RefundFailed).Count(&failedCount)
+	config.DB.Model(&models.Refund{}).Where("status = ?", services.RefundReconcile).Count(&reconcileCount)
+
+	totalPages := int((total + refundPageSize - 1) / refundPageSize)
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	logger.Log.Info("Refunds fetched successfully", zap.Int64("total", total))
+	c.HTML(http.StatusOK, "refunds.html", gin.H{
+		"Refunds":        refunds,
+		"Retryable":      retryable,
+		"Reconcilable":   reconcilable,
+		"Status":         status,
+		"Statuses":       []string{services.RefundPending, services.RefundProcessing, services.RefundProcessed, services.RefundFailed, services.RefundReconcile},
+		"FailedCount":    failedCount,
+		"ReconcileCount": reconcileCount,
+		"Total":          total,
+		"Page":           page,
+		"TotalPages":     totalPages,
+		"PrevPage":       page - 1,
+		"NextPage":       page + 1,
+		"HasNext":        page < totalPages,
+	})
+}
+
+func RetryRefund(c *gin.Context) {
+	logger.Log.Info("Requested to retry refund")
+
+	refundID := c.Param("id")
+	var refund models.Refund
+	if err := config.DB.First(&refund, "id = ?", refundID).Error; err != nil {
+		logger.Log.Error("Refund not found", zap.String("refundID", refundID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Refund not found", "Refund not found", "")
+		return
+	}
+	if refund.Destination != services.RefundToOriginal {
+		helper.RespondWithError(c, http.StatusBadRequest, "Refund cannot be retried", "Wallet refunds are credited immediately", "")
+		return
+	}
+
+	previousStatus := refund.Status
+	err := services.ProcessRefund(config.DB, &refund)
+	if errors.Is(err, services.ErrRefundNotRetryable) {
+		helper.RespondWithError(c, http.StatusConflict, "Refund cannot be retried", "Only pending or failed refunds can be retried", "")
+		return
+	}
+
+	// The refund is settled in its own transaction by the time the gateway
+	// answers, so the retry is recorded afterwards whatever the outcome.
+	changes := audit.Changes{}
+	changes.Set("status", previousStatus, refund.Status)
+	changes.Added("amount", refund.Amount)
+	if auditErr := audit.Record(config.DB, helper.AuditActor(c), audit.ActionRetry, audit.EntityRefund, refund.ID, changes); auditErr != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("refundUID", refund.RefundUID), zap.Error(auditErr))
+	}
+
+	if err != nil {
+		logger.Log.Error("Refund retry failed", zap.String("refundUID", refund.RefundUID), zap.Error(err))
+		if refund.Status == services.RefundReconcile {
+			helper.RespondWithError(c, http.StatusBadGateway, "Refund outcome unknown", "The gateway did not answer, so the refund may have gone through. Check the gateway before releasing it for retry", "")
+			return
+		}
+		helper.RespondWithError(c, http.StatusBadGateway, "Refund failed", "Gateway refund failed: "+err.Error(), "")
+		return
+	}
+
+	logger.Log.Info("Refund retried successfully",
+		zap.String("refundUID", refund.RefundUID),
+		zap.String("gatewayRefundID", refund.GatewayRefundID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Refund processed successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func ReconcileRefund(c *gin.Context) {
+	logger.Log.Info("Requested to reconcile refund")
+
+	refundID := c.Param("id")
+	var refund models.Refund
+	if err := config.DB.First(&refund, "id = ?", refundID).Error; err != nil {
+		logger.Log.Error("Refund not found", zap.String("refundID", refundID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Refund not found", "Refund not found", "")
+		return
+	}
+
+	previousStatus := refund.Status
+	err := services.ReconcileRefund(config.DB, &refund)
+	if errors.Is(err, services.ErrRefundNotReconcilable) {
+		helper.RespondWithError(c, http.StatusConflict, "Refund cannot be reconciled", "Only refunds stuck in processing or awaiting reconciliation can be checked", "")
+		return
+	}
+
+	changes := audit.Changes{}
+	changes.Set("status", previousStatus, refund.Status)
+	if auditErr := audit.Record(config.DB, helper.AuditActor(c), audit.ActionReconcile, audit.EntityRefund, refund.ID, changes); auditErr != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("refundUID", refund.RefundUID), zap.Error(auditErr))
+	}
+
+	if err != nil {
+		logger.Log.Error("Refund reconciliation failed", zap.String("refundUID", refund.RefundUID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadGateway, "Refund lookup failed", "Gateway lookup failed: "+err.Error(), "")
+		return
+	}
+
+	message := "Refund found at the gateway and marked processed"
+	if refund.Status == services.RefundReconcile {
+		message = "No refund found at the gateway. Check the gateway dashboard before releasing it for retry"
+	}
+	logger.Log.Info("Refund reconciled", zap.String("refundUID", refund.RefundUID), zap.String("status", refund.Status))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": message,
+		"code":    http.StatusOK,
+	})
+}
+
+// ReleaseRefund lets a refund awaiting reconciliation be retried once the
+// admin has confirmed at the gateway that it was never made.
+func ReleaseRefund(c *gin.Context) {
+	logger.Log.Info("Requested to release refund for retry")
+
+	refundID := c.Param("id")
+	var refund models.Refund
+	if err := config.DB.First(&refund, "id = ?", refundID).Error; err != nil {
+		logger.Log.Error("Refund not found", zap.String("refundID", refundID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Refund not found", "Refund not found", "")
+		return
+	}
+
+	previousStatus := refund.Status
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.ReleaseRefund(tx, &refund); err != nil {
+			return err
+		}
+		changes := audit.Changes{}
+		changes.Set("status", previousStatus, refund.Status)
+		return audit.Record(tx, helper.AuditActor(c), audit.ActionRelease, audit.EntityRefund, refund.ID, changes)
+	})
+	if errors.Is(err, services.ErrRefundNotReleasable) {
+		helper.RespondWithError(c, http.StatusConflict, "Refund cannot be released", "Only refunds awaiting reconciliation can be released", "")
+		return
+	}
+	if err != nil {
+		logger.Log.Error("Failed to release refund", zap.String("refundUID", refund.RefundUID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to release refund", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Refund released for retry", zap.String("refundUID", refund.RefundUID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Refund released for retry",
+		"code":    http.StatusOK,
+	})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AdminReviewResponse struct {
+	ID          uint
+	UserName    string
+	Email       string
+	ProductName string
+	Rating      int
+	Title       string
+	Review      string
+	Status      string
+	AdminNotes  string
+	Date        string
+}
+
+func ShowReviewModeration(c *gin.Context) {
+	logger.Log.Info("Requested to show review moderation")
+
+	status := c.DefaultQuery("status", "All")
+	query := config.DB.Preload("UserAuth").Preload("ProductDetail").Preload("Rating").Order("created_at DESC")
+	if status == "Visible" || status == "Hidden" {
+		query = query.Where("status = ?", status)
+	}
+
+	var reviews []models.Review
+	if err := query.Find(&reviews).Error; err != nil {
+		logger.Log.Error("Failed to fetch reviews", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch reviews", "Something Went Wrong", "")
+		return
+	}
+
+	var response []AdminReviewResponse
+	for _, review := range reviews {
+		response = append(response, AdminReviewResponse{
+			ID:          review.ID,
+			UserName:    review.UserAuth.FullName,
+			Email:       review.UserAuth.Email,
+			ProductName: review.ProductDetail.ProductName,
+			Rating:      int(review.Rating.Value),
+			Title:       review.Title,
+			Review:      review.Review,
+			Status:      review.Status,
+			AdminNotes:  review.AdminNotes,
+			Date:        review.CreatedAt.Format("02 Jan 2006"),
+		})
+	}
+
+	logger.Log.Info("Reviews fetched successfully",
+		zap.String("status", status),
+		zap.Int("reviewCount", len(response)))
+	c.HTML(http.StatusOK, "reviewManagement.html", gin.H{
+		"Reviews": response,
+		"Status":  status,
+	})
+}
+
+func HideReview(c *gin.Context) {
+	logger.Log.Info("Requested to hide/unhide review")
+
+	reviewID := c.Param("id")
+	adminNotes := c.PostForm("adminNotes")
+
+	tx := config.DB.Begin()
+	var review models.Review
+	if err := tx.First(&review, "id = ?", reviewID).Error; err != nil {
+		logger.Log.Error("Review not found",
+			zap.String("reviewID", reviewID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusNotFound, "Review Not Found", "Review Not Found", "")
+		return
+	}
+
+	var message string
+	if review.Status == "Hidden" {
+		review.Status = "Visible"
+		message = "Review is visible again"
+	} else {
+		review.Status = "Hidden"
+		message = "Review hidden"
+	}
+	review.AdminNotes = adminNotes
+
+	if err := tx.Save(&review).Error; err != nil {
+		logger.Log.Error("Failed to update review status",
+			zap.String("reviewID", reviewID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update review", "Something Went Wrong", "")
+		return
+	}
+
+	if err := tx.Model(&models.Rating{}).Where("id = ?", review.RatingID).
+		Update("is_hidden", review.Status == "Hidden").Error; err != nil {
+		logger.Log.Error("Failed to update rating visibility",
+			zap.Uint("ratingID", review.RatingID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update review", "Something Went Wrong", "")
+		return
+	}
+	if err := services.RefreshProductRating(tx, review.ProductID); err != nil {
+		logger.Log.Error("Failed to refresh product rating",
+			zap.Uint("productID", review.ProductID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update review", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Review status updated successfully",
+		zap.String("reviewID", reviewID),
+		zap.String("status", review.Status))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": message,
+		"code":    http.StatusOK,
+	})
+}
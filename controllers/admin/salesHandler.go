@@ -10,6 +10,7 @@ import (
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/now"
 	"github.com/jung-kurt/gofpdf"
@@ -31,6 +32,16 @@ type CategorySalesDTO struct {
 	Count    int     `json:"count"`
 }
 
+// CurrencySalesDTO is the sales of orders placed while the customer saw
+// prices in one currency. Revenue is in rupees, as charged; Amount is what
+// the customer saw, at each order's recorded rate.
+type CurrencySalesDTO struct {
+	Currency string  `json:"currency"`
+	Orders   int     `json:"orders"`
+	Revenue  float64 `json:"revenue"`
+	Amount   float64 `json:"amount"`
+}
+
 type SalesDashboardDTO struct {
 	TotalAmount     float64            `json:"totalAmount"`
 	TotalRevenue    float64            `json:"totalRevenue"`
@@ -40,6 +51,7 @@ type SalesDashboardDTO struct {
 	CouponDiscount  float64            `json:"couponDiscount"`
 	SalesOverview   []SalesOverviewDTO `json:"salesOverview"`
 	CategorySales   []CategorySalesDTO `json:"categorySales"`
+	CurrencySales   []CurrencySalesDTO `json:"currencySales"`
 	RecentOrders    []RecentOrderDTO   `json:"recentOrders"`
 	TotalOrderCount int                `json:"totalOrderCount"`
 	CurrentPage     int                `json:"currentPage"`
@@ -288,12 +300,13 @@ func GetSalesDashboardData(filter Filter) (SalesDashboardDTO, error) {
 		ItemsPerPage: filter.PageSize,
 	}
 
+	// Sums are scanned as money.Amount so numeric totals keep their paise.
 	var totalStats struct {
-		TotalAmount     float64
-		TotalRevenue    float64
+		TotalAmount     money.Amount
+		TotalRevenue    money.Amount
 		OrderCount      int64
-		DiscountApplied float64
-		CouponDiscount  float64
+		DiscountApplied money.Amount
+		CouponDiscount  money.Amount
 	}
 
 	query := db.Model(&models.Order{}).
@@ -325,17 +338,16 @@ func GetSalesDashboardData(filter Filter) (SalesDashboardDTO, error) {
 		return result, err
 	}
 
-	result.TotalAmount = totalStats.TotalAmount
-	result.TotalRevenue = totalStats.TotalRevenue
+	result.TotalAmount = totalStats.TotalAmount.Rupees()
+	result.TotalRevenue = totalStats.TotalRevenue.Rupees()
 	result.OrderCount = int(totalStats.OrderCount)
-	result.DiscountApplied = totalStats.DiscountApplied
-	result.CouponDiscount = totalStats.CouponDiscount
-	if result.OrderCount > 0 {
-		result.AverageOrder = result.TotalRevenue / float64(result.OrderCount)
-	}
+	result.DiscountApplied = totalStats.DiscountApplied.Rupees()
+	result.CouponDiscount = totalStats.CouponDiscount.Rupees()
+	result.AverageOrder = totalStats.TotalRevenue.Part(1, result.OrderCount).Rupees()
 
 	result.SalesOverview = getSalesOverviewData(db, filter, startDate, endDate)
 	result.CategorySales = getCategorySalesData(db, startDate, endDate, filter.Status)
+	result.CurrencySales = getCurrencySalesData(db, startDate, endDate, filter.Status)
 	recentOrders, totalOrders := getRecentOrders(db, filter, startDate, endDate)
 	result.RecentOrders = recentOrders
 	result.TotalOrderCount = totalOrders
@@ -480,8 +492,8 @@ func getSalesOverviewData(db *gorm.DB, filter Filter, startDate, endDate *time.T
 
 	var tempResults []struct {
 		Month       time.Time
-		TotalAmount float64
-		Revenue     float64
+		TotalAmount money.Amount
+		Revenue     money.Amount
 		Orders      int
 	}
 	if err := query.Group(groupBy).Order(orderBy + " ASC").Scan(&tempResults).Error; err != nil {
@@ -499,8 +511,8 @@ func getSalesOverviewData(db *gorm.DB, filter Filter, startDate, endDate *time.T
 			label := labelFunc(temp.Month)
 			dateMap[label] = SalesOverviewDTO{
 				Month:       label,
-				TotalAmount: temp.TotalAmount,
-				Revenue:     temp.Revenue,
+				TotalAmount: temp.TotalAmount.Rupees(),
+				Revenue:     temp.Revenue.Rupees(),
 				Orders:      temp.Orders,
 			}
 		}
@@ -529,8 +541,8 @@ func getSalesOverviewData(db *gorm.DB, filter Filter, startDate, endDate *time.T
 			hour := temp.Month.Hour()
 			index := hour / 4
 			if index < 6 {
-				results[index].TotalAmount += temp.TotalAmount
-				results[index].Revenue += temp.Revenue
+				results[index].TotalAmount = (money.FromRupees(results[index].TotalAmount) + temp.TotalAmount).Rupees()
+				results[index].Revenue = (money.FromRupees(results[index].Revenue) + temp.Revenue).Rupees()
 				results[index].Orders += temp.Orders
 			}
 		}
@@ -548,8 +560,8 @@ func getSalesOverviewData(db *gorm.DB, filter Filter, startDate, endDate *time.T
 			label := labelFunc(temp.Month)
 			tempMap[label] = SalesOverviewDTO{
 				Month:       label,
-				TotalAmount: temp.TotalAmount,
-				Revenue:     temp.Revenue,
+				TotalAmount: temp.TotalAmount.Rupees(),
+				Revenue:     temp.Revenue.Rupees(),
 				Orders:      temp.Orders,
 			}
 		}
@@ -576,8 +588,8 @@ func getSalesOverviewData(db *gorm.DB, filter Filter, startDate, endDate *time.T
 		for _, temp := range tempResults {
 			results = append(results, SalesOverviewDTO{
 				Month:       labelFunc(temp.Month),
-				TotalAmount: temp.TotalAmount,
-				Revenue:     temp.Revenue,
+				TotalAmount: temp.TotalAmount.Rupees(),
+				Revenue:     temp.Revenue.Rupees(),
 				Orders:      temp.Orders,
 			})
 		}
@@ -617,6 +629,50 @@ func getCategorySalesData(db *gorm.DB, startDate, endDate *time.Time, status str
 	return results
 }
 
+func getCurrencySalesData(db *gorm.DB, startDate, endDate *time.Time, status string) []CurrencySalesDTO {
+	logger.Log.Info("Fetching currency sales data")
+
+	var rows []struct {
+		Currency string
+		Orders   int
+		Revenue  money.Amount
+		Amount   money.Amount
+	}
+
+	query := db.Model(&models.Order{}).
+		Select(`
+            orders.currency as currency,
+            COUNT(*) as orders,
+            SUM(orders.total_amount) as revenue,
+            SUM(ROUND(orders.total_amount / orders.exchange_rate, 2)) as amount
+        `).
+		Where("EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id AND order_items.order_status = ?)", status)
+
+	if startDate != nil && endDate != nil {
+		query = query.Where("orders.order_date BETWEEN ? AND ?", startDate, endDate)
+	}
+
+	if err := query.Group("orders.currency").
+		Order("revenue DESC").
+		Scan(&rows).Error; err != nil {
+		logger.Log.Error("Failed to fetch currency sales data", zap.Error(err))
+		return nil
+	}
+
+	results := make([]CurrencySalesDTO, len(rows))
+	for i, row := range rows {
+		results[i] = CurrencySalesDTO{
+			Currency: row.Currency,
+			Orders:   row.Orders,
+			Revenue:  row.Revenue.Rupees(),
+			Amount:   row.Amount.Rupees(),
+		}
+	}
+
+	logger.Log.Info("Currency sales data fetched successfully", zap.Int("count", len(results)))
+	return results
+}
+
 func getRecentOrders(db *gorm.DB, filter Filter, startDate, endDate *time.Time) ([]RecentOrderDTO, int) {
 	logger.Log.Info("Fetching recent orders")
 
@@ -749,6 +805,20 @@ func generateExcelReport(data SalesDashboardDTO, fileName string) ([]byte, strin
 		f.SetCellValue(catSheet, fmt.Sprintf("C%d", row), item.Count)
 	}
 
+	currencySheet := "Currencies"
+	f.NewSheet(currencySheet)
+	f.SetCellValue(currencySheet, "A1", "Currency")
+	f.SetCellValue(currencySheet, "B1", "Orders")
+	f.SetCellValue(currencySheet, "C1", "Revenue (INR)")
+	f.SetCellValue(currencySheet, "D1", "Amount in Currency")
+	for i, item := range data.CurrencySales {
+		row := i + 2
+		f.SetCellValue(currencySheet, fmt.Sprintf("A%d", row), item.Currency)
+		f.SetCellValue(currencySheet, fmt.Sprintf("B%d", row), item.Orders)
+		f.SetCellValue(currencySheet, fmt.Sprintf("C%d", row), item.Revenue)
+		f.SetCellValue(currencySheet, fmt.Sprintf("D%d", row), item.Amount)
+	}
+
 	ordersSheet := "Recent Orders"
 	f.NewSheet(ordersSheet)
 	f.SetCellValue(ordersSheet, "A1", "Order ID")
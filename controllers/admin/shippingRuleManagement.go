@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func normalizeList(value string) string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func ShowShippingRules(c *gin.Context) {
+	logger.Log.Info("Requested to show shipping rules")
+
+	var zones []models.ShippingZone
+	if err := config.DB.Preload("Slabs", func(db *gorm.DB) *gorm.DB {
+		return db.Order("min_value ASC")
+	}).Order("priority DESC, id ASC").Find(&zones).Error; err != nil {
+		logger.Log.Error("Failed to fetch shipping zones", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch shipping zones", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Shipping zones fetched successfully", zap.Int("zoneCount", len(zones)))
+	c.HTML(http.StatusOK, "shippingRules.html", gin.H{
+		"Zones":                 zones,
+		"DefaultShippingCharge": services.DefaultShippingCharge,
+		"DefaultFreeThreshold":  services.DefaultFreeShippingThreshold,
+	})
+}
+
+func AddShippingZone(c *gin.Context) {
+	logger.Log.Info("Requested to add shipping zone")
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	pinCodePrefixes := normalizeList(c.PostForm("pinCodePrefixes"))
+	states := normalizeList(c.PostForm("states"))
+	isDefault := c.PostForm("isDefault") == "true"
+	if name == "" {
+		logger.Log.Error("Shipping zone name is missing")
+		helper.RespondWithError(c, http.StatusBadRequest, "Name is required", "Name is required", "")
+		return
+	}
+	if pinCodePrefixes == "" && states == "" && !isDefault {
+		logger.Log.Error("Shipping zone has no pin codes or states")
+		helper.RespondWithError(c, http.StatusBadRequest, "Zone is empty", "Enter pin code prefixes or states, or mark the zone as default", "")
+		return
+	}
+
+	slabBasis := c.PostForm("slabBasis")
+	if slabBasis != services.SlabBasisWeight {
+		slabBasis = services.SlabBasisQuantity
+	}
+
+	freeThreshold, err := strconv.ParseFloat(c.DefaultPostForm("freeShippingThreshold", "0"), 64)
+	if err != nil || freeThreshold < 0 {
+		logger.Log.Error("Invalid free shipping threshold", zap.String("freeShippingThreshold", c.PostForm("freeShippingThreshold")))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid free shipping threshold", "Invalid free shipping threshold", "")
+		return
+	}
+	priority, _ := strconv.Atoi(c.DefaultPostForm("priority", "0"))
+
+	tx := config.DB.Begin()
+	if isDefault {
+		if err := tx.Model(&models.ShippingZone{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+			logger.Log.Error("Failed to clear default shipping zone", zap.Error(err))
+			tx.Rollback()
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add shipping zone", "Something Went Wrong", "")
+			return
+		}
+	}
+
+	zone := models.ShippingZone{
+		Name:                  name,
+		PinCodePrefixes:       pinCodePrefixes,
+		States:                states,
+		SlabBasis:             slabBasis,
+		FreeShippingThreshold: freeThreshold,
+		Priority:              priority,
+		IsDefault:             isDefault,
+		IsActive:              true,
+	}
+	if err := tx.Create(&zone).Error; err != nil {
+		logger.Log.Error("Failed to create shipping zone", zap.String("name", name), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add shipping zone", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Shipping zone added successfully", zap.Uint("zoneID", zone.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Shipping zone added successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func ToggleShippingZone(c *gin.Context) {
+	logger.Log.Info("Requested to toggle shipping zone")
+
+	id := c.Param("id")
+	var zone models.ShippingZone
+	if err := config.DB.First(&zone, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Shipping zone not found", zap.String("zoneID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Shipping zone not found", "Shipping zone not found", "")
+		return
+	}
+
+	if err := config.DB.Model(&zone).Update("is_active", !zone.IsActive).Error; err != nil {
+		logger.Log.Error("Failed to update shipping zone", zap.String("zoneID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update shipping zone", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Shipping zone updated successfully", zap.String("zoneID", id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Shipping zone updated successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func DeleteShippingZone(c *gin.Context) {
+	logger.Log.Info("Requested to delete shipping zone")
+
+	id := c.Param("id")
+	tx := config.DB.Begin()
+	var zone models.ShippingZone
+	if err := tx.First(&zone, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Shipping zone not found", zap.String("zoneID", id), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusNotFound, "Shipping zone not found", "Shipping zone not found", "")
+		return
+	}
+
+	if err := tx.Where("zone_id = ?", zone.ID).Delete(&models.ShippingSlab{}).Error; err != nil {
+		logger.Log.Error("Failed to delete shipping slabs", zap.String("zoneID", id), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete shipping zone", "Something Went Wrong", "")
+		return
+	}
+	if err := tx.Delete(&zone).Error; err != nil {
+		logger.Log.Error("Failed to delete shipping zone", zap.String("zoneID", id), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete shipping zone", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Shipping zone deleted successfully", zap.String("zoneID", id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Shipping zone removed successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func AddShippingSlab(c *gin.Context) {
+	logger.Log.Info("Requested to add shipping slab")
+
+	id := c.Param("id")
+	var zone models.ShippingZone
+	if err := config.DB.First(&zone, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Shipping zone not found", zap.String("zoneID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Shipping zone not found", "Shipping zone not found", "")
+		return
+	}
+
+	minValue, minErr := strconv.ParseFloat(c.DefaultPostForm("minValue", "0"), 64)
+	maxValue, maxErr := strconv.ParseFloat(c.DefaultPostForm("maxValue", "0"), 64)
+	charge, chargeErr := strconv.ParseFloat(c.PostForm("charge"), 64)
+	if minErr != nil || maxErr != nil || chargeErr != nil || minValue < 0 || maxValue < 0 || charge < 0 {
+		logger.Log.Error("Invalid shipping slab values", zap.String("zoneID", id))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid slab", "Enter valid slab values", "")
+		return
+	}
+	if maxValue != 0 && maxValue < minValue {
+		logger.Log.Error("Shipping slab max below min",
+			zap.Float64("minValue", minValue),
+			zap.Float64("maxValue", maxValue))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid slab", "Slab upper limit must be above the lower limit", "")
+		return
+	}
+
+	slab := models.ShippingSlab{
+		ZoneID:   zone.ID,
+		MinValue: minValue,
+		MaxValue: maxValue,
+		Charge:   charge,
+	}
+	if err := config.DB.Create(&slab).Error; err != nil {
+		logger.Log.Error("Failed to create shipping slab", zap.String("zoneID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add slab", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Shipping slab added successfully", zap.Uint("slabID", slab.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Slab added successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func DeleteShippingSlab(c *gin.Context) {
+	logger.Log.Info("Requested to delete shipping slab")
+
+	id := c.Param("id")
+	if err := config.DB.Delete(&models.ShippingSlab{}, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Failed to delete shipping slab", zap.String("slabID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete slab", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Shipping slab deleted successfully", zap.String("slabID", id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Slab removed successfully",
+		"code":    http.StatusOK,
+	})
+}
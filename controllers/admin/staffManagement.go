@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/rbac"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const minAdminPasswordLength = 8
+
+type adminUserRow struct {
+	ID        uint
+	Name      string
+	Email     string
+	Role      string
+	RoleLabel string
+	IsActive  bool
+	IsSelf    bool
+	HasTwoFA  bool
+	CreatedAt string
+}
+
+func ShowAdminUsers(c *gin.Context) {
+	logger.Log.Info("Requested to show admin users")
+
+	var admins []models.AdminModel
+	if err := config.DB.Order("created_at ASC").Find(&admins).Error; err != nil {
+		logger.Log.Error("Failed to fetch admin users", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch admin users", "Something Went Wrong", "")
+		return
+	}
+
+	var enrolled []uint
+	if err := config.DB.Model(&models.TwoFactorAuth{}).
+		Where("role = ? AND enabled_at IS NOT NULL", RoleAdmin).
+		Pluck("user_id", &enrolled).Error; err != nil {
+		logger.Log.Error("Failed to fetch two-factor status", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch admin users", "Something Went Wrong", "")
+		return
+	}
+	hasTwoFA := make(map[uint]bool, len(enrolled))
+	for _, id := range enrolled {
+		hasTwoFA[id] = true
+	}
+
+	currentID := c.GetUint("userid")
+	rows := make([]adminUserRow, 0, len(admins))
+	for _, admin := range admins {
+		rows = append(rows, adminUserRow{
+			ID:        admin.ID,
+			Name:      admin.Name,
+			Email:     admin.Email,
+			Role:      admin.Role,
+			RoleLabel: rbac.Label(admin.Role),
+			IsActive:  admin.IsActive,
+			IsSelf:    admin.ID == currentID,
+			HasTwoFA:  hasTwoFA[admin.ID],
+			CreatedAt: admin.CreatedAt.Format("02 Jan 2006"),
+		})
+	}
+
+	logger.Log.Info("Admin users fetched successfully", zap.Int("count", len(rows)))
+	c.HTML(http.StatusOK, "adminUsers.html", gin.H{
+		"Admins": rows,
+		"Roles":  rbac.Roles,
+	})
+}
+
+type adminUserInput struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+func AddAdminUser(c *gin.Context) {
+	logger.Log.Info("Requested to add admin user")
+
+	var input adminUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Log.Error("Invalid admin user data", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid Data", "Invalid Data", "")
+		return
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+	if input.Name == "" {
+		helper.RespondWithError(c, http.StatusBadRequest, "Name is required", "Name is required", "")
+		return
+	}
+	if _, err := mail.ParseAddress(input.Email); err != nil {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid email", "Enter a valid email address", "")
+		return
+	}
+	if len(input.Password) < minAdminPasswordLength {
+		helper.RespondWithError(c, http.StatusBadRequest, "Password too short", "Password must be at least 8 characters", "")
+		return
+	}
+	if !rbac.Valid(input.Role) {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid role", "Select a valid role", "")
+		return
+	}
+
+	var count int64
+	if err := config.DB.Unscoped().Model(&models.AdminModel{}).Where("email = ?", input.Email).Count(&count).Error; err != nil {
+		logger.Log.Error("Failed to check admin email", zap.String("email", input.Email), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add admin", "Something Went Wrong", "")
+		return
+	}
+	if count > 0 {
+		helper.RespondWithError(c, http.StatusConflict, "Email already in use", "An admin with this email already exists", "")
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		logger.Log.Error("Failed to hash admin password", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add admin", "Something Went Wrong", "")
+		return
+	}
+
+	admin := models.AdminModel{
+		Name:     input.Name,
+		Email:    input.Email,
+		Password: string(hashed),
+		Role:     input.Role,
+		IsActive: true,
+	}
+
+	tx := config.DB.Begin()
+	if err := tx.Create(&admin).Error; err != nil {
+		logger.Log.Error("Failed to create admin", zap.String("email", input.Email), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add admin", "Something Went Wrong", "")
+		return
+	}
+
+	changes := audit.Changes{}
+	changes.Added("name", admin.Name)
+	changes.Added("email", admin.Email)
+	changes.Added("role", admin.Role)
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionCreate, audit.EntityAdmin, admin.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Uint("adminID", admin.ID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add admin", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Admin user added", zap.Uint("adminID", admin.ID), zap.String("role", admin.Role))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Admin added successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+type adminRoleInput struct {
+	Role string `json:"role"`
+}
+
+func ChangeAdminRole(c *gin.Context) {
+	logger.Log.Info("Requested to change admin role")
+
+	var input adminRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil || !rbac.Valid(input.Role) {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid role", "Select a valid role", "")
+		return
+	}
+
+	admin, ok := loadStaffMember(c)
+	if !ok {
+		return
+	}
+	if admin.Role == input.Role {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "OK",
+			"message": "Role unchanged",
+			"code":    http.StatusOK,
+		})
+		return
+	}
+	if admin.ID == c.GetUint("userid") {
+		helper.RespondWithError(c, http.StatusBadRequest, "Can't change own role", "You cannot change your own role", "")
+		return
+	}
+
+	tx := config.DB.Begin()
+	if admin.Role == rbac.RoleSuperAdmin && admin.IsActive && !otherActiveSuperAdmin(tx, admin.ID) {
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusConflict, "Last super admin", "At least one active super admin is required", "")
+		return
+	}
+
+	changes := audit.Changes{}
+	changes.Set("role", admin.Role, input.Role)
+	if err := tx.Model(admin).Update("role", input.Role).Error; err != nil {
+		logger.Log.Error("Failed to change admin role", zap.Uint("adminID", admin.ID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change role", "Something Went Wrong", "")
+		return
+	}
+	if err := audit.Record(tx, helper.AuditActor(c), audit.ActionRoleChange, audit.EntityAdmin, admin.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Uint("adminID", admin.ID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change role", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Admin role changed", zap.Uint("adminID", admin.ID), zap.String("role", input.Role))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Role updated to " + rbac.Label(input.Role),
+		"code":    http.StatusOK,
+	})
+}
+
+// ToggleAdminActive deactivates or reactivates a staff account. Deactivating
+// signs the admin out everywhere.
+func ToggleAdminActive(c *gin.Context) {
+	logger.Log.Info("Requested to toggle admin status")
+
+	admin, ok := loadStaffMember(c)
+	if !ok {
+		return
+	}
+	if admin.ID == c.GetUint("userid") {
+		helper.RespondWithError(c, http.StatusBadRequest, "Can't deactivate yourself", "You cannot deactivate your own account", "")
+		return
+	}
+
+	tx := config.DB.Begin()
+	if admin.IsActive && admin.Role == rbac.RoleSuperAdmin && !otherActiveSuperAdmin(tx, admin.ID) {
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusConflict, "Last super admin", "At least one active super admin is required", "")
+		return
+	}
+
+	active := !admin.IsActive
+	action, message := audit.ActionActivate, "Admin activated"
+	if !active {
+		action, message = audit.ActionDeactivate, "Admin deactivated"
+	}
+
+	changes := audit.Changes{}
+	changes.Set("is_active", admin.IsActive, active)
+	if err := tx.Model(admin).Update("is_active", active).Error; err != nil {
+		logger.Log.Error("Failed to update admin status", zap.Uint("adminID", admin.ID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update admin", "Something Went Wrong", "")
+		return
+	}
+	if err := audit.Record(tx, helper.AuditActor(c), action, audit.EntityAdmin, admin.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Uint("adminID", admin.ID), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update admin", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	if !active {
+		if _, err := sessions.RevokeAll(config.DB, admin.ID, RoleAdmin, "", sessions.ReasonBlocked); err != nil {
+			logger.Log.Error("Failed to sign out deactivated admin", zap.Uint("adminID", admin.ID), zap.Error(err))
+		}
+	}
+
+	logger.Log.Info(message, zap.Uint("adminID", admin.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": message,
+		"code":    http.StatusOK,
+	})
+}
+
+// ResetAdminTwoFactor removes an admin's authenticator, for example after a
+// lost phone. They set up a new one at their next login.
+func ResetAdminTwoFactor(c *gin.Context) {
+	logger.Log.Info("Requested to reset admin two-factor authentication")
+
+	admin, ok := loadStaffMember(c)
+	if !ok {
+		return
+	}
+	if admin.ID == c.GetUint("userid") {
+		helper.RespondWithError(c, http.StatusBadRequest, "Can't reset own 2FA", "Ask another super admin to reset your two-factor authentication", "")
+		return
+	}
+
+	if err := twofactor.Disable(config.DB, admin.ID, RoleAdmin); err != nil {
+		logger.Log.Error("Failed to reset admin two-factor authentication", zap.Uint("adminID", admin.ID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to reset 2FA", "Something Went Wrong", "")
+		return
+	}
+	if err := audit.Record(config.DB, helper.AuditActor(c), audit.ActionTwoFactorReset, audit.EntityAdmin, admin.ID, nil); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.Uint("adminID", admin.ID), zap.Error(err))
+	}
+	if _, err := sessions.RevokeAll(config.DB, admin.ID, RoleAdmin, "", sessions.ReasonRevoked); err != nil {
+		logger.Log.Error("Failed to sign out admin after 2FA reset", zap.Uint("adminID", admin.ID), zap.Error(err))
+	}
+
+	logger.Log.Info("Admin two-factor authentication reset", zap.Uint("adminID", admin.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Two-factor authentication reset. The admin will set it up again at their next login.",
+		"code":    http.StatusOK,
+	})
+}
+
+func loadStaffMember(c *gin.Context) (*models.AdminModel, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid admin", "Invalid admin ID", "")
+		return nil, false
+	}
+
+	var admin models.AdminModel
+	if err := config.DB.First(&admin, id).Error; err != nil {
+		logger.Log.Error("Admin not found", zap.Uint64("adminID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Admin not found", "Admin not found", "")
+		return nil, false
+	}
+	return &admin, true
+}
+
+// otherActiveSuperAdmin locks the super admin rows so two requests cannot
+// demote the last two super admins at the same time.
+func otherActiveSuperAdmin(tx *gorm.DB, adminID uint) bool {
+	var ids []uint
+	if err := tx.Model(&models.AdminModel{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND is_active = ? AND id <> ?", rbac.RoleSuperAdmin, true, adminID).
+		Pluck("id", &ids).Error; err != nil {
+		logger.Log.Error("Failed to count super admins", zap.Error(err))
+		return false
+	}
+	return len(ids) > 0
+}
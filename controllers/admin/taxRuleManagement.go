@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TaxRuleResponse struct {
+	ID           uint
+	Name         string
+	CategoryName string
+	HSNCode      string
+	Rate         float64
+	IsInclusive  bool
+	IsActive     bool
+}
+
+func ShowTaxRules(c *gin.Context) {
+	logger.Log.Info("Requested to show tax rules")
+
+	var rules []models.TaxRule
+	if err := config.DB.Order("hsn_code DESC, category_id ASC, created_at DESC").Find(&rules).Error; err != nil {
+		logger.Log.Error("Failed to fetch tax rules", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch tax rules", "Something Went Wrong", "")
+		return
+	}
+
+	var categories []models.Categories
+	if err := config.DB.Find(&categories, "is_deleted = ?", false).Error; err != nil {
+		logger.Log.Error("Failed to fetch categories", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories", "Something Went Wrong", "")
+		return
+	}
+	categoryNames := make(map[uint]string)
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	var response []TaxRuleResponse
+	for _, rule := range rules {
+		response = append(response, TaxRuleResponse{
+			ID:           rule.ID,
+			Name:         rule.Name,
+			CategoryName: categoryNames[rule.CategoryID],
+			HSNCode:      rule.HSNCode,
+			Rate:         rule.Rate,
+			IsInclusive:  rule.IsInclusive,
+			IsActive:     rule.IsActive,
+		})
+	}
+
+	logger.Log.Info("Tax rules fetched successfully", zap.Int("ruleCount", len(response)))
+	c.HTML(http.StatusOK, "taxRules.html", gin.H{
+		"Rules":          response,
+		"Categories":     categories,
+		"CompanyState":   config.CompanyConfig.State,
+		"DefaultTaxRate": services.DefaultTaxRate,
+	})
+}
+
+func AddTaxRule(c *gin.Context) {
+	logger.Log.Info("Requested to add tax rule")
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	hsnCode := strings.TrimSpace(c.PostForm("hsnCode"))
+	if name == "" {
+		logger.Log.Error("Tax rule name is missing")
+		helper.RespondWithError(c, http.StatusBadRequest, "Name is required", "Name is required", "")
+		return
+	}
+
+	rate, err := strconv.ParseFloat(c.PostForm("rate"), 64)
+	if err != nil || rate < 0 || rate > 100 {
+		logger.Log.Error("Invalid tax rate", zap.String("rate", c.PostForm("rate")))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid tax rate", "Tax rate must be between 0 and 100", "")
+		return
+	}
+
+	var categoryID uint
+	if categoryStr := c.PostForm("categoryId"); categoryStr != "" {
+		id, err := strconv.ParseUint(categoryStr, 10, 64)
+		if err != nil {
+			logger.Log.Error("Invalid category ID", zap.String("categoryID", categoryStr), zap.Error(err))
+			helper.RespondWithError(c, http.StatusBadRequest, "Invalid category", "Invalid category", "")
+			return
+		}
+		categoryID = uint(id)
+	}
+
+	if categoryID == 0 && hsnCode == "" {
+		logger.Log.Error("Tax rule has no category or HSN code")
+		helper.RespondWithError(c, http.StatusBadRequest, "Category or HSN code is required", "Select a category or enter an HSN code", "")
+		return
+	}
+	if hsnCode != "" {
+		categoryID = 0
+	}
+
+	var count int64
+	config.DB.Model(&models.TaxRule{}).
+		Where("category_id = ? AND hsn_code = ? AND is_active = ?", categoryID, hsnCode, true).
+		Count(&count)
+	if count > 0 {
+		logger.Log.Warn("Active tax rule already exists",
+			zap.Uint("categoryID", categoryID),
+			zap.String("hsnCode", hsnCode))
+		helper.RespondWithError(c, http.StatusConflict, "Tax rule already exists", "An active rule already exists for this category or HSN code", "")
+		return
+	}
+
+	rule := models.TaxRule{
+		Name:        name,
+		CategoryID:  categoryID,
+		HSNCode:     hsnCode,
+		Rate:        rate,
+		IsInclusive: c.PostForm("isInclusive") == "true",
+		IsActive:    true,
+	}
+	if err := config.DB.Create(&rule).Error; err != nil {
+		logger.Log.Error("Failed to create tax rule", zap.String("name", name), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to add tax rule", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Tax rule added successfully", zap.Uint("taxRuleID", rule.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Tax rule added successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func ToggleTaxRule(c *gin.Context) {
+	logger.Log.Info("Requested to toggle tax rule")
+
+	id := c.Param("id")
+	var rule models.TaxRule
+	if err := config.DB.First(&rule, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Tax rule not found", zap.String("taxRuleID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Tax rule not found", "Tax rule not found", "")
+		return
+	}
+
+	if !rule.IsActive {
+		var count int64
+		config.DB.Model(&models.TaxRule{}).
+			Where("id <> ? AND category_id = ? AND hsn_code = ? AND is_active = ?", rule.ID, rule.CategoryID, rule.HSNCode, true).
+			Count(&count)
+		if count > 0 {
+			logger.Log.Warn("Another active tax rule exists", zap.String("taxRuleID", id))
+			helper.RespondWithError(c, http.StatusConflict, "Tax rule already exists", "Deactivate the other rule for this category or HSN code first", "")
+			return
+		}
+	}
+
+	if err := config.DB.Model(&rule).Update("is_active", !rule.IsActive).Error; err != nil {
+		logger.Log.Error("Failed to update tax rule", zap.String("taxRuleID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update tax rule", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Tax rule updated successfully", zap.String("taxRuleID", id), zap.Bool("isActive", rule.IsActive))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Tax rule updated successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func DeleteTaxRule(c *gin.Context) {
+	logger.Log.Info("Requested to delete tax rule")
+
+	id := c.Param("id")
+	var rule models.TaxRule
+	if err := config.DB.First(&rule, "id = ?", id).Error; err != nil {
+		logger.Log.Error("Tax rule not found", zap.String("taxRuleID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Tax rule not found", "Tax rule not found", "")
+		return
+	}
+
+	if err := config.DB.Delete(&rule).Error; err != nil {
+		logger.Log.Error("Failed to delete tax rule", zap.String("taxRuleID", id), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete tax rule", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Tax rule deleted successfully", zap.String("taxRuleID", id))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Tax rule removed successfully",
+		"code":    http.StatusOK,
+	})
+}
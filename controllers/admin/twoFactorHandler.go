@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ShowAdminTwoFactor asks for the authenticator code after the password was
+// accepted, or shows the QR code to scan when the admin has not set up an
+// authenticator yet.
+func ShowAdminTwoFactor(c *gin.Context) {
+	challenge, err := middleware.PendingTwoFactor(c, RoleAdmin)
+	if err != nil {
+		logger.Log.Info("No pending admin two-factor login", zap.Error(err))
+		c.Redirect(http.StatusSeeOther, "/admin/login")
+		return
+	}
+
+	enabled, err := twofactor.IsEnabled(config.DB, challenge.UserID, RoleAdmin)
+	if err != nil {
+		logger.Log.Error("Failed to check two-factor status", zap.Uint("adminID", challenge.UserID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to load two-factor authentication", "Something Went Wrong", "")
+		return
+	}
+
+	data := gin.H{
+		"Email": challenge.Email,
+		"Setup": !enabled,
+	}
+	if !enabled {
+		secret, err := twofactor.Setup(config.DB, challenge.UserID, RoleAdmin)
+		if err != nil {
+			logger.Log.Error("Failed to set up two-factor authentication", zap.Uint("adminID", challenge.UserID), zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to set up two-factor authentication", "Something Went Wrong", "")
+			return
+		}
+		qrCode, err := twofactor.QRCodeDataURI(twofactor.ProvisioningURI(twofactor.Issuer()+" Admin", challenge.Email, secret))
+		if err != nil {
+			logger.Log.Error("Failed to render two-factor QR code", zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to set up two-factor authentication", "Something Went Wrong", "")
+			return
+		}
+		data["Secret"] = secret
+		data["QRCode"] = template.URL(qrCode)
+	}
+
+	c.HTML(http.StatusOK, "adminTwoFactor.html", data)
+}
+
+type twoFactorCodeInput struct {
+	Code string `json:"code"`
+}
+
+func VerifyAdminTwoFactor(c *gin.Context) {
+	var input twoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		helper.RespondWithError(c, http.StatusBadRequest, "Code is required", "Enter the code from your authenticator app", "")
+		return
+	}
+
+	challenge, err := middleware.PendingTwoFactor(c, RoleAdmin)
+	if err != nil {
+		middleware.ClearTwoFactor(c, RoleAdmin)
+		helper.RespondWithError(c, http.StatusUnauthorized, "Login expired", "Your login has expired, please sign in again", "/admin/login")
+		return
+	}
+	if helper.AccountLocked(c, ratelimit.ScopeAdminLogin, challenge.Email) {
+		middleware.ClearTwoFactor(c, RoleAdmin)
+		return
+	}
+
+	result, err := twofactor.Answer(config.DB, challenge, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			logger.Log.Warn("Invalid admin two-factor code", zap.String("email", challenge.Email))
+			if helper.RecordFailedAttempt(c, ratelimit.ScopeAdminLogin, challenge.Email) {
+				middleware.ClearTwoFactor(c, RoleAdmin)
+				return
+			}
+			helper.RespondWithError(c, http.StatusUnauthorized, "Invalid code", "Invalid code, please try again", "")
+		case errors.Is(err, twofactor.ErrTooManyAttempts):
+			logger.Log.Warn("Too many invalid admin two-factor codes", zap.String("email", challenge.Email))
+			middleware.ClearTwoFactor(c, RoleAdmin)
+			if helper.RecordFailedAttempt(c, ratelimit.ScopeAdminLogin, challenge.Email) {
+				return
+			}
+			helper.RespondWithError(c, http.StatusUnauthorized, "Too many attempts", "Too many invalid codes, please sign in again", "/admin/login")
+		default:
+			logger.Log.Error("Failed to verify admin two-factor code", zap.String("email", challenge.Email), zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to verify code", "Something Went Wrong", "")
+		}
+		return
+	}
+	if result.UsedRecoveryCode {
+		logger.Log.Warn("Admin signed in with a recovery code", zap.String("email", challenge.Email))
+	}
+
+	token, err := middleware.FinishTwoFactor(c, challenge)
+	if err != nil {
+		logger.Log.Error("Failed to start session", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to generate JWT tokens", "Failed to generate JWT tokens", "")
+		return
+	}
+
+	helper.ClearFailedAttempts(ratelimit.ScopeAdminLogin, challenge.Email)
+	logger.Log.Info("Admin Logined successfully", zap.String("email", challenge.Email))
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "success",
+		"message":        "Login successful",
+		"token":          token,
+		"recovery_codes": result.RecoveryCodes,
+		"redirect":       "/admin/dashboard/",
+		"code":           http.StatusOK,
+	})
+}
@@ -7,7 +7,9 @@ import (
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -82,25 +84,47 @@ func BlockUser(c *gin.Context) {
 		return
 	}
 
-	var message string
+	changes := audit.Changes{}
+	changes.Set("is_blocked", user.IsBlocked, !user.IsBlocked)
+	previousStatus := user.Status
+
+	var message, action string
 	if user.IsBlocked {
 		user.IsBlocked = false
 		user.Status = "Active"
 		message = "User's account unblocked"
+		action = audit.ActionUnblock
 	} else {
 		user.IsBlocked = true
 		user.Status = "Blocked"
 		message = "User's account blocked"
+		action = audit.ActionBlock
 	}
+	changes.Set("status", previousStatus, user.Status)
 
-	if err := config.DB.Save(&user).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Save(&user).Error; err != nil {
 		logger.Log.Error("Failed to update user block status",
 			zap.String("userID", id),
 			zap.Bool("isBlocked", user.IsBlocked),
 			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to block/unblock user", "Failed to block/unblock user", "")
+		return
+	}
+	if err := audit.Record(tx, helper.AuditActor(c), action, audit.EntityUser, user.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("userID", id), zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to block/unblock user", "Failed to block/unblock user", "")
 		return
 	}
+	tx.Commit()
+
+	if user.IsBlocked {
+		if _, err := sessions.RevokeAll(config.DB, user.ID, "User", "", sessions.ReasonBlocked); err != nil {
+			logger.Log.Error("Failed to sign out blocked user", zap.String("userID", id), zap.Error(err))
+		}
+	}
 
 	logger.Log.Info("User block status updated successfully",
 		zap.String("userID", id),
@@ -124,21 +148,30 @@ func DeleteUser(c *gin.Context) {
 		return
 	}
 
+	changes := audit.Changes{}
+	changes.Set("is_deleted", user.IsDeleted, !user.IsDeleted)
+	previousStatus := user.Status
+
 	user.IsDeleted = !user.IsDeleted
-	var message string
+	var message, auditAction string
 	if user.IsDeleted {
 		user.Status = "Deleted"
 		user.IsBlocked = true
 		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
 		message = "User deleted successfully"
+		auditAction = audit.ActionDelete
 	} else {
 		user.Status = "Active"
 		user.IsBlocked = false
 		user.DeletedAt = gorm.DeletedAt{}
 		message = "User restored successfully"
+		auditAction = audit.ActionRestore
 	}
+	changes.Set("status", previousStatus, user.Status)
 
-	if err := config.DB.Save(&user).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Save(&user).Error; err != nil {
+		tx.Rollback()
 		action := "delete"
 		if !user.IsDeleted {
 			action = "restore"
@@ -151,6 +184,19 @@ func DeleteUser(c *gin.Context) {
 		helper.RespondWithError(c, http.StatusInternalServerError, errMsg, errMsg, "")
 		return
 	}
+	if err := audit.Record(tx, helper.AuditActor(c), auditAction, audit.EntityUser, user.ID, changes); err != nil {
+		logger.Log.Error("Failed to record audit entry", zap.String("userID", id), zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update user", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	if user.IsDeleted {
+		if _, err := sessions.RevokeAll(config.DB, user.ID, "User", "", sessions.ReasonBlocked); err != nil {
+			logger.Log.Error("Failed to sign out deleted user", zap.String("userID", id), zap.Error(err))
+		}
+	}
 
 	logger.Log.Info("User delete status updated successfully",
 		zap.String("userID", id),
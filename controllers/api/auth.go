@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const RoleUser = "User"
+
+// RequireUser checks the bearer access token and makes the user available
+// to the handlers behind it, the same way AuthMiddleware does for pages.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := middleware.AuthenticateBearer(c, RoleUser)
+		if err != nil {
+			if middleware.SessionEnded(err) {
+				respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Sign in to continue")
+				return
+			}
+			respondInternalError(c, "Failed to check bearer token", err)
+			return
+		}
+		c.Set("userid", claims.UserId)
+		c.Set("sessionid", claims.SessionID)
+		c.Next()
+	}
+}
+
+func userID(c *gin.Context) uint {
+	return c.MustGet("userid").(uint)
+}
+
+// Login checks the email and password. Accounts with two-factor sign-in get
+// a challenge to answer at /auth/login/2fa instead of tokens.
+func Login(c *gin.Context) {
+	var input LoginRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Email and password are required")
+		return
+	}
+	if accountLocked(c, input.Email) {
+		return
+	}
+
+	var user models.UserAuth
+	if err := config.DB.Unscoped().Where("email = ?", input.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warn("API login with unknown email", zap.String("email", input.Email))
+			respondError(c, http.StatusUnauthorized, CodeInvalidLogin, "Invalid email or password")
+			return
+		}
+		respondInternalError(c, "Failed to fetch user", err)
+		return
+	}
+	if user.IsDeleted {
+		respondError(c, http.StatusUnauthorized, CodeInvalidLogin, "Invalid email or password")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)) != nil {
+		logger.Log.Warn("API login with invalid password", zap.String("email", input.Email))
+		if recordFailedAttempt(c, input.Email) {
+			return
+		}
+		respondError(c, http.StatusUnauthorized, CodeInvalidLogin, "Invalid email or password")
+		return
+	}
+	if user.Status == "Blocked" {
+		respondError(c, http.StatusForbidden, CodeAccountBlocked, "Your account is blocked")
+		return
+	}
+
+	twoFactorEnabled, err := twofactor.IsEnabled(config.DB, user.ID, RoleUser)
+	if err != nil {
+		respondInternalError(c, "Failed to check two-factor status", err)
+		return
+	}
+	if twoFactorEnabled {
+		challenge, err := twofactor.NewChallenge(config.DB, user.ID, user.Email, RoleUser)
+		if err != nil {
+			respondInternalError(c, "Failed to start two-factor challenge", err)
+			return
+		}
+		respondOK(c, http.StatusOK, LoginResponse{TwoFactorRequired: true, ChallengeID: challenge.ID})
+		return
+	}
+
+	issueTokens(c, user.ID, user.Email, false)
+}
+
+// LoginTwoFactor answers the challenge returned by Login.
+func LoginTwoFactor(c *gin.Context) {
+	var input TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Challenge and code are required")
+		return
+	}
+
+	challenge, err := twofactor.FindChallenge(config.DB, input.ChallengeID, RoleUser)
+	if err != nil {
+		if errors.Is(err, twofactor.ErrChallengeExpired) || errors.Is(err, twofactor.ErrTooManyAttempts) {
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Your login has expired, please sign in again")
+			return
+		}
+		respondInternalError(c, "Failed to fetch two-factor challenge", err)
+		return
+	}
+	if accountLocked(c, challenge.Email) {
+		return
+	}
+
+	result, err := twofactor.Answer(config.DB, challenge, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			logger.Log.Warn("Invalid two-factor code", zap.String("email", challenge.Email))
+			if recordFailedAttempt(c, challenge.Email) {
+				return
+			}
+			respondError(c, http.StatusUnauthorized, CodeInvalidLogin, "Invalid code, please try again")
+		case errors.Is(err, twofactor.ErrTooManyAttempts):
+			if recordFailedAttempt(c, challenge.Email) {
+				return
+			}
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Too many invalid codes, please sign in again")
+		default:
+			respondInternalError(c, "Failed to verify two-factor code", err)
+		}
+		return
+	}
+
+	var user models.UserAuth
+	if err := config.DB.First(&user, "id = ? AND is_blocked = ? AND is_deleted = ?", challenge.UserID, false, false).Error; err != nil {
+		logger.Log.Warn("Blocked or deleted user finished two-factor login", zap.String("email", challenge.Email), zap.Error(err))
+		respondError(c, http.StatusForbidden, CodeAccountBlocked, "Your account is blocked")
+		return
+	}
+
+	issueTokens(c, user.ID, user.Email, result.UsedRecoveryCode)
+}
+
+// Refresh swaps a refresh token for a new access and refresh token.
+func Refresh(c *gin.Context) {
+	var input RefreshRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Refresh token is required")
+		return
+	}
+
+	tokens, err := middleware.RefreshTokens(c, input.RefreshToken, RoleUser)
+	if err != nil {
+		if middleware.SessionEnded(err) {
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Sign in to continue")
+			return
+		}
+		respondInternalError(c, "Failed to refresh tokens", err)
+		return
+	}
+	respondOK(c, http.StatusOK, tokenResponse(tokens))
+}
+
+// Logout ends the session of the access token.
+func Logout(c *gin.Context) {
+	err := sessions.Revoke(config.DB, userID(c), RoleUser, c.GetString("sessionid"), sessions.ReasonLogout)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		respondInternalError(c, "Failed to revoke session", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func issueTokens(c *gin.Context, userID uint, email string, usedRecoveryCode bool) {
+	tokens, err := middleware.IssueTokens(c, userID, email, RoleUser)
+	if err != nil {
+		respondInternalError(c, "Failed to issue tokens", err)
+		return
+	}
+	ratelimit.RecordSuccess(config.DB, ratelimit.ScopeUserLogin, email)
+	if usedRecoveryCode {
+		logger.Log.Warn("User signed in with a recovery code", zap.String("email", email))
+	}
+	logger.Log.Info("API login successful", zap.Uint("userID", userID))
+	respondOK(c, http.StatusOK, LoginResponse{Tokens: tokenResponse(tokens)})
+}
+
+func tokenResponse(tokens *middleware.TokenPair) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+}
+
+// accountLocked and recordFailedAttempt are helper.AccountLocked and
+// helper.RecordFailedAttempt answering in the API envelope.
+func accountLocked(c *gin.Context, email string) bool {
+	until, err := ratelimit.LockedUntil(config.DB, ratelimit.ScopeUserLogin, email)
+	if err != nil {
+		logger.Log.Error("Failed to check account lockout", zap.Error(err))
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+	respondLocked(c, until)
+	return true
+}
+
+func recordFailedAttempt(c *gin.Context, email string) bool {
+	until, err := ratelimit.RecordFailure(config.DB, ratelimit.ScopeUserLogin, email, c.ClientIP())
+	if err != nil {
+		logger.Log.Error("Failed to record failed attempt", zap.Error(err))
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+	respondLocked(c, until)
+	return true
+}
+
+func respondLocked(c *gin.Context, until time.Time) {
+	wait := time.Until(until)
+	c.Header("Retry-After", middleware.RetryAfterSeconds(wait))
+	respondError(c, http.StatusTooManyRequests, CodeAccountLocked,
+		"Too many failed attempts, please try again in "+middleware.RetryAfterText(wait))
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCart prices the cart for delivery to the user's most recently used
+// address, as the cart page does.
+func GetCart(c *gin.Context) {
+	cartItems, err := fetchCart(userID(c))
+	if err != nil {
+		respondInternalError(c, "Failed to fetch cart", err)
+		return
+	}
+	respondOK(c, http.StatusOK, cartResponse(cartItems, latestAddress(userID(c))))
+}
+
+func AddCartItem(c *gin.Context) {
+	var input AddCartItemRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "product_id is required")
+		return
+	}
+
+	if _, err := services.AddCartItem(config.DB, userID(c), input.ProductID); err != nil {
+		respondWithCartError(c, err)
+		return
+	}
+	GetCart(c)
+}
+
+func UpdateCartItem(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid cart item id")
+		return
+	}
+	var input UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "quantity must be zero or more")
+		return
+	}
+
+	if _, err := services.SetCartItemQuantity(config.DB, userID(c), uint(itemID), input.Quantity); err != nil {
+		respondWithCartError(c, err)
+		return
+	}
+	GetCart(c)
+}
+
+func RemoveCartItem(c *gin.Context) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid cart item id")
+		return
+	}
+
+	if err := services.RemoveCartItem(config.DB, userID(c), uint(itemID)); err != nil {
+		respondWithCartError(c, err)
+		return
+	}
+	GetCart(c)
+}
+
+func respondWithCartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		respondError(c, http.StatusNotFound, CodeNotFound, "Product not found")
+	case errors.Is(err, services.ErrCartItemNotFound):
+		respondError(c, http.StatusNotFound, CodeNotFound, "Cart item not found")
+	case errors.Is(err, services.ErrOutOfStock), errors.Is(err, services.ErrNotEnoughStock):
+		respondError(c, http.StatusConflict, CodeOutOfStock, "Not enough stock available")
+	case errors.Is(err, services.ErrMaxCartQuantity):
+		respondError(c, http.StatusConflict, CodeQuantityLimit,
+			"You can add up to "+strconv.Itoa(services.MaxCartQuantity)+" units of a product")
+	default:
+		respondInternalError(c, "Failed to update cart", err)
+	}
+}
+
+// fetchCart returns the user's cart items, creating the cart on first use.
+func fetchCart(userID uint) ([]services.CartItemDetailWithDiscount, error) {
+	if err := config.DB.FirstOrCreate(&models.Cart{}, models.Cart{UserID: userID}).Error; err != nil {
+		return nil, err
+	}
+	_, cartItems, err := services.FetchCartItems(userID)
+	return cartItems, err
+}
+
+func latestAddress(userID uint) models.UserAddress {
+	var address models.UserAddress
+	config.DB.Order("updated_at DESC").First(&address, "user_id = ?", userID)
+	return address
+}
+
+func cartResponse(cartItems []services.CartItemDetailWithDiscount, address models.UserAddress) CartResponse {
+	regularPrice, salePrice, tax, productDiscount, _, shipping := services.CalculateCartPrices(cartItems, address.PinCode, address.State)
+	cart := CartResponse{
+		Items:           []CartItemResponse{},
+		Subtotal:        regularPrice,
+		ProductDiscount: productDiscount,
+		Tax:             tax,
+		Shipping:        shipping.Charge,
+		ShippingWaived:  shipping.WaivedCharge,
+		Total:           salePrice + tax + shipping.Charge,
+	}
+	for _, item := range cartItems {
+		cart.Items = append(cart.Items, CartItemResponse{
+			ID:           item.CartItem.ID,
+			ProductID:    item.CartItem.ProductVariantID,
+			Name:         item.ProductDetails.ProductName,
+			Image:        item.ProductImage,
+			Quantity:     item.CartItem.Quantity,
+			RegularPrice: item.ProductDetails.RegularPrice,
+			UnitPrice:    item.DiscountPrice,
+			LineTotal:    item.DiscountPrice * float64(item.CartItem.Quantity),
+			InStock:      !item.ProductDetails.IsDeleted && item.ProductDetails.StockQuantity >= item.CartItem.Quantity,
+		})
+	}
+	return cart
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListProducts takes the same filter and sort parameters as the shop page.
+func ListProducts(c *gin.Context) {
+	page, perPage := paging(c)
+	filter := services.ParseProductFilter(c.Request.URL.Query())
+
+	var total int64
+	if err := services.ApplyProductFilters(config.DB.Model(&models.ProductVariantDetails{}), filter, "").
+		Distinct("product_variant_details.id").
+		Count(&total).Error; err != nil {
+		respondInternalError(c, "Failed to count products", err)
+		return
+	}
+
+	query := services.ApplyProductFilters(config.DB.Model(&models.ProductVariantDetails{}), filter, "").
+		Preload("VariantsImages", "is_deleted = ?", false).
+		Preload("Category").
+		Preload("Product")
+	query = services.SortProducts(query, c.Query("sort"), filter.Search)
+
+	var variants []models.ProductVariantDetails
+	if err := query.Offset((page - 1) * perPage).Limit(perPage).Find(&variants).Error; err != nil {
+		respondInternalError(c, "Failed to fetch products", err)
+		return
+	}
+
+	products := []ProductSummaryResponse{}
+	for _, variant := range variants {
+		product, err := productSummary(variant)
+		if err != nil {
+			respondInternalError(c, "Discount calculation failed", err)
+			return
+		}
+		products = append(products, product)
+	}
+	respondPage(c, products, Meta{Page: page, PerPage: perPage, Total: total})
+}
+
+func GetProduct(c *gin.Context) {
+	var variant models.ProductVariantDetails
+	if err := config.DB.
+		Preload("VariantsImages", "is_deleted = ?", false).
+		Preload("Specification", "is_deleted = ?", false).
+		Preload("Category").
+		Preload("Product.Descriptions", "is_deleted = ?", false).
+		First(&variant, "id = ? AND is_deleted = ?", c.Param("id"), false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "Product not found")
+			return
+		}
+		respondInternalError(c, "Failed to fetch product", err)
+		return
+	}
+
+	summary, err := productSummary(variant)
+	if err != nil {
+		respondInternalError(c, "Discount calculation failed", err)
+		return
+	}
+	product := ProductDetailResponse{
+		ProductSummaryResponse: summary,
+		Summary:                variant.ProductSummary,
+		Images:                 []string{},
+		Size:                   variant.Size,
+		Colour:                 variant.Colour,
+		Ram:                    variant.Ram,
+		Storage:                variant.Storage,
+		Stock:                  variant.StockQuantity,
+		Specifications:         []ProductSpecificationResponse{},
+		Descriptions:           []ProductDescriptionResponse{},
+	}
+	for _, image := range variant.VariantsImages {
+		product.Images = append(product.Images, helper.ZoomImage(image.ProductVariantsImages, image.ImageRenditions))
+	}
+	for _, spec := range variant.Specification {
+		product.Specifications = append(product.Specifications, ProductSpecificationResponse{
+			Key:   spec.SpecificationKey,
+			Value: spec.SpecificationValue,
+		})
+	}
+	for _, description := range variant.Product.Descriptions {
+		product.Descriptions = append(product.Descriptions, ProductDescriptionResponse{
+			Heading:     description.Heading,
+			Description: description.Description,
+		})
+	}
+	respondOK(c, http.StatusOK, product)
+}
+
+func ListCategories(c *gin.Context) {
+	var categories []models.Categories
+	if err := config.DB.Where("status = ? AND is_deleted = ?", "Active", false).Order("name").Find(&categories).Error; err != nil {
+		respondInternalError(c, "Failed to fetch categories", err)
+		return
+	}
+
+	response := []CategoryResponse{}
+	for _, category := range categories {
+		response = append(response, CategoryResponse{ID: category.ID, Name: category.Name})
+	}
+	respondOK(c, http.StatusOK, response)
+}
+
+func productSummary(variant models.ProductVariantDetails) (ProductSummaryResponse, error) {
+	discountAmount, totalPercentage, err := helper.DiscountCalculation(variant.ProductID, variant.CategoryID, variant.RegularPrice, variant.SalePrice)
+	if err != nil {
+		return ProductSummaryResponse{}, err
+	}
+	product := ProductSummaryResponse{
+		ID:              variant.ID,
+		Name:            variant.ProductName,
+		Category:        variant.Category.Name,
+		RegularPrice:    variant.RegularPrice,
+		SalePrice:       variant.SalePrice - discountAmount,
+		OfferPercentage: int(totalPercentage),
+		InStock:         variant.StockQuantity > 0,
+		AverageRating:   variant.Product.AverageRating,
+		RatingCount:     variant.Product.RatingCount,
+	}
+	if len(variant.VariantsImages) > 0 {
+		image := variant.VariantsImages[0]
+		product.Image = helper.ListingImage(image.ProductVariantsImages, image.ImageRenditions)
+		product.ImageWebP = image.ListingWebPURL
+	}
+	return product, nil
+}
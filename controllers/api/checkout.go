@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/services/checkout"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAddresses returns the user's addresses with whether the cart can be
+// delivered to each and when.
+func ListAddresses(c *gin.Context) {
+	var addresses []models.UserAddress
+	if err := config.DB.Order("updated_at DESC").Find(&addresses, "user_id = ?", userID(c)).Error; err != nil {
+		respondInternalError(c, "Failed to fetch addresses", err)
+		return
+	}
+
+	dispatchDays := 1
+	if _, cartItems, err := services.FetchCartItems(userID(c)); err == nil {
+		for _, item := range cartItems {
+			if days := services.ProductDispatchDays(item.ProductDetails.ProductID); days > dispatchDays {
+				dispatchDays = days
+			}
+		}
+	}
+
+	response := []AddressResponse{}
+	for _, address := range addresses {
+		delivery := services.EstimateDelivery(address.PinCode, dispatchDays, time.Now())
+		item := AddressResponse{
+			ID:             address.ID,
+			FirstName:      address.FirstName,
+			LastName:       address.LastName,
+			Mobile:         address.Mobile,
+			Address:        address.Address,
+			Landmark:       address.Landmark,
+			City:           address.City,
+			State:          address.State,
+			Country:        address.Country,
+			PinCode:        address.PinCode,
+			IsDefault:      address.IsDefault,
+			IsServiceable:  delivery.IsServiceable,
+			IsCODAvailable: delivery.IsCODAvailable,
+		}
+		if delivery.IsServiceable {
+			item.ExpectedDelivery = delivery.ExpectedDate.Format("2006-01-02")
+		}
+		response = append(response, item)
+	}
+	respondOK(c, http.StatusOK, response)
+}
+
+// StartCheckout holds the stock in the cart for checkout.ReservationTTL.
+// Orders have to be placed before it runs out.
+func StartCheckout(c *gin.Context) {
+	cartItems, err := checkout.ReserveStock(c.Request.Context(), userID(c))
+	if err != nil {
+		respondWithCheckoutError(c, err)
+		return
+	}
+	respondOK(c, http.StatusOK, CheckoutResponse{
+		ExpiresAt: time.Now().Add(checkout.ReservationTTL),
+		Cart:      cartResponse(cartItems, latestAddress(userID(c))),
+	})
+}
+
+type quoteQuery struct {
+	AddressID  uint   `form:"address_id" binding:"required"`
+	CouponCode string `form:"coupon_code"`
+}
+
+// GetQuote prices the reserved checkout for an address, with the coupon if
+// one is given. It reserves nothing; the coupon is only taken when the
+// order is placed.
+func GetQuote(c *gin.Context) {
+	var query quoteQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "address_id is required")
+		return
+	}
+
+	uid := userID(c)
+	var address models.UserAddress
+	if err := config.DB.First(&address, "id = ? AND user_id = ?", query.AddressID, uid).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "Address not found")
+			return
+		}
+		respondInternalError(c, "Failed to fetch address", err)
+		return
+	}
+
+	_, cartItems, err := services.FetchCartItems(uid)
+	if err != nil {
+		respondInternalError(c, "Failed to fetch cart", err)
+		return
+	}
+	reservations, err := checkout.FetchReservations(config.DB, uid)
+	if err != nil {
+		respondInternalError(c, "Failed to fetch reservations", err)
+		return
+	}
+	quote, err := checkout.PriceReservations(reservations, cartItems, &address)
+	if err != nil {
+		respondWithCheckoutError(c, err)
+		return
+	}
+
+	response := QuoteResponse{
+		Subtotal:        quote.RegularPrice,
+		ProductDiscount: quote.ProductDiscount,
+		Tax:             quote.Tax,
+		Shipping:        quote.ShippingCharge,
+		ShippingWaived:  quote.ShippingDiscount,
+		Total:           quote.Total,
+	}
+	if code := normalizeCouponCode(query.CouponCode); code != "" {
+		coupon, discount, err := services.ApplyCoupon(config.DB, code, cartItems, quote.SalePrice)
+		if err != nil {
+			respondWithCheckoutError(c, err)
+			return
+		}
+		response.CouponDiscount = discount
+		response.Total -= discount
+		response.Coupon = &CouponResponse{Code: coupon.CouponCode, Description: coupon.Discription, Discount: discount}
+	}
+
+	codAvailable, err := checkout.CODAvailable(config.DB, cartItems)
+	if err != nil {
+		respondInternalError(c, "Failed to check cash on delivery", err)
+		return
+	}
+	response.IsCODAvailable = codAvailable && response.Total <= checkout.CODLimit &&
+		services.CheckPinCodeDelivery(address.PinCode).IsCODAvailable
+	respondOK(c, http.StatusOK, response)
+}
+
+// PlaceOrder places the order for the reserved checkout. Online payments are
+// only available on the website for now.
+func PlaceOrder(c *gin.Context) {
+	var input PlaceOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "payment_method must be COD or Wallet and address_id is required")
+		return
+	}
+
+	result, err := checkout.PlaceOrder(c.Request.Context(), userID(c), checkout.Request{
+		PaymentMethod: input.PaymentMethod,
+		AddressID:     input.AddressID,
+		CouponCode:    normalizeCouponCode(input.CouponCode),
+	})
+	if err != nil {
+		respondWithCheckoutError(c, err)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("ShippingAddress").Preload("OrderItem").First(&order, result.Order.ID).Error; err != nil {
+		respondInternalError(c, "Failed to fetch placed order", err)
+		return
+	}
+	respondOK(c, http.StatusCreated, orderResponse(order))
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.TrimSpace(strings.ToUpper(code))
+}
+
+func respondWithCheckoutError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCartEmpty):
+		respondError(c, http.StatusConflict, CodeCartEmpty, "Your cart is empty")
+	case errors.Is(err, services.ErrOutOfStock), errors.Is(err, checkout.ErrProductUnavailable):
+		respondError(c, http.StatusConflict, CodeOutOfStock, "Some items in your cart are unavailable or out of stock")
+	case errors.Is(err, checkout.ErrCartChanged):
+		respondError(c, http.StatusConflict, CodeCartChanged, "Your cart changed, please start checkout again")
+	case errors.Is(err, checkout.ErrReservationExpired):
+		respondError(c, http.StatusConflict, CodeCheckoutExpired, "Your checkout expired, please start checkout again")
+	case errors.Is(err, checkout.ErrAddressNotFound):
+		respondError(c, http.StatusNotFound, CodeNotFound, "Address not found")
+	case errors.Is(err, checkout.ErrNotServiceable):
+		respondError(c, http.StatusUnprocessableEntity, CodeNotServiceable, "We do not deliver to this address yet")
+	case errors.Is(err, checkout.ErrCODUnavailable):
+		respondError(c, http.StatusUnprocessableEntity, CodeCODUnavailable, "Cash on delivery is not available for this order")
+	case errors.Is(err, checkout.ErrInsufficientBalance):
+		respondError(c, http.StatusPaymentRequired, CodeInsufficientFund, "Not enough balance in your wallet")
+	case errors.Is(err, checkout.ErrUnsupportedPayment):
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Payment method not supported")
+	case errors.Is(err, services.ErrCouponNotFound):
+		respondError(c, http.StatusUnprocessableEntity, CodeCouponInvalid, "Invalid coupon code")
+	case errors.Is(err, services.ErrCouponExpired):
+		respondError(c, http.StatusUnprocessableEntity, CodeCouponInvalid, "Coupon expired")
+	case errors.Is(err, services.ErrCouponNotStarted):
+		respondError(c, http.StatusUnprocessableEntity, CodeCouponInvalid, "Coupon not available yet")
+	case errors.Is(err, services.ErrCouponNotApplicable):
+		respondError(c, http.StatusUnprocessableEntity, CodeCouponInvalid, "Coupon not applicable to this cart")
+	default:
+		respondInternalError(c, "Checkout failed", err)
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import "time"
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	// RefreshToken is left out when another refresh with the same token
+	// won the race; keep using the refresh token you have.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type LoginResponse struct {
+	TwoFactorRequired bool           `json:"two_factor_required"`
+	ChallengeID       string         `json:"challenge_id,omitempty"`
+	Tokens            *TokenResponse `json:"tokens,omitempty"`
+}
+
+type CategoryResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type ProductSummaryResponse struct {
+	ID              uint    `json:"id"`
+	Name            string  `json:"name"`
+	Category        string  `json:"category"`
+	RegularPrice    float64 `json:"regular_price"`
+	SalePrice       float64 `json:"sale_price"`
+	OfferPercentage int     `json:"offer_percentage"`
+	Image           string  `json:"image"`
+	ImageWebP       string  `json:"image_webp,omitempty"`
+	InStock         bool    `json:"in_stock"`
+	AverageRating   float64 `json:"average_rating"`
+	RatingCount     int     `json:"rating_count"`
+}
+
+type ProductSpecificationResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type ProductDescriptionResponse struct {
+	Heading     string `json:"heading"`
+	Description string `json:"description"`
+}
+
+type ProductDetailResponse struct {
+	ProductSummaryResponse
+	Summary        string                         `json:"summary"`
+	Images         []string                       `json:"images"`
+	Size           string                         `json:"size,omitempty"`
+	Colour         string                         `json:"colour,omitempty"`
+	Ram            string                         `json:"ram,omitempty"`
+	Storage        string                         `json:"storage,omitempty"`
+	Stock          int                            `json:"stock"`
+	Specifications []ProductSpecificationResponse `json:"specifications"`
+	Descriptions   []ProductDescriptionResponse   `json:"descriptions"`
+}
+
+type AddCartItemRequest struct {
+	ProductID uint `json:"product_id" binding:"required"`
+}
+
+type UpdateCartItemRequest struct {
+	// Quantity of zero removes the item.
+	Quantity int `json:"quantity" binding:"gte=0"`
+}
+
+type CartItemResponse struct {
+	ID           uint    `json:"id"`
+	ProductID    uint    `json:"product_id"`
+	Name         string  `json:"name"`
+	Image        string  `json:"image"`
+	Quantity     int     `json:"quantity"`
+	RegularPrice float64 `json:"regular_price"`
+	UnitPrice    float64 `json:"unit_price"`
+	LineTotal    float64 `json:"line_total"`
+	InStock      bool    `json:"in_stock"`
+}
+
+type CartResponse struct {
+	Items           []CartItemResponse `json:"items"`
+	Subtotal        float64            `json:"subtotal"`
+	ProductDiscount float64            `json:"product_discount"`
+	Tax             float64            `json:"tax"`
+	Shipping        float64            `json:"shipping"`
+	ShippingWaived  float64            `json:"shipping_waived"`
+	Total           float64            `json:"total"`
+}
+
+type AddressResponse struct {
+	ID               uint   `json:"id"`
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	Mobile           string `json:"mobile"`
+	Address          string `json:"address"`
+	Landmark         string `json:"landmark,omitempty"`
+	City             string `json:"city"`
+	State            string `json:"state"`
+	Country          string `json:"country"`
+	PinCode          string `json:"pin_code"`
+	IsDefault        bool   `json:"is_default"`
+	IsServiceable    bool   `json:"is_serviceable"`
+	IsCODAvailable   bool   `json:"is_cod_available"`
+	ExpectedDelivery string `json:"expected_delivery,omitempty"`
+}
+
+type CheckoutResponse struct {
+	ExpiresAt time.Time    `json:"expires_at"`
+	Cart      CartResponse `json:"cart"`
+}
+
+type CouponResponse struct {
+	Code        string  `json:"code"`
+	Description string  `json:"description"`
+	Discount    float64 `json:"discount"`
+}
+
+type QuoteResponse struct {
+	Subtotal        float64         `json:"subtotal"`
+	ProductDiscount float64         `json:"product_discount"`
+	CouponDiscount  float64         `json:"coupon_discount"`
+	Tax             float64         `json:"tax"`
+	Shipping        float64         `json:"shipping"`
+	ShippingWaived  float64         `json:"shipping_waived"`
+	Total           float64         `json:"total"`
+	IsCODAvailable  bool            `json:"is_cod_available"`
+	Coupon          *CouponResponse `json:"coupon,omitempty"`
+}
+
+type PlaceOrderRequest struct {
+	PaymentMethod string `json:"payment_method" binding:"required,oneof=COD Wallet"`
+	AddressID     uint   `json:"address_id" binding:"required"`
+	CouponCode    string `json:"coupon_code"`
+}
+
+type OrderItemResponse struct {
+	ID               uint      `json:"id"`
+	OrderUID         string    `json:"order_uid"`
+	ProductID        uint      `json:"product_id"`
+	Name             string    `json:"name"`
+	Image            string    `json:"image"`
+	Quantity         int       `json:"quantity"`
+	UnitPrice        float64   `json:"unit_price"`
+	Tax              float64   `json:"tax"`
+	Total            float64   `json:"total"`
+	Status           string    `json:"status"`
+	ExpectedDelivery time.Time `json:"expected_delivery"`
+}
+
+type ShippingAddressResponse struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Mobile    string `json:"mobile"`
+	Address   string `json:"address"`
+	Landmark  string `json:"landmark,omitempty"`
+	City      string `json:"city"`
+	State     string `json:"state"`
+	Country   string `json:"country"`
+	PinCode   string `json:"pin_code"`
+}
+
+// OrderResponse amounts are in rupees. Currency is what the customer saw
+// them in, at ExchangeRate rupees to one unit of it.
+type OrderResponse struct {
+	ID              uint                     `json:"id"`
+	OrderUID        string                   `json:"order_uid"`
+	OrderDate       time.Time                `json:"order_date"`
+	Subtotal        float64                  `json:"subtotal"`
+	TotalDiscount   float64                  `json:"total_discount"`
+	CouponCode      string                   `json:"coupon_code,omitempty"`
+	CouponDiscount  float64                  `json:"coupon_discount"`
+	Tax             float64                  `json:"tax"`
+	Shipping        float64                  `json:"shipping"`
+	Total           float64                  `json:"total"`
+	Currency        string                   `json:"currency"`
+	ExchangeRate    float64                  `json:"exchange_rate"`
+	ShippingAddress *ShippingAddressResponse `json:"shipping_address,omitempty"`
+	Items           []OrderItemResponse      `json:"items"`
+}
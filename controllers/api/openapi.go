@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/openapi"
+)
+
+// dataResponse and pageResponse are the shapes Envelope takes on success,
+// spelt out for the OpenAPI document; errorResponse is the shape it takes
+// on failure.
+type dataResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+type pageResponse[T any] struct {
+	Data []T  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+// Operations documents the API handlers in the OpenAPI document. Request
+// must be the type the handler binds.
+var Operations = []openapi.Description{
+	openapi.Describe(Login, openapi.Operation{Summary: "Sign in with email and password", Request: LoginRequest{}, Response: dataResponse[LoginResponse]{}, Error: errorResponse{}}),
+	openapi.Describe(LoginTwoFactor, openapi.Operation{Summary: "Answer a two-factor challenge", Request: TwoFactorLoginRequest{}, Response: dataResponse[LoginResponse]{}, Error: errorResponse{}}),
+	openapi.Describe(Refresh, openapi.Operation{Summary: "Exchange a refresh token for new tokens", Request: RefreshRequest{}, Response: dataResponse[TokenResponse]{}, Error: errorResponse{}}),
+	openapi.Describe(Logout, openapi.Operation{Summary: "End the session", Status: http.StatusNoContent, Error: errorResponse{}, Security: openapi.BearerAuth}),
+
+	openapi.Describe(ListProducts, openapi.Operation{Summary: "List products with the shop's filters", Response: pageResponse[ProductSummaryResponse]{}, Error: errorResponse{}}),
+	openapi.Describe(GetProduct, openapi.Operation{Summary: "Get a product", Response: dataResponse[ProductDetailResponse]{}, Error: errorResponse{}}),
+	openapi.Describe(ListCategories, openapi.Operation{Summary: "List categories", Response: dataResponse[[]CategoryResponse]{}, Error: errorResponse{}}),
+
+	openapi.Describe(GetCart, openapi.Operation{Summary: "Get the cart", Response: dataResponse[CartResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(AddCartItem, openapi.Operation{Summary: "Add one unit of a product", Request: AddCartItemRequest{}, Response: dataResponse[CartResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(UpdateCartItem, openapi.Operation{Summary: "Change the quantity of a cart item", Request: UpdateCartItemRequest{}, Response: dataResponse[CartResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(RemoveCartItem, openapi.Operation{Summary: "Remove a cart item", Response: dataResponse[CartResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+
+	openapi.Describe(ListAddresses, openapi.Operation{Summary: "List addresses with delivery availability", Response: dataResponse[[]AddressResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(StartCheckout, openapi.Operation{Summary: "Hold the cart's stock for checkout", Response: dataResponse[CheckoutResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(GetQuote, openapi.Operation{Summary: "Price the checkout for an address", Query: quoteQuery{}, Response: dataResponse[QuoteResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(PlaceOrder, openapi.Operation{Summary: "Place the order", Request: PlaceOrderRequest{}, Response: dataResponse[OrderResponse]{}, Status: http.StatusCreated, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(ListOrders, openapi.Operation{Summary: "List orders", Response: pageResponse[OrderResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+	openapi.Describe(GetOrder, openapi.Operation{Summary: "Get an order by id or order number", Response: dataResponse[OrderResponse]{}, Error: errorResponse{}, Security: openapi.BearerAuth}),
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ListOrders(c *gin.Context) {
+	page, perPage := paging(c)
+	query := config.DB.Model(&models.Order{}).Where("user_id = ?", userID(c))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondInternalError(c, "Failed to count orders", err)
+		return
+	}
+
+	var orders []models.Order
+	if err := query.Preload("OrderItem").
+		Order("order_date DESC").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Find(&orders).Error; err != nil {
+		respondInternalError(c, "Failed to fetch orders", err)
+		return
+	}
+
+	response := []OrderResponse{}
+	for _, order := range orders {
+		response = append(response, orderResponse(order))
+	}
+	respondPage(c, response, Meta{Page: page, PerPage: perPage, Total: total})
+}
+
+// GetOrder finds an order by its id or order number.
+func GetOrder(c *gin.Context) {
+	var order models.Order
+	if err := config.DB.Preload("ShippingAddress").Preload("OrderItem").
+		Where("user_id = ?", userID(c)).
+		Where("order_uid = ? OR CAST(id AS TEXT) = ?", c.Param("id"), c.Param("id")).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "Order not found")
+			return
+		}
+		respondInternalError(c, "Failed to fetch order", err)
+		return
+	}
+	respondOK(c, http.StatusOK, orderResponse(order))
+}
+
+func orderResponse(order models.Order) OrderResponse {
+	response := OrderResponse{
+		ID:             order.ID,
+		OrderUID:       order.OrderUID,
+		OrderDate:      order.OrderDate,
+		Subtotal:       order.SubTotal,
+		TotalDiscount:  order.TotalDiscount,
+		CouponCode:     order.CouponCode,
+		CouponDiscount: order.CouponDiscountAmount,
+		Tax:            order.Tax,
+		Shipping:       order.ShippingCharge,
+		Total:          order.TotalAmount,
+		Currency:       order.Currency,
+		ExchangeRate:   order.ExchangeRate,
+		Items:          []OrderItemResponse{},
+	}
+	if order.ShippingAddress.ID != 0 {
+		address := order.ShippingAddress
+		response.ShippingAddress = &ShippingAddressResponse{
+			FirstName: address.FirstName,
+			LastName:  address.LastName,
+			Mobile:    address.Mobile,
+			Address:   address.Address,
+			Landmark:  address.Landmark,
+			City:      address.City,
+			State:     address.State,
+			Country:   address.Country,
+			PinCode:   address.PinCode,
+		}
+	}
+	for _, item := range order.OrderItem {
+		response.Items = append(response.Items, OrderItemResponse{
+			ID:               item.ID,
+			OrderUID:         item.OrderUID,
+			ProductID:        item.ProductVariantID,
+			Name:             item.ProductName,
+			Image:            item.ProductImage,
+			Quantity:         item.Quantity,
+			UnitPrice:        item.ProductSalePrice,
+			Tax:              item.Tax,
+			Total:            item.Total,
+			Status:           item.OrderStatus,
+			ExpectedDelivery: item.ExpectedDeliveryDate,
+		})
+	}
+	return response
+}
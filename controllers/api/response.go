@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Envelope is the body of every /api/v1 response. Data is set on success and
+// Error on failure; Meta is only set on paged lists.
+type Envelope struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error *Error      `json:"error,omitempty"`
+	Meta  *Meta       `json:"meta,omitempty"`
+}
+
+// Error tells clients what went wrong. Code is stable and meant for
+// programs; Message is meant for people.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Meta struct {
+	Page    int   `json:"page"`
+	PerPage int   `json:"per_page"`
+	Total   int64 `json:"total"`
+}
+
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeInvalidLogin     = "invalid_credentials"
+	CodeAccountBlocked   = "account_blocked"
+	CodeAccountLocked    = "account_locked"
+	CodeRateLimited      = "rate_limited"
+	CodeNotFound         = "not_found"
+	CodeOutOfStock       = "out_of_stock"
+	CodeQuantityLimit    = "quantity_limit"
+	CodeCartEmpty        = "cart_empty"
+	CodeCartChanged      = "cart_changed"
+	CodeCheckoutExpired  = "checkout_expired"
+	CodeNotServiceable   = "not_serviceable"
+	CodeCODUnavailable   = "cod_unavailable"
+	CodeInsufficientFund = "insufficient_balance"
+	CodeCouponInvalid    = "coupon_invalid"
+	CodeInternal         = "internal_error"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+func respondOK(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, Envelope{Data: data})
+}
+
+func respondPage(c *gin.Context, data interface{}, meta Meta) {
+	c.JSON(http.StatusOK, Envelope{Data: data, Meta: &meta})
+}
+
+func respondError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, Envelope{Error: &Error{Code: code, Message: message}})
+}
+
+func respondInternalError(c *gin.Context, msg string, err error) {
+	logger.Log.Error(msg, zap.String("path", c.Request.URL.Path), zap.Error(err))
+	respondError(c, http.StatusInternalServerError, CodeInternal, "Something went wrong")
+}
+
+// paging reads the page and per_page query parameters.
+func paging(c *gin.Context) (page, perPage int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ = strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(defaultPerPage)))
+	if perPage < 1 || perPage > maxPerPage {
+		perPage = defaultPerPage
+	}
+	return page, perPage
+}
+
+// RateLimit is middleware.RateLimit answering in the API envelope.
+func RateLimit(rule ratelimit.Rule, account middleware.AccountKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if retryAfter, limited := middleware.RateLimited(c, rule, account); limited {
+			c.Header("Retry-After", middleware.RetryAfterSeconds(retryAfter))
+			respondError(c, http.StatusTooManyRequests, CodeRateLimited,
+				"Too many attempts, please try again in "+middleware.RetryAfterText(retryAfter))
+			return
+		}
+		c.Next()
+	}
+}
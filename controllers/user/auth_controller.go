@@ -11,10 +11,13 @@ import (
 	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"github.com/anfastk/E-Commerce-Website/pkg/outbox"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	authsessions "github.com/anfastk/E-Commerce-Website/pkg/sessions"
 	"github.com/anfastk/E-Commerce-Website/utils"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/sessions"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -25,19 +28,11 @@ var Store = sessions.NewCookieStore([]byte("laptixsecretkey"))
 func SendOtp(c *gin.Context) {
 	logger.Log.Info("Requested to send OTP")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already authenticated, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already authenticated, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
 	session, _ := Store.Get(c.Request, "session")
@@ -56,35 +51,49 @@ func SendOtp(c *gin.Context) {
 	otpRecord.OTP = otp
 	otpRecord.ExpireTime = expiry
 
-	if err := config.DB.Create(&otpRecord).Error; err != nil {
+	tx := config.DB.Begin()
+	if err := tx.Create(&otpRecord).Error; err != nil {
 		logger.Log.Error("Failed to store OTP in database",
 			zap.String("email", email),
 			zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to store OTP", "Something Went Wrong,Please Try Again ", "")
 		return
 	}
 
-	if err := utils.SendOTPToEmail(email, otp); err != nil {
-		logger.Log.Error("Failed to send OTP email",
+	if err := outbox.Enqueue(tx, notifier.EventOTPEmail, notifier.OTPEmail{Email: email, OTP: otp}); err != nil {
+		logger.Log.Error("Failed to queue OTP email",
 			zap.String("email", email),
 			zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to send OTP", "Failed To Send OTP , Please Try Again ", "")
 		return
 	}
 
+	if err := tx.Commit().Error; err != nil {
+		logger.Log.Error("Failed to commit OTP",
+			zap.String("email", email),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to send OTP", "Failed To Send OTP , Please Try Again ", "")
+		return
+	}
+	outbox.Kick(config.DB)
+
 	logger.Log.Info("OTP sent successfully",
 		zap.String("email", email),
 		zap.String("otp", otp))
 	c.Redirect(http.StatusSeeOther, "/auth/signup/verifyotp")
 }
 
+type otpInput struct {
+	Email string `form:"email"`
+	OTP   string `form:"otp"`
+}
+
 func VerifyOtp(c *gin.Context) {
 	logger.Log.Info("Requested to verify OTP")
 
-	var otpInput struct {
-		Email string `form:"email"`
-		OTP   string `form:"otp"`
-	}
+	var otpInput otpInput
 
 	if err := c.ShouldBind(&otpInput); err != nil {
 		logger.Log.Error("Failed to bind OTP input",
@@ -93,12 +102,21 @@ func VerifyOtp(c *gin.Context) {
 		return
 	}
 
+	if helper.AccountLocked(c, ratelimit.ScopeOTP, otpInput.Email) {
+		logger.Log.Warn("OTP attempt on locked email",
+			zap.String("email", otpInput.Email))
+		return
+	}
+
 	var otpRecord models.Otp
 	if err := config.DB.Where("email = ? AND otp = ?", otpInput.Email, otpInput.OTP).Order("created_at DESC").First(&otpRecord).Error; err != nil {
 		logger.Log.Warn("Invalid OTP provided",
 			zap.String("email", otpInput.Email),
 			zap.String("otp", otpInput.OTP),
 			zap.Error(err))
+		if helper.RecordFailedAttempt(c, ratelimit.ScopeOTP, otpInput.Email) {
+			return
+		}
 		helper.RespondWithError(c, http.StatusBadRequest, "Invalid OTP", "Invalid OTP", "")
 		return
 	}
@@ -150,6 +168,11 @@ func VerifyOtp(c *gin.Context) {
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change password", "Something Went Wrong , Please Try Again ", "")
 			return
 		}
+		if _, err := authsessions.RevokeAll(config.DB, userAuth.ID, RoleUser, "", authsessions.ReasonPassword); err != nil {
+			logger.Log.Error("Failed to sign out sessions after password reset",
+				zap.String("email", userAuth.Email),
+				zap.Error(err))
+		}
 		logger.Log.Info("User password updated successfully",
 			zap.String("email", userAuth.Email))
 	}
@@ -161,6 +184,7 @@ func VerifyOtp(c *gin.Context) {
 			zap.Error(err))
 	}
 
+	helper.ClearFailedAttempts(ratelimit.ScopeOTP, otpInput.Email)
 	logger.Log.Info("OTP verified successfully",
 		zap.String("email", otpInput.Email))
 	c.JSON(http.StatusOK, gin.H{
@@ -170,6 +194,14 @@ func VerifyOtp(c *gin.Context) {
 	})
 }
 
+// OTPSessionEmail is the email waiting for an OTP in this browser, used to
+// rate limit resends per address.
+func OTPSessionEmail(c *gin.Context) string {
+	session, _ := Store.Get(c.Request, "session")
+	email, _ := session.Values["email"].(string)
+	return email
+}
+
 func ResendOTP(c *gin.Context) {
 	logger.Log.Info("Requested to resend OTP")
 
@@ -184,8 +216,9 @@ func ResendOTP(c *gin.Context) {
 	otp := utils.GenerateOTP(6)
 	expiry := time.Now().Add(5 * time.Minute)
 
+	tx := config.DB.Begin()
 	var otpRecord models.Otp
-	err := config.DB.Where("email = ?", email).First(&otpRecord).Error
+	err := tx.Where("email = ?", email).First(&otpRecord).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			otpRecord = models.Otp{
@@ -193,10 +226,11 @@ func ResendOTP(c *gin.Context) {
 				OTP:        otp,
 				ExpireTime: expiry,
 			}
-			if err := config.DB.Create(&otpRecord).Error; err != nil {
+			if err := tx.Create(&otpRecord).Error; err != nil {
 				logger.Log.Error("Failed to create new OTP record",
 					zap.String("email", email),
 					zap.Error(err))
+				tx.Rollback()
 				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create OTP record", "Something Went Wrong , Please Try Again ", "")
 				return
 			}
@@ -204,28 +238,40 @@ func ResendOTP(c *gin.Context) {
 			logger.Log.Error("Failed to retrieve existing OTP record",
 				zap.String("email", email),
 				zap.Error(err))
+			tx.Rollback()
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve OTP record", "Something Went Wrong , Please Try Again ", "")
 			return
 		}
 	} else { 
 		otpRecord.OTP = otp
 		otpRecord.ExpireTime = expiry
-		if err := config.DB.Save(&otpRecord).Error; err != nil {
+		if err := tx.Save(&otpRecord).Error; err != nil {
 			logger.Log.Error("Failed to update OTP record",
 				zap.String("email", email),
 				zap.Error(err))
+			tx.Rollback()
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update OTP record", "Something Went Wrong , Please Try Again", "")
 			return
 		}
 	}
 
-	if err := utils.SendOTPToEmail(email, otp); err != nil {
-		logger.Log.Error("Failed to resend OTP email",
+	if err := outbox.Enqueue(tx, notifier.EventOTPEmail, notifier.OTPEmail{Email: email, OTP: otp}); err != nil {
+		logger.Log.Error("Failed to queue OTP email",
+			zap.String("email", email),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to send OTP", "Something Went Wrong , Please Try Again", "")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Log.Error("Failed to commit OTP",
 			zap.String("email", email),
 			zap.Error(err))
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to send OTP", "Something Went Wrong , Please Try Again", "")
 		return
 	}
+	outbox.Kick(config.DB)
 
 	logger.Log.Info("OTP resent successfully",
 		zap.String("email", email),
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -128,7 +129,7 @@ func ShowCart(c *gin.Context) {
 			RegularPrice:    variant.RegularPrice,
 			SalePrice:       variant.SalePrice - DiscountAmount,
 			OfferPersentage: int(TotalPercentage),
-			Images:          variant.VariantsImages[0].ProductVariantsImages,
+			Images:          helper.ListingImage(variant.VariantsImages[0].ProductVariantsImages, variant.VariantsImages[0].ImageRenditions),
 			CategoryName:    variant.Category.Name,
 			IsInWishlist:    IsInWishlist,
 		})
@@ -141,6 +142,7 @@ func ShowCart(c *gin.Context) {
 	c.HTML(http.StatusOK, "cart.html", gin.H{
 		"Suggestion": suggest,
 		"CartItem":   cartItemResponceDetails,
+		"Currency":   helper.DisplayCurrency(c),
 	})
 }
 
@@ -150,167 +152,106 @@ func AddToCart(c *gin.Context) {
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var cart models.Cart
-	tx := config.DB.Begin()
-
-	if err := tx.First(&cart, "user_id = ?", userID).Error; err != nil {
-		cart = models.Cart{
-			UserID: userID,
-		}
-		if createErr := tx.Create(&cart).Error; createErr != nil {
-			logger.Log.Error("Failed to create cart", zap.Uint("userID", userID), zap.Error(createErr))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Cart creation Failed", "Cart creation Failed", "")
-			return
-		}
-		logger.Log.Info("Created new cart", zap.Uint("cartID", cart.ID))
-	}
-
 	productID, iderr := strconv.Atoi(c.Param("id"))
 	if iderr != nil {
 		logger.Log.Error("Invalid product ID", zap.String("productID", c.Param("id")), zap.Error(iderr))
-		tx.Rollback()
 		helper.RespondWithError(c, http.StatusBadRequest, "Product Id Not Found", "Product Id Not Found", "")
 		return
 	}
 
-	var product models.ProductVariantDetails
-	if err := tx.First(&product, productID).Error; err != nil {
-		logger.Log.Error("Product not found", zap.Int("productID", productID), zap.Error(err))
-		tx.Rollback()
-		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found ", "Product Not Found ", "")
-		return
-	}
-
-	if product.StockQuantity == 0 {
-		logger.Log.Warn("Product out of stock", zap.Int("productID", productID))
-		tx.Rollback()
-		helper.RespondWithError(c, http.StatusConflict, "Product Out Of Stock", "Product Out Of Stock", "")
-		return
-	}
-
-	var cartItems models.CartItem
-	if err := tx.First(&cartItems, "product_id = ? AND product_variant_id = ? AND cart_id = ?", product.ProductID, product.ID, cart.ID).Error; err != nil {
-		cartItems = models.CartItem{
-			CartID:           cart.ID,
-			ProductID:        product.ProductID,
-			ProductVariantID: uint(productID),
-			Quantity:         1,
-		}
-		if createErr := tx.Create(&cartItems).Error; createErr != nil {
-			logger.Log.Error("Failed to create cart item",
+	cartItem, err := services.AddCartItem(config.DB, userID, uint(productID))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductNotFound):
+			logger.Log.Error("Product not found", zap.Int("productID", productID), zap.Error(err))
+			helper.RespondWithError(c, http.StatusNotFound, "Product Not Found ", "Product Not Found ", "")
+		case errors.Is(err, services.ErrOutOfStock):
+			logger.Log.Warn("Product out of stock", zap.Int("productID", productID))
+			helper.RespondWithError(c, http.StatusConflict, "Product Out Of Stock", "Product Out Of Stock", "")
+		default:
+			logger.Log.Error("Failed to add item to cart",
+				zap.Uint("userID", userID),
 				zap.Int("productID", productID),
-				zap.Error(createErr))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Add to Cart Failed", "Add to Cart Failed", "")
-			return
-		}
-	} else if cartItems.Quantity < 3 {
-		qty := cartItems.Quantity
-		cartItems.Quantity = qty + 1
-		if updateErr := tx.Model(&cartItems).Updates(cartItems).Error; updateErr != nil {
-			logger.Log.Error("Failed to update cart item quantity",
-				zap.Uint("cartItemID", cartItems.ID),
-				zap.Error(updateErr))
-			tx.Rollback()
+				zap.Error(err))
 			helper.RespondWithError(c, http.StatusInternalServerError, "Add to Cart Failed", "Add to Cart Failed", "")
-			return
 		}
+		return
 	}
 
-	tx.Commit()
 	logger.Log.Info("Item added to cart successfully",
 		zap.Uint("userID", userID),
 		zap.Int("productID", productID),
-		zap.Int("quantity", int(cartItems.Quantity)))
+		zap.Int("quantity", cartItem.Quantity))
 	helper.RespondWithError(c, http.StatusOK, "Add to Cart Success", "Add to Cart Success", "")
 }
 
+type cartQuantityInput struct {
+	Action string `json:"action"`
+}
+
 func CartItemUpdate(c *gin.Context) {
 	logger.Log.Info("Requested to update cart item")
 
-	itemID := c.Param("id")
-	var cartItems models.CartItem
-	if err := config.DB.First(&cartItems, itemID).Error; err != nil {
-		logger.Log.Error("Cart item not found", zap.String("itemID", itemID), zap.Error(err))
+	userID := helper.FetchUserID(c)
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Error("Invalid cart item ID", zap.String("itemID", c.Param("id")), zap.Error(err))
 		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Product Not Found", "")
 		return
 	}
 
-	var requestBody struct {
-		Action string `json:"action"`
+	cartItem, err := services.FindCartItem(config.DB, userID, uint(itemID))
+	if err != nil {
+		logger.Log.Error("Cart item not found", zap.Int("itemID", itemID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Product Not Found", "")
+		return
 	}
+
+	var requestBody cartQuantityInput
 	if err := c.BindJSON(&requestBody); err != nil {
 		logger.Log.Error("Invalid request body", zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "Invalid request body", "Invalid request body", "")
 		return
 	}
 
-	qty := cartItems.Quantity
+	quantity := cartItem.Quantity - 1
 	if requestBody.Action == "increase" {
-		var product models.ProductVariantDetails
-		if err := config.DB.First(&product, cartItems.ProductVariantID).Error; err != nil {
+		quantity = cartItem.Quantity + 1
+	}
+
+	cartItem, err = services.SetCartItemQuantity(config.DB, userID, cartItem.ID, quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductNotFound):
 			logger.Log.Error("Product not found for cart item",
-				zap.Uint("productVariantID", cartItems.ProductVariantID),
+				zap.Uint("productVariantID", cartItem.ProductVariantID),
 				zap.Error(err))
 			helper.RespondWithError(c, http.StatusNotFound, "Product not found", "Product not found", "")
-			return
-		}
-
-		if cartItems.Quantity < product.StockQuantity {
-			if cartItems.Quantity < 3 {
-				cartItems.Quantity = qty + 1
-				if createErr := config.DB.Model(&cartItems).Updates(cartItems).Error; createErr != nil {
-					logger.Log.Error("Failed to increase cart item quantity",
-						zap.Uint("cartItemID", cartItems.ID),
-						zap.Error(createErr))
-					helper.RespondWithError(c, http.StatusInternalServerError, "Add to Cart Failed", "Add to Cart Failed", "")
-					return
-				}
-			} else {
-				logger.Log.Warn("Maximum quantity exceeded",
-					zap.Uint("cartItemID", cartItems.ID))
-				helper.RespondWithError(c, http.StatusBadRequest, "Maximum quantity exceeded", "Maximum quantity exceeded", "")
-				return
-			}
-		} else {
+		case errors.Is(err, services.ErrMaxCartQuantity):
+			logger.Log.Warn("Maximum quantity exceeded",
+				zap.Uint("cartItemID", cartItem.ID))
+			helper.RespondWithError(c, http.StatusBadRequest, "Maximum quantity exceeded", "Maximum quantity exceeded", "")
+		case errors.Is(err, services.ErrNotEnoughStock):
 			logger.Log.Warn("Not enough stock available",
-				zap.Uint("cartItemID", cartItems.ID),
-				zap.Int("stockQuantity", int(product.StockQuantity)))
+				zap.Uint("cartItemID", cartItem.ID))
 			helper.RespondWithError(c, http.StatusBadRequest, "Not enough stock available", "Not enough stock available", "")
-			return
-		}
-	} else {
-		cartItems.Quantity = qty - 1
-		if cartItems.Quantity <= 0 {
-			if err := config.DB.Unscoped().Delete(&cartItems).Error; err != nil {
-				logger.Log.Error("Failed to delete cart item",
-					zap.Uint("cartItemID", cartItems.ID),
-					zap.Error(err))
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete item", "Failed to delete item", "")
-				return
-			}
-			logger.Log.Info("Cart item deleted due to zero quantity",
-				zap.Uint("cartItemID", cartItems.ID))
-		} else {
-			if createErr := config.DB.Model(&cartItems).Updates(cartItems).Error; createErr != nil {
-				logger.Log.Error("Failed to decrease cart item quantity",
-					zap.Uint("cartItemID", cartItems.ID),
-					zap.Error(createErr))
-				helper.RespondWithError(c, http.StatusInternalServerError, "Add to Cart Failed", "Add to Cart Failed", "")
-				return
-			}
+		default:
+			logger.Log.Error("Failed to update cart item quantity",
+				zap.Uint("cartItemID", cartItem.ID),
+				zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Add to Cart Failed", "Add to Cart Failed", "")
 		}
+		return
 	}
 
 	logger.Log.Info("Cart item quantity updated successfully",
-		zap.Uint("cartItemID", cartItems.ID),
-		zap.Int("newQuantity", int(cartItems.Quantity)))
+		zap.Uint("cartItemID", cartItem.ID),
+		zap.Int("newQuantity", int(cartItem.Quantity)))
 	c.JSON(http.StatusOK, gin.H{
 		"status":   "OK",
 		"message":  "Quantity updated",
 		"code":     http.StatusOK,
-		"quantity": cartItems.Quantity,
+		"quantity": cartItem.Quantity,
 	})
 }
 
@@ -340,13 +281,26 @@ func ShowCartTotal(c *gin.Context) {
 		return
 	}
 
+	var shippingItems []services.ShippingItem
 	for _, items := range cartItems {
 		discountAmount, _, _ := helper.DiscountCalculation(items.ProductID, items.ProductVariant.CategoryID, items.ProductVariant.RegularPrice, items.ProductVariant.SalePrice)
+		lineTotal := (items.ProductVariant.SalePrice - discountAmount) * float64(items.Quantity)
 		subTotal += items.ProductVariant.RegularPrice * float64(items.Quantity)
-		total += (items.ProductVariant.SalePrice - discountAmount) * float64(items.Quantity)
+		total += lineTotal
+		shippingItems = append(shippingItems, services.ShippingItem{
+			ProductVariantID: items.ProductVariantID,
+			ProductID:        items.ProductVariant.ProductID,
+			Quantity:         items.Quantity,
+			Amount:           lineTotal,
+		})
 	}
 	cartDiscountAmount := subTotal - total
 
+	var latestAddress models.UserAddress
+	config.DB.Order("updated_at DESC").First(&latestAddress, "user_id = ?", userID)
+	shipping := services.CalculateShipping(shippingItems, latestAddress.PinCode, latestAddress.State)
+	total += shipping.Charge
+
 	count := CartCount(c)
 	logger.Log.Info("Cart total calculated successfully",
 		zap.Uint("userID", userID),
@@ -359,6 +313,8 @@ func ShowCartTotal(c *gin.Context) {
 		"Count":          count,
 		"SubTotal":       subTotal,
 		"DiscountAmount": cartDiscountAmount,
+		"Shipping":       shipping.Charge,
+		"ShippingWaived": shipping.WaivedCharge,
 		"Total":          total,
 		"code":           http.StatusOK,
 	})
@@ -414,27 +370,23 @@ func DeleteCartItems(c *gin.Context) {
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var cart models.Cart
-	if err := config.DB.First(&cart, "user_id = ?", userID).Error; err != nil {
-		logger.Log.Error("Cart not found", zap.Uint("userID", userID), zap.Error(err))
-		helper.RespondWithError(c, http.StatusBadRequest, "Cart Not Found", "Something Went Wrong", "")
-		return
-	}
-
-	itemID := c.Param("id")
-	var cartItem models.CartItem
-	if err := config.DB.First(&cartItem, "id = ? AND cart_id = ?", itemID, cart.ID).Error; err != nil {
-		logger.Log.Error("Cart item not found",
-			zap.String("itemID", itemID),
-			zap.Uint("cartID", cart.ID),
-			zap.Error(err))
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Log.Error("Invalid cart item ID", zap.String("itemID", c.Param("id")), zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "Inavlid request", "Inavlid request", "")
 		return
 	}
 
-	if err := config.DB.Unscoped().Delete(&cartItem).Error; err != nil {
+	if err := services.RemoveCartItem(config.DB, userID, uint(itemID)); err != nil {
+		if errors.Is(err, services.ErrCartItemNotFound) {
+			logger.Log.Error("Cart item not found",
+				zap.Int("itemID", itemID),
+				zap.Uint("userID", userID))
+			helper.RespondWithError(c, http.StatusBadRequest, "Inavlid request", "Inavlid request", "")
+			return
+		}
 		logger.Log.Error("Failed to delete cart item",
-			zap.Uint("cartItemID", cartItem.ID),
+			zap.Int("cartItemID", itemID),
 			zap.Error(err))
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete item", "Failed to delete item", "")
 		return
@@ -442,7 +394,7 @@ func DeleteCartItems(c *gin.Context) {
 
 	logger.Log.Info("Cart item deleted successfully",
 		zap.Uint("userID", userID),
-		zap.Uint("cartItemID", cartItem.ID))
+		zap.Int("cartItemID", itemID))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Status OK",
 		"message": "Item delete successfully",
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
 	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/services/checkout"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -20,38 +22,23 @@ func ShowCheckoutPage(c *gin.Context) {
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	shippingCharge := 100
-
-	_, cartItems, err := services.FetchCartItems(userID)
+	cartItems, err := checkout.ReserveStock(c.Request.Context(), userID)
 	if err != nil {
-		logger.Log.Error("Failed to fetch cart items", zap.Uint("userID", userID), zap.Error(err))
-		helper.RespondWithError(c, http.StatusNotFound, "Cart Error", err.Error(), "/cart")
-		return
-	}
-
-	if len(cartItems) == 0 {
-		logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
-		helper.RespondWithError(c, http.StatusNotFound, "Cart is empty", "Cart is empty", "/cart")
-		return
-	}
-
-	for _, item := range cartItems {
-		if item.ProductDetails.StockQuantity < item.CartItem.Quantity || item.ProductDetails.StockQuantity == 0 || item.CartItem.Quantity == 0 {
-			logger.Log.Warn("Product Out Of Stock",
-				zap.Uint("productVariantID", item.ProductDetails.ID),
-				zap.Int("stockQuantity", int(item.ProductDetails.StockQuantity)),
-				zap.Int("cartQuantity", int(item.CartItem.Quantity)))
+		switch {
+		case errors.Is(err, services.ErrCartEmpty):
+			logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
+			helper.RespondWithError(c, http.StatusNotFound, "Cart is empty", "Cart is empty", "/cart")
+		case errors.Is(err, services.ErrOutOfStock):
+			logger.Log.Warn("Product Out Of Stock", zap.Uint("userID", userID))
 			helper.RespondWithError(c, http.StatusConflict, "Out of stock", "Some items in your cart are out of stock or have lower availability than your selected quantity. Please update your cart.", "/cart")
-			return
-		}
-		if item.ProductDetails.IsDeleted {
-			logger.Log.Warn("Product Unavailable",
-				zap.Uint("productVariantID", item.ProductDetails.ID),
-				zap.Int("stockQuantity", int(item.ProductDetails.StockQuantity)),
-				zap.Int("cartQuantity", int(item.CartItem.Quantity)))
+		case errors.Is(err, checkout.ErrProductUnavailable):
+			logger.Log.Warn("Product Unavailable", zap.Uint("userID", userID))
 			helper.RespondWithError(c, http.StatusConflict, "Product unavailable", "Some items in your cart are unavailable. Please update your cart.", "/cart")
-			return
+		default:
+			logger.Log.Error("Failed to reserve stock", zap.Uint("userID", userID), zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to Reserve stock", "Something Went Wrong", "/cart")
 		}
+		return
 	}
 
 	var categoryIdForOffer uint
@@ -59,8 +46,11 @@ func ShowCheckoutPage(c *gin.Context) {
 		categoryIdForOffer = items.ProductDetails.CategoryID
 	}
 
-	regularPrice, salePrice, tax, productDiscount, totalDiscount, shippingCharge := services.CalculateCartPrices(cartItems)
-	total := salePrice + tax + float64(shippingCharge)
+	var latestAddress models.UserAddress
+	config.DB.Order("updated_at DESC").First(&latestAddress, "user_id = ?", userID)
+
+	regularPrice, salePrice, tax, productDiscount, totalDiscount, shipping := services.CalculateCartPrices(cartItems, latestAddress.PinCode, latestAddress.State)
+	total := salePrice + tax + shipping.Charge
 
 	isAllCategorySame := true
 	for _, items := range cartItems {
@@ -142,106 +132,6 @@ func ShowCheckoutPage(c *gin.Context) {
 		}
 	}
 
-	tx := config.DB.Begin()
-	var expiredReservations []models.ReservedStock
-	if err := tx.Where("is_confirmed = ? AND reserve_till >= ?", false, time.Now()).
-		Find(&expiredReservations).Error; err != nil {
-		logger.Log.Error("Failed to find expired reservations", zap.Error(err))
-		tx.Rollback()
-		return
-	}
-
-	for _, reservation := range expiredReservations {
-		var coupon models.ReservedCoupon
-		if err := tx.First(&coupon, reservation.ReservedCouponID).Error; err != nil {
-			logger.Log.Warn("Failed to fetch reserved coupon",
-				zap.Uint("reservedCouponID", reservation.ReservedCouponID),
-				zap.Error(err))
-		}
-		if err := tx.Exec("UPDATE coupons SET users_used_count = users_used_count + ? WHERE id = ?", 1, coupon.CouponID).Error; err != nil {
-			logger.Log.Error("Failed to update coupon users count",
-				zap.Uint("couponID", coupon.CouponID),
-				zap.Error(err))
-		}
-		if err := tx.Unscoped().Delete(&coupon).Error; err != nil {
-			logger.Log.Warn("Failed to delete reserved coupon",
-				zap.Uint("reservedCouponID", reservation.ReservedCouponID),
-				zap.Error(err))
-		}
-		if err := tx.Exec(
-			"UPDATE product_variant_details SET stock_quantity = stock_quantity + ? WHERE id = ?",
-			reservation.Quantity, reservation.ProductVariantID,
-		).Error; err != nil {
-			logger.Log.Error("Failed to release stock",
-				zap.Uint("productVariantID", reservation.ProductVariantID),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed releasing stock", "Something Went Wrong", "")
-			tx.Rollback()
-			return
-		}
-		if err := tx.Unscoped().Delete(&reservation).Error; err != nil {
-			logger.Log.Error("Failed to delete released stock",
-				zap.Uint("reservationID", reservation.ID),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete released stock", "Something Went Wrong", "")
-			tx.Rollback()
-			return
-		}
-		logger.Log.Info("Expired reservation cleaned up",
-			zap.Uint("reservationID", reservation.ID))
-	}
-
-	for _, item := range cartItems {
-		if item.ProductDetails.StockQuantity < item.CartItem.Quantity {
-			logger.Log.Warn("Stock unavailable",
-				zap.Uint("productID", item.CartItem.ProductID),
-				zap.Int("stockQty", int(item.CartItem.ProductVariant.StockQuantity)),
-				zap.Int("requestedQty", int(item.CartItem.Quantity)))
-			helper.RespondWithError(c, http.StatusConflict, "Stock unavailable", "One or more items in your cart are out of stock. Please update your cart.", "/checkout")
-			return
-		}
-		var product models.ProductVariantDetails
-		if err := tx.First(&product, item.CartItem.ProductID).Error; err != nil {
-			logger.Log.Error("Product not found",
-				zap.Uint("productID", item.CartItem.ProductID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Product not found", "Something Went Wrong", "/checkout")
-			return
-		}
-		product.StockQuantity -= item.CartItem.Quantity
-		if err := tx.Save(&product).Error; err != nil {
-			logger.Log.Error("Failed to reserve stock",
-				zap.Uint("productID", item.CartItem.ProductID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusConflict, "Failed to Reserve stock", "Something Went Wrong", "/checkout")
-			return
-		}
-		reserveStock := models.ReservedStock{
-			UserID:           userID,
-			ProductVariantID: item.CartItem.ProductID,
-			Quantity:         item.CartItem.Quantity,
-			ReservedAt:       time.Now(),
-			ReserveTill:      time.Now().Add(15 * time.Minute),
-			IsConfirmed:      false,
-		}
-
-		if err := tx.Create(&reserveStock).Error; err != nil {
-			logger.Log.Error("Failed to store reserved stock",
-				zap.Uint("productID", item.CartItem.ProductID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to store reserved stock", "Something Went Wrong", "/checkout")
-			return
-		}
-		logger.Log.Info("Stock reserved",
-			zap.Uint("productID", item.CartItem.ProductID),
-			zap.Int("quantity", int(item.CartItem.Quantity)))
-	}
-
-	tx.Commit()
-
 	helper.CreateWallet(c, userID)
 	logger.Log.Info("Checkout page loaded successfully",
 		zap.Uint("userID", userID),
@@ -253,16 +143,25 @@ func ShowCheckoutPage(c *gin.Context) {
 		"message":         "Checkout fetch success",
 		"CartItem":        cartItems,
 		"SubTotal":        regularPrice,
-		"Shipping":        shippingCharge,
+		"Shipping":        shipping.Charge,
+		"ShippingWaived":  shipping.WaivedCharge,
 		"Tax":             tax,
 		"ProductDiscount": productDiscount,
 		"TotalDiscount":   totalDiscount,
 		"Total":           total,
 		"Coupons":         allResponceCoupons,
+		"Currency":        helper.DisplayCurrency(c),
 		"code":            http.StatusOK,
 	})
 }
 
+type CheckoutAddressResponse struct {
+	models.UserAddress
+	IsServiceable    bool   `json:"is_serviceable"`
+	IsCODAvailable   bool   `json:"is_cod_available"`
+	ExpectedDelivery string `json:"expected_delivery"`
+}
+
 func ShippingAddress(c *gin.Context) {
 	logger.Log.Info("Requested to address")
 
@@ -276,24 +175,93 @@ func ShippingAddress(c *gin.Context) {
 		return
 	}
 
+	dispatchDays := 1
+	if _, cartItems, err := services.FetchCartItems(userID); err == nil {
+		for _, item := range cartItems {
+			if days := services.ProductDispatchDays(item.ProductDetails.ProductID); days > dispatchDays {
+				dispatchDays = days
+			}
+		}
+	}
+
+	var addresses []CheckoutAddressResponse
+	for _, userAddress := range address {
+		delivery := services.EstimateDelivery(userAddress.PinCode, dispatchDays, time.Now())
+		response := CheckoutAddressResponse{
+			UserAddress:    userAddress,
+			IsServiceable:  delivery.IsServiceable,
+			IsCODAvailable: delivery.IsCODAvailable,
+		}
+		if delivery.IsServiceable {
+			response.ExpectedDelivery = delivery.ExpectedDate.Format("Mon, Jan 2")
+		}
+		addresses = append(addresses, response)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "Success",
-		"Addresses": address,
+		"Addresses": addresses,
 		"code":      http.StatusOK,
 	})
 }
 
+func CheckoutShippingQuote(c *gin.Context) {
+	logger.Log.Info("Requested checkout shipping quote")
+
+	userID := helper.FetchUserID(c)
+	addressID := c.Param("id")
+	logger.Log.Debug("Fetched user ID and address ID",
+		zap.Uint("userID", userID),
+		zap.String("addressID", addressID))
+
+	var address models.UserAddress
+	if err := config.DB.First(&address, "id = ? AND user_id = ?", addressID, userID).Error; err != nil {
+		logger.Log.Error("Address not found",
+			zap.String("addressID", addressID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Address not found", "Address not found", "")
+		return
+	}
+
+	_, cartItems, err := services.FetchCartItems(userID)
+	if err != nil {
+		logger.Log.Error("Failed to fetch cart items", zap.Uint("userID", userID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Cart Error", err.Error(), "/cart")
+		return
+	}
+
+	_, salePrice, tax, _, totalDiscount, shipping := services.CalculateCartPrices(cartItems, address.PinCode, address.State)
+
+	logger.Log.Info("Checkout shipping quote calculated",
+		zap.Uint("userID", userID),
+		zap.String("zone", shipping.ZoneName),
+		zap.Float64("shipping", shipping.Charge))
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "OK",
+		"message":        "Shipping fetch success",
+		"Zone":           shipping.ZoneName,
+		"Shipping":       shipping.Charge,
+		"ShippingWaived": shipping.WaivedCharge,
+		"TotalDiscount":  totalDiscount,
+		"Total":          salePrice + tax + shipping.Charge,
+		"code":           http.StatusOK,
+	})
+}
+
+type checkCouponInput struct {
+	CouponCode      string  `json:"couponCode"`
+	SubTotal        float64 `json:"subTotal"`
+	ProductDiscount float64 `json:"productDiscount"`
+}
+
 func CheckCoupon(c *gin.Context) {
 	logger.Log.Info("Requested to check coupon")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var couponInput struct {
-		CouponCode      string  `json:"couponCode"`
-		SubTotal        float64 `json:"subTotal"`
-		ProductDiscount float64 `json:"productDiscount"`
-	}
+	var couponInput checkCouponInput
 	if err := c.ShouldBindJSON(&couponInput); err != nil {
 		logger.Log.Error("Failed to bind coupon input", zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "Binding the data", "Invalid data entered", "")
@@ -301,121 +269,37 @@ func CheckCoupon(c *gin.Context) {
 	}
 
 	couponCode := strings.TrimSpace(strings.ToUpper(couponInput.CouponCode))
-	var coupon models.Coupon
-	if err := config.DB.First(&coupon, "UPPER(coupon_code) = ?", couponCode).Error; err != nil {
-		logger.Log.Warn("Invalid coupon code", zap.String("couponCode", couponCode), zap.Error(err))
-		helper.RespondWithError(c, http.StatusBadRequest, "Invalid Coupon Code", "Invalid Coupon Code", "")
-		return
-	}
-
-	if coupon.Status == "Deleted" {
-		logger.Log.Warn("Coupon is deleted", zap.String("couponCode", couponCode))
-		helper.RespondWithError(c, http.StatusNotFound, "Coupon Not Found", "Coupon Not Found", "")
-		return
-	}
-	if coupon.Status == "Expired" || coupon.UsersUsedCount >= coupon.MaxUseCount {
-		logger.Log.Warn("Coupon is expired or max uses reached",
-			zap.String("couponCode", couponCode),
-			zap.String("status", coupon.Status),
-			zap.Int("usersUsedCount", int(coupon.UsersUsedCount)),
-			zap.Int("maxUseCount", int(coupon.MaxUseCount)))
-		helper.RespondWithError(c, http.StatusNotFound, "Coupon Expired", "Coupon Expired", "")
-		return
-	}
-
-	if coupon.ExpirationDate.Before(time.Now().Truncate(24 * time.Hour)) {
-		logger.Log.Warn("Coupon has expired",
-			zap.String("couponCode", couponCode),
-			zap.Time("expirationDate", coupon.ExpirationDate))
-		helper.RespondWithError(c, http.StatusBadRequest, "Coupon Expired", "Coupon Expired", "")
+	_, cartItems, err := services.FetchCartItems(userID)
+	if err != nil {
+		logger.Log.Error("Failed to fetch cart items for coupon check",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Cart Error", err.Error(), "/cart")
 		return
 	}
 
-	if time.Now().Before(coupon.ValidFrom) {
-		logger.Log.Warn("Coupon not yet valid",
+	purchaseAmount := couponInput.SubTotal - couponInput.ProductDiscount
+	coupon, Discount, err := services.ApplyCoupon(config.DB, couponCode, cartItems, purchaseAmount)
+	if err != nil {
+		logger.Log.Warn("Coupon not applied",
 			zap.String("couponCode", couponCode),
-			zap.Time("validFrom", coupon.ValidFrom))
-		helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Started", "Coupon Not Available", "")
-		return
-	}
-
-	if coupon.ApplicableFor != "AllProducts" {
-		_, cartItems, err := services.FetchCartItems(userID)
-		if err != nil {
-			logger.Log.Error("Failed to fetch cart items for coupon check",
-				zap.Uint("userID", userID),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusNotFound, "Cart Error", err.Error(), "/cart")
-			return
-		}
-
-		if len(cartItems) == 0 {
-			logger.Log.Warn("Cart is empty for coupon check", zap.Uint("userID", userID))
+			zap.Float64("purchaseAmount", purchaseAmount),
+			zap.Error(err))
+		switch {
+		case errors.Is(err, services.ErrCouponNotFound):
+			helper.RespondWithError(c, http.StatusNotFound, "Invalid Coupon Code", "Invalid Coupon Code", "")
+		case errors.Is(err, services.ErrCouponExpired):
+			helper.RespondWithError(c, http.StatusBadRequest, "Coupon Expired", "Coupon Expired", "")
+		case errors.Is(err, services.ErrCouponNotStarted):
+			helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Started", "Coupon Not Available", "")
+		case errors.Is(err, services.ErrCartEmpty):
 			helper.RespondWithError(c, http.StatusBadRequest, "Cart Empty", "Add products to apply coupon", "/cart")
-			return
-		}
-
-		categoryIdForOffer := cartItems[0].ProductDetails.CategoryID
-		isAllCategorySame := true
-		for _, items := range cartItems {
-			if categoryIdForOffer != items.ProductDetails.CategoryID {
-				isAllCategorySame = false
-				break
-			}
-		}
-		if !isAllCategorySame {
-			logger.Log.Warn("Coupon not applicable due to mixed categories",
-				zap.String("couponCode", couponCode),
-				zap.String("applicableFor", coupon.ApplicableFor))
+		case errors.Is(err, services.ErrCouponNotApplicable):
 			helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Applicable", "Coupon Not Applicable", "/cart")
-			return
+		default:
+			helper.RespondWithError(c, http.StatusInternalServerError, "Coupon check failed", "Something Went Wrong", "")
 		}
-	}
-
-	purchaseAmount := couponInput.SubTotal - couponInput.ProductDiscount
-	if purchaseAmount < coupon.MinOrderValue {
-		logger.Log.Warn("Purchase amount below minimum for coupon",
-			zap.String("couponCode", couponCode),
-			zap.Float64("purchaseAmount", purchaseAmount),
-			zap.Float64("minOrderValue", coupon.MinOrderValue))
-		helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Applicable", "Coupon Not Applicable", "/cart")
 		return
-	} else {
-		if coupon.IsFixedCoupon == true && coupon.CouponType == "Fixed" {
-			if coupon.MaxDiscountValue > purchaseAmount {
-				logger.Log.Warn("Discount amount is greater than the purchase amount",
-					zap.String("couponCode", couponCode),
-					zap.Float64("purchaseAmount", purchaseAmount),
-					zap.Float64("minOrderValue", coupon.MinOrderValue))
-				helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Applicable", "Coupon Not Applicable", "/cart")
-				return
-			}
-		}
-
-		if coupon.IsFixedCoupon == false && coupon.CouponType == "Percentage" {
-			discountAmount := (coupon.DiscountValue * purchaseAmount) / 100
-			if discountAmount > coupon.MaxDiscountValue {
-				discountAmount = coupon.MaxDiscountValue
-			}
-			if discountAmount > purchaseAmount {
-				logger.Log.Warn("Discount amount is greater than the purchase amount",
-					zap.String("couponCode", couponCode),
-					zap.Float64("purchaseAmount", purchaseAmount),
-					zap.Float64("minOrderValue", coupon.MinOrderValue))
-				helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Applicable", "Coupon Not Applicable", "/cart")
-				return
-			}
-		}
-	}
-
-	var Discount float64
-	if coupon.IsFixedCoupon {
-		Discount = coupon.MaxDiscountValue
-	} else {
-		Discount = purchaseAmount * coupon.DiscountValue / 100
-		if Discount > coupon.MaxDiscountValue {
-			Discount = coupon.MaxDiscountValue
-		}
 	}
 
 	logger.Log.Info("Coupon applied successfully",
@@ -5,13 +5,13 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
-	"github.com/anfastk/E-Commerce-Website/utils"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -95,12 +95,12 @@ func HandleGoogleCallback(c *gin.Context) {
 	result := config.DB.Unscoped().Where("email = ?", googleUser.Email).First(&user)
 
 	if result.Error != nil {
-		cloudinaryURL, uploadErr := utils.UploadImageToCloudinary(nil, nil, config.InitCloudinary(), "ProfilePicture", googleUser.Picture)
+		profilePic, uploadErr := storage.SaveFromURL(c.Request.Context(), storage.FolderProfilePictures, googleUser.Picture)
 		if uploadErr != nil {
-			logger.Log.Warn("Failed to upload profile picture to Cloudinary",
+			logger.Log.Warn("Failed to save Google profile picture",
 				zap.String("email", googleUser.Email),
 				zap.Error(uploadErr))
-			cloudinaryURL = "" 
+			profilePic = ""
 		}
 
 		referralCode := helper.GenerateReferralCode()
@@ -109,7 +109,7 @@ func HandleGoogleCallback(c *gin.Context) {
 			Email:        googleUser.Email,
 			Password:     "",
 			GoogleID:     googleUser.Email,
-			ProfilePic:   cloudinaryURL,
+			ProfilePic:   profilePic,
 			IsVerified:   googleUser.VerifiedEmail,
 			Status:       "Active",
 			ReferralCode: strings.ToUpper(referralCode),
@@ -136,16 +136,36 @@ func HandleGoogleCallback(c *gin.Context) {
 		return
 	}
 
-	jwtToken, err := middleware.GenerateJWT(user.ID, user.Email, RoleUser)
+	twoFactorEnabled, err := twofactor.IsEnabled(config.DB, user.ID, RoleUser)
 	if err != nil {
-		logger.Log.Error("Failed to generate JWT token",
+		logger.Log.Error("Failed to check two-factor status",
 			zap.String("email", user.Email),
 			zap.Error(err))
-		c.Redirect(http.StatusTemporaryRedirect, "/auth/login?error=Failed+to+generate+JWT+token")
+		c.Redirect(http.StatusTemporaryRedirect, "/auth/login?error=Failed+to+sign+in")
+		return
+	}
+	if twoFactorEnabled {
+		if err := middleware.BeginTwoFactor(c, user.ID, user.Email, RoleUser); err != nil {
+			logger.Log.Error("Failed to start two-factor challenge",
+				zap.String("email", user.Email),
+				zap.Error(err))
+			c.Redirect(http.StatusTemporaryRedirect, "/auth/login?error=Failed+to+sign+in")
+			return
+		}
+		logger.Log.Info("Google OAuth verified, waiting for two-factor code",
+			zap.String("email", user.Email))
+		c.Redirect(http.StatusTemporaryRedirect, "/auth/login/2fa")
+		return
+	}
+
+	if _, err := middleware.StartSession(c, user.ID, user.Email, RoleUser); err != nil {
+		logger.Log.Error("Failed to start session",
+			zap.String("email", user.Email),
+			zap.Error(err))
+		c.Redirect(http.StatusTemporaryRedirect, "/auth/login?error=Failed+to+sign+in")
 		return
 	}
 
-	c.SetCookie("jwtTokensUser", jwtToken, int((time.Hour * 1).Seconds()), "/", "", false, true)
 	logger.Log.Info("Google OAuth login successful",
 		zap.String("email", user.Email),
 		zap.Uint("userID", user.ID))
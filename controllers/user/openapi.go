@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"github.com/anfastk/E-Commerce-Website/pkg/openapi"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+)
+
+// Operations documents the storefront handlers in the OpenAPI document.
+// Request must be the type the handler binds.
+var Operations = []openapi.Description{
+	// Sign up and sign in
+	openapi.Describe(SignUp, openapi.Operation{Summary: "Create an account and send the signup OTP", Request: signUpInput{}, Form: true, Error: helper.ErrorResponse{}}),
+	openapi.Describe(VerifyOtp, openapi.Operation{Summary: "Verify the signup OTP", Request: otpInput{}, Form: true, Error: helper.ErrorResponse{}}),
+	openapi.Describe(UserLoginHandler, openapi.Operation{Summary: "Sign in with email and password", Request: UserInput{}, Error: helper.ErrorResponse{}}),
+	openapi.Describe(VerifyTwoFactorLogin, openapi.Operation{Summary: "Finish signing in with an authenticator or recovery code", Request: twoFactorCodeInput{}, Error: helper.ErrorResponse{}}),
+	openapi.Describe(ForgotUserEmail, openapi.Operation{Summary: "Send a password reset OTP", Request: forgotPasswordInput{}, Error: helper.ErrorResponse{}}),
+
+	// Settings
+	openapi.Describe(EnableTwoFactor, openapi.Operation{Summary: "Turn on two-factor sign-in", Request: twoFactorCodeInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(DisableTwoFactor, openapi.Operation{Summary: "Turn off two-factor sign-in", Request: twoFactorCodeInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(RegenerateRecoveryCodes, openapi.Operation{Summary: "Replace the two-factor recovery codes", Request: twoFactorCodeInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+
+	// Profile
+	openapi.Describe(ProfileUpdate, openapi.Operation{Summary: "Update the profile", Request: profileUpdateInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(UpdateCurrency, openapi.Operation{Summary: "Choose the currency prices are shown in", Request: currencyInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(AddAddress, openapi.Operation{Summary: "Add an address", Request: addressInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(EditAddress, openapi.Operation{Summary: "Edit an address", Request: editAddressInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+
+	// Catalog
+	openapi.Describe(ShowProductDetail, openapi.Operation{Summary: "Product page", HTML: true}),
+	openapi.Describe(FilterProducts, openapi.Operation{Summary: "Filter and sort products", Error: helper.ErrorResponse{}}),
+	openapi.Describe(SubmitReview, openapi.Operation{Summary: "Review a delivered product", Request: reviewInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(EditReview, openapi.Operation{Summary: "Edit a review", Request: reviewInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+
+	// Cart and checkout
+	openapi.Describe(CartItemUpdate, openapi.Operation{Summary: "Add or remove one unit of a cart item", Request: cartQuantityInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(CheckCoupon, openapi.Operation{Summary: "Check a coupon against the cart", Request: checkCouponInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(PaymentPage, openapi.Operation{Summary: "Payment step of checkout", Request: paymentPageInput{}, HTML: true, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(ProceedToPayment, openapi.Operation{Summary: "Place the order or start a Razorpay payment", Request: proceedToPaymentInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(VerifyRazorpayPayment, openapi.Operation{Summary: "Place the order once Razorpay confirms the payment", Request: razorpayVerifyInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(PaymentFailureHandler, openapi.Operation{Summary: "Record a failed Razorpay payment", Request: razorpayFailureInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+
+	// Orders
+	openapi.Describe(CancelSpecificOrder, openapi.Operation{Summary: "Cancel an order item", Request: cancelOrderItemInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(CancelAllOrderItems, openapi.Operation{Summary: "Cancel every item of an order", Request: cancelOrderInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(ReturnOrder, openapi.Operation{Summary: "Request a return", Request: returnOrderInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(PayNow, openapi.Operation{Summary: "Pay for an unpaid order item", Request: payNowInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(VerifyPayNowRazorpayPayment, openapi.Operation{Summary: "Confirm a Razorpay payment for an unpaid order item", Request: payNowVerifyInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(DownloadInvoice, openapi.Operation{Summary: "Download the invoice PDF", Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+
+	// Wallet and referrals
+	openapi.Describe(AddReferral, openapi.Operation{Summary: "Apply a referral code", Request: referralCodeInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(AddMoneyTOWalltet, openapi.Operation{Summary: "Start adding money to the wallet", Request: addMoneyInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(VerifyAddTOWalletRazorpayPayment, openapi.Operation{Summary: "Credit the wallet once Razorpay confirms the payment", Request: walletPaymentVerifyInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(SendGiftCard, openapi.Operation{Summary: "Send a gift card from the wallet", Request: giftCardInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+	openapi.Describe(RedeemGiftCard, openapi.Operation{Summary: "Redeem a gift card into the wallet", Request: redeemGiftCardInput{}, Error: helper.ErrorResponse{}, Security: openapi.UserCookie}),
+}
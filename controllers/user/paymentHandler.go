@@ -1,201 +1,96 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"log"
-	"math/rand"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
 	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/services/checkout"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+type paymentPageInput struct {
+	AddressID  string `json:"addressId"`
+	CouponCode string `json:"couponCode"`
+}
+
 func PaymentPage(c *gin.Context) {
 	logger.Log.Info("Requested payment page")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var request struct {
-		AddressID            string  `json:"addressId"`
-		CouponCode           string  `json:"couponCode"`
-		CouponId             int     `json:"couponId"`
-		CouponDiscountAmount float64 `json:"couponDiscountAmount"`
-	}
-
-	tx := config.DB.Begin()
-
+	var request paymentPageInput
 	if err := c.ShouldBindJSON(&request); err != nil {
 		logger.Log.Error("Failed to bind request data", zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "Binding the data", "Binding the data", "")
 		return
 	}
 
-	var address models.UserAddress
-	if err := tx.First(&address, "id = ? AND user_id = ?", request.AddressID, userID).Error; err != nil {
-		logger.Log.Error("Address not found",
+	addressID, err := strconv.Atoi(request.AddressID)
+	if err != nil {
+		logger.Log.Error("Invalid address ID",
 			zap.String("addressID", request.AddressID),
-			zap.Uint("userID", userID),
 			zap.Error(err))
-		tx.Rollback()
 		helper.RespondWithError(c, http.StatusBadRequest, "Address not found", "Address not found", "")
 		return
 	}
 
-	shippingCharge := 100
-
-	_, cartItems, err := services.FetchCartItems(userID)
-	if err != nil {
-		logger.Log.Error("Failed to fetch cart items",
-			zap.Uint("userID", userID),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusNotFound, "Cart Error", err.Error(), "/cart")
-		return
-	}
-
-	if len(cartItems) == 0 {
-		logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
-		helper.RespondWithError(c, http.StatusNotFound, "Cart is empty", "Cart is empty", "/cart")
-		return
-	}
-
-	reservedProducts := FetchReservedProducts(c, userID)
-	if reservedProducts == nil {
-		return
-	}
-
-	if len(reservedProducts) != len(cartItems) {
-		logger.Log.Error("Mismatch between cart items and reserved products",
-			zap.Int("cartItemCount", len(cartItems)),
-			zap.Int("reservedProductCount", len(reservedProducts)))
-		helper.RespondWithError(c, http.StatusBadRequest, "Mismatch cart items and reserved product", "Something Went Wrong", "/cart")
-		return
-	}
-	_, err = ReservedProductCheck(c, reservedProducts, cartItems)
+	summary, err := checkout.PreparePayment(c.Request.Context(), userID, uint(addressID), request.CouponCode)
 	if err != nil {
+		respondWithCheckoutError(c, userID, err)
 		return
 	}
 
-	var couponId uint
-	if request.CouponId != 0 {
-		var coupon models.Coupon
-		if err := tx.First(&coupon, request.CouponId).Error; err != nil {
-			logger.Log.Error("Coupon not found",
-				zap.Int("couponID", request.CouponId),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Coupon not found", "Something Went Wrong", "/checkout")
-			return
-		}
-		coupon.UsersUsedCount += 1
-		if err := tx.Save(&coupon).Error; err != nil {
-			logger.Log.Error("Failed to reserve coupon",
-				zap.Int("couponID", request.CouponId),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusConflict, "Failed to Reserve coupon", "Something Went Wrong", "/checkout")
-			return
-		}
-
-		reserveCoupon := models.ReservedCoupon{
-			CouponCode:           request.CouponCode,
-			Discription:          coupon.Discription,
-			CouponDiscountAmount: request.CouponDiscountAmount,
-			CouponID:             uint(request.CouponId),
-		}
-		if err := tx.Create(&reserveCoupon).Error; err != nil {
-			logger.Log.Error("Failed to create reserved coupon",
-				zap.String("couponCode", request.CouponCode),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to coupon reserved stock", "Something Went Wrong", "/checkout")
-			return
-		}
-		couponId = reserveCoupon.ID
-		logger.Log.Info("Coupon reserved",
-			zap.Uint("reservedCouponID", couponId),
-			zap.String("couponCode", request.CouponCode))
-	}
-
-	for _, itm := range reservedProducts {
-		itm.ReservedCouponID = couponId
-
-		if err := tx.Save(&itm).Error; err != nil {
-			logger.Log.Error("Product detail not found",
-				zap.Uint("productID", itm.ID),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to save reserved coupon id", "Something Went Wrong", "/checkout")
-			return
-		}
-	}
-
-	regularPrice, salePrice, tax, productDiscount, totalDiscount, shippingCharge := services.CalculateCartPrices(cartItems)
-	TotalDiscount := totalDiscount + request.CouponDiscountAmount
-	total := ((salePrice + tax) - request.CouponDiscountAmount) + float64(shippingCharge)
-
-	IsCodAvailable := true
-	for _, itm := range cartItems {
-		var productDetail models.ProductDetail
-		if err := config.DB.First(&productDetail, itm.CartItem.ProductID).Error; err != nil {
-			logger.Log.Error("Product detail not found",
-				zap.Uint("productID", itm.CartItem.ProductID),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusInternalServerError, "Product Not Found", "Something Went Wrong", "/checkout")
-			return
-		}
-		if !productDetail.IsCODAvailable {
-			IsCodAvailable = false
-			break
-		}
-	}
-	if total > 75000 {
-		IsCodAvailable = false
-	}
-
-	tx.Commit()
 	var referralDetails models.ReferralAccount
 	if err := config.DB.First(&referralDetails, "user_id = ?", userID).Error; err == nil {
 		CheckForReferrer(c)
 		CheckForJoinee(c)
 	}
 
+	var couponID uint
+	var couponCode string
+	if summary.Coupon != nil {
+		couponID, couponCode = summary.Coupon.ID, summary.Coupon.CouponCode
+	}
+
 	logger.Log.Info("Payment page loaded successfully",
 		zap.Uint("userID", userID),
-		zap.Float64("total", total),
-		zap.Int("itemCount", len(cartItems)))
+		zap.Float64("total", summary.Total),
+		zap.Int("itemCount", len(summary.CartItems)))
 	c.HTML(http.StatusOK, "paymentPage.html", gin.H{
 		"status":          "OK",
 		"message":         "Checkout fetch success",
-		"Address":         address,
-		"CartItem":        cartItems,
-		"SubTotal":        regularPrice,
-		"Shipping":        shippingCharge,
-		"Tax":             tax,
-		"CouponID":        request.CouponId,
-		"CouponCode":      request.CouponCode,
-		"CouponDiscount":  request.CouponDiscountAmount,
-		"ProductDiscount": productDiscount,
-		"TotalDiscount":   TotalDiscount,
-		"IsCodAvailable":  IsCodAvailable,
-		"Total":           total,
+		"Address":         summary.Address,
+		"CartItem":        summary.CartItems,
+		"SubTotal":        summary.Quote.RegularPrice,
+		"Shipping":        summary.Quote.ShippingCharge,
+		"ShippingWaived":  summary.Quote.ShippingDiscount,
+		"Tax":             summary.Quote.Tax,
+		"CouponID":        couponID,
+		"CouponCode":      couponCode,
+		"CouponDiscount":  summary.CouponDiscount,
+		"ProductDiscount": summary.Quote.ProductDiscount,
+		"TotalDiscount":   (money.FromRupees(summary.Quote.TotalDiscount) + money.FromRupees(summary.CouponDiscount)).Rupees(),
+		"IsCodAvailable":  summary.IsCODAvailable,
+		"Total":           summary.Total,
+		"Currency":        summary.Currency,
 		"code":            http.StatusOK,
 	})
 }
 
-var paymentRequest struct {
-	PaymentMethod        string `json:"paymentMethod"`
-	AddressID            string `json:"addressId"`
-	CouponCode           string `json:"couponCode"`
-	CouponId             string `json:"couponId"`
-	CouponDiscountAmount string `json:"couponDiscountAmount"`
+type proceedToPaymentInput struct {
+	PaymentMethod string `json:"paymentMethod"`
+	AddressID     string `json:"addressId"`
+	CouponCode    string `json:"couponCode"`
 }
 
 func ProceedToPayment(c *gin.Context) {
@@ -204,326 +99,189 @@ func ProceedToPayment(c *gin.Context) {
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
+	var paymentRequest proceedToPaymentInput
 	if err := c.ShouldBind(&paymentRequest); err != nil {
 		logger.Log.Error("Failed to bind payment request", zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "Request Not Found", "Request Not Found", "")
 		return
 	}
 
-	var userDetails models.UserAuth
-	if err := config.DB.First(&userDetails, userID).Error; err != nil {
-		logger.Log.Error("User not found",
-			zap.Uint("userID", userID),
+	addressID, err := strconv.Atoi(paymentRequest.AddressID)
+	if err != nil {
+		logger.Log.Error("Invalid address ID",
+			zap.String("addressID", paymentRequest.AddressID),
 			zap.Error(err))
-		helper.RespondWithError(c, http.StatusNotFound, "User not found", "User not found", "/cart")
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid address", "Invalid address", "/checkout")
 		return
 	}
+	request := checkout.Request{
+		PaymentMethod: paymentRequest.PaymentMethod,
+		AddressID:     uint(addressID),
+		CouponCode:    paymentRequest.CouponCode,
+	}
 
-	currentTime := time.Now()
-	_, cartItems, err := services.FetchCartItems(userID)
-
-	if len(cartItems) == 0 {
-		logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
-		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Add Product in Your Cart", "/cart")
-		return
+	switch paymentRequest.PaymentMethod {
+	case checkout.MethodCOD, checkout.MethodWallet:
+		placeOrder(c, userID, request)
+	case checkout.MethodRazorpay:
+		startRazorpayPayment(c, userID, request)
+	default:
+		logger.Log.Warn("Invalid payment method",
+			zap.String("method", paymentRequest.PaymentMethod))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid Payment Method", "Invalid Payment Method", "/checkout")
 	}
+}
 
-	reservedProducts := FetchReservedProducts(c, userID)
-	if reservedProducts == nil {
+// placeOrder places a cash on delivery or wallet order for the checkout in
+// progress and shows the confirmation page.
+func placeOrder(c *gin.Context, userID uint, request checkout.Request) {
+	result, err := checkout.PlaceOrder(c.Request.Context(), userID, request)
+	if err != nil {
+		respondWithCheckoutError(c, userID, err)
 		return
 	}
 
-	if len(reservedProducts) != len(cartItems) {
-		logger.Log.Error("Mismatch between cart items and reserved products",
-			zap.Int("cartItemCount", len(cartItems)),
-			zap.Int("reservedProductCount", len(reservedProducts)))
-		helper.RespondWithError(c, http.StatusBadRequest, "Mismatch cart items and reserved product 11", "Something Went Wrong", "/cart")
+	logger.Log.Info("Order placed successfully",
+		zap.Uint("userID", userID),
+		zap.String("orderUID", result.Order.OrderUID),
+		zap.String("paymentMethod", result.PaymentMethod))
+	renderOrderSuccess(c, result)
+}
+
+func renderOrderSuccess(c *gin.Context, result *checkout.Result) {
+	c.HTML(http.StatusOK, "orderSuccess.html", gin.H{
+		"status":        "Success",
+		"message":       "Order Success",
+		"OrderID":       result.Order.OrderUID,
+		"PaymentMethod": result.PaymentMethod,
+		"OrderDate":     result.Order.CreatedAt.Format("January 2, 2006"),
+		"ExpextedDate":  result.ExpectedDelivery.Format("January 2, 2006"),
+		"code":          http.StatusOK,
+	})
+}
+
+// startRazorpayPayment opens a Razorpay order for the checkout in progress.
+// The order itself is placed once the gateway answers.
+func startRazorpayPayment(c *gin.Context, userID uint, request checkout.Request) {
+	var userDetails models.UserAuth
+	if err := config.DB.First(&userDetails, userID).Error; err != nil {
+		logger.Log.Error("User not found",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "User not found", "User not found", "/cart")
 		return
 	}
 
-	result, err := ReservedProductCheck(c, reservedProducts, cartItems)
+	summary, err := checkout.PriceCheckout(c.Request.Context(), userID, request)
 	if err != nil {
+		respondWithCheckoutError(c, userID, err)
 		return
 	}
 
-	var reservedCoupon models.ReservedCoupon
-	if err := config.DB.First(&reservedCoupon, "coupon_id = ?", paymentRequest.CouponId).Error; err != nil {
-		logger.Log.Warn("Reserved coupon not found",
-			zap.String("couponID", paymentRequest.CouponId),
+	razorpayOrder, err := CreateRazorpayOrder(c, summary.Total)
+	if err != nil {
+		logger.Log.Error("Failed to create Razorpay order",
+			zap.Float64("amount", summary.Total),
 			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create Razorpay order", "Something Went Wrong", "/checkout")
+		return
 	}
 
-	var coupon models.Coupon
-	if err := config.DB.First(&coupon, reservedCoupon.CouponID).Error; err != nil {
-		logger.Log.Warn("Reserved coupon not found",
-			zap.String("couponID", paymentRequest.CouponId),
-			zap.Error(err))
+	razorpayOrderID, ok := razorpayOrder["id"].(string)
+	if !ok {
+		logger.Log.Error("Failed to extract Razorpay order ID",
+			zap.Any("razorpayOrder", razorpayOrder))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Invalid Razorpay response", "Something Went Wrong", "/checkout")
+		return
 	}
-
-	couponDiscountAmount, err := strconv.ParseFloat(paymentRequest.CouponDiscountAmount, 64)
-	if err != nil {
-		logger.Log.Error("Failed to parse coupon discount amount",
-			zap.String("couponDiscountAmount", paymentRequest.CouponDiscountAmount),
+	if err := checkout.RecordGatewayOrder(c.Request.Context(), userID, request, razorpayOrderID, summary.Total); err != nil {
+		logger.Log.Error("Failed to record Razorpay order",
+			zap.String("razorpayOrderID", razorpayOrderID),
 			zap.Error(err))
-		helper.RespondWithError(c, http.StatusBadRequest, "Coverting Failed", "Something Went Wrong", "/cart")
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create Razorpay order", "Something Went Wrong", "/checkout")
 		return
 	}
 
-	switch paymentRequest.PaymentMethod {
-	case "COD":
-		paymentStatus := true
-		tx := config.DB.Begin()
-		orderID := CreateOrder(c, tx, userDetails.ID, result.RegularPrice, result.ProductDiscount, result.TotalDiscount+couponDiscountAmount, result.Tax, float64(result.ShippingCharge), result.Total-couponDiscountAmount, currentTime, paymentRequest.CouponCode, couponDiscountAmount, coupon.Discription, coupon.DiscountValue, coupon.IsFixedCoupon)
-		if orderID == 0 {
-			return
-		}
-		SaveOrderAddress(c, tx, orderID, userDetails.ID, paymentRequest.AddressID)
-		CreateOrderItems(c, tx, reservedProducts, float64(result.ShippingCharge), orderID, userDetails.ID, currentTime, couponDiscountAmount)
-		orderItems := FetchOrderItems(c, tx, orderID)
-		if orderItems == nil {
-			return
-		}
-
-		for _, ordItems := range orderItems {
-			if err := CODPayment(c, tx, userDetails.ID, ordItems.OrderUID, ordItems.ID, ordItems.Total, "Cash On Delivery"); err != nil {
-				logger.Log.Error("COD payment failed",
-					zap.Uint("orderItemID", ordItems.ID),
-					zap.Error(err))
-				tx.Rollback()
-				paymentStatus = false
-				helper.RespondWithError(c, http.StatusInternalServerError, "Payment failed", "Something Went Wrong", "/checkout")
-				return
-			}
-			DeleteReservedItems(c, tx, ordItems.ProductVariantID, userID)
-		}
-
-		if paymentStatus {
-			ClearCart(c, tx, result.ReservedMap)
-		}
-		if err := tx.Unscoped().Delete(&reservedCoupon, paymentRequest.CouponId).Error; err != nil {
-			logger.Log.Warn("Failed to delete reserved coupon",
-				zap.String("couponID", paymentRequest.CouponId),
-				zap.Error(err))
-		}
-		var orderDetails models.Order
-		if err := tx.First(&orderDetails, orderID).Error; err != nil {
-			logger.Log.Error("Order not found",
-				zap.Uint("orderID", orderID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Order not found", "Something Went Wrong", "")
-			return
-		}
-		tx.Commit()
-		logger.Log.Info("COD payment processed successfully",
-			zap.Uint("userID", userID),
-			zap.Uint("orderID", orderID))
-		c.HTML(http.StatusOK, "orderSuccess.html", gin.H{
-			"status":        "Success",
-			"message":       "Order Success",
-			"OrderID":       orderDetails.OrderUID,
-			"PaymentMethod": "Cash On Delivery",
-			"OrderDate":     orderDetails.CreatedAt.Format("January 2, 2006"),
-			"ExpextedDate":  orderDetails.CreatedAt.AddDate(0, 0, 7).Format("January 2, 2006"),
-			"code":          http.StatusOK,
-		})
-
-	case "Razorpay":
-		address := FetchAddressByIDAndUserID(c, userID, paymentRequest.AddressID)
-		if address == nil {
-			return
-		}
-		razorpayOrder, err := CreateRazorpayOrder(c, result.Total-couponDiscountAmount)
-		if err != nil {
-			logger.Log.Error("Failed to create Razorpay order",
-				zap.Float64("amount", result.Total-couponDiscountAmount),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to create Razorpay order", "Something Went Wrong", "/checkout")
-			return
-		}
-
-		razorpayOrderID, ok := razorpayOrder["id"].(string)
-		if !ok {
-			logger.Log.Error("Failed to extract Razorpay order ID",
-				zap.Any("razorpayOrder", razorpayOrder))
-			log.Println("Failed to extract order ID from Razorpay response:", razorpayOrder)
-			helper.RespondWithError(c, http.StatusInternalServerError, "Invalid Razorpay response", "Something Went Wrong", "/checkout")
-			return
-		}
-		RazorPayOrderID = razorpayOrderID
-		logger.Log.Info("Razorpay payment initiated",
-			zap.String("razorpayOrderID", razorpayOrderID),
-			zap.Float64("amount", result.Total-couponDiscountAmount))
-		c.JSON(http.StatusOK, gin.H{
-			"status":   "OK",
-			"order_id": razorpayOrderID,
-			"amount":   result.Total * 100,
-			"currency": "INR",
-			"key_id":   config.RAZORPAY_KEY_ID,
-			"prefill": gin.H{
-				"name":    userDetails.FullName,
-				"email":   userDetails.Email,
-				"contact": address.Mobile,
-			},
-			"notes": gin.H{
-				"address": address.Address,
-				"user_id": userDetails.ID,
-			},
-		})
-
-	case "Wallet":
-		tx := config.DB.Begin()
-		var walletDetails models.Wallet
-		if err := tx.First(&walletDetails, "user_id = ?", userID).Error; err != nil {
-			logger.Log.Error("Wallet not found",
-				zap.Uint("userID", userID),
-				zap.Error(err))
-			helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Not Found", "Something Went Wrong", "/cart")
-			return
-		}
-
-		orderID := CreateOrder(c, tx, userDetails.ID, result.RegularPrice, result.ProductDiscount, result.TotalDiscount+couponDiscountAmount, result.Tax, float64(result.ShippingCharge), result.Total-couponDiscountAmount, currentTime, paymentRequest.CouponCode, couponDiscountAmount, coupon.Discription, coupon.DiscountValue, coupon.IsFixedCoupon)
-		if orderID == 0 {
-			return
-		}
-		SaveOrderAddress(c, tx, orderID, userDetails.ID, paymentRequest.AddressID)
-		CreateOrderItems(c, tx, reservedProducts, float64(result.ShippingCharge), orderID, userDetails.ID, currentTime, couponDiscountAmount)
-		orderItems := FetchOrderItems(c, tx, orderID)
-		if orderItems == nil {
-			return
-		}
-
-		var orderDetails models.Order
-		if err := tx.First(&orderDetails, orderID).Error; err != nil {
-			logger.Log.Error("Order not found",
-				zap.Uint("orderID", orderID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Order not found", "Something Went Wrong", "")
-			return
-		}
-
-		for _, orderItem := range orderItems {
-			receiptID := "rcpt-" + uuid.New().String()
-			rand.Seed(time.Now().UnixNano())
-			transactionID := fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Intn(10000))
-
-			createPayment := models.PaymentDetail{
-				UserID:        userID,
-				OrderItemID:   orderItem.ID,
-				PaymentStatus: "Completed",
-				PaymentAmount: orderItem.Total,
-				PaymentMethod: "Wallet",
-				OrderId:       orderDetails.OrderUID,
-				TransactionID: transactionID,
-				Receipt:       receiptID,
-			}
-			if err := tx.Create(&createPayment).Error; err != nil {
-				logger.Log.Error("Failed to create payment",
-					zap.Uint("orderItemID", orderItem.ID),
-					zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Payment creation failed", "Something Went Wrong", "/cart")
-				return
-			}
-
-			var orderedItem models.OrderItem
-			if err := tx.First(&orderedItem, orderItem.ID).Error; err != nil {
-				logger.Log.Error("Order item not found",
-					zap.Uint("orderItemID", orderItem.ID),
-					zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Order not found", "Something Went Wrong", "")
-				return
-			}
-
-			orderedItem.OrderStatus = "Confirmed"
-			if err := tx.Save(&orderedItem).Error; err != nil {
-				logger.Log.Error("Failed to update order item status",
-					zap.Uint("orderItemID", orderItem.ID),
-					zap.Error(err))
-				tx.Rollback()
-				helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order", "Something Went Wrong", "")
-				return
-			}
-			DeleteReservedItems(c, tx, orderItem.ProductVariantID, userID)
-		}
-
-		lastBalance := walletDetails.Balance
-		walletDetails.Balance -= (result.Total - couponDiscountAmount)
-		if err := tx.Save(&walletDetails).Error; err != nil {
-			logger.Log.Error("Failed to update wallet balance",
-				zap.Uint("userID", userID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update wallet details", "Failed to update order", "")
-			return
-		}
-
-		walletReceiptID := "rcpt-" + uuid.New().String()
-		rand.Seed(time.Now().UnixNano())
-		walletTransactionID := fmt.Sprintf("TXN-%d-%d", time.Now().UnixNano(), rand.Intn(10000))
-
-		walletHistory := models.WalletTransaction{
-			UserID:        userID,
-			WalletID:      walletDetails.ID,
-			Amount:        result.Total - couponDiscountAmount,
-			LastBalance:   lastBalance,
-			Description:   "Product Purchase ORD ID" + orderDetails.OrderUID,
-			Type:          "Debited",
-			Receipt:       walletReceiptID,
-			OrderId:       orderDetails.OrderUID,
-			TransactionID: walletTransactionID,
-			PaymentMethod: "Wallet",
-		}
-		if err := tx.Create(&walletHistory).Error; err != nil {
-			logger.Log.Error("Failed to create wallet transaction",
-				zap.Uint("userID", userID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Creation Failed", "Something Went Wrong", "/cart")
-			return
-		}
+	logger.Log.Info("Razorpay payment initiated",
+		zap.String("razorpayOrderID", razorpayOrderID),
+		zap.Float64("amount", summary.Total))
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "OK",
+		"order_id": razorpayOrderID,
+		"amount":   int64(money.FromRupees(summary.Total)),
+		"currency": "INR",
+		"key_id":   config.RAZORPAY_KEY_ID,
+		"prefill": gin.H{
+			"name":    userDetails.FullName,
+			"email":   userDetails.Email,
+			"contact": summary.Address.Mobile,
+		},
+		"notes": gin.H{
+			"address": summary.Address.Address,
+			"user_id": userDetails.ID,
+		},
+	})
+}
 
-		ClearCart(c, tx, result.ReservedMap)
-		if err := tx.Unscoped().Delete(&reservedCoupon, paymentRequest.CouponId).Error; err != nil {
-			logger.Log.Warn("Failed to delete reserved coupon",
-				zap.String("couponID", paymentRequest.CouponId),
-				zap.Error(err))
+func respondWithCheckoutError(c *gin.Context, userID uint, err error) {
+	switch {
+	// Checked first: it wraps the reason the order was not placed, which
+	// would otherwise match one of the cases below.
+	case errors.Is(err, checkout.ErrPaymentRefunded):
+		logger.Log.Warn("Gateway payment refunded instead of placed", zap.Uint("userID", userID), zap.Error(err))
+		message := "We could not place your order, so your payment is being refunded to the original payment method. Please check out again."
+		if errors.Is(err, checkout.ErrAmountMismatch) {
+			message = "Your order total changed while you were paying, so your payment is being refunded to the original payment method. Please check out again."
 		}
-		tx.Commit()
-		logger.Log.Info("Wallet payment processed successfully",
-			zap.Uint("userID", userID),
-			zap.Uint("orderID", orderID),
-			zap.Float64("amount", result.Total))
-		c.HTML(http.StatusOK, "orderSuccess.html", gin.H{
-			"status":        "Success",
-			"message":       "Order Success",
-			"OrderID":       orderDetails.OrderUID,
-			"PaymentMethod": "Wallet",
-			"OrderDate":     orderDetails.CreatedAt.Format("January 2, 2006"),
-			"ExpextedDate":  orderDetails.CreatedAt.AddDate(0, 0, 7).Format("January 2, 2006"),
-			"code":          http.StatusOK,
-		})
-	default:
-		logger.Log.Warn("Invalid payment method",
-			zap.String("method", paymentRequest.PaymentMethod))
+		helper.RespondWithError(c, http.StatusConflict, "Payment refunded", message, "/cart")
+	case errors.Is(err, services.ErrCartEmpty):
+		logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Add Product in Your Cart", "/cart")
+	case errors.Is(err, checkout.ErrCartChanged), errors.Is(err, checkout.ErrReservationExpired):
+		logger.Log.Warn("Checkout no longer matches the cart", zap.Uint("userID", userID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusConflict, "Checkout expired", "Your cart has changed or checkout has expired. Please check out again.", "/cart")
+	case errors.Is(err, checkout.ErrAddressNotFound):
+		logger.Log.Warn("Address not found", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusBadRequest, "Address not found", "Address not found", "/checkout")
+	case errors.Is(err, checkout.ErrNotServiceable):
+		logger.Log.Warn("Delivery not available", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusBadRequest, "Delivery not available", "Delivery is not available to the selected address", "/checkout")
+	case errors.Is(err, checkout.ErrCODUnavailable):
+		logger.Log.Warn("Cash on delivery not available", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusBadRequest, "COD not available", "Cash on delivery is not available for this order", "/checkout")
+	case errors.Is(err, checkout.ErrInsufficientBalance):
+		logger.Log.Warn("Insufficient wallet balance", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusBadRequest, "Insufficient balance", "Your wallet balance is not enough for this order", "/checkout")
+	case errors.Is(err, services.ErrCouponNotFound),
+		errors.Is(err, services.ErrCouponExpired),
+		errors.Is(err, services.ErrCouponNotStarted),
+		errors.Is(err, services.ErrCouponNotApplicable):
+		logger.Log.Warn("Coupon rejected at order", zap.Uint("userID", userID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Coupon Not Applicable", "The coupon can no longer be applied to this order", "/checkout")
+	case errors.Is(err, checkout.ErrPaymentNotFound):
+		logger.Log.Warn("Razorpay order not found for user", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusNotFound, "Payment not found", "Payment not found", "/cart")
+	case errors.Is(err, checkout.ErrUnsupportedPayment):
 		helper.RespondWithError(c, http.StatusBadRequest, "Invalid Payment Method", "Invalid Payment Method", "/checkout")
-		return
+	default:
+		logger.Log.Error("Failed to place order", zap.Uint("userID", userID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to place order", "Something Went Wrong", "/checkout")
 	}
 }
 
+type payNowInput struct {
+	Method      string `json:"method"`
+	OrderItemID string `json:"orderId"`
+	AddressID   string `json:"addressId"`
+}
+
 func PayNow(c *gin.Context) {
 	logger.Log.Info("Requested pay now")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var PayNowRequest struct {
-		Method      string `json:"method"`
-		OrderItemID string `json:"orderId"`
-		AddressID   string `json:"addressId"`
-	}
+	var PayNowRequest payNowInput
 
 	if err := c.ShouldBind(&PayNowRequest); err != nil {
 		logger.Log.Error("Failed to bind pay now request", zap.Error(err))
@@ -610,7 +368,7 @@ func PayNow(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":   "OK",
 			"order_id": razorpayOrderID,
-			"amount":   order.TotalAmount * 100,
+			"amount":   int64(money.FromRupees(order.TotalAmount)),
 			"currency": "INR",
 			"key_id":   config.RAZORPAY_KEY_ID,
 			"prefill": gin.H{
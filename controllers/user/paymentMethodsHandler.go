@@ -4,53 +4,25 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/anfastk/E-Commerce-Website/pkg/orderlifecycle"
 	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/services/checkout"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/razorpay/razorpay-go"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
-func CODPayment(c *gin.Context, tx *gorm.DB, userId uint, orderId string, order_item_id uint, paymentAmount float64, method string) error {
-	logger.Log.Info("Processing COD payment",
-		zap.Uint("userID", userId),
-		zap.String("orderID", orderId),
-		zap.Uint("orderItemID", order_item_id))
-
-	paymentDetail := models.PaymentDetail{
-		UserID:        userId,
-		OrderItemID:   order_item_id,
-		OrderId:       orderId,
-		PaymentStatus: "Pending",
-		PaymentAmount: paymentAmount,
-		PaymentMethod: method,
-	}
-	if err := tx.Create(&paymentDetail).Error; err != nil {
-		logger.Log.Error("Failed to create COD payment",
-			zap.Uint("userID", userId),
-			zap.String("orderID", orderId),
-			zap.Error(err))
-		tx.Rollback()
-		return errors.New("Payment Creation Failed")
-	}
-
-	logger.Log.Info("COD payment created successfully",
-		zap.Uint("paymentID", paymentDetail.ID))
-	return nil
-}
-
 func FetchWalletBalance(c *gin.Context) {
 	logger.Log.Info("Fetching wallet balance")
 
@@ -84,7 +56,7 @@ func CreateRazorpayOrder(c *gin.Context, amount float64) (map[string]interface{}
 	receiptID := uuid.New().String()[:30]
 
 	data := map[string]interface{}{
-		"amount":   int64(amount * 100),
+		"amount":   int64(money.FromRupees(amount)),
 		"currency": "INR",
 		"receipt":  "rcpt_" + receiptID,
 	}
@@ -105,20 +77,19 @@ func CreateRazorpayOrder(c *gin.Context, amount float64) (map[string]interface{}
 	return order, nil
 }
 
-var RazorPayOrderID string
+type razorpayVerifyInput struct {
+	PaymentID string `json:"razorpay_payment_id"`
+	OrderID   string `json:"razorpay_order_id"`
+	Signature string `json:"razorpay_signature"`
+}
 
 func VerifyRazorpayPayment(c *gin.Context) {
 	logger.Log.Info("Verifying Razorpay payment")
 
-	currentTime := time.Now()
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var verifyRequest struct {
-		PaymentID string `json:"razorpay_payment_id"`
-		OrderID   string `json:"razorpay_order_id"`
-		Signature string `json:"razorpay_signature"`
-	}
+	var verifyRequest razorpayVerifyInput
 
 	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
 		logger.Log.Error("Failed to bind verification request", zap.Error(err))
@@ -126,15 +97,6 @@ func VerifyRazorpayPayment(c *gin.Context) {
 		return
 	}
 
-	var userDetails models.UserAuth
-	if err := config.DB.First(&userDetails, userID).Error; err != nil {
-		logger.Log.Error("User not found",
-			zap.Uint("userID", userID),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusNotFound, "User not found", "User not found", "/cart")
-		return
-	}
-
 	data := verifyRequest.OrderID + "|" + verifyRequest.PaymentID
 	expectedSignature := hmac.New(sha256.New, []byte(config.RAZORPAY_KEY_SECRET))
 	expectedSignature.Write([]byte(data))
@@ -144,171 +106,38 @@ func VerifyRazorpayPayment(c *gin.Context) {
 		logger.Log.Error("Payment signature verification failed",
 			zap.String("orderID", verifyRequest.OrderID),
 			zap.String("paymentID", verifyRequest.PaymentID))
-		fmt.Println("Payment verification failed")
 		helper.RespondWithError(c, http.StatusBadRequest, "Invalid signature", "Payment verification failed", "")
 		return
 	}
 
-	_, cartItems, err := services.FetchCartItems(userID)
-
-	if len(cartItems) == 0 {
-		logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
-		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Add Product in Your Cart", "/cart")
-		return
-	}
-
-	reservedProducts := FetchReservedProducts(c, userID)
-	if reservedProducts == nil {
-		helper.RespondWithError(c, http.StatusNotFound, "Failed to fetch reserved stock", "Something Went Wrong", "/cart")
-		return
-	}
-
-	if len(reservedProducts) != len(cartItems) {
-		logger.Log.Error("Mismatch between cart items and reserved products",
-			zap.Int("cartItemCount", len(cartItems)),
-			zap.Int("reservedProductCount", len(reservedProducts)))
-		helper.RespondWithError(c, http.StatusBadRequest, "Mismatch cart items and reserved product", "Something Went Wrong", "/cart")
-		return
-	}
-
-	result, err := ReservedProductCheck(c, reservedProducts, cartItems)
-	if err != nil {
-		logger.Log.Error(err.Error(),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusInternalServerError, err.Error(), "Something Went Wrong", "/cart")
-		return
-	}
-
-	tx := config.DB.Begin()
-	var reservedCoupon models.ReservedCoupon
-	if err := config.DB.First(&reservedCoupon, "coupon_id = ?", paymentRequest.CouponId).Error; err != nil {
-		logger.Log.Warn("Reserved coupon not found",
-			zap.String("couponID", paymentRequest.CouponId),
-			zap.Error(err))
-	}
-
-	var coupon models.Coupon
-	if err := config.DB.First(&coupon, reservedCoupon.CouponID).Error; err != nil {
-		logger.Log.Warn("Reserved coupon not found",
-			zap.String("couponID", paymentRequest.CouponId),
-			zap.Error(err))
-	}
-
-	couponDiscountAmount, err := strconv.ParseFloat(paymentRequest.CouponDiscountAmount, 64)
+	result, err := checkout.CompleteGatewayPayment(c.Request.Context(), userID, checkout.GatewayPayment{
+		OrderID:   verifyRequest.OrderID,
+		PaymentID: verifyRequest.PaymentID,
+	})
 	if err != nil {
-		logger.Log.Error("Failed to parse coupon discount amount",
-			zap.String("couponDiscountAmount", paymentRequest.CouponDiscountAmount),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusBadRequest, "Coverting Failed", "Something Went Wrong", "/cart")
-		return
-	}
-
-	orderID := CreateOrder(c, tx, userDetails.ID, result.RegularPrice, result.ProductDiscount, result.TotalDiscount+couponDiscountAmount, result.Tax, float64(result.ShippingCharge), result.Total-couponDiscountAmount, currentTime, paymentRequest.CouponCode, couponDiscountAmount, coupon.Discription, coupon.DiscountValue, coupon.IsFixedCoupon)
-	if orderID == 0 {
-		helper.RespondWithError(c, http.StatusNotFound, "Order not found", "Something Went Wrong", "/cart")
-		return
-	}
-
-	SaveOrderAddress(c, tx, orderID, userDetails.ID, paymentRequest.AddressID)
-	CreateOrderItems(c, tx, reservedProducts, float64(result.ShippingCharge), orderID, userDetails.ID, currentTime, couponDiscountAmount)
-	orderItems := FetchOrderItems(c, tx, orderID)
-	if orderItems == nil {
-		helper.RespondWithError(c, http.StatusNotFound, "Failed to fetch order items", "Something Went Wrong", "/cart")
+		respondWithCheckoutError(c, userID, err)
 		return
 	}
 
-	var OrderItemIDs []int
-	for _, item := range orderItems {
-		OrderItemIDs = append(OrderItemIDs, int(item.ID))
-	}
-
-	for _, orderItem := range orderItems {
-		receiptID := "rcpt_" + uuid.New().String()
-		createPayment := models.PaymentDetail{
-			UserID:        userID,
-			OrderItemID:   orderItem.ID,
-			PaymentStatus: "Completed",
-			PaymentAmount: orderItem.Total,
-			PaymentMethod: "Razorpay",
-			OrderId:       RazorPayOrderID,
-			TransactionID: verifyRequest.PaymentID,
-			Receipt:       receiptID,
-		}
-
-		if err := tx.Create(&createPayment).Error; err != nil {
-			logger.Log.Error("Failed to create payment",
-				zap.Uint("orderItemID", orderItem.ID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Payment creation failed", "Something Went Wrong", "/cart")
-			return
-		}
-
-		var orderedItem models.OrderItem
-		if err := tx.First(&orderedItem, orderItem.ID).Error; err != nil {
-			logger.Log.Error("Order item not found",
-				zap.Uint("orderItemID", orderItem.ID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Order not found", "Order not found", "")
-			return
-		}
-
-		orderedItem.OrderStatus = "Confirmed"
-		if err := tx.Save(&orderedItem).Error; err != nil {
-			logger.Log.Error("Failed to update order status",
-				zap.Uint("orderItemID", orderItem.ID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order", "Failed to update order", "")
-			return
-		}
-		DeleteReservedItems(c, tx, orderItem.ProductVariantID, userID)
-	}
-
-	ClearCart(c, tx, result.ReservedMap)
-	if err := tx.Unscoped().Delete(&reservedCoupon, paymentRequest.CouponId).Error; err != nil {
-		logger.Log.Warn("Failed to delete reserved coupon",
-			zap.String("couponID", paymentRequest.CouponId),
-			zap.Error(err))
-	}
-	var orderDetails models.Order
-	if err := tx.First(&orderDetails, orderID).Error; err != nil {
-		logger.Log.Error("Order not found",
-			zap.Uint("orderID", orderID),
-			zap.Error(err))
-		tx.Rollback()
-		helper.RespondWithError(c, http.StatusInternalServerError, "Order not found", "Something Went Wrong", "")
-		return
-	}
-	tx.Commit()
-
 	logger.Log.Info("Razorpay payment verified successfully",
 		zap.Uint("userID", userID),
-		zap.Uint("orderID", orderID),
+		zap.String("orderUID", result.Order.OrderUID),
 		zap.String("paymentID", verifyRequest.PaymentID))
-	c.HTML(http.StatusOK, "orderSuccess.html", gin.H{
-		"status":        "Success",
-		"message":       "Order Success",
-		"OrderID":       orderDetails.OrderUID,
-		"PaymentMethod": "Razorpay",
-		"OrderDate":     orderDetails.CreatedAt.Format("January 2, 2006"),
-		"ExpextedDate":  orderDetails.CreatedAt.AddDate(0, 0, 7).Format("January 2, 2006"),
-		"code":          http.StatusOK,
-	})
+	renderOrderSuccess(c, result)
+}
+
+type razorpayFailureInput struct {
+	PaymentID string `json:"razorpay_payment_id"`
+	OrderID   string `json:"razorpay_order_id"`
 }
 
 func PaymentFailureHandler(c *gin.Context) {
 	logger.Log.Info("Handling payment failure")
 
-	currentTime := time.Now()
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var verifyRequest struct {
-		PaymentID string `json:"razorpay_payment_id"`
-		OrderID   string `json:"razorpay_order_id"`
-	}
+	var verifyRequest razorpayFailureInput
 
 	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
 		logger.Log.Error("Failed to bind failure request", zap.Error(err))
@@ -316,152 +145,36 @@ func PaymentFailureHandler(c *gin.Context) {
 		return
 	}
 
-	var userDetails models.UserAuth
-	if err := config.DB.First(&userDetails, userID).Error; err != nil {
-		logger.Log.Error("User not found",
-			zap.Uint("userID", userID),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusNotFound, "User not found", "User not found", "/cart")
-		return
-	}
-
-	_, cartItems, err := services.FetchCartItems(userID)
-
-	if len(cartItems) == 0 {
-		logger.Log.Warn("Cart is empty", zap.Uint("userID", userID))
-		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Add Product in Your Cart", "/cart")
-		return
-	}
-
-	reservedProducts := FetchReservedProducts(c, userID)
-	if reservedProducts == nil {
-		helper.RespondWithError(c, http.StatusNotFound, "Failed to fetch reserved stock", "Something Went Wrong", "/cart")
-		return
-	}
-
-	if len(reservedProducts) != len(cartItems) {
-		logger.Log.Error("Mismatch between cart items and reserved products",
-			zap.Int("cartItemCount", len(cartItems)),
-			zap.Int("reservedProductCount", len(reservedProducts)))
-		helper.RespondWithError(c, http.StatusBadRequest, "Mismatch cart items and reserved product 11", "Something Went Wrong", "/cart")
-		return
-	}
-
-	result, err := ReservedProductCheck(c, reservedProducts, cartItems)
-	if err != nil {
-		logger.Log.Error(err.Error(),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusInternalServerError, err.Error(), "Something Went Wrong", "/cart")
-		return
-	}
-
-	tx := config.DB.Begin()
-	var coupon models.Coupon
-	if err := tx.First(&coupon, paymentRequest.CouponId).Error; err != nil {
-		logger.Log.Warn("Reserved coupon not found",
-			zap.String("couponID", paymentRequest.CouponId),
-			zap.Error(err))
-	}
-
-	couponDiscountAmount, err := strconv.ParseFloat(paymentRequest.CouponDiscountAmount, 64)
+	result, err := checkout.FailGatewayPayment(c.Request.Context(), userID, checkout.GatewayPayment{
+		OrderID:   verifyRequest.OrderID,
+		PaymentID: verifyRequest.PaymentID,
+	})
 	if err != nil {
-		logger.Log.Error("Failed to parse coupon discount amount",
-			zap.String("couponDiscountAmount", paymentRequest.CouponDiscountAmount),
-			zap.Error(err))
-		helper.RespondWithError(c, http.StatusBadRequest, "Coverting Failed", "Something Went Wrong", "/cart")
+		respondWithCheckoutError(c, userID, err)
 		return
 	}
 
-	orderID := CreateOrder(c, tx, userDetails.ID, result.RegularPrice, result.ProductDiscount, result.TotalDiscount+couponDiscountAmount, result.Tax, float64(result.ShippingCharge), result.Total-couponDiscountAmount, currentTime, paymentRequest.CouponCode, couponDiscountAmount, coupon.Discription, coupon.DiscountValue, coupon.IsFixedCoupon)
-	if orderID == 0 {
-		helper.RespondWithError(c, http.StatusNotFound, "Order not found", "Something Went Wrong", "/cart")
-		return
-	}
-
-	SaveOrderAddress(c, tx, orderID, userDetails.ID, paymentRequest.AddressID)
-	CreateOrderItems(c, tx, reservedProducts, float64(result.ShippingCharge), orderID, userDetails.ID, currentTime, couponDiscountAmount)
-	orderItems := FetchOrderItems(c, tx, orderID)
-	if orderItems == nil {
-		helper.RespondWithError(c, http.StatusNotFound, "Failed to fetch order items", "Something Went Wrong", "/cart")
-		return
-	}
-
-	var OrderItemIDs []int
-	for _, item := range orderItems {
-		OrderItemIDs = append(OrderItemIDs, int(item.ID))
-	}
-
-	for _, orderItem := range orderItems {
-		receiptID := "rcpt_" + uuid.New().String()
-		createPayment := models.PaymentDetail{
-			UserID:        userID,
-			OrderItemID:   orderItem.ID,
-			PaymentStatus: "Failed",
-			PaymentAmount: result.Total,
-			PaymentMethod: "Razorpay",
-			OrderId:       RazorPayOrderID,
-			TransactionID: verifyRequest.PaymentID,
-			Receipt:       receiptID,
-		}
-
-		if err := tx.Create(&createPayment).Error; err != nil {
-			logger.Log.Error("Failed to create failed payment",
-				zap.Uint("orderItemID", orderItem.ID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Payment creation failed", "Something Went Wrong", "/cart")
-			return
-		}
-
-		var orderedItem models.OrderItem
-		if err := tx.First(&orderedItem, orderItem.ID).Error; err != nil {
-			logger.Log.Error("Order item not found",
-				zap.Uint("orderItemID", orderItem.ID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Order not found", "Order not found", "")
-			return
-		}
-
-		orderedItem.OrderStatus = "Order Not Placed"
-		if err := tx.Save(&orderedItem).Error; err != nil {
-			logger.Log.Error("Failed to update order status",
-				zap.Uint("orderItemID", orderItem.ID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order", "Failed to update order", "")
-			return
-		}
-		DeleteReservedItems(c, tx, orderItem.ProductVariantID, userID)
-	}
-
-	ClearCart(c, tx, result.ReservedMap)
-	if err := tx.Unscoped().Delete(&coupon, paymentRequest.CouponId).Error; err != nil {
-		logger.Log.Warn("Failed to delete reserved coupon",
-			zap.String("couponID", paymentRequest.CouponId),
-			zap.Error(err))
-	}
-	tx.Commit()
-
 	logger.Log.Info("Payment failure handled",
 		zap.Uint("userID", userID),
-		zap.Uint("orderID", orderID),
+		zap.String("orderUID", result.Order.OrderUID),
 		zap.String("paymentID", verifyRequest.PaymentID))
 	c.Redirect(http.StatusSeeOther, "/profile/order/details")
 }
 
+type payNowVerifyInput struct {
+	PaymentID   string `json:"razorpay_payment_id"`
+	OrderID     string `json:"razorpay_order_id"`
+	Signature   string `json:"razorpay_signature"`
+	OrderItemID string `json:"order_id"`
+}
+
 func VerifyPayNowRazorpayPayment(c *gin.Context) {
 	logger.Log.Info("Verifying PayNow Razorpay payment")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var verifyRequest struct {
-		PaymentID   string `json:"razorpay_payment_id"`
-		OrderID     string `json:"razorpay_order_id"`
-		Signature   string `json:"razorpay_signature"`
-		OrderItemID string `json:"order_id"`
-	}
+	var verifyRequest payNowVerifyInput
 
 	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
 		logger.Log.Error("Failed to bind verification request", zap.Error(err))
@@ -528,7 +241,15 @@ func VerifyPayNowRazorpayPayment(c *gin.Context) {
 		return
 	}
 
+	confirmed := 0
 	for _, items := range allOrderItem {
+		if !orderlifecycle.CanTransition(items.OrderStatus, orderlifecycle.StatusConfirmed) {
+			logger.Log.Debug("Skipping order item not awaiting payment",
+				zap.Uint("orderItemID", items.ID),
+				zap.String("status", items.OrderStatus))
+			continue
+		}
+
 		var paymentDetails models.PaymentDetail
 		if err := config.DB.First(&paymentDetails, "order_item_id = ?", items.ID).Error; err != nil {
 			logger.Log.Error("Payment details not found",
@@ -547,14 +268,22 @@ func VerifyPayNowRazorpayPayment(c *gin.Context) {
 			return
 		}
 
-		items.OrderStatus = "Confirmed"
-		if err := config.DB.Save(&items).Error; err != nil {
+		if err := orderlifecycle.Transition(config.DB, &items, orderlifecycle.StatusConfirmed, orderlifecycle.SystemActor(), "Payment received via Razorpay", nil); err != nil {
 			logger.Log.Error("Failed to update order item status",
 				zap.Uint("orderItemID", items.ID),
 				zap.Error(err))
 			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update order", "Failed to update order", "/profile/order/details")
 			return
 		}
+		confirmed++
+	}
+
+	if confirmed > 0 {
+		if err := services.NotifyOrderPlaced(config.DB, orderDetails.ID, "Razorpay"); err != nil {
+			logger.Log.Error("Failed to queue order placed email",
+				zap.Uint("orderID", orderDetails.ID),
+				zap.Error(err))
+		}
 	}
 
 	logger.Log.Info("PayNow Razorpay payment verified successfully",
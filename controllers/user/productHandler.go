@@ -4,45 +4,26 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
 )
 
 func UserHome(c *gin.Context) {
 	logger.Log.Info("Loading user home page")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
 	isLoggedIn := false
 	var userID uint
-
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.GetJwtKey(), nil
-		})
-
-		if err == nil && token.Valid && claims.Role == "User" {
-			isLoggedIn = true
-			userID = claims.UserId
-
-			var user models.UserAuth
-			if err := config.DB.First(&user, userID).Error; err != nil || user.IsBlocked || user.IsDeleted {
-				logger.Log.Warn("User invalid or blocked",
-					zap.Uint("userID", userID),
-					zap.Error(err))
-				c.SetCookie("jwtTokensUser", "", -1, "/", "", false, true)
-				isLoggedIn = false
-			}
-		} else {
-			logger.Log.Warn("Invalid JWT token", zap.Error(err))
-		}
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		isLoggedIn = true
+		userID = claims.UserId
 	}
 
 	keyboard, err := helper.RelatedProducts(2)
@@ -78,6 +59,7 @@ func UserHome(c *gin.Context) {
 		"Laptop":     laptop,
 		"Mouse":      mouse,
 		"IsLoggedIn": isLoggedIn,
+		"Currency":   helper.DisplayCurrency(c),
 	})
 }
 
@@ -88,9 +70,12 @@ type ProductVariantResponse struct {
 	SalePrice       float64 `json:"sale_price"`
 	OfferPercentage int     `json:"offer_persentage"`
 	Images          string  `json:"images"`
+	ImagesWebP      string  `json:"images_webp,omitempty"`
 	IsInCart        bool    `json:"is_in_cart"`
 	IsInWishlist    bool    `json:"is_in_wishlist"`
 	IsInStock       bool    `json:"is_in_stock"`
+	AverageRating   float64 `json:"average_rating"`
+	RatingCount     int     `json:"rating_count"`
 }
 
 func ShowProducts(c *gin.Context) {
@@ -168,8 +153,11 @@ func ShowProducts(c *gin.Context) {
 			RegularPrice:    variant.RegularPrice,
 			SalePrice:       variant.SalePrice - discountAmount,
 			OfferPercentage: int(TotalPercentage),
-			Images:          variant.VariantsImages[0].ProductVariantsImages,
+			Images:          helper.ListingImage(variant.VariantsImages[0].ProductVariantsImages, variant.VariantsImages[0].ImageRenditions),
+			ImagesWebP:      variant.VariantsImages[0].ListingWebPURL,
 			IsInStock:       variant.StockQuantity > 0,
+			AverageRating:   variant.Product.AverageRating,
+			RatingCount:     variant.Product.RatingCount,
 		}
 		if cartMap[variant.ID] {
 			resp.IsInCart = true
@@ -180,6 +168,11 @@ func ShowProducts(c *gin.Context) {
 		response = append(response, resp)
 	}
 
+	facets, err := services.FetchProductFacets(services.ProductFilter{})
+	if err != nil {
+		logger.Log.Warn("Failed to fetch product facets", zap.Error(err))
+	}
+
 	logger.Log.Info("Products page loaded",
 		zap.Uint("userID", userID),
 		zap.Int("productCount", len(response)))
@@ -189,6 +182,8 @@ func ShowProducts(c *gin.Context) {
 		"data":     response,
 		"Brand":    Brand,
 		"Category": Category,
+		"Facets":   facets,
+		"Currency": helper.DisplayCurrency(c),
 	})
 }
 
@@ -222,79 +217,15 @@ func FilterProducts(c *gin.Context) {
 		wishlistMap[item.ProductVariantID] = true
 	}
 
-	search := c.Query("search")
+	filter := services.ParseProductFilter(c.Request.URL.Query())
 	sort := c.Query("sort")
-	categories := c.QueryArray("categories")
-	priceRanges := c.QueryArray("priceRanges")
-	discountsStr := c.QueryArray("discounts")
-	brands := c.QueryArray("brands")
-	includeOutOfStock, _ := strconv.ParseBool(c.DefaultQuery("includeOutOfStock", "false"))
-
-	var discounts []int
-	for _, d := range discountsStr {
-		if val, err := strconv.Atoi(d); err == nil {
-			discounts = append(discounts, val)
-		}
-	}
 
-	query := config.DB.Model(&models.ProductVariantDetails{}).
+	query := services.ApplyProductFilters(config.DB.Model(&models.ProductVariantDetails{}), filter, "").
 		Preload("VariantsImages", "is_deleted = ?", false).
 		Preload("Category").
-		Preload("Product").
-		Where("product_variant_details.is_deleted = ?", false)
-
-	query = query.Joins("JOIN product_details ON product_variant_details.product_id = product_details.id")
-
-	if search != "" {
-		searchTerm := "%" + search + "%"
-		query = query.Joins("JOIN categories ON product_variant_details.category_id = categories.id").
-			Where("product_variant_details.product_name ILIKE ? OR product_details.brand_name ILIKE ? OR categories.name ILIKE ?",
-				searchTerm, searchTerm, searchTerm)
-	} else if len(categories) > 0 {
-		query = query.Joins("JOIN categories ON product_variant_details.category_id = categories.id").
-			Where("categories.name IN ?", categories)
-	}
-
-	if len(brands) > 0 {
-		query = query.Where("product_details.brand_name IN ?", brands)
-	}
-
-	if len(priceRanges) > 0 {
-		priceConditions := []string{}
-		for _, pr := range priceRanges {
-			switch pr {
-			case "1":
-				priceConditions = append(priceConditions, "(product_variant_details.sale_price BETWEEN 1000 AND 50000)")
-			case "2":
-				priceConditions = append(priceConditions, "(product_variant_details.sale_price BETWEEN 50000 AND 100000)")
-			case "3":
-				priceConditions = append(priceConditions, "(product_variant_details.sale_price BETWEEN 100000 AND 500000)")
-			}
-		}
-		if len(priceConditions) > 0 {
-			query = query.Where(strings.Join(priceConditions, " OR "))
-		}
-	}
-
-	if len(discounts) > 0 {
-		query = query.Where("((product_variant_details.regular_price - product_variant_details.sale_price) / product_variant_details.regular_price * 100) >= ?",
-			discounts[len(discounts)-1])
-	}
-
-	if !includeOutOfStock {
-		query = query.Where("product_variant_details.stock_quantity > 0")
-	}
+		Preload("Product")
 
-	switch sort {
-	case "price-low":
-		query = query.Order("product_variant_details.sale_price ASC")
-	case "price-high":
-		query = query.Order("product_variant_details.sale_price DESC")
-	case "newest":
-		query = query.Order("product_variant_details.created_at DESC")
-	default:
-		query = query.Order("product_variant_details.created_at DESC")
-	}
+	query = services.SortProducts(query, sort, filter.Search)
 
 	var variants []models.ProductVariantDetails
 	if err := query.Find(&variants).Error; err != nil {
@@ -319,8 +250,11 @@ func FilterProducts(c *gin.Context) {
 			RegularPrice:    variant.RegularPrice,
 			SalePrice:       variant.SalePrice - discountAmount,
 			OfferPercentage: int(TotalPercentage),
-			Images:          variant.VariantsImages[0].ProductVariantsImages,
+			Images:          helper.ListingImage(variant.VariantsImages[0].ProductVariantsImages, variant.VariantsImages[0].ImageRenditions),
+			ImagesWebP:      variant.VariantsImages[0].ListingWebPURL,
 			IsInStock:       variant.StockQuantity > 0,
+			AverageRating:   variant.Product.AverageRating,
+			RatingCount:     variant.Product.RatingCount,
 		}
 		if cartMap[variant.ID] {
 			resp.IsInCart = true
@@ -331,19 +265,107 @@ func FilterProducts(c *gin.Context) {
 		response = append(response, resp)
 	}
 
+	facets, err := services.FetchProductFacets(filter)
+	if err != nil {
+		logger.Log.Error("Failed to fetch product facets", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch filters", "Something Went Wrong", "")
+		return
+	}
+
 	logger.Log.Info("Products filtered successfully",
 		zap.Uint("userID", userID),
 		zap.Int("productCount", len(response)),
-		zap.String("search", search),
-		zap.Strings("categories", categories),
-		zap.Strings("brands", brands))
+		zap.String("search", filter.Search),
+		zap.Strings("categories", filter.Categories),
+		zap.Strings("brands", filter.Brands),
+		zap.Float64("minRating", filter.MinRating))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
 		"message": "Products filtered successfully",
 		"data":    response,
+		"facets":  facets,
+	})
+}
+
+func SearchSuggestions(c *gin.Context) {
+	term := c.Query("q")
+	logger.Log.Info("Requested search suggestions", zap.String("query", term))
+
+	if len(strings.TrimSpace(term)) < 2 {
+		c.JSON(http.StatusOK, gin.H{
+			"status": true,
+			"data":   []services.SearchSuggestion{},
+		})
+		return
+	}
+
+	suggestions, err := services.FetchSearchSuggestions(term, 8)
+	if err != nil {
+		logger.Log.Error("Failed to fetch search suggestions",
+			zap.String("query", term),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch suggestions", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Search suggestions fetched",
+		zap.String("query", term),
+		zap.Int("count", len(suggestions)))
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data":   suggestions,
 	})
 }
 
+func CheckProductDelivery(c *gin.Context) {
+	pinCode := strings.TrimSpace(c.Query("pincode"))
+	variantID := c.Query("id")
+	logger.Log.Info("Requested delivery check",
+		zap.String("pinCode", pinCode),
+		zap.String("variantID", variantID))
+
+	if len(pinCode) != 6 {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid pin code", "Enter a valid 6 digit pin code", "")
+		return
+	}
+	if _, err := strconv.Atoi(pinCode); err != nil {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid pin code", "Enter a valid 6 digit pin code", "")
+		return
+	}
+
+	var variant models.ProductVariantDetails
+	if err := config.DB.First(&variant, "id = ? AND is_deleted = ?", variantID, false).Error; err != nil {
+		logger.Log.Error("Product variant not found", zap.String("variantID", variantID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Product not found", "Product not found", "")
+		return
+	}
+	var product models.ProductDetail
+	if err := config.DB.Unscoped().First(&product, variant.ProductID).Error; err != nil {
+		logger.Log.Error("Product not found", zap.Uint("productID", variant.ProductID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Product not found", "Product not found", "")
+		return
+	}
+
+	delivery := services.EstimateDelivery(pinCode, product.DispatchDays, time.Now())
+	response := gin.H{
+		"status":         "OK",
+		"message":        "Delivery check success",
+		"PinCode":        pinCode,
+		"City":           delivery.City,
+		"IsServiceable":  delivery.IsServiceable,
+		"IsCODAvailable": delivery.IsCODAvailable && product.IsCODAvailable,
+		"code":           http.StatusOK,
+	}
+	if delivery.IsServiceable {
+		response["ExpectedDelivery"] = delivery.ExpectedDate.Format("Monday, January 2")
+	}
+
+	logger.Log.Info("Delivery check completed",
+		zap.String("pinCode", pinCode),
+		zap.Bool("serviceable", delivery.IsServiceable))
+	c.JSON(http.StatusOK, response)
+}
+
 type ProductDetailResponse struct {
 	ID              uint                    `json:"id"`
 	ProductName     string                  `json:"product_name"`
@@ -363,6 +385,8 @@ type ProductDetailResponse struct {
 	IsInWishlist    bool                    `json:"is_in_wishlist"`
 	Specifications  []SpecificationResponse `json:"specifications"`
 	Description     []DescriptionResponse   `json:"description"`
+	AverageRating   float64                 `json:"average_rating"`
+	RatingCount     int                     `json:"rating_count"`
 }
 
 type DescriptionResponse struct {
@@ -414,7 +438,7 @@ func ShowProductDetail(c *gin.Context) {
 
 	var images []string
 	for _, img := range variant.VariantsImages {
-		images = append(images, img.ProductVariantsImages)
+		images = append(images, helper.ZoomImage(img.ProductVariantsImages, img.ImageRenditions))
 	}
 
 	var specs []SpecificationResponse
@@ -442,6 +466,33 @@ func ShowProductDetail(c *gin.Context) {
 		return
 	}
 
+	ratingSummary, err := services.FetchRatingSummary(variant.ProductID)
+	if err != nil {
+		logger.Log.Warn("Failed to fetch rating summary",
+			zap.Uint("productID", variant.ProductID),
+			zap.Error(err))
+	}
+
+	reviews, err := fetchProductReviews(variant.ProductID)
+	if err != nil {
+		logger.Log.Warn("Failed to fetch product reviews",
+			zap.Uint("productID", variant.ProductID),
+			zap.Error(err))
+	}
+
+	var userReview *ReviewResponse
+	canReview := false
+	if userID != 0 {
+		var review models.Review
+		if err := config.DB.Preload("UserAuth").Preload("Rating").
+			First(&review, "user_id = ? AND product_id = ?", userID, variant.ProductID).Error; err == nil {
+			resp := toReviewResponse(review)
+			userReview = &resp
+		} else {
+			canReview = services.HasDeliveredProduct(userID, variant.ProductID)
+		}
+	}
+
 	product := ProductDetailResponse{
 		ID:              variant.ID,
 		ProductName:     variant.ProductName,
@@ -461,6 +512,8 @@ func ShowProductDetail(c *gin.Context) {
 		Description:     description,
 		IsInCart:        IsInCart,
 		IsInWishlist:    IsInWishlist,
+		AverageRating:   ratingSummary.Average,
+		RatingCount:     ratingSummary.Count,
 	}
 
 	type otherVariantDetail struct {
@@ -502,7 +555,7 @@ func ShowProductDetail(c *gin.Context) {
 			RegularPrice:    row.RegularPrice,
 			SalePrice:       row.SalePrice - discountAmount,
 			OfferPersentage: int(TotalPercentage),
-			Image:           row.VariantsImages[0].ProductVariantsImages,
+			Image:           helper.ThumbnailImage(row.VariantsImages[0].ProductVariantsImages, row.VariantsImages[0].ImageRenditions),
 			Stock:           row.StockQuantity,
 		}
 		otherVariantDetails = append(otherVariantDetails, itm)
@@ -531,7 +584,7 @@ func ShowProductDetail(c *gin.Context) {
 	for _, product := range relatedProducts {
 		var images []string
 		for _, image := range product.VariantsImages {
-			images = append(images, image.ProductVariantsImages)
+			images = append(images, helper.ListingImage(image.ProductVariantsImages, image.ImageRenditions))
 		}
 		discountAmount, TotalPercentage, disErr := helper.DiscountCalculation(product.ProductID, product.CategoryID, product.RegularPrice, product.SalePrice)
 		if disErr != nil {
@@ -559,5 +612,11 @@ func ShowProductDetail(c *gin.Context) {
 		"product":             product,
 		"relatedProducts":     relatedProductsResponce,
 		"OtherVariantDetails": otherVariantDetails,
+		"RatingSummary":       ratingSummary,
+		"Reviews":             reviews,
+		"UserReview":          userReview,
+		"CanReview":           canReview,
+		"ReviewStarOptions":   reviewStarOptions(userReview),
+		"Currency":            helper.DisplayCurrency(c),
 	})
 }
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"strconv"
@@ -8,12 +9,17 @@ import (
 	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
-	"github.com/anfastk/E-Commerce-Website/utils"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func ProfileDetails(c *gin.Context) {
@@ -40,17 +46,23 @@ func ProfileDetails(c *gin.Context) {
 	})
 }
 
+type currencyInput struct {
+	Currency string `json:"currency"`
+}
+
+type profileUpdateInput struct {
+	Id       string `json:"userid"`
+	FullName string `json:"fullName"`
+	Mobile   string `json:"phone"`
+	Country  string `json:"country"`
+	State    string `json:"state"`
+	Pincode  string `json:"zipcode"`
+}
+
 func ProfileUpdate(c *gin.Context) {
 	logger.Log.Info("Updating profile")
 
-	var userUpdate struct {
-		Id       string `json:"userid"`
-		FullName string `json:"fullName"`
-		Mobile   string `json:"phone"`
-		Country  string `json:"country"`
-		State    string `json:"state"`
-		Pincode  string `json:"zipcode"`
-	}
+	var userUpdate profileUpdateInput
 	if err := c.ShouldBindJSON(&userUpdate); err != nil {
 		logger.Log.Error("Failed to bind profile update data", zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "Invalid data", "Invalid data", "")
@@ -139,7 +151,7 @@ func ProfileImageUpdate(c *gin.Context) {
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
 	tx := config.DB.Begin()
-	file, fileHeader, err := c.Request.FormFile("image")
+	fileHeader, err := c.FormFile("image")
 	if err != nil {
 		logger.Log.Error("Invalid file upload", zap.Error(err))
 		tx.Rollback()
@@ -147,10 +159,9 @@ func ProfileImageUpdate(c *gin.Context) {
 		return
 	}
 
-	cld := config.InitCloudinary()
-	cloudinaryURL, err := utils.UploadImageToCloudinary(file, fileHeader, cld, "ProfilePicture", "")
+	imageURL, err := storage.SaveUpload(c.Request.Context(), storage.FolderProfilePictures, fileHeader)
 	if err != nil {
-		logger.Log.Error("Failed to upload image to Cloudinary",
+		logger.Log.Error("Failed to upload profile image",
 			zap.Uint("userID", userID),
 			zap.Error(err))
 		tx.Rollback()
@@ -169,7 +180,7 @@ func ProfileImageUpdate(c *gin.Context) {
 	}
 
 	currentAvathar := userDetails.ProfilePic
-	userDetails.ProfilePic = cloudinaryURL
+	userDetails.ProfilePic = imageURL
 	if err := config.DB.Save(&userDetails).Error; err != nil {
 		logger.Log.Error("Failed to save profile picture update",
 			zap.Uint("userID", userID),
@@ -180,22 +191,13 @@ func ProfileImageUpdate(c *gin.Context) {
 	}
 
 	defaultAvathar := os.Getenv("DEFAULT_PROFILE_PIC")
-	if currentAvathar != defaultAvathar {
-		publicID, err := helper.ExtractCloudinaryPublicID(currentAvathar)
-		if err != nil {
-			logger.Log.Error("Failed to extract Cloudinary public ID",
+	if currentAvathar != "" && currentAvathar != defaultAvathar {
+		if err := storage.Delete(c.Request.Context(), currentAvathar); err != nil {
+			logger.Log.Error("Failed to delete old profile image",
 				zap.String("currentAvatar", currentAvathar),
 				zap.Error(err))
 			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to extract Cloudinary public ID", "Failed to extract Cloudinary public ID", "")
-			return
-		}
-		if err := utils.DeleteCloudinaryImage(cld, publicID, c); err != nil {
-			logger.Log.Error("Failed to delete old image from Cloudinary",
-				zap.String("publicID", publicID),
-				zap.Error(err))
-			tx.Rollback()
-			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete image from Cloudinary", "Failed to delete image from Cloudinary", "")
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete old image", "Failed to delete old image", "")
 			return
 		}
 	}
@@ -203,7 +205,7 @@ func ProfileImageUpdate(c *gin.Context) {
 	tx.Commit()
 	logger.Log.Info("Profile image updated successfully",
 		zap.Uint("userID", userID),
-		zap.String("newImageURL", cloudinaryURL))
+		zap.String("newImageURL", imageURL))
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Profile image updated",
@@ -226,9 +228,198 @@ func Settings(c *gin.Context) {
 		return
 	}
 
+	activeSessions, err := sessions.List(config.DB, userID, RoleUser)
+	if err != nil {
+		logger.Log.Error("Failed to fetch sessions",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch sessions", "Something Went Wrong", "")
+		return
+	}
+
+	type sessionResponse struct {
+		ID         string
+		Device     string
+		IPAddress  string
+		SignedInAt time.Time
+		LastUsedAt time.Time
+		IsCurrent  bool
+	}
+	currentSessionID := c.GetString("sessionid")
+	var sessionList []sessionResponse
+	for _, session := range activeSessions {
+		sessionList = append(sessionList, sessionResponse{
+			ID:         session.ID,
+			Device:     sessions.DeviceName(session.UserAgent),
+			IPAddress:  session.IPAddress,
+			SignedInAt: session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			IsCurrent:  session.ID == currentSessionID,
+		})
+	}
+
+	twoFactorEnabled, err := twofactor.IsEnabled(config.DB, userID, RoleUser)
+	if err != nil {
+		logger.Log.Error("Failed to check two-factor status",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch settings", "Something Went Wrong", "")
+		return
+	}
+	var recoveryCodesLeft int64
+	if twoFactorEnabled {
+		if recoveryCodesLeft, err = twofactor.RecoveryCodesLeft(config.DB, userID, RoleUser); err != nil {
+			logger.Log.Warn("Failed to count recovery codes",
+				zap.Uint("userID", userID),
+				zap.Error(err))
+		}
+	}
+
+	currencies, err := currency.Available(config.DB)
+	if err != nil {
+		logger.Log.Warn("Failed to load currencies",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		currencies = []currency.Display{currency.BaseDisplay()}
+	}
+
 	logger.Log.Info("Settings page loaded", zap.Uint("userID", userID))
 	c.HTML(http.StatusOK, "profileSettings.html", gin.H{
-		"User": userDetails,
+		"User":              userDetails,
+		"Sessions":          sessionList,
+		"TwoFactorEnabled":  twoFactorEnabled,
+		"RecoveryCodesLeft": recoveryCodesLeft,
+		"Currencies":        currencies,
+		"Currency":          helper.DisplayCurrency(c),
+	})
+}
+
+func UpdateCurrency(c *gin.Context) {
+	logger.Log.Info("Requested to change display currency")
+
+	var input currencyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid data", "Choose a currency", "")
+		return
+	}
+
+	userID := helper.FetchUserID(c)
+	display, err := currency.ForCode(config.DB, input.Currency)
+	if errors.Is(err, currency.ErrUnsupported) || errors.Is(err, currency.ErrNoRate) {
+		logger.Log.Warn("Currency not available",
+			zap.Uint("userID", userID),
+			zap.String("currency", input.Currency),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Currency not available", "Prices can't be shown in this currency right now", "")
+		return
+	}
+	if err != nil {
+		logger.Log.Error("Failed to load exchange rate",
+			zap.String("currency", input.Currency),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change currency", "Something Went Wrong", "")
+		return
+	}
+
+	tx := config.DB.Begin()
+
+	var profile models.UserProfile
+	if err := tx.First(&profile, "user_id = ?", userID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Error("Failed to fetch user profile",
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			tx.Rollback()
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change currency", "Something Went Wrong", "")
+			return
+		}
+		// Mobile is unique, so a profile made here leaves it null rather than empty.
+		profile = models.UserProfile{UserID: userID, Currency: display.Code}
+		if err := tx.Omit("Mobile").Create(&profile).Error; err != nil {
+			logger.Log.Error("Failed to create user profile",
+				zap.Uint("userID", userID),
+				zap.Error(err))
+			tx.Rollback()
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change currency", "Something Went Wrong", "")
+			return
+		}
+	} else if err := tx.Model(&profile).Update("currency", display.Code).Error; err != nil {
+		logger.Log.Error("Failed to update currency",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to change currency", "Something Went Wrong", "")
+		return
+	}
+
+	tx.Commit()
+	logger.Log.Info("Display currency changed",
+		zap.Uint("userID", userID),
+		zap.String("currency", display.Code))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Prices will be shown in " + display.Name,
+		"code":    http.StatusOK,
+	})
+}
+
+func RevokeSession(c *gin.Context) {
+	logger.Log.Info("Requested to sign out a device")
+
+	userID := helper.FetchUserID(c)
+	sessionID := c.Param("id")
+
+	if err := sessions.Revoke(config.DB, userID, RoleUser, sessionID, sessions.ReasonRevoked); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warn("Session not found",
+				zap.Uint("userID", userID),
+				zap.String("sessionID", sessionID))
+			helper.RespondWithError(c, http.StatusNotFound, "Session not found", "Session not found", "")
+			return
+		}
+		logger.Log.Error("Failed to revoke session",
+			zap.Uint("userID", userID),
+			zap.String("sessionID", sessionID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to sign out device", "Something Went Wrong", "")
+		return
+	}
+
+	if sessionID == c.GetString("sessionid") {
+		middleware.ClearSessionCookies(c, RoleUser)
+	}
+
+	logger.Log.Info("Session revoked",
+		zap.Uint("userID", userID),
+		zap.String("sessionID", sessionID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Device signed out",
+		"code":    http.StatusOK,
+	})
+}
+
+func LogoutEverywhere(c *gin.Context) {
+	logger.Log.Info("Requested to log out everywhere")
+
+	userID := helper.FetchUserID(c)
+	count, err := sessions.RevokeAll(config.DB, userID, RoleUser, "", sessions.ReasonLogoutAll)
+	if err != nil {
+		logger.Log.Error("Failed to revoke sessions",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to log out everywhere", "Something Went Wrong", "")
+		return
+	}
+	middleware.ClearSessionCookies(c, RoleUser)
+
+	logger.Log.Info("Logged out everywhere",
+		zap.Uint("userID", userID),
+		zap.Int64("sessions", count))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Logged out from all devices",
+		"code":    http.StatusOK,
 	})
 }
 
@@ -286,23 +477,25 @@ func ShowAddAddress(c *gin.Context) {
 	})
 }
 
+type addressInput struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Mobile    string `json:"phoneNumber"`
+	Address   string `json:"address"`
+	Landmark  string `json:"landmark"`
+	Country   string `json:"country"`
+	State     string `json:"state"`
+	City      string `json:"city"`
+	PinCode   string `json:"zipCode"`
+}
+
 func AddAddress(c *gin.Context) {
 	logger.Log.Info("Adding new address")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var addAddress struct {
-		FirstName string `json:"firstName"`
-		LastName  string `json:"lastName"`
-		Mobile    string `json:"phoneNumber"`
-		Address   string `json:"address"`
-		Landmark  string `json:"landmark"`
-		Country   string `json:"country"`
-		State     string `json:"state"`
-		City      string `json:"city"`
-		PinCode   string `json:"zipCode"`
-	}
+	var addAddress addressInput
 
 	if err := c.ShouldBindJSON(&addAddress); err != nil {
 		logger.Log.Error("Failed to bind address data", zap.Error(err))
@@ -377,24 +570,26 @@ func ShowEditAddress(c *gin.Context) {
 	})
 }
 
+type editAddressInput struct {
+	Id        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Mobile    string `json:"phoneNumber"`
+	Address   string `json:"address"`
+	Landmark  string `json:"landmark"`
+	Country   string `json:"country"`
+	State     string `json:"state"`
+	City      string `json:"city"`
+	PinCode   string `json:"zipCode"`
+}
+
 func EditAddress(c *gin.Context) {
 	logger.Log.Info("Editing address")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var UpdateAddress struct {
-		Id        string `json:"id"`
-		FirstName string `json:"firstName"`
-		LastName  string `json:"lastName"`
-		Mobile    string `json:"phoneNumber"`
-		Address   string `json:"address"`
-		Landmark  string `json:"landmark"`
-		Country   string `json:"country"`
-		State     string `json:"state"`
-		City      string `json:"city"`
-		PinCode   string `json:"zipCode"`
-	}
+	var UpdateAddress editAddressInput
 
 	if err := c.ShouldBindJSON(&UpdateAddress); err != nil {
 		logger.Log.Error("Failed to bind address update data", zap.Error(err))
@@ -595,6 +790,12 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	if _, err := sessions.RevokeAll(config.DB, userAuth.ID, RoleUser, c.GetString("sessionid"), sessions.ReasonPassword); err != nil {
+		logger.Log.Error("Failed to sign out other sessions after password change",
+			zap.Uint("userID", userAuth.ID),
+			zap.Error(err))
+	}
+
 	logger.Log.Info("Password changed successfully",
 		zap.Uint("userID", userAuth.ID))
 	c.JSON(http.StatusOK, gin.H{
@@ -10,6 +10,7 @@ import (
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -84,6 +85,10 @@ func CreateReferralAccount(c *gin.Context, userID uint) {
 		zap.Uint("referralID", createReferral.ID))
 }
 
+type referralCodeInput struct {
+	ReferralCode string `json:"referralCode"`
+}
+
 func AddReferral(c *gin.Context) {
 	logger.Log.Info("Adding referral")
 
@@ -102,9 +107,7 @@ func AddReferral(c *gin.Context) {
 	}
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var inputReferralCode struct {
-		ReferralCode string `json:"referralCode"`
-	}
+	var inputReferralCode referralCodeInput
 	if err := c.ShouldBindJSON(&inputReferralCode); err != nil {
 		logger.Log.Error("Failed to bind referral code data", zap.Error(err))
 		helper.RespondWithError(c, http.StatusBadRequest, "invalid data", "Enter Correct Code", "")
@@ -322,6 +325,14 @@ func CheckForReferrer(c *gin.Context) {
 				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
 				return
 			}
+			if err := services.NotifyWalletTransaction(tx, &createReferrerWalletHistory); err != nil {
+				logger.Log.Error("Failed to queue wallet transaction email",
+					zap.Uint("userID", createReferrerWalletHistory.UserID),
+					zap.Error(err))
+				tx.Rollback()
+				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
+				return
+			}
 
 			var joineeWallet models.Wallet
 			if err := tx.First(&joineeWallet, "user_id = ? ", joineeDetails.ID).Error; err != nil {
@@ -362,6 +373,14 @@ func CheckForReferrer(c *gin.Context) {
 				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
 				return
 			}
+			if err := services.NotifyWalletTransaction(tx, &createJoineeWalletHistory); err != nil {
+				logger.Log.Error("Failed to queue wallet transaction email",
+					zap.Uint("userID", createJoineeWalletHistory.UserID),
+					zap.Error(err))
+				tx.Rollback()
+				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
+				return
+			}
 
 			logger.Log.Info("Referral reward processed",
 				zap.Uint("referrerID", userID),
@@ -441,6 +460,14 @@ func CheckForJoinee(c *gin.Context) {
 				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
 				return
 			}
+			if err := services.NotifyWalletTransaction(tx, &createJoineeWalletHistory); err != nil {
+				logger.Log.Error("Failed to queue wallet transaction email",
+					zap.Uint("userID", createJoineeWalletHistory.UserID),
+					zap.Error(err))
+				tx.Rollback()
+				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
+				return
+			}
 
 			var referrerAccountDetails models.ReferralAccount
 			if err := tx.First(&referrerAccountDetails, referralHistory.ReferralID).Error; err != nil {
@@ -521,6 +548,14 @@ func CheckForJoinee(c *gin.Context) {
 				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
 				return
 			}
+			if err := services.NotifyWalletTransaction(tx, &createReferrerWalletHistory); err != nil {
+				logger.Log.Error("Failed to queue wallet transaction email",
+					zap.Uint("userID", createReferrerWalletHistory.UserID),
+					zap.Error(err))
+				tx.Rollback()
+				helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Added Failed", "Something Went Wrong", "")
+				return
+			}
 
 			logger.Log.Info("Joinee referral reward processed",
 				zap.Uint("joineeID", userID),
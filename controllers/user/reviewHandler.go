@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type reviewInput struct {
+	Rating int    `json:"rating" binding:"required"`
+	Title  string `json:"title"`
+	Review string `json:"review"`
+}
+
+func validateReviewInput(c *gin.Context, input *reviewInput) bool {
+	input.Title = strings.TrimSpace(input.Title)
+	input.Review = strings.TrimSpace(input.Review)
+	if input.Rating < 1 || input.Rating > 5 {
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid rating", "Rating must be between 1 and 5", "")
+		return false
+	}
+	if len(input.Title) > 255 || len(input.Review) > 1000 {
+		helper.RespondWithError(c, http.StatusBadRequest, "Review too long", "Review must be under 1000 characters", "")
+		return false
+	}
+	return true
+}
+
+func SubmitReview(c *gin.Context) {
+	logger.Log.Info("Requested to submit review")
+
+	userID := helper.FetchUserID(c)
+	variantID := c.Param("id")
+	logger.Log.Debug("Fetched user ID and variant ID",
+		zap.Uint("userID", userID),
+		zap.String("variantID", variantID))
+
+	var input reviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Log.Error("Failed to bind review input", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid data", "Rating is required", "")
+		return
+	}
+	if !validateReviewInput(c, &input) {
+		return
+	}
+
+	var variant models.ProductVariantDetails
+	if err := config.DB.First(&variant, "id = ?", variantID).Error; err != nil {
+		logger.Log.Error("Product variant not found",
+			zap.String("variantID", variantID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusNotFound, "Product Not Found", "Product Not Found", "")
+		return
+	}
+
+	if !services.HasDeliveredProduct(userID, variant.ProductID) {
+		logger.Log.Warn("Review rejected, product not delivered to user",
+			zap.Uint("userID", userID),
+			zap.Uint("productID", variant.ProductID))
+		helper.RespondWithError(c, http.StatusForbidden, "Not Eligible", "You can review this product once it has been delivered to you", "")
+		return
+	}
+
+	var existing models.Rating
+	if err := config.DB.First(&existing, "user_id = ? AND product_id = ?", userID, variant.ProductID).Error; err == nil {
+		logger.Log.Warn("User already reviewed product",
+			zap.Uint("userID", userID),
+			zap.Uint("productID", variant.ProductID))
+		helper.RespondWithError(c, http.StatusConflict, "Already Reviewed", "You have already reviewed this product", "")
+		return
+	}
+
+	tx := config.DB.Begin()
+	rating := models.Rating{
+		UserID:    userID,
+		ProductID: variant.ProductID,
+		Value:     float64(input.Rating),
+	}
+	if err := tx.Create(&rating).Error; err != nil {
+		logger.Log.Error("Failed to create rating",
+			zap.Uint("userID", userID),
+			zap.Uint("productID", variant.ProductID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to submit review", "Something Went Wrong", "")
+		return
+	}
+
+	review := models.Review{
+		UserID:    userID,
+		ProductID: variant.ProductID,
+		RatingID:  rating.ID,
+		Title:     input.Title,
+		Review:    input.Review,
+		Status:    "Visible",
+	}
+	if err := tx.Create(&review).Error; err != nil {
+		logger.Log.Error("Failed to create review",
+			zap.Uint("userID", userID),
+			zap.Uint("productID", variant.ProductID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to submit review", "Something Went Wrong", "")
+		return
+	}
+	if err := services.RefreshProductRating(tx, variant.ProductID); err != nil {
+		logger.Log.Error("Failed to refresh product rating",
+			zap.Uint("productID", variant.ProductID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to submit review", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Review submitted successfully",
+		zap.Uint("userID", userID),
+		zap.Uint("reviewID", review.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Review submitted successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func EditReview(c *gin.Context) {
+	logger.Log.Info("Requested to edit review")
+
+	userID := helper.FetchUserID(c)
+	reviewID := c.Param("id")
+
+	var input reviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Log.Error("Failed to bind review input", zap.Error(err))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid data", "Rating is required", "")
+		return
+	}
+	if !validateReviewInput(c, &input) {
+		return
+	}
+
+	tx := config.DB.Begin()
+	var review models.Review
+	if err := tx.First(&review, "id = ? AND user_id = ?", reviewID, userID).Error; err != nil {
+		logger.Log.Error("Review not found",
+			zap.String("reviewID", reviewID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusNotFound, "Review Not Found", "Review Not Found", "")
+		return
+	}
+
+	if err := tx.Model(&models.Rating{}).Where("id = ? AND user_id = ?", review.RatingID, userID).
+		Update("value", float64(input.Rating)).Error; err != nil {
+		logger.Log.Error("Failed to update rating",
+			zap.Uint("ratingID", review.RatingID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update review", "Something Went Wrong", "")
+		return
+	}
+
+	if err := tx.Model(&review).Updates(map[string]interface{}{
+		"title":  input.Title,
+		"review": input.Review,
+	}).Error; err != nil {
+		logger.Log.Error("Failed to update review",
+			zap.Uint("reviewID", review.ID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update review", "Something Went Wrong", "")
+		return
+	}
+	if err := services.RefreshProductRating(tx, review.ProductID); err != nil {
+		logger.Log.Error("Failed to refresh product rating",
+			zap.Uint("productID", review.ProductID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to update review", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Review updated successfully",
+		zap.Uint("userID", userID),
+		zap.Uint("reviewID", review.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Review updated successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+func DeleteReview(c *gin.Context) {
+	logger.Log.Info("Requested to delete review")
+
+	userID := helper.FetchUserID(c)
+	reviewID := c.Param("id")
+
+	tx := config.DB.Begin()
+	var review models.Review
+	if err := tx.First(&review, "id = ? AND user_id = ?", reviewID, userID).Error; err != nil {
+		logger.Log.Error("Review not found",
+			zap.String("reviewID", reviewID),
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusNotFound, "Review Not Found", "Review Not Found", "")
+		return
+	}
+
+	if err := tx.Unscoped().Delete(&review).Error; err != nil {
+		logger.Log.Error("Failed to delete review",
+			zap.Uint("reviewID", review.ID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete review", "Something Went Wrong", "")
+		return
+	}
+
+	if err := tx.Unscoped().Where("id = ? AND user_id = ?", review.RatingID, userID).Delete(&models.Rating{}).Error; err != nil {
+		logger.Log.Error("Failed to delete rating",
+			zap.Uint("ratingID", review.RatingID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete review", "Something Went Wrong", "")
+		return
+	}
+	if err := services.RefreshProductRating(tx, review.ProductID); err != nil {
+		logger.Log.Error("Failed to refresh product rating",
+			zap.Uint("productID", review.ProductID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to delete review", "Something Went Wrong", "")
+		return
+	}
+	tx.Commit()
+
+	logger.Log.Info("Review deleted successfully",
+		zap.Uint("userID", userID),
+		zap.Uint("reviewID", review.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Review deleted successfully",
+		"code":    http.StatusOK,
+	})
+}
+
+type ReviewResponse struct {
+	ID         uint
+	UserName   string
+	ProfilePic string
+	Rating     int
+	Stars      []bool
+	Title      string
+	Review     string
+	Date       string
+}
+
+type reviewStarOption struct {
+	Value  int
+	Filled bool
+}
+
+func reviewStarOptions(userReview *ReviewResponse) []reviewStarOption {
+	var options []reviewStarOption
+	for value := 1; value <= 5; value++ {
+		options = append(options, reviewStarOption{
+			Value:  value,
+			Filled: userReview != nil && value <= userReview.Rating,
+		})
+	}
+	return options
+}
+
+func fetchProductReviews(productID uint) ([]ReviewResponse, error) {
+	var reviews []models.Review
+	if err := config.DB.Preload("UserAuth").Preload("Rating").
+		Order("created_at DESC").
+		Find(&reviews, "product_id = ? AND status = ?", productID, "Visible").Error; err != nil {
+		return nil, err
+	}
+
+	var response []ReviewResponse
+	for _, review := range reviews {
+		response = append(response, toReviewResponse(review))
+	}
+	return response, nil
+}
+
+func toReviewResponse(review models.Review) ReviewResponse {
+	value := int(review.Rating.Value)
+	stars := make([]bool, 5)
+	for i := range stars {
+		stars[i] = i < value
+	}
+	return ReviewResponse{
+		ID:         review.ID,
+		UserName:   review.UserAuth.FullName,
+		ProfilePic: review.UserAuth.ProfilePic,
+		Rating:     value,
+		Stars:      stars,
+		Title:      review.Title,
+		Review:     review.Review,
+		Date:       review.CreatedAt.Format("January 2, 2006"),
+	}
+}
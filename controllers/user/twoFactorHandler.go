@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type twoFactorCodeInput struct {
+	Code string `json:"code"`
+}
+
+func ShowTwoFactorLogin(c *gin.Context) {
+	challenge, err := middleware.PendingTwoFactor(c, RoleUser)
+	if err != nil {
+		logger.Log.Info("No pending two-factor login", zap.Error(err))
+		c.Redirect(http.StatusSeeOther, "/auth/login")
+		return
+	}
+
+	c.HTML(http.StatusOK, "twoFactorLogin.html", gin.H{
+		"Email": challenge.Email,
+	})
+}
+
+func VerifyTwoFactorLogin(c *gin.Context) {
+	logger.Log.Info("Processing two-factor login")
+
+	var input twoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		helper.RespondWithError(c, http.StatusBadRequest, "Code is required", "Enter the code from your authenticator app", "")
+		return
+	}
+
+	challenge, err := middleware.PendingTwoFactor(c, RoleUser)
+	if err != nil {
+		middleware.ClearTwoFactor(c, RoleUser)
+		helper.RespondWithError(c, http.StatusUnauthorized, "Login expired", "Your login has expired, please sign in again", "/auth/login")
+		return
+	}
+	if helper.AccountLocked(c, ratelimit.ScopeUserLogin, challenge.Email) {
+		middleware.ClearTwoFactor(c, RoleUser)
+		return
+	}
+
+	result, err := twofactor.Answer(config.DB, challenge, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			logger.Log.Warn("Invalid two-factor code", zap.String("email", challenge.Email))
+			if helper.RecordFailedAttempt(c, ratelimit.ScopeUserLogin, challenge.Email) {
+				middleware.ClearTwoFactor(c, RoleUser)
+				return
+			}
+			helper.RespondWithError(c, http.StatusUnauthorized, "Invalid code", "Invalid code, please try again", "")
+		case errors.Is(err, twofactor.ErrTooManyAttempts):
+			logger.Log.Warn("Too many invalid two-factor codes", zap.String("email", challenge.Email))
+			middleware.ClearTwoFactor(c, RoleUser)
+			if helper.RecordFailedAttempt(c, ratelimit.ScopeUserLogin, challenge.Email) {
+				return
+			}
+			helper.RespondWithError(c, http.StatusUnauthorized, "Too many attempts", "Too many invalid codes, please sign in again", "/auth/login")
+		default:
+			logger.Log.Error("Failed to verify two-factor code", zap.String("email", challenge.Email), zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to verify code", "Something Went Wrong", "")
+		}
+		return
+	}
+
+	var user models.UserAuth
+	if err := config.DB.First(&user, "id = ? AND is_blocked = ? AND is_deleted = ?", challenge.UserID, false, false).Error; err != nil {
+		logger.Log.Warn("Blocked or deleted user finished two-factor login", zap.String("email", challenge.Email), zap.Error(err))
+		middleware.ClearTwoFactor(c, RoleUser)
+		helper.RespondWithError(c, http.StatusUnauthorized, "Your Account Is Blocked", "Your Account Is Blocked", "/auth/login")
+		return
+	}
+
+	token, err := middleware.FinishTwoFactor(c, challenge)
+	if err != nil {
+		logger.Log.Error("Failed to start session", zap.String("email", challenge.Email), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to generate JWT tokens", "Failed to generate JWT tokens", "")
+		return
+	}
+
+	helper.ClearFailedAttempts(ratelimit.ScopeUserLogin, challenge.Email)
+	message := "Login successful"
+	if result.UsedRecoveryCode {
+		message = "Login successful. You used a recovery code; generate new ones from Settings if you are running low."
+		logger.Log.Warn("User signed in with a recovery code", zap.String("email", challenge.Email))
+	}
+	logger.Log.Info("User two-factor login successful", zap.String("email", challenge.Email))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+		"token":   token,
+		"code":    http.StatusOK,
+	})
+}
+
+// SetupTwoFactor returns the QR code for the settings page. Two-factor
+// sign-in is only turned on by EnableTwoFactor once a code from the app
+// proves it was scanned.
+func SetupTwoFactor(c *gin.Context) {
+	logger.Log.Info("Requested two-factor setup")
+
+	claims, _ := middleware.Authenticate(c, RoleUser)
+	secret, err := twofactor.Setup(config.DB, claims.UserId, RoleUser)
+	if err != nil {
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			helper.RespondWithError(c, http.StatusConflict, "Already enabled", "Two-factor authentication is already enabled", "")
+			return
+		}
+		logger.Log.Error("Failed to set up two-factor authentication", zap.Uint("userID", claims.UserId), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to set up two-factor authentication", "Something Went Wrong", "")
+		return
+	}
+
+	qrCode, err := twofactor.QRCodeDataURI(twofactor.ProvisioningURI(twofactor.Issuer(), claims.Email, secret))
+	if err != nil {
+		logger.Log.Error("Failed to render two-factor QR code", zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to set up two-factor authentication", "Something Went Wrong", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Scan the QR code with your authenticator app",
+		"secret":  secret,
+		"qr_code": qrCode,
+		"code":    http.StatusOK,
+	})
+}
+
+func EnableTwoFactor(c *gin.Context) {
+	logger.Log.Info("Requested to enable two-factor authentication")
+
+	var input twoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		helper.RespondWithError(c, http.StatusBadRequest, "Code is required", "Enter the code from your authenticator app", "")
+		return
+	}
+
+	userID := helper.FetchUserID(c)
+	codes, err := twofactor.Enable(config.DB, userID, RoleUser, input.Code)
+	if err != nil {
+		respondWithTwoFactorError(c, userID, err)
+		return
+	}
+
+	logger.Log.Info("Two-factor authentication enabled", zap.Uint("userID", userID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "OK",
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+		"code":           http.StatusOK,
+	})
+}
+
+func DisableTwoFactor(c *gin.Context) {
+	logger.Log.Info("Requested to disable two-factor authentication")
+
+	var input twoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		helper.RespondWithError(c, http.StatusBadRequest, "Code is required", "Enter a code from your authenticator app or a recovery code", "")
+		return
+	}
+
+	userID := helper.FetchUserID(c)
+	if _, err := twofactor.Verify(config.DB, userID, RoleUser, input.Code); err != nil {
+		respondWithTwoFactorError(c, userID, err)
+		return
+	}
+	if err := twofactor.Disable(config.DB, userID, RoleUser); err != nil {
+		logger.Log.Error("Failed to disable two-factor authentication", zap.Uint("userID", userID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to disable two-factor authentication", "Something Went Wrong", "")
+		return
+	}
+
+	logger.Log.Info("Two-factor authentication disabled", zap.Uint("userID", userID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "OK",
+		"message": "Two-factor authentication disabled",
+		"code":    http.StatusOK,
+	})
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	logger.Log.Info("Requested new recovery codes")
+
+	var input twoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		helper.RespondWithError(c, http.StatusBadRequest, "Code is required", "Enter the code from your authenticator app", "")
+		return
+	}
+
+	userID := helper.FetchUserID(c)
+	if _, err := twofactor.Verify(config.DB, userID, RoleUser, input.Code); err != nil {
+		respondWithTwoFactorError(c, userID, err)
+		return
+	}
+	codes, err := twofactor.RegenerateRecoveryCodes(config.DB, userID, RoleUser)
+	if err != nil {
+		respondWithTwoFactorError(c, userID, err)
+		return
+	}
+
+	logger.Log.Info("Recovery codes regenerated", zap.Uint("userID", userID))
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "OK",
+		"message":        "New recovery codes generated",
+		"recovery_codes": codes,
+		"code":           http.StatusOK,
+	})
+}
+
+func respondWithTwoFactorError(c *gin.Context, userID uint, err error) {
+	switch {
+	case errors.Is(err, twofactor.ErrInvalidCode):
+		logger.Log.Warn("Invalid two-factor code", zap.Uint("userID", userID))
+		helper.RespondWithError(c, http.StatusBadRequest, "Invalid code", "Invalid code, please try again", "")
+	case errors.Is(err, twofactor.ErrNotEnabled):
+		helper.RespondWithError(c, http.StatusConflict, "Not enabled", "Two-factor authentication is not enabled", "")
+	case errors.Is(err, twofactor.ErrAlreadyEnabled):
+		helper.RespondWithError(c, http.StatusConflict, "Already enabled", "Two-factor authentication is already enabled", "")
+	default:
+		logger.Log.Error("Two-factor request failed", zap.Uint("userID", userID), zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Two-factor request failed", "Something Went Wrong", "")
+	}
+}
@@ -3,15 +3,15 @@ package controllers
 import (
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -22,20 +22,11 @@ var RoleUser = "User"
 func ShowSignup(c *gin.Context) {
 	logger.Log.Info("Showing signup page")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already logged in, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
-		logger.Log.Warn("Invalid token found", zap.Error(err))
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already logged in, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
 	logger.Log.Info("Signup page loaded")
@@ -62,15 +53,17 @@ func CheckPasswordHash(password, hash string) bool {
 	return true
 }
 
+type signUpInput struct {
+	FullName        string `form:"full_name"`
+	Email           string `form:"email"`
+	Password        string `form:"password"`
+	ConfirmPassword string `form:"confirm_password"`
+}
+
 func SignUp(c *gin.Context) {
 	logger.Log.Info("Processing signup")
 
-	var userInput struct {
-		FullName        string `form:"full_name"`
-		Email           string `form:"email"`
-		Password        string `form:"password"`
-		ConfirmPassword string `form:"confirm_password"`
-	}
+	var userInput signUpInput
 
 	if err := c.ShouldBind(&userInput); err != nil {
 		logger.Log.Error("Failed to bind signup data", zap.Error(err))
@@ -130,20 +123,11 @@ func SignUp(c *gin.Context) {
 func ShowOtpVerifyPage(c *gin.Context) {
 	logger.Log.Info("Showing OTP verification page")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already logged in, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
-		logger.Log.Warn("Invalid token found", zap.Error(err))
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already logged in, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
 	session, _ := Store.Get(c.Request, "session")
@@ -165,20 +149,11 @@ func ShowOtpVerifyPage(c *gin.Context) {
 func ShowLogin(c *gin.Context) {
 	logger.Log.Info("Showing login page")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already logged in, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
-		logger.Log.Warn("Invalid token found", zap.Error(err))
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already logged in, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
 	logger.Log.Info("Login page loaded")
@@ -208,6 +183,12 @@ func UserLoginHandler(c *gin.Context) {
 		return
 	}
 
+	if helper.AccountLocked(c, ratelimit.ScopeUserLogin, input.Email) {
+		logger.Log.Warn("Login attempt on locked account",
+			zap.String("email", input.Email))
+		return
+	}
+
 	if err := config.DB.Unscoped().Where("email = ?", input.Email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Log.Warn("Invalid email",
@@ -232,6 +213,9 @@ func UserLoginHandler(c *gin.Context) {
 	if !CheckPasswordHash(input.Password, user.Password) {
 		logger.Log.Warn("Invalid password",
 			zap.String("email", input.Email))
+		if helper.RecordFailedAttempt(c, ratelimit.ScopeUserLogin, input.Email) {
+			return
+		}
 		helper.RespondWithError(c, http.StatusBadRequest, "Invalid password", "Invalid password", "")
 		return
 	}
@@ -243,16 +227,43 @@ func UserLoginHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := middleware.GenerateJWT(user.ID, user.Email, RoleUser)
+	twoFactorEnabled, err := twofactor.IsEnabled(config.DB, user.ID, RoleUser)
 	if err != nil {
-		logger.Log.Error("Failed to generate JWT",
+		logger.Log.Error("Failed to check two-factor status",
+			zap.String("email", input.Email),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Database error", "Something Went Wrong", "")
+		return
+	}
+	if twoFactorEnabled {
+		if err := middleware.BeginTwoFactor(c, user.ID, user.Email, RoleUser); err != nil {
+			logger.Log.Error("Failed to start two-factor challenge",
+				zap.String("email", input.Email),
+				zap.Error(err))
+			helper.RespondWithError(c, http.StatusInternalServerError, "Failed to start two-factor authentication", "Something Went Wrong", "")
+			return
+		}
+		logger.Log.Info("User password verified, waiting for two-factor code",
+			zap.String("email", input.Email))
+		c.JSON(http.StatusOK, gin.H{
+			"status":   "two_factor_required",
+			"message":  "Enter the code from your authenticator app",
+			"redirect": "/auth/login/2fa",
+			"code":     http.StatusOK,
+		})
+		return
+	}
+
+	token, err := middleware.StartSession(c, user.ID, user.Email, RoleUser)
+	if err != nil {
+		logger.Log.Error("Failed to start session",
 			zap.String("email", input.Email),
 			zap.Error(err))
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to generate JWT tokens", "Failed to generate JWT tokens", "")
 		return
 	}
 
-	c.SetCookie("jwtTokensUser", token, int((time.Hour * 1).Seconds()), "/", "", false, true)
+	helper.ClearFailedAttempts(ratelimit.ScopeUserLogin, input.Email)
 	logger.Log.Info("User login successful",
 		zap.String("email", input.Email),
 		zap.Uint("userID", user.ID))
@@ -267,19 +278,12 @@ func UserLoginHandler(c *gin.Context) {
 func UserLogoutHandler(c *gin.Context) {
 	logger.Log.Info("Processing user logout")
 
-	tokenString, _ := c.Cookie("jwtTokensUser")
-	if tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-		if err == nil && token.Valid {
-			logger.Log.Info("User logged out",
-				zap.String("email", claims.Email))
-		}
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User logged out",
+			zap.String("email", claims.Email))
 	}
 
-	c.SetCookie("jwtTokensUser", "", -1, "/", "", false, true)
+	middleware.EndSession(c, RoleUser)
 	logger.Log.Info("Logout completed")
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -291,48 +295,32 @@ func UserLogoutHandler(c *gin.Context) {
 func ForgotPasswordEmail(c *gin.Context) {
 	logger.Log.Info("Showing forgot password email page")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already logged in, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
-		logger.Log.Warn("Invalid token found", zap.Error(err))
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already logged in, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
 	logger.Log.Info("Forgot password email page loaded")
 	c.HTML(http.StatusSeeOther, "forgotPasswordEmail.html", nil)
 }
 
+type forgotPasswordInput struct {
+	Email string `json:"email" form:"email"`
+}
+
 func ForgotUserEmail(c *gin.Context) {
 	logger.Log.Info("Processing forgot password email")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already logged in, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
-		logger.Log.Warn("Invalid token found", zap.Error(err))
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already logged in, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
-	var userInput struct {
-		Email string `json:"email" form:"email"`
-	}
+	var userInput forgotPasswordInput
 
 	if err := c.ShouldBind(&userInput); err != nil {
 		logger.Log.Error("Invalid request payload", zap.Error(err))
@@ -367,20 +355,11 @@ func ForgotUserEmail(c *gin.Context) {
 func PasswordReset(c *gin.Context) {
 	logger.Log.Info("Processing password reset")
 
-	tokenString, err := c.Cookie("jwtTokensUser")
-	if err == nil && tokenString != "" {
-		claims := &middleware.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return middleware.JwtSecretKey, nil
-		})
-
-		if err == nil && token.Valid {
-			logger.Log.Info("User already logged in, redirecting to home",
-				zap.String("email", claims.Email))
-			c.Redirect(http.StatusSeeOther, "/")
-			return
-		}
-		logger.Log.Warn("Invalid token found", zap.Error(err))
+	if claims, ok := middleware.Authenticate(c, RoleUser); ok {
+		logger.Log.Info("User already logged in, redirecting to home",
+			zap.String("email", claims.Email))
+		c.Redirect(http.StatusSeeOther, "/")
+		return
 	}
 
 	userEmail := c.PostForm("email")
@@ -11,9 +11,14 @@ import (
 	"time"
 
 	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
 	"github.com/anfastk/E-Commerce-Website/models"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
-	"github.com/anfastk/E-Commerce-Website/utils"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"github.com/anfastk/E-Commerce-Website/pkg/outbox"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/services"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -103,16 +108,18 @@ func WalletHandler(c *gin.Context) {
 	})
 }
 
+type addMoneyInput struct {
+	PaymentMethod string  `json:"paymentMethod"`
+	Amount        float64 `json:"amount"`
+}
+
 func AddMoneyTOWalltet(c *gin.Context) {
 	logger.Log.Info("Adding money to wallet")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var addMoneyInput struct {
-		PaymentMethod string  `json:"paymentMethod"`
-		Amount        float64 `json:"amount"`
-	}
+	var addMoneyInput addMoneyInput
 
 	if err := c.ShouldBindJSON(&addMoneyInput); err != nil {
 		logger.Log.Error("Failed to bind add money data", zap.Error(err))
@@ -164,7 +171,7 @@ func AddMoneyTOWalltet(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":   "OK",
 			"order_id": razorpayOrderID,
-			"amount":   addMoneyInput.Amount * 100,
+			"amount":   int64(money.FromRupees(addMoneyInput.Amount)),
 			"currency": "INR",
 			"key_id":   config.RAZORPAY_KEY_ID,
 			"prefill": gin.H{
@@ -182,18 +189,20 @@ func AddMoneyTOWalltet(c *gin.Context) {
 	}
 }
 
+type walletPaymentVerifyInput struct {
+	PaymentID string  `json:"razorpay_payment_id"`
+	OrderID   string  `json:"razorpay_order_id"`
+	Signature string  `json:"razorpay_signature"`
+	Amount    float64 `json:"amount"`
+}
+
 func VerifyAddTOWalletRazorpayPayment(c *gin.Context) {
 	logger.Log.Info("Verifying Razorpay payment for wallet")
 
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var verifyRequest struct {
-		PaymentID string  `json:"razorpay_payment_id"`
-		OrderID   string  `json:"razorpay_order_id"`
-		Signature string  `json:"razorpay_signature"`
-		Amount    float64 `json:"amount"`
-	}
+	var verifyRequest walletPaymentVerifyInput
 
 	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
 		logger.Log.Error("Failed to bind verification data", zap.Error(err))
@@ -238,9 +247,9 @@ func VerifyAddTOWalletRazorpayPayment(c *gin.Context) {
 		return
 	}
 
-	actualAmount := verifyRequest.Amount / 100
+	actualAmount := money.Round(verifyRequest.Amount / 100)
 	lastBalance := wallet.Balance
-	wallet.Balance += actualAmount
+	wallet.Balance = (money.FromRupees(wallet.Balance) + money.FromRupees(actualAmount)).Rupees()
 	if err := tx.Save(&wallet).Error; err != nil {
 		logger.Log.Error("Failed to update wallet balance",
 			zap.Uint("walletID", wallet.ID),
@@ -274,6 +283,14 @@ func VerifyAddTOWalletRazorpayPayment(c *gin.Context) {
 		helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Adding Failed", "Something Went Wrong", "")
 		return
 	}
+	if err := services.NotifyWalletTransaction(tx, &createHistory); err != nil {
+		logger.Log.Error("Failed to queue wallet transaction email",
+			zap.Uint("userID", createHistory.UserID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Adding Failed", "Something Went Wrong", "")
+		return
+	}
 
 	tx.Commit()
 	logger.Log.Info("Wallet payment verified and updated",
@@ -298,17 +315,19 @@ func GenerateGiftCardCode() string {
 	return fmt.Sprintf("LPTX-%s-%s-%s-%s", hexStr[0:4], hexStr[4:8], hexStr[8:12], hexStr[12:16])
 }
 
+type giftCardInput struct {
+	RecipientName  string `json:"recipient_name"`
+	RecipientEmail string `json:"recipient_email"`
+	Amount         int    `json:"amount"`
+	Message        string `json:"message"`
+}
+
 func SendGiftCard(c *gin.Context) {
 	logger.Log.Info("Sending Gift Card")
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var Details struct {
-		RecipientName  string `json:"recipient_name"`
-		RecipientEmail string `json:"recipient_email"`
-		Amount         int    `json:"amount"`
-		Message        string `json:"message"`
-	}
+	var Details giftCardInput
 
 	if err := c.ShouldBindJSON(&Details); err != nil {
 		logger.Log.Error("Failed to bind details data", zap.Error(err))
@@ -337,7 +356,7 @@ func SendGiftCard(c *gin.Context) {
 		return
 	}
 
-	if walletDetails.Balance < float64(Details.Amount) {
+	if money.FromRupees(walletDetails.Balance) < money.FromRupees(float64(Details.Amount)) {
 		tx.Rollback()
 		logger.Log.Error("Insufficient wallet balance!")
 		helper.RespondWithError(c, http.StatusBadRequest, "Insufficient wallet balance!", "Insufficient wallet balance! Please add funds to your wallet to proceed with the gift card transaction.", "")
@@ -376,16 +395,25 @@ func SendGiftCard(c *gin.Context) {
 	}
 	formattedExpDate := data.ExpDate.Format("January 02, 2006")
 	giftCardValueStr := fmt.Sprintf("%.2f", data.GiftCardValue)
-	if err := utils.SendGiftCardToEmail(userDetails.FullName, userDetails.ProfilePic, data.Message, data.RecipientEmail, giftCardValueStr, GiftCode, formattedExpDate); err != nil {
-		logger.Log.Error("Failed to send gift card to email",
+	if err := outbox.Enqueue(tx, notifier.EventGiftCardEmail, notifier.GiftCardEmail{
+		SenderName:    userDetails.FullName,
+		SenderProfile: userDetails.ProfilePic,
+		Message:       data.Message,
+		Email:         data.RecipientEmail,
+		Amount:        giftCardValueStr,
+		GiftCode:      GiftCode,
+		ExpDate:       formattedExpDate,
+	}); err != nil {
+		logger.Log.Error("Failed to queue gift card email",
 			zap.String("email", data.RecipientEmail),
 			zap.Error(err))
+		tx.Rollback()
 		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to send Gift Card", "Failed To Gift Card , Please Try Again ", "")
 		return
 	}
 
 	lastBalance := walletDetails.Balance
-	walletDetails.Balance -= data.GiftCardValue
+	walletDetails.Balance = (money.FromRupees(walletDetails.Balance) - money.FromRupees(data.GiftCardValue)).Rupees()
 	if err := tx.Save(&walletDetails).Error; err != nil {
 		logger.Log.Error("Failed to update wallet balance",
 			zap.Uint("userID", userID),
@@ -420,8 +448,23 @@ func SendGiftCard(c *gin.Context) {
 		helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Creation Failed", "Something Went Wrong", "/cart")
 		return
 	}
+	if err := services.NotifyWalletTransaction(tx, &walletHistory); err != nil {
+		logger.Log.Error("Failed to queue wallet transaction email",
+			zap.Uint("userID", walletHistory.UserID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Creation Failed", "Something Went Wrong", "/cart")
+		return
+	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		logger.Log.Error("Failed to commit gift card transaction",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+		helper.RespondWithError(c, http.StatusInternalServerError, "Failed to send Gift Card", "Failed To Gift Card , Please Try Again ", "")
+		return
+	}
+	outbox.Kick(config.DB)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "Success",
@@ -430,14 +473,16 @@ func SendGiftCard(c *gin.Context) {
 	})
 }
 
+type redeemGiftCardInput struct {
+	GiftCode string `json:"code"`
+}
+
 func RedeemGiftCard(c *gin.Context) {
 	logger.Log.Info("Redeeming Gift Card")
 	userID := helper.FetchUserID(c)
 	logger.Log.Debug("Fetched user ID", zap.Uint("userID", userID))
 
-	var userInput struct {
-		GiftCode string `json:"code"`
-	}
+	var userInput redeemGiftCardInput
 
 	if err := c.ShouldBindJSON(&userInput); err != nil {
 		logger.Log.Error("Failed to bind details data", zap.Error(err))
@@ -448,9 +493,20 @@ func RedeemGiftCard(c *gin.Context) {
 	cleanedGiftCode := strings.ReplaceAll(userInput.GiftCode, " ", "")
 	cleanedGiftCode = strings.ReplaceAll(cleanedGiftCode, "-", "")
 
+	claims, _ := middleware.Authenticate(c, RoleUser)
+	if helper.AccountLocked(c, ratelimit.ScopeGiftCard, claims.Email) {
+		logger.Log.Warn("Gift card redeem attempt on locked account", zap.Uint("userID", userID))
+		return
+	}
+
 	tx := config.DB.Begin()
 	var giftCardDetails models.WalletGiftCard
 	if err := tx.First(&giftCardDetails, "gift_card_code = ?", cleanedGiftCode).Error; err != nil {
+		tx.Rollback()
+		logger.Log.Warn("Invalid gift card code", zap.Uint("userID", userID))
+		if helper.RecordFailedAttempt(c, ratelimit.ScopeGiftCard, claims.Email) {
+			return
+		}
 		helper.RespondWithError(c, http.StatusNotFound, "Invalid Code Entered", "Invalid Code Entered", "")
 		return
 	}
@@ -483,7 +539,7 @@ func RedeemGiftCard(c *gin.Context) {
 	}
 
 	lastBalance := walletDetails.Balance
-	walletDetails.Balance += giftCardDetails.GiftCardValue
+	walletDetails.Balance = (money.FromRupees(walletDetails.Balance) + money.FromRupees(giftCardDetails.GiftCardValue)).Rupees()
 	if err := tx.Save(&walletDetails).Error; err != nil {
 		logger.Log.Error("Failed to update wallet balance",
 			zap.Uint("userID", userID),
@@ -518,6 +574,14 @@ func RedeemGiftCard(c *gin.Context) {
 		helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Creation Failed", "Something Went Wrong", "/cart")
 		return
 	}
+	if err := services.NotifyWalletTransaction(tx, &walletHistory); err != nil {
+		logger.Log.Error("Failed to queue wallet transaction email",
+			zap.Uint("userID", walletHistory.UserID),
+			zap.Error(err))
+		tx.Rollback()
+		helper.RespondWithError(c, http.StatusInternalServerError, "Wallet Transaction Creation Failed", "Something Went Wrong", "/cart")
+		return
+	}
 
 	giftCardDetails.Status = "Redeemed"
 	giftCardDetails.RedeemedUserID = &userID
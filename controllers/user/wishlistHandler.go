@@ -85,7 +85,7 @@ func ShowWishlist(c *gin.Context) {
 			WishListID:          item.ID,
 			ProductID:           item.ProductVariantDetails.ID,
 			ProductName:         item.ProductVariantDetails.ProductName,
-			ProductImage:        item.ProductVariantDetails.VariantsImages[0].ProductVariantsImages,
+			ProductImage:        helper.ListingImage(item.ProductVariantDetails.VariantsImages[0].ProductVariantsImages, item.ProductVariantDetails.VariantsImages[0].ImageRenditions),
 			ProductRegularPrice: item.ProductVariantDetails.RegularPrice,
 			ProductSalePrice:    item.ProductVariantDetails.SalePrice - discountAmount,
 			DiscountPercentage:  int(discountPercentage),
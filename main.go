@@ -1,12 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
+	"github.com/anfastk/E-Commerce-Website/pkg/emails"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"github.com/anfastk/E-Commerce-Website/pkg/outbox"
+	"github.com/anfastk/E-Commerce-Website/pkg/paymentgateway"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
 	"github.com/anfastk/E-Commerce-Website/routes"
 	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/services/checkout"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 var r *gin.Engine
@@ -14,10 +33,24 @@ var r *gin.Engine
 func init() {
 	logger.InitLogger()
 	config.LoadEnvFile()
+	paymentgateway.Init()
+	emails.Init()
+	notifier.Init()
+	storage.Init()
 	r = gin.Default()
+	// Rate limits are kept per client IP, so X-Forwarded-For is only
+	// believed from the proxies we run behind.
+	if err := r.SetTrustedProxies(config.TRUSTED_PROXIES); err != nil {
+		logger.Log.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
 	r.Static("static", "./static")
+	if local, ok := storage.Default.(*storage.LocalStore); ok {
+		r.Static(local.URLPrefix, local.Dir)
+	}
+	r.SetFuncMap(currency.TemplateFuncs)
 	r.LoadHTMLGlob("views/**/*")
 	config.DBconnect()
+	ratelimit.Init()
 	r.Use(middleware.DBRecoveryMiddleware())
 	r.Use(middleware.ErrorHandlerMiddleware())
 	r.Use(middleware.NoCacheMiddleware())
@@ -26,9 +59,47 @@ func init() {
 }
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	routes.AdminRoutes(r)
 	routes.UserRouter(r)
-	services.StartReservationCleanupTask(config.DB)
+	routes.APIRoutes(r)
+	routes.DocsRoutes(r)
+	scheduler := jobs.NewScheduler(config.DB)
+	scheduler.Register(services.ReservationCleanupJob(config.DB))
+	scheduler.Register(outbox.DispatchJob(config.DB))
+	scheduler.Register(outbox.CleanupJob(config.DB))
+	scheduler.Register(sessions.CleanupJob(config.DB))
+	scheduler.Register(ratelimit.CleanupJob(config.DB))
+	scheduler.Register(services.ReconciliationJob(config.DB))
+	scheduler.Register(services.RefundReconcileJob(config.DB))
+	scheduler.Register(checkout.CheckoutRefundJob(config.DB))
+	scheduler.Start(ctx)
+	services.RefreshAllProductRatings(config.DB)
+	services.SetupProductSearch(config.DB)
+	if config.MoneyColumnsMigrated {
+		go services.ReconcileHistory(config.DB)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Fatal("Server failed", zap.Error(err))
+		}
+	}()
 	logger.Log.Info("E-commerce website started!")
-	r.Run()
+
+	<-ctx.Done()
+	logger.Log.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Error("Server shutdown failed", zap.Error(err))
+	}
+	scheduler.Wait()
 }
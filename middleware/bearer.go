@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// TokenPair is what API clients get instead of cookies. RefreshToken is
+// empty when a refresh raced another one for the same token; the client
+// should keep the refresh token it already has.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+	SessionID    string
+}
+
+// IssueTokens signs the account in for an API client and returns its tokens
+// instead of setting cookies.
+func IssueTokens(c *gin.Context, userID uint, email, role string) (*TokenPair, error) {
+	session, refreshToken, err := sessions.Create(config.DB, userID, email, role, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+	token, err := GenerateJWT(userID, email, role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	c.Set(claimsKey(role), &Claims{UserId: userID, Email: email, Role: role, SessionID: session.ID})
+	return &TokenPair{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(sessions.AccessTokenTTL.Seconds()),
+		SessionID:    session.ID,
+	}, nil
+}
+
+// RefreshTokens exchanges an API client's refresh token for new tokens.
+func RefreshTokens(c *gin.Context, refreshToken, role string) (*TokenPair, error) {
+	session, newRefreshToken, err := sessions.Rotate(config.DB, refreshToken, role, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccount(session.UserID, role); err != nil {
+		return nil, err
+	}
+	token, err := GenerateJWT(session.UserID, session.Email, role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  token,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    int(sessions.AccessTokenTTL.Seconds()),
+		SessionID:    session.ID,
+	}, nil
+}
+
+// AuthenticateBearer checks the access token in the Authorization header.
+// Unlike Authenticate it never renews anything: API clients call the refresh
+// endpoint themselves once the access token runs out. The claims are cached
+// on the request, so FetchUserID and Authenticate work behind it as usual.
+func AuthenticateBearer(c *gin.Context, role string) (*Claims, error) {
+	header := c.GetHeader("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errNoSession
+	}
+	tokenString := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return JwtSecretKey, nil
+	})
+	if err != nil || !token.Valid || claims.Role != role || claims.SessionID == "" {
+		return nil, sessions.ErrInvalidToken
+	}
+	if _, err := sessions.Active(config.DB, claims.SessionID); err != nil {
+		return nil, err
+	}
+	if err := checkAccount(claims.UserId, role); err != nil {
+		return nil, err
+	}
+
+	c.Set(claimsKey(role), claims)
+	return claims, nil
+}
+
+// SessionEnded reports whether err means the client has to sign in again,
+// as opposed to a failure to check the session.
+func SessionEnded(err error) bool {
+	return errors.Is(err, errNoSession) || sessionEnded(err)
+}
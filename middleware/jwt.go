@@ -1,51 +1,35 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"os"
-	"strings"
 	"time"
- 
-	"github.com/anfastk/E-Commerce-Website/config"
-	"github.com/anfastk/E-Commerce-Website/models"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
 type Claims struct {
-	UserId uint
-	Email  string `json:"username"`
-	Role   string `json:"role"`
+	UserId    uint
+	Email     string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.StandardClaims
 }
 
 var JwtSecretKey = []byte(os.Getenv("SECRETKEY"))
 
-func JwtTocken(c *gin.Context, userId uint, email string, role string) {
-	tokenString, err := GenerateJWT(userId, email, role)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status": "Bad Request",
-			"error":  "Failed to generate jwt tocken",
-			"code":   400,
-		})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{
-		"Status": "OK",
-		"token":  tokenString,
-		"code":   "200",
-	})
-}
-
-func GenerateJWT(userId uint, email string, role string) (string, error) {
+// GenerateJWT issues a short-lived access token for a session. Use
+// StartSession to sign someone in.
+func GenerateJWT(userId uint, email string, role string, sessionID string) (string, error) {
 	claims := Claims{
-		UserId: uint(userId),
-		Email:  email,
-		Role:   role,
+		UserId:    uint(userId),
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 1).Unix(),
+			ExpiresAt: time.Now().Add(sessions.AccessTokenTTL).Unix(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -58,43 +42,14 @@ func GenerateJWT(userId uint, email string, role string) (string, error) {
 
 func AuthMiddleware(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString, err := c.Cookie("jwtTokens" + requiredRole)
-
-		if err != nil || tokenString == "" {
-			if requiredRole == "Admin" || requiredRole == "User" {
-				role := strings.ToLower(requiredRole)
-				if requiredRole == "User" {
-					role = "auth"
-				}
-				redirectPath := fmt.Sprintf("/%s/login", role)
-				c.Redirect(http.StatusSeeOther, redirectPath)
-			} else {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"status":  "Unauthorized",
-					"message": "Can't find cookie",
-					"code":    401,
-				})
-			}
-			c.Abort()
-			return
-		}
-
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return JwtSecretKey, nil
-		})
-		if requiredRole == "User" {
-			var userDetails models.UserAuth
-			if err := config.DB.First(&userDetails, "id=? AND is_blocked = ?", claims.UserId, false).Error; err != nil {
-				c.SetCookie("jwtTokensUser", "", -1, "/", "", false, true)
-			}
-		}
-
-		if err != nil || !token.Valid {
-			if requiredRole == "Admin" || requiredRole == "User" {
-				redirectPath := fmt.Sprintf("/%s/login", strings.ToLower(requiredRole))
-				c.Redirect(http.StatusSeeOther, redirectPath)
-			} else {
+		claims, ok := Authenticate(c, requiredRole)
+		if !ok {
+			switch requiredRole {
+			case "Admin":
+				c.Redirect(http.StatusSeeOther, "/admin/login")
+			case "User":
+				c.Redirect(http.StatusSeeOther, "/auth/login")
+			default:
 				c.JSON(http.StatusUnauthorized, gin.H{
 					"status":  "Unauthorized",
 					"message": "Invalid or expired JWT Token.",
@@ -105,20 +60,12 @@ func AuthMiddleware(requiredRole string) gin.HandlerFunc {
 			return
 		}
 
-		if claims.Role != requiredRole {
-			c.JSON(http.StatusForbidden, gin.H{
-				"status": "Forbidden",
-				"error":  "Insufficient permissions",
-				"code":   403,
-			})
-			c.Abort()
-			return
-		}
-
 		c.Set("userid", claims.UserId)
+		c.Set("sessionid", claims.SessionID)
 		c.Next()
 	}
 }
+
 func GetJwtKey() []byte {
-    return JwtSecretKey
+	return JwtSecretKey
 }
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+)
+
+// AccountKey picks the account a request acts on, or "" when it has none.
+type AccountKey func(c *gin.Context) string
+
+// AccountFromField reads the account from a JSON or form field, leaving the
+// body in place for the handler.
+func AccountFromField(field string) AccountKey {
+	return func(c *gin.Context) string {
+		if c.ContentType() != binding.MIMEJSON {
+			return c.PostForm(field)
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return ""
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		var values map[string]interface{}
+		if err := json.Unmarshal(body, &values); err != nil {
+			return ""
+		}
+		value, _ := values[field].(string)
+		return value
+	}
+}
+
+// AccountFromUser uses the signed-in user of role as the account.
+func AccountFromUser(role string) AccountKey {
+	return func(c *gin.Context) string {
+		if claims, ok := Authenticate(c, role); ok {
+			return strconv.FormatUint(uint64(claims.UserId), 10)
+		}
+		return ""
+	}
+}
+
+// RateLimit rejects requests with 429 once the client IP or the account runs
+// out of tokens under rule. account may be nil to limit by IP only.
+func RateLimit(rule ratelimit.Rule, account AccountKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if retryAfter, limited := RateLimited(c, rule, account); limited {
+			c.Header("Retry-After", RetryAfterSeconds(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"status":  http.StatusText(http.StatusTooManyRequests),
+				"error":   "Too many requests",
+				"message": "Too many attempts, please try again in " + RetryAfterText(retryAfter),
+				"code":    http.StatusTooManyRequests,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimited takes a token for the request under rule and reports how long
+// the client has to wait when it is over the limit. Handlers with their own
+// response format use it instead of RateLimit.
+func RateLimited(c *gin.Context, rule ratelimit.Rule, account AccountKey) (time.Duration, bool) {
+	ctx := c.Request.Context()
+	if rule.PerIP.Burst > 0 {
+		result := ratelimit.Allow(ctx, ratelimit.Key(rule.Name, "ip", c.ClientIP()), rule.PerIP)
+		if !result.Allowed {
+			logRateLimited(c, rule, "ip")
+			return result.RetryAfter, true
+		}
+	}
+	if rule.PerAccount.Burst > 0 && account != nil {
+		if value := ratelimit.NormalizeAccount(account(c)); value != "" {
+			result := ratelimit.Allow(ctx, ratelimit.Key(rule.Name, "account", value), rule.PerAccount)
+			if !result.Allowed {
+				logRateLimited(c, rule, "account")
+				return result.RetryAfter, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func logRateLimited(c *gin.Context, rule ratelimit.Rule, kind string) {
+	logger.Log.Warn("Rate limit exceeded",
+		zap.String("rule", rule.Name),
+		zap.String("by", kind),
+		zap.String("ip", c.ClientIP()),
+		zap.String("path", c.Request.URL.Path))
+}
+
+// RetryAfterSeconds formats a wait for the Retry-After header.
+func RetryAfterSeconds(wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// RetryAfterText turns a wait into "30 seconds" or "5 minutes".
+func RetryAfterText(wait time.Duration) string {
+	if wait < time.Minute {
+		seconds := int(math.Ceil(wait.Seconds()))
+		if seconds <= 1 {
+			return "1 second"
+		}
+		return strconv.Itoa(seconds) + " seconds"
+	}
+	minutes := int(math.Ceil(wait.Minutes()))
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return strconv.Itoa(minutes) + " minutes"
+}
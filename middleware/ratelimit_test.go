@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const proxyIP = "10.0.0.5"
+
+var testRule = ratelimit.Rule{Name: "test", PerIP: ratelimit.Limit{Burst: 2, Per: time.Hour}}
+
+func newLimitedRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	ratelimit.Default = ratelimit.NewMemoryStore()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	r.POST("/login", RateLimit(testRule, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func post(r *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestRateLimitIgnoresSpoofedForwardedFor checks a client cannot get a fresh
+// bucket by sending a different X-Forwarded-For with each request.
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	r := newLimitedRouter(t, nil)
+
+	spoofed := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"}
+	for i, ip := range spoofed {
+		want := http.StatusOK
+		if i >= testRule.PerIP.Burst {
+			want = http.StatusTooManyRequests
+		}
+		if code := post(r, "198.51.100.7:4000", ip); code != want {
+			t.Fatalf("request %d with X-Forwarded-For %s = %d, want %d", i+1, ip, code, want)
+		}
+	}
+}
+
+// TestRateLimitTrustedProxy checks clients behind a trusted proxy are limited
+// by their own address, not the proxy's.
+func TestRateLimitTrustedProxy(t *testing.T) {
+	r := newLimitedRouter(t, []string{proxyIP})
+
+	for i := 0; i < testRule.PerIP.Burst; i++ {
+		if code := post(r, proxyIP+":4000", "203.0.113.1"); code != http.StatusOK {
+			t.Fatalf("request %d = %d, want %d", i+1, code, http.StatusOK)
+		}
+	}
+	if code := post(r, proxyIP+":4000", "203.0.113.1"); code != http.StatusTooManyRequests {
+		t.Errorf("client over the limit = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := post(r, proxyIP+":4000", "203.0.113.2"); code != http.StatusOK {
+		t.Errorf("another client behind the proxy = %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const adminContextKey = "admin"
+
+// RequirePermission lets a request through only when the signed-in admin's
+// role grants perm. It runs after AuthMiddleware("Admin").
+func RequirePermission(perm rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin, err := CurrentAdmin(c)
+		if err != nil {
+			logger.Log.Error("Failed to load admin", zap.Uint("adminID", c.GetUint("userid")), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"status":  http.StatusText(http.StatusInternalServerError),
+				"error":   "Failed to load admin",
+				"message": "Something Went Wrong",
+				"code":    http.StatusInternalServerError,
+			})
+			return
+		}
+
+		if !admin.IsActive || !rbac.Can(admin.Role, perm) {
+			logger.Log.Warn("Admin permission denied",
+				zap.Uint("adminID", admin.ID),
+				zap.String("role", admin.Role),
+				zap.String("permission", string(perm)),
+				zap.String("path", c.Request.URL.Path))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"status":  http.StatusText(http.StatusForbidden),
+				"error":   "Permission denied",
+				"message": "Your role does not allow access to this section",
+				"code":    http.StatusForbidden,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentAdmin loads the signed-in admin once per request.
+func CurrentAdmin(c *gin.Context) (*models.AdminModel, error) {
+	if cached, exists := c.Get(adminContextKey); exists {
+		return cached.(*models.AdminModel), nil
+	}
+
+	var admin models.AdminModel
+	if err := config.DB.First(&admin, c.GetUint("userid")).Error; err != nil {
+		return nil, err
+	}
+	c.Set(adminContextKey, &admin)
+	return &admin, nil
+}
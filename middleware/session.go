@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+)
+
+var (
+	errNoSession       = errors.New("not signed in")
+	errAccountDisabled = errors.New("account is blocked or deleted")
+)
+
+func accessCookie(role string) string  { return "jwtTokens" + role }
+func refreshCookie(role string) string { return "refreshToken" + role }
+
+// StartSession signs the account in on this device: it records a session,
+// sets the access and refresh cookies and returns the access token.
+func StartSession(c *gin.Context, userID uint, email, role string) (string, error) {
+	session, refreshToken, err := sessions.Create(config.DB, userID, email, role, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return "", err
+	}
+	token, err := GenerateJWT(userID, email, role, session.ID)
+	if err != nil {
+		return "", err
+	}
+	setSessionCookies(c, role, token, refreshToken)
+	c.Set(claimsKey(role), &Claims{UserId: userID, Email: email, Role: role, SessionID: session.ID})
+	return token, nil
+}
+
+// EndSession revokes the current session and clears its cookies.
+func EndSession(c *gin.Context, role string) {
+	if claims, ok := Authenticate(c, role); ok {
+		if err := sessions.Revoke(config.DB, claims.UserId, role, claims.SessionID, sessions.ReasonLogout); err != nil {
+			logger.Log.Warn("Failed to revoke session on logout", zap.String("sessionID", claims.SessionID), zap.Error(err))
+		}
+	} else if refreshToken, err := c.Cookie(refreshCookie(role)); err == nil && refreshToken != "" {
+		if err := sessions.RevokeByToken(config.DB, refreshToken, role, sessions.ReasonLogout); err != nil {
+			logger.Log.Warn("Failed to revoke session on logout", zap.Error(err))
+		}
+	}
+	ClearSessionCookies(c, role)
+}
+
+func ClearSessionCookies(c *gin.Context, role string) {
+	c.SetCookie(accessCookie(role), "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookie(role), "", -1, "/", "", false, true)
+}
+
+// setSessionCookies stores the tokens. An empty refresh token leaves the
+// refresh cookie alone.
+func setSessionCookies(c *gin.Context, role, accessToken, refreshToken string) {
+	c.SetCookie(accessCookie(role), accessToken, int(sessions.AccessTokenTTL.Seconds()), "/", "", false, true)
+	if refreshToken != "" {
+		c.SetCookie(refreshCookie(role), refreshToken, int(sessions.RefreshTokenTTL(role).Seconds()), "/", "", false, true)
+	}
+}
+
+func claimsKey(role string) string { return "sessionClaims" + role }
+
+// Authenticate returns the signed-in account for role, or false when there
+// is none. An expired access token is renewed from the refresh cookie, and a
+// revoked session or a blocked account is signed out. The result is cached
+// on the request, so it is cheap to call from handlers behind AuthMiddleware.
+func Authenticate(c *gin.Context, role string) (*Claims, bool) {
+	if cached, exists := c.Get(claimsKey(role)); exists {
+		claims, _ := cached.(*Claims)
+		return claims, claims != nil
+	}
+
+	claims, err := authenticate(c, role)
+	if err != nil {
+		if sessionEnded(err) {
+			logger.Log.Info("Session rejected", zap.String("role", role), zap.Error(err))
+			ClearSessionCookies(c, role)
+		} else if !errors.Is(err, errNoSession) {
+			logger.Log.Error("Failed to check session", zap.String("role", role), zap.Error(err))
+		}
+		c.Set(claimsKey(role), (*Claims)(nil))
+		return nil, false
+	}
+	c.Set(claimsKey(role), claims)
+	return claims, true
+}
+
+// sessionEnded tells a session that is over apart from a failure to check it,
+// which should not sign anyone out.
+func sessionEnded(err error) bool {
+	for _, target := range []error{sessions.ErrInvalidToken, sessions.ErrExpired, sessions.ErrRevoked, sessions.ErrTokenReused, errAccountDisabled} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func authenticate(c *gin.Context, role string) (*Claims, error) {
+	if tokenString, err := c.Cookie(accessCookie(role)); err == nil && tokenString != "" {
+		claims := &Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return JwtSecretKey, nil
+		})
+		if err == nil && token.Valid && claims.Role == role && claims.SessionID != "" {
+			if _, err := sessions.Active(config.DB, claims.SessionID); err != nil {
+				return nil, err
+			}
+			if err := checkAccount(claims.UserId, role); err != nil {
+				return nil, err
+			}
+			return claims, nil
+		}
+	}
+
+	refreshToken, err := c.Cookie(refreshCookie(role))
+	if err != nil || refreshToken == "" {
+		return nil, errNoSession
+	}
+	session, newRefreshToken, err := sessions.Rotate(config.DB, refreshToken, role, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccount(session.UserID, role); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := GenerateJWT(session.UserID, session.Email, role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	setSessionCookies(c, role, accessToken, newRefreshToken)
+	return &Claims{UserId: session.UserID, Email: session.Email, Role: role, SessionID: session.ID}, nil
+}
+
+// checkAccount makes sure a blocked or deleted user, or a deactivated admin,
+// loses every session at once rather than when their tokens run out.
+func checkAccount(userID uint, role string) error {
+	var count int64
+	switch role {
+	case "User":
+		if err := config.DB.Model(&models.UserAuth{}).
+			Where("id = ? AND is_blocked = ? AND is_deleted = ?", userID, false, false).
+			Count(&count).Error; err != nil {
+			return err
+		}
+	case "Admin":
+		if err := config.DB.Model(&models.AdminModel{}).Where("id = ? AND is_active = ?", userID, true).Count(&count).Error; err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	if count == 0 {
+		if _, err := sessions.RevokeAll(config.DB, userID, role, "", sessions.ReasonBlocked); err != nil {
+			logger.Log.Error("Failed to revoke sessions of disabled account", zap.Uint("userID", userID), zap.Error(err))
+		}
+		return errAccountDisabled
+	}
+	return nil
+}
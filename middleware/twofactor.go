@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/twofactor"
+	"github.com/gin-gonic/gin"
+)
+
+func twoFactorCookie(role string) string { return "twoFactor" + role }
+
+// BeginTwoFactor parks a login that passed the password check. The session
+// is only started by FinishTwoFactor once the second factor is given.
+func BeginTwoFactor(c *gin.Context, userID uint, email, role string) error {
+	challenge, err := twofactor.NewChallenge(config.DB, userID, email, role)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(twoFactorCookie(role), challenge.ID, int(twofactor.ChallengeTTL.Seconds()), "/", "", false, true)
+	return nil
+}
+
+// PendingTwoFactor returns the login waiting for its second factor.
+func PendingTwoFactor(c *gin.Context, role string) (*models.TwoFactorChallenge, error) {
+	id, err := c.Cookie(twoFactorCookie(role))
+	if err != nil || id == "" {
+		return nil, twofactor.ErrChallengeExpired
+	}
+	return twofactor.FindChallenge(config.DB, id, role)
+}
+
+// FinishTwoFactor signs in the account of an answered challenge.
+func FinishTwoFactor(c *gin.Context, challenge *models.TwoFactorChallenge) (string, error) {
+	ClearTwoFactor(c, challenge.Role)
+	return StartSession(c, challenge.UserID, challenge.Email, challenge.Role)
+}
+
+func ClearTwoFactor(c *gin.Context, role string) {
+	c.SetCookie(twoFactorCookie(role), "", -1, "/", "", false, true)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AdminAuditLog records a change made by a staff member in the admin panel.
+type AdminAuditLog struct {
+	ID         uint      `gorm:"primarykey"`
+	AdminID    uint      `gorm:"index;not null"`
+	AdminEmail string    `gorm:"not null"`
+	AdminRole  string    `gorm:"size:30;not null"`
+	Action     string    `gorm:"size:50;not null"`
+	EntityType string    `gorm:"size:30;not null;index:idx_admin_audit_logs_entity"`
+	EntityID   uint      `gorm:"not null;index:idx_admin_audit_logs_entity"`
+	Changes    string    `gorm:"type:text"`
+	IPAddress  string    `gorm:"size:45"`
+	CreatedAt  time.Time `gorm:"index"`
+}
@@ -7,5 +7,7 @@ type AdminModel struct {
 	Name     string `gorm:"not null"`
 	Email    string `gorm:"unique,not null" json:"email"`
 	Password string `gorm:"not null" json:"password"`
+	Role     string `gorm:"not null;size:30;default:'super_admin'" json:"role"`
+	IsActive bool   `gorm:"not null;default:true" json:"is_active"`
 }
  
\ No newline at end of file
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CheckoutPayment is a Razorpay order opened on the payment step. It keeps
+// what the user picked until the gateway answers, so the order is placed for
+// the same address and coupon whichever request carries the answer.
+type CheckoutPayment struct {
+	gorm.Model
+	UserID         uint    `gorm:"not null;index"`
+	GatewayOrderID string  `gorm:"uniqueIndex;size:100;not null"`
+	AddressID      uint    `gorm:"not null"`
+	CouponCode     string  `gorm:"size:50"`
+	Amount         float64 `gorm:"type:numeric(10,2);not null"`
+	// PaymentID is set once the gateway reports the payment captured. A
+	// captured payment that could not be placed as an order is refunded
+	// instead: FlaggedAt is set and FlagReason says why, RefundStatus tracks
+	// the refund and RefundError the last gateway error. Paid and flagged
+	// records are kept for the finance team.
+	PaymentID       string `gorm:"size:100"`
+	GatewayRefundID string `gorm:"size:100"`
+	FlagReason      string `gorm:"type:text"`
+	FlaggedAt       *time.Time
+	RefundStatus    string `gorm:"size:20"`
+	RefundError     string `gorm:"type:text"`
+}
@@ -10,8 +10,8 @@ type Coupon struct {
 	gorm.Model
 	CouponCode       string    `gorm:"unique;index" json:"code"`
 	Discription      string    `gorm:"not null"`
-	DiscountValue    float64   `gorm:"not null;index" json:"discount_value"`
-	MaxDiscountValue float64   `gorm:"not null;index" json:"max_value"`
+	DiscountValue    float64   `gorm:"type:numeric(10,2);not null;index" json:"discount_value"`
+	MaxDiscountValue float64   `gorm:"type:numeric(10,2);not null;index" json:"max_value"`
 	MinOrderValue    float64   `gorm:"type:numeric(10,2)" json:"min_productvalue"`
 	UsersUsedCount   int       `gorm:"not null;index" json:"used_count"`
 	MaxUseCount      int       `gorm:"not null;index" json:"max_use_count"`
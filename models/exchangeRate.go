@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// ExchangeRate is a rate an admin entered or imported for a currency. Rates
+// are kept as history; the newest one for a currency is the one in use.
+type ExchangeRate struct {
+	gorm.Model
+	CurrencyCode string  `gorm:"size:3;not null;index" json:"currency_code"`
+	Rate         float64 `gorm:"type:numeric(14,6);not null;check:rate > 0" json:"rate"`
+	Source       string  `gorm:"size:20;not null" json:"source"`
+	AdminID      uint    `gorm:"index" json:"admin_id"`
+}
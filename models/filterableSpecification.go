@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+type FilterableSpecification struct {
+	gorm.Model
+	SpecificationKey string `gorm:"not null;size:255;uniqueIndex" json:"specification_key"`
+	Label            string `gorm:"size:255" json:"label"`
+	SortOrder        int    `gorm:"default:0" json:"sort_order"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// ImageRenditions holds the resized copies of an uploaded product photo.
+// Images uploaded before renditions existed leave these empty.
+type ImageRenditions struct {
+	ThumbnailURL     string
+	ThumbnailWebPURL string `gorm:"column:thumbnail_webp_url"`
+	ListingURL       string
+	ListingWebPURL   string `gorm:"column:listing_webp_url"`
+	ZoomURL          string
+	ZoomWebPURL      string `gorm:"column:zoom_webp_url"`
+}
@@ -15,15 +15,18 @@ type Order struct {
 	TotalDiscount        float64         `gorm:"type:numeric(10,2)"`
 	TotalAmount          float64         `gorm:"index;type:numeric(10,2)"`
 	ShippingCharge       float64         `gorm:"type:numeric(10,2)"`
-	Tax                  float64         `gorm:"index;not null"`
+	ShippingDiscount     float64         `gorm:"type:numeric(10,2);default:0"`
+	Tax                  float64         `gorm:"index;type:numeric(10,2);not null"`
 	OrderDate            time.Time       `gorm:"not null"`
 	UserAuth             UserAuth        `gorm:"foreignKey:UserID;references:ID"`
 	IsCouponApplied      bool            `gorm:"index;default:false"`
 	CouponCode           string          `gorm:"size:255"`
 	CouponDiscountAmount float64         `gorm:"index;type:numeric(10,2)"`
 	CouponDiscription    string          `gorm:"size:255"`
-	CouponValue          float64             
+	CouponValue          float64         `gorm:"type:numeric(10,2)"`
 	IsCouponFixed        bool            `gorm:"default:false"`
+	Currency             string          `gorm:"size:3;not null;default:'INR'"`
+	ExchangeRate         float64         `gorm:"type:numeric(14,6);not null;default:1"`
 	ShippingAddress      ShippingAddress `gorm:"foreignKey:OrderID;references:ID"`
 	OrderItem            []OrderItem     `gorm:"foreignKey:OrderID;references:ID"`
 }
@@ -12,6 +12,9 @@ type OrderItem struct {
 	UserID                uint      `gorm:"not null;index"`
 	ProductVariantID      uint      `gorm:"not null;index"`
 	Quantity              int       `gorm:"not null;index"`
+	ActiveQuantity        int       `gorm:"not null;default:0" json:"active_quantity"`
+	CancelledQuantity     int       `gorm:"not null;default:0" json:"cancelled_quantity"`
+	ReturnedQuantity      int       `gorm:"not null;default:0" json:"returned_quantity"`
 	ProductImage          string    `gorm:"not null"`
 	ProductName           string    `gorm:"not null;index"`
 	ProductSummary        string    `gorm:"not null"`
@@ -20,6 +23,13 @@ type OrderItem struct {
 	ProductSalePrice      float64   `gorm:"index;type:numeric(10,2);not null"`
 	SubTotal              float64   `gorm:"index;type:numeric(10,2)" json:"subtotal"`
 	Tax                   float64   `gorm:"index;type:numeric(10,2)" json:"tax"`
+	HSNCode               string    `gorm:"size:20" json:"hsn_code"`
+	TaxRate               float64   `gorm:"type:numeric(5,2);default:0" json:"tax_rate"`
+	IsTaxInclusive        bool      `gorm:"default:false" json:"is_tax_inclusive"`
+	TaxableValue          float64   `gorm:"type:numeric(10,2);default:0" json:"taxable_value"`
+	CGST                  float64   `gorm:"type:numeric(10,2);default:0" json:"cgst"`
+	SGST                  float64   `gorm:"type:numeric(10,2);default:0" json:"sgst"`
+	IGST                  float64   `gorm:"type:numeric(10,2);default:0" json:"igst"`
 	Total                 float64   `gorm:"index;type:numeric(10,2)" json:"total"`
 	OrderStatus           string    `gorm:"index;type:varchar(255);index;default:'Pending'" json:"status"`
 	IsDelivered           bool      `gorm:"index;default:false"`
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OrderStatusHistory is append-only; rows are never updated or deleted.
+type OrderStatusHistory struct {
+	ID          uint      `gorm:"primarykey"`
+	OrderItemID uint      `gorm:"not null;index"`
+	FromStatus  string    `gorm:"type:varchar(255)"`
+	ToStatus    string    `gorm:"type:varchar(255);not null"`
+	ActorType   string    `gorm:"type:varchar(20);not null"`
+	ActorID     uint      `gorm:"default:0"`
+	Note        string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// OutboxMessage is a side effect, such as an email, recorded in the same
+// transaction as the change that caused it and delivered afterwards.
+type OutboxMessage struct {
+	ID            uint      `gorm:"primarykey"`
+	EventType     string    `gorm:"index;size:100;not null"`
+	Payload       string    `gorm:"type:text;not null"`
+	Status        string    `gorm:"index;size:20;not null;default:'Pending'"`
+	Attempts      int       `gorm:"default:0"`
+	MaxAttempts   int       `gorm:"not null"`
+	NextAttemptAt time.Time `gorm:"index;not null"`
+	LastError     string    `gorm:"type:text"`
+	SentAt        *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
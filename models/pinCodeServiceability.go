@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+type PinCodeServiceability struct {
+	gorm.Model
+	PinCode        string `gorm:"size:10;not null;uniqueIndex" json:"pin_code"`
+	City           string `gorm:"size:100" json:"city"`
+	State          string `gorm:"size:100" json:"state"`
+	IsServiceable  bool   `gorm:"default:true;index" json:"is_serviceable"`
+	IsCODAvailable bool   `gorm:"default:true" json:"is_cod_available"`
+	TransitDays    int    `gorm:"default:5;not null" json:"transit_days"`
+}
@@ -6,16 +6,22 @@ import (
 
 type ProductDetail struct {
 	gorm.Model
-	ID             uint                    `gorm:"primaryKey;index"`
-	ProductName    string                  `gorm:"size:255" json:"productname"`
-	CategoryID     uint                    `gorm:"not null;index"`
-	BrandName      string                  `gorm:"index;size:100" json:"brand"`
-	IsCODAvailable bool                    `gorm:"default:true"`
-	IsReturnable   bool                    `gorm:"default:true"`
-	IsDeleted      bool                    `gorm:"default:false"`
-	Category       Categories              `gorm:"foreignKey:CategoryID"`
-	Descriptions   []ProductDescription    `gorm:"foreignKey:ProductID"`
-	Variants       []ProductVariantDetails `gorm:"foreignKey:ProductID"`
-	Images         []ProductImage          `gorm:"foreignKey:ProductID"`
-	Offers         []ProductOffer          `gorm:"foreignKey:ProductID"`
-} 
+	ID                uint                    `gorm:"primaryKey;index"`
+	ProductName       string                  `gorm:"size:255" json:"productname"`
+	CategoryID        uint                    `gorm:"not null;index"`
+	BrandName         string                  `gorm:"index;size:100" json:"brand"`
+	HSNCode           string                  `gorm:"index;size:20" json:"hsn_code"`
+	Weight            float64                 `gorm:"type:numeric(10,3);default:0" json:"weight"`
+	ShippingSurcharge float64                 `gorm:"type:numeric(10,2);default:0" json:"shipping_surcharge"`
+	DispatchDays      int                     `gorm:"default:1" json:"dispatch_days"`
+	IsCODAvailable    bool                    `gorm:"default:true"`
+	IsReturnable      bool                    `gorm:"default:true"`
+	IsDeleted         bool                    `gorm:"default:false"`
+	AverageRating     float64                 `gorm:"default:0;index" json:"average_rating"`
+	RatingCount       int                     `gorm:"default:0;index" json:"rating_count"`
+	Category          Categories              `gorm:"foreignKey:CategoryID"`
+	Descriptions      []ProductDescription    `gorm:"foreignKey:ProductID"`
+	Variants          []ProductVariantDetails `gorm:"foreignKey:ProductID"`
+	Images            []ProductImage          `gorm:"foreignKey:ProductID"`
+	Offers            []ProductOffer          `gorm:"foreignKey:ProductID"`
+}
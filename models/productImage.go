@@ -8,6 +8,7 @@ type ProductImage struct {
 	ProductID     uint          `gorm:"not null;index"`
 	IsDeleted     bool          `gorm:"default:false"`
 	Product       ProductDetail `gorm:"foreignKey:ProductID"`
+	ImageRenditions
 }
 
  
\ No newline at end of file
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type ProductSearchDocument struct {
+	ProductVariantID uint      `gorm:"primaryKey;autoIncrement:false"`
+	Document         string    `gorm:"type:tsvector;not null"`
+	SearchText       string    `gorm:"type:text;not null"`
+	UpdatedAt        time.Time
+}
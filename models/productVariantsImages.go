@@ -8,5 +8,6 @@ type ProductVariantsImage struct {
 	ProductVariantID      uint                  `gorm:"not null;index"`
 	IsDeleted             bool                  `gorm:"default:false"`
 	ProductVariant        ProductVariantDetails `gorm:"foreignKey:ProductVariantID"`
+	ImageRenditions
 }
  
\ No newline at end of file
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// RateLimitBucket is a token bucket kept in PostgreSQL so that every
+// application instance shares the same limits.
+type RateLimitBucket struct {
+	BucketKey  string    `gorm:"primaryKey;size:255"`
+	Tokens     float64   `gorm:"not null"`
+	RefilledAt time.Time `gorm:"not null;index"`
+}
+
+// AccountLockout counts the failed attempts against one account. While
+// LockedUntil is in the future the account cannot be used for Scope.
+type AccountLockout struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	Scope         string     `gorm:"size:30;not null;uniqueIndex:idx_account_lockouts_account" json:"scope"`
+	Account       string     `gorm:"size:255;not null;uniqueIndex:idx_account_lockouts_account" json:"account"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	FirstFailedAt time.Time  `gorm:"not null" json:"first_failed_at"`
+	LastFailedAt  time.Time  `gorm:"not null;index" json:"last_failed_at"`
+	LastIP        string     `gorm:"size:45" json:"last_ip"`
+	LockedUntil   *time.Time `gorm:"index" json:"locked_until"`
+	LockCount     int        `gorm:"not null;default:0" json:"lock_count"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -6,10 +6,10 @@ import (
 
 type Rating struct {
 	gorm.Model
-	UserID        uint           `gorm:"not null;index" json:"rateing_user"`
-	ProductID     uint           `gorm:"not null;index" json:"rateing_product"`
-	Value         float64       `json:"rateing"`
+	UserID        uint          `gorm:"not null;index;uniqueIndex:idx_rating_user_product" json:"rateing_user"`
+	ProductID     uint          `gorm:"not null;index;uniqueIndex:idx_rating_user_product" json:"rateing_product"`
+	Value         float64       `gorm:"not null;check:value BETWEEN 1 AND 5" json:"rateing"`
+	IsHidden      bool          `gorm:"index;default:false" json:"is_hidden"`
 	UserAuth      UserAuth      `gorm:"foreignKey:UserID;references:ID"`
 	ProductDetail ProductDetail `gorm:"foreignKey:ProductID;references:ID"`
 }
- 
\ No newline at end of file
@@ -9,7 +9,7 @@ type ReferalHistory struct {
 	ReferralID      uint            `gorm:"not null;index"`
 	JoinedUserId    uint            `gorm:"not null;index"`
 	Status          string          `gorm:"type:varchar(10);default:'Pending'"`
-	Reward          float64         `gorm:"not null"`
+	Reward          float64         `gorm:"type:numeric(10,2);not null"`
 	JoinedUser      UserAuth        `gorm:"foreignKey:JoinedUserId;references:ID"`
 	ReferralAccount ReferralAccount `gorm:"foreignKey:ReferralID;references:ID"`
 }
@@ -6,7 +6,7 @@ type ReferralAccount struct {
 	gorm.Model
 	UserID         uint `gorm:"not null;index"`
 	Count          uint
-	Balance        float64
+	Balance        float64 `gorm:"type:numeric(10,2)"`
 	UserAuth       UserAuth         `gorm:"foreignKey:UserID;references:ID"`
 	ReferalHistory []ReferalHistory `gorm:"foreignKey:ReferralID;references:ID"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Refund is one refund of an order item payment, either credited to the
+// wallet or sent back to the original payment through the gateway.
+type Refund struct {
+	gorm.Model
+	RefundUID        string  `gorm:"uniqueIndex;size:100;not null"`
+	PaymentDetailID  uint    `gorm:"not null;index"`
+	OrderItemID      uint    `gorm:"not null;index"`
+	UserID           uint    `gorm:"not null;index"`
+	Amount           float64 `gorm:"type:numeric(10,2);not null"`
+	Quantity         int     `gorm:"not null;default:0"`
+	Reason           string  `gorm:"size:50"`
+	Destination      string  `gorm:"size:20;not null;index"`
+	Status           string  `gorm:"size:20;not null;index;default:'Pending'"`
+	GatewayPaymentID string  `gorm:"size:100"`
+	GatewayRefundID  string  `gorm:"size:100;index"`
+	FailureReason    string  `gorm:"type:text"`
+	Attempts         int     `gorm:"default:0"`
+	ClaimedAt        *time.Time
+	ProcessedAt      *time.Time
+	PaymentDetail    PaymentDetail `gorm:"foreignKey:PaymentDetailID"`
+	OrderItem        OrderItem     `gorm:"foreignKey:OrderItemID"`
+	UserAuth         UserAuth      `gorm:"foreignKey:UserID"`
+}
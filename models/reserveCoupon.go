@@ -8,7 +8,7 @@ type ReservedCoupon struct {
 	gorm.Model
 	CouponCode           string    `gorm:"not null" json:"code"`
 	Discription          string    `gorm:"not null" json:"description"`
-	CouponDiscountAmount float64   `json:"couponDiscountAmount"`
+	CouponDiscountAmount float64   `gorm:"type:numeric(10,2)" json:"couponDiscountAmount"`
 	CouponID             uint      `gorm:"not null;index"`
 }
  
\ No newline at end of file
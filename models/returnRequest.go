@@ -8,6 +8,7 @@ type ReturnRequest struct {
 	OrderItemID           uint                  `gorm:"not null;index"`
 	ProductVariantID      uint                  `gorm:"not null;index"`
 	UserID                uint                  `gorm:"not null;index"`
+	Quantity              int                   `gorm:"not null;default:0"`
 	Reason                string                `gorm:"not null"`
 	AdditionalDetails     string                `gorm:"not null"`
 	AdminNotes            string              
@@ -8,8 +8,12 @@ type Review struct {
 	gorm.Model
 	UserID        uint          `gorm:"not null;index" json:"review_user"`
 	ProductID     uint          `gorm:"not null;index" json:"review_product"`
+	RatingID      uint          `gorm:"not null;index" json:"review_rating"`
+	Title         string        `gorm:"size:255" json:"review_title"`
 	Review        string        `gorm:"size:1000" json:"review"`
+	Status        string        `gorm:"type:varchar(20);index;default:'Visible'" json:"status"`
+	AdminNotes    string        `gorm:"size:255" json:"admin_notes"`
 	UserAuth      UserAuth      `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	ProductDetail ProductDetail `gorm:"foreignKey:ProductID;references:ID"`
+	Rating        Rating        `gorm:"foreignKey:RatingID;references:ID"`
 }
- 
\ No newline at end of file
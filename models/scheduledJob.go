@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ScheduledJob is the shared state of a background job across replicas.
+type ScheduledJob struct {
+	ID              uint   `gorm:"primarykey"`
+	Name            string `gorm:"uniqueIndex;size:100;not null"`
+	Description     string `gorm:"size:255"`
+	IntervalSeconds int    `gorm:"not null"`
+	IsPaused        bool   `gorm:"default:false"`
+	RunRequestedAt  *time.Time
+	LastStartedAt   *time.Time
+	LastFinishedAt  *time.Time
+	LastDurationMs  int64  `gorm:"default:0"`
+	LastError       string `gorm:"type:text"`
+	LastRunOn       string `gorm:"size:255"`
+	RunCount        int64  `gorm:"default:0"`
+	FailureCount    int64  `gorm:"default:0"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
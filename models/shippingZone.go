@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+type ShippingZone struct {
+	gorm.Model
+	Name                  string         `gorm:"size:255;not null" json:"name"`
+	PinCodePrefixes       string         `gorm:"type:text" json:"pin_code_prefixes"`
+	States                string         `gorm:"type:text" json:"states"`
+	SlabBasis             string         `gorm:"type:varchar(20);default:'Quantity'" json:"slab_basis"`
+	FreeShippingThreshold float64        `gorm:"type:numeric(10,2);default:0" json:"free_shipping_threshold"`
+	Priority              int            `gorm:"default:0;index" json:"priority"`
+	IsDefault             bool           `gorm:"default:false" json:"is_default"`
+	IsActive              bool           `gorm:"default:true;index" json:"is_active"`
+	Slabs                 []ShippingSlab `gorm:"foreignKey:ZoneID"`
+}
+
+type ShippingSlab struct {
+	gorm.Model
+	ZoneID   uint    `gorm:"not null;index" json:"zone_id"`
+	MinValue float64 `gorm:"type:numeric(10,2);default:0" json:"min_value"`
+	MaxValue float64 `gorm:"type:numeric(10,2);default:0" json:"max_value"`
+	Charge   float64 `gorm:"type:numeric(10,2);not null" json:"charge"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+type TaxRule struct {
+	gorm.Model
+	Name        string  `gorm:"size:255;not null" json:"name"`
+	CategoryID  uint    `gorm:"index;default:0" json:"category_id"`
+	HSNCode     string  `gorm:"index;size:20" json:"hsn_code"`
+	Rate        float64 `gorm:"type:numeric(5,2);not null;check:rate >= 0 AND rate <= 100" json:"rate"`
+	IsInclusive bool    `gorm:"default:false" json:"is_inclusive"`
+	IsActive    bool    `gorm:"default:true;index" json:"is_active"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// TwoFactorAuth holds the encrypted TOTP secret of an account. Two-factor
+// sign-in only applies once EnabledAt is set; until then the secret is
+// waiting for its first code.
+type TwoFactorAuth struct {
+	ID           uint   `gorm:"primarykey"`
+	UserID       uint   `gorm:"not null;uniqueIndex:idx_two_factor_auths_owner"`
+	Role         string `gorm:"size:10;not null;uniqueIndex:idx_two_factor_auths_owner"`
+	Secret       string `gorm:"type:text;not null"`
+	EnabledAt    *time.Time
+	LastUsedStep int64 `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type TwoFactorRecoveryCode struct {
+	ID              uint   `gorm:"primarykey"`
+	TwoFactorAuthID uint   `gorm:"index;not null"`
+	CodeHash        string `gorm:"size:64;not null;index"`
+	UsedAt          *time.Time
+	CreatedAt       time.Time
+}
+
+// TwoFactorChallenge is a login that passed the password check and is
+// waiting for its second factor.
+type TwoFactorChallenge struct {
+	ID        string    `gorm:"primarykey;size:36"`
+	UserID    uint      `gorm:"not null"`
+	Role      string    `gorm:"size:10;not null"`
+	Email     string    `gorm:"not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+}
@@ -9,5 +9,6 @@ type UserProfile struct {
 	Country  string   `gorm:"size:100" json:"user_country"`
 	State    string   `gorm:"size:100" json:"user_state"`
 	Pincode  string   `gorm:"size:10"  json:"pincode"`
+	Currency string   `gorm:"size:3;not null;default:'INR'" json:"currency"`
 }
  
\ No newline at end of file
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// UserSession is one signed-in device. Only hashes of the refresh tokens are
+// kept; PreviousTokenHash lets a replayed, already rotated token be spotted.
+type UserSession struct {
+	ID                string `gorm:"primaryKey;size:36"`
+	UserID            uint   `gorm:"not null;index:idx_user_sessions_owner"`
+	Role              string `gorm:"not null;index:idx_user_sessions_owner"`
+	Email             string `gorm:"not null"`
+	RefreshTokenHash  string `gorm:"not null;uniqueIndex"`
+	PreviousTokenHash string `gorm:"index"`
+	RotatedAt         *time.Time
+	UserAgent         string
+	IPAddress         string
+	LastUsedAt        time.Time `gorm:"not null"`
+	ExpiresAt         time.Time `gorm:"not null;index"`
+	RevokedAt         *time.Time
+	RevokeReason      string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
@@ -5,7 +5,7 @@ import "gorm.io/gorm"
 type Wallet struct {
 	gorm.Model
 	UserID            uint `gorm:"not null;index"`
-	Balance           float64
+	Balance           float64 `gorm:"type:numeric(10,2)"`
 	UserAuth          UserAuth          `gorm:"foreignKey:UserID;references:ID"`
 	WalletTransaction []WalletTransaction `gorm:"foreignKey:WalletID;references:ID"`
 }
@@ -9,7 +9,7 @@ import (
 type WalletGiftCard struct {
 	gorm.Model
 	GiftCardCode   string    `gorm:"unique;not null"`
-	GiftCardValue  float64   `gorm:"not null;index"`
+	GiftCardValue  float64   `gorm:"type:numeric(10,2);not null;index"`
 	ExpDate        time.Time `gorm:"not null;index"`
 	UserID         uint      `gorm:"not null;index"`
 	RecipientName  string    `gorm:"size:255"` 
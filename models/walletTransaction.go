@@ -6,8 +6,8 @@ type WalletTransaction struct {
 	gorm.Model
 	UserID        uint     `gorm:"not null;index"`
 	WalletID      uint     `gorm:"not null;index"`
-	Amount        float64  `gorm:"not null"`
-	LastBalance   float64  `gorm:"not null"`
+	Amount        float64  `gorm:"type:numeric(10,2);not null"`
+	LastBalance   float64  `gorm:"type:numeric(10,2);not null"`
 	Description   string   `gorm:"size:150"`
 	Type          string   `gorm:"size:50"`
 	Receipt       string   `gorm:"size:255"`
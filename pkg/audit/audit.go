@@ -0,0 +1,109 @@
+// Package audit keeps a record of which staff member changed what in the
+// admin panel. Entries are written with the same transaction as the change,
+// so a change is never saved without its entry or the other way round.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EntityOrderItem      = "order_item"
+	EntityReturnRequest  = "return_request"
+	EntityCoupon         = "coupon"
+	EntityProductVariant = "product_variant"
+	EntityProductOffer   = "product_offer"
+	EntityCategoryOffer  = "category_offer"
+	EntityRefund         = "refund"
+	EntityUser           = "user"
+	EntityAdmin          = "admin"
+	EntityAccountLockout = "account_lockout"
+	EntityExchangeRate   = "exchange_rate"
+)
+
+const (
+	ActionCreate         = "Created"
+	ActionUpdate         = "Updated"
+	ActionDelete         = "Deleted"
+	ActionStatusChange   = "Status changed"
+	ActionApprove        = "Approved"
+	ActionReject         = "Rejected"
+	ActionRetry          = "Retried"
+	ActionReconcile      = "Reconciled"
+	ActionRelease        = "Released"
+	ActionBlock          = "Blocked"
+	ActionUnblock        = "Unblocked"
+	ActionRestore        = "Restored"
+	ActionRoleChange     = "Role changed"
+	ActionActivate       = "Activated"
+	ActionDeactivate     = "Deactivated"
+	ActionTwoFactorReset = "2FA reset"
+	ActionUnlock         = "Unlocked"
+)
+
+// Entities lists the entity types for filtering the audit log.
+var Entities = []string{
+	EntityOrderItem, EntityReturnRequest, EntityCoupon, EntityProductVariant, EntityProductOffer,
+	EntityCategoryOffer, EntityRefund, EntityUser, EntityAdmin, EntityAccountLockout, EntityExchangeRate,
+}
+
+// Actor is the staff member making a change.
+type Actor struct {
+	AdminID   uint
+	Email     string
+	Role      string
+	IPAddress string
+}
+
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Changes maps a field name to its old and new value.
+type Changes map[string]Change
+
+// Set records a field only when its value actually changed.
+func (ch Changes) Set(field string, from, to interface{}) {
+	if fmt.Sprint(from) == fmt.Sprint(to) {
+		return
+	}
+	ch[field] = Change{From: from, To: to}
+}
+
+// Added records a field of a newly created entity.
+func (ch Changes) Added(field string, value interface{}) {
+	ch[field] = Change{To: value}
+}
+
+// Removed records a field of a deleted entity.
+func (ch Changes) Removed(field string, value interface{}) {
+	ch[field] = Change{From: value}
+}
+
+func Record(db *gorm.DB, actor Actor, action, entityType string, entityID uint, changes Changes) error {
+	entry := models.AdminAuditLog{
+		AdminID:    actor.AdminID,
+		AdminEmail: actor.Email,
+		AdminRole:  actor.Role,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IPAddress:  actor.IPAddress,
+	}
+	if len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return fmt.Errorf("encode audit changes: %w", err)
+		}
+		entry.Changes = string(data)
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		return fmt.Errorf("record audit entry: %w", err)
+	}
+	return nil
+}
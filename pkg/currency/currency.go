@@ -0,0 +1,100 @@
+// Package currency shows rupee prices in the currency a customer picks.
+// Prices, orders and payments stay in rupees; a currency only changes how an
+// amount is shown, at the latest exchange rate an admin has entered. Orders
+// keep the rate they were shown at, so their invoice converts the same way
+// later.
+package currency
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+)
+
+// Base is the currency prices are kept and charged in.
+const Base = "INR"
+
+var (
+	ErrUnsupported = errors.New("currency is not supported")
+	ErrNoRate      = errors.New("currency has no exchange rate")
+	ErrInvalidRate = errors.New("exchange rate must be above zero")
+)
+
+// Currency is a currency the shop can show prices in. All of them have two
+// decimals, like the rupee.
+type Currency struct {
+	Code   string
+	Name   string
+	Symbol string
+}
+
+// Supported lists the currencies admins can set a rate for, base first.
+var Supported = []Currency{
+	{Code: Base, Name: "Indian Rupee", Symbol: "₹"},
+	{Code: "USD", Name: "US Dollar", Symbol: "$"},
+	{Code: "EUR", Name: "Euro", Symbol: "€"},
+	{Code: "GBP", Name: "Pound Sterling", Symbol: "£"},
+	{Code: "AED", Name: "UAE Dirham", Symbol: "AED "},
+	{Code: "SGD", Name: "Singapore Dollar", Symbol: "S$"},
+	{Code: "AUD", Name: "Australian Dollar", Symbol: "A$"},
+	{Code: "CAD", Name: "Canadian Dollar", Symbol: "C$"},
+}
+
+// Lookup finds a supported currency by its code, in any case.
+func Lookup(code string) (Currency, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, currency := range Supported {
+		if currency.Code == code {
+			return currency, true
+		}
+	}
+	return Currency{}, false
+}
+
+// Display is a currency with the rate rupee amounts are shown at.
+type Display struct {
+	Currency
+	// Rate is the rupees one unit of the currency is worth.
+	Rate float64
+}
+
+// BaseDisplay shows amounts in rupees as they are.
+func BaseDisplay() Display {
+	currency, _ := Lookup(Base)
+	return Display{Currency: currency, Rate: 1}
+}
+
+// IsBase reports whether amounts are shown in rupees.
+func (d Display) IsBase() bool {
+	return d.Code == Base || d.Code == ""
+}
+
+// Convert is the rupee amount in the display currency, in its cents, rounded
+// to the cent.
+func (d Display) Convert(rupees float64) money.Amount {
+	paise := money.FromRupees(rupees)
+	if d.IsBase() || d.Rate <= 0 {
+		return paise
+	}
+	return money.FromRupees(paise.Rupees() / d.Rate)
+}
+
+// Format shows the rupee amount in the display currency, like "$12.49".
+func (d Display) Format(rupees float64) string {
+	symbol := d.Symbol
+	if d.IsBase() {
+		symbol = "₹"
+	}
+	amount := d.Convert(rupees)
+	if amount < 0 {
+		return "-" + symbol + (-amount).String()
+	}
+	return symbol + amount.String()
+}
+
+// TemplateFuncs lets templates show amounts with {{price .Currency .Total}}.
+var TemplateFuncs = template.FuncMap{
+	"price": Display.Format,
+}
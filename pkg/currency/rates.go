@@ -0,0 +1,186 @@
+package currency
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"gorm.io/gorm"
+)
+
+// Rate sources, as recorded on models.ExchangeRate.
+const (
+	SourceManual = "Manual"
+	SourceImport = "Import"
+)
+
+// RateInput is a rate to save for a currency.
+type RateInput struct {
+	Code string
+	Rate float64
+}
+
+// CurrentRates loads the newest rate of every currency that has one, keyed by
+// currency code.
+func CurrentRates(db *gorm.DB) (map[string]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	if err := db.Raw(`SELECT DISTINCT ON (currency_code) * FROM exchange_rates
+		WHERE deleted_at IS NULL ORDER BY currency_code, created_at DESC, id DESC`).
+		Scan(&rates).Error; err != nil {
+		return nil, err
+	}
+	latest := make(map[string]models.ExchangeRate, len(rates))
+	for _, rate := range rates {
+		latest[rate.CurrencyCode] = rate
+	}
+	return latest, nil
+}
+
+// Available lists the currencies prices can be shown in: the base currency
+// and every supported currency with a rate.
+func Available(db *gorm.DB) ([]Display, error) {
+	latest, err := CurrentRates(db)
+	if err != nil {
+		return nil, err
+	}
+	displays := []Display{BaseDisplay()}
+	for _, currency := range Supported {
+		if rate, ok := latest[currency.Code]; ok && currency.Code != Base {
+			displays = append(displays, Display{Currency: currency, Rate: rate.Rate})
+		}
+	}
+	return displays, nil
+}
+
+// ForCode is the currency with its current rate.
+func ForCode(db *gorm.DB, code string) (Display, error) {
+	currency, ok := Lookup(code)
+	if !ok {
+		return Display{}, ErrUnsupported
+	}
+	if currency.Code == Base {
+		return BaseDisplay(), nil
+	}
+	var rate models.ExchangeRate
+	err := db.Where("currency_code = ?", currency.Code).Order("created_at DESC, id DESC").First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Display{}, ErrNoRate
+	}
+	if err != nil {
+		return Display{}, err
+	}
+	return Display{Currency: currency, Rate: rate.Rate}, nil
+}
+
+// ForUser is the currency the user picked, at its current rate. Guests, users
+// who have not picked one, and users whose currency has lost its rate see
+// rupees.
+func ForUser(db *gorm.DB, userID uint) (Display, error) {
+	if userID == 0 {
+		return BaseDisplay(), nil
+	}
+	var code string
+	if err := db.Model(&models.UserProfile{}).Where("user_id = ?", userID).Limit(1).Pluck("currency", &code).Error; err != nil {
+		return BaseDisplay(), err
+	}
+	if code == "" || code == Base {
+		return BaseDisplay(), nil
+	}
+	display, err := ForCode(db, code)
+	if errors.Is(err, ErrUnsupported) || errors.Is(err, ErrNoRate) {
+		return BaseDisplay(), nil
+	}
+	if err != nil {
+		return BaseDisplay(), err
+	}
+	return display, nil
+}
+
+// ForOrder shows an order's amounts in the currency and at the rate it was
+// placed with, not today's rate.
+func ForOrder(order models.Order) Display {
+	currency, ok := Lookup(order.Currency)
+	if !ok || currency.Code == Base || order.ExchangeRate <= 0 {
+		return BaseDisplay()
+	}
+	return Display{Currency: currency, Rate: order.ExchangeRate}
+}
+
+// SetRates saves new rates, each as the newest for its currency, and returns
+// the saved rows.
+func SetRates(db *gorm.DB, inputs []RateInput, source string, adminID uint) ([]models.ExchangeRate, error) {
+	rates := make([]models.ExchangeRate, 0, len(inputs))
+	for _, input := range inputs {
+		currency, ok := Lookup(input.Code)
+		if !ok || currency.Code == Base {
+			return nil, fmt.Errorf("%s: %w", input.Code, ErrUnsupported)
+		}
+		if input.Rate <= 0 {
+			return nil, fmt.Errorf("%s: %w", currency.Code, ErrInvalidRate)
+		}
+		rates = append(rates, models.ExchangeRate{
+			CurrencyCode: currency.Code,
+			Rate:         input.Rate,
+			Source:       source,
+			AdminID:      adminID,
+		})
+	}
+	if len(rates) == 0 {
+		return nil, nil
+	}
+	if err := db.Create(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// ParseRates reads a CSV with the columns currency,rate, where rate is the
+// rupees one unit of the currency is worth. A header row is skipped, and a
+// currency listed twice keeps its last rate.
+func ParseRates(r io.Reader) ([]RateInput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var inputs []RateInput
+	seen := make(map[string]int)
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "currency") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected currency,rate", line)
+		}
+
+		currency, ok := Lookup(record[0])
+		if !ok || currency.Code == Base {
+			return nil, fmt.Errorf("line %d: %q is not a supported currency", line, strings.TrimSpace(record[0]))
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: rate must be a number above zero", line)
+		}
+
+		input := RateInput{Code: currency.Code, Rate: rate}
+		if index, ok := seen[currency.Code]; ok {
+			inputs[index] = input
+			continue
+		}
+		seen[currency.Code] = len(inputs)
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
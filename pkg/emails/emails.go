@@ -0,0 +1,105 @@
+// Package emails renders the customer emails from the html/template files in
+// templates/emails and sends them.
+//
+// Each email is a file that defines the HTML body plus "subject" and "text"
+// templates. Bodies can use the shared layout.html by calling
+// {{template "layout" .}} and defining "content". Templates get the company
+// branding as .Company, the current year as .Year and the email's own data as
+// .Data.
+package emails
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+)
+
+var TemplateDir = "templates/emails"
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+type Message struct {
+	ToName      string
+	ToEmail     string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []Attachment
+}
+
+type view struct {
+	Company interface{}
+	Year    int
+	Data    interface{}
+}
+
+type templateSet struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*templateSet{}
+)
+
+// load parses an email with the layout. The subject and plain text parts are
+// parsed with text/template so they are not HTML escaped.
+func load(name string) (*templateSet, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if set, ok := cache[name]; ok {
+		return set, nil
+	}
+
+	files := []string{filepath.Join(TemplateDir, "layout.html"), filepath.Join(TemplateDir, name+".html")}
+	html, err := htmltemplate.ParseFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.ParseFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+	set := &templateSet{html: html, text: text}
+	cache[name] = set
+	return set, nil
+}
+
+// New renders the named email for one recipient.
+func New(name, toName, toEmail string, data interface{}) (Message, error) {
+	set, err := load(name)
+	if err != nil {
+		return Message{}, err
+	}
+	v := view{Company: config.CompanyConfig, Year: time.Now().Year(), Data: data}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", v); err != nil {
+		return Message{}, err
+	}
+	if err := set.text.ExecuteTemplate(&text, "text", v); err != nil {
+		return Message{}, err
+	}
+	if err := set.html.ExecuteTemplate(&html, name+".html", v); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		ToName:  toName,
+		ToEmail: toEmail,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()),
+	}, nil
+}
@@ -0,0 +1,37 @@
+package emails
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileMailer writes each email to a directory as an .eml file instead of
+// sending it, so staging and CI can inspect outgoing mail.
+type FileMailer struct {
+	Dir  string
+	From Sender
+}
+
+func NewFileMailer(dir string, from Sender) *FileMailer {
+	if dir == "" {
+		dir = "mail"
+	}
+	return &FileMailer{Dir: dir, From: from}
+}
+
+func (m *FileMailer) Send(message Message) error {
+	body, err := buildMIME(m.From, message)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(message.ToEmail, "_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), body, 0o644)
+}
@@ -0,0 +1,38 @@
+package emails
+
+import (
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Mailer delivers a rendered email.
+type Mailer interface {
+	Send(message Message) error
+}
+
+// Default writes to files until Init has read the configuration, so nothing
+// is sent by accident.
+var Default Mailer = NewFileMailer("mail", Sender{Name: config.CompanyConfig.Name, Address: config.CompanyConfig.Email})
+
+// Sender is the From address of outgoing mail.
+type Sender struct {
+	Name    string
+	Address string
+}
+
+// Init selects the mailer from the MAILER setting.
+func Init() {
+	from := Sender{Name: config.MAIL_FROM_NAME, Address: config.MAIL_FROM}
+	switch config.MAILER {
+	case "smtp":
+		Default = NewSMTPMailer(config.SMTP_HOST, config.SMTP_PORT, config.SMTP_USERNAME, config.SMTP_PASSWORD, from)
+	case "file":
+		Default = NewFileMailer(config.MAIL_DIR, from)
+	default:
+		Default = NewSendGridMailer(config.SENDGRID_API_KEY, from)
+	}
+	logger.Log.Info("Mailer configured",
+		zap.String("mailer", config.MAILER),
+		zap.String("from", from.Address))
+}
@@ -0,0 +1,41 @@
+package emails
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+type SendGridMailer struct {
+	APIKey string
+	From   Sender
+}
+
+func NewSendGridMailer(apiKey string, from Sender) *SendGridMailer {
+	return &SendGridMailer{APIKey: apiKey, From: from}
+}
+
+func (m *SendGridMailer) Send(message Message) error {
+	from := mail.NewEmail(m.From.Name, m.From.Address)
+	to := mail.NewEmail(message.ToName, message.ToEmail)
+	email := mail.NewSingleEmail(from, message.Subject, to, message.Text, message.HTML)
+	for _, file := range message.Attachments {
+		attachment := mail.NewAttachment()
+		attachment.SetFilename(file.Filename)
+		attachment.SetType(file.ContentType)
+		attachment.SetDisposition("attachment")
+		attachment.SetContent(base64.StdEncoding.EncodeToString(file.Content))
+		email.AddAttachment(attachment)
+	}
+
+	response, err := sendgrid.NewSendClient(m.APIKey).Send(email)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid returned %d: %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package emails
+
+import (
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer sends through a plain SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     Sender
+}
+
+func NewSMTPMailer(host, port, username, password string, from Sender) *SMTPMailer {
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+func (m *SMTPMailer) Send(message Message) error {
+	body, err := buildMIME(m.From, message)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From.Address, []string{message.ToEmail}, body)
+}
@@ -0,0 +1,203 @@
+// Package imageproc checks uploaded product photos and turns them into the
+// cleaned original and the resized renditions that get stored.
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MaxUploadBytes = 10 << 20
+	MinDimension   = 200
+	MaxDimension   = 8000
+	// OriginalMaxSize bounds the longest side of the stored original.
+	OriginalMaxSize = 2400
+	jpegQuality     = 85
+)
+
+var (
+	ErrTooLarge        = fmt.Errorf("image is larger than %d MB", MaxUploadBytes>>20)
+	ErrUnsupportedType = errors.New("only JPEG, PNG and WebP images are accepted")
+	ErrDimensions      = fmt.Errorf("image must be between %d and %d pixels on each side", MinDimension, MaxDimension)
+)
+
+// Rendition is one of the standard sizes every product photo is resized to.
+type Rendition struct {
+	Name    string
+	MaxSize int
+}
+
+var (
+	Thumbnail = Rendition{Name: "thumb", MaxSize: 160}
+	Listing   = Rendition{Name: "listing", MaxSize: 480}
+	Zoom      = Rendition{Name: "zoom", MaxSize: 1600}
+
+	Renditions = []Rendition{Thumbnail, Listing, Zoom}
+)
+
+// File is an encoded image ready to be stored.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Variant is a rendition in the default format plus its WebP copy. The WebP
+// encoder is lossless, so for photos the copy can be larger than the JPEG; it
+// is kept anyway so pages can always offer it to browsers that take WebP.
+type Variant struct {
+	Rendition Rendition
+	Image     File
+	WebP      File
+}
+
+// Processed is the result of running an upload through the pipeline.
+type Processed struct {
+	Original File
+	Variants []Variant
+}
+
+// ProcessUpload reads and processes an uploaded form file.
+func ProcessUpload(fileHeader *multipart.FileHeader) (*Processed, error) {
+	if fileHeader.Size > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Process(file)
+}
+
+// Process validates the image by its content rather than its name or
+// declared type, applies and drops the EXIF orientation, and encodes the
+// original and every rendition. Re-encoding leaves all metadata behind.
+func Process(r io.Reader) (*Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadBytes {
+		return nil, ErrTooLarge
+	}
+
+	format := sniff(data)
+	if format == "" {
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width < MinDimension || cfg.Height < MinDimension ||
+		cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, ErrDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	encoder := encodeJPEG
+	ext := ".jpg"
+	if !isOpaque(img) {
+		encoder = png.Encode
+		ext = ".png"
+	}
+
+	processed := &Processed{}
+	original, err := encode(fit(img, OriginalMaxSize), encoder)
+	if err != nil {
+		return nil, err
+	}
+	processed.Original = File{Name: "original" + ext, Data: original}
+
+	for _, rendition := range Renditions {
+		resized := fit(img, rendition.MaxSize)
+		data, err := encode(resized, encoder)
+		if err != nil {
+			return nil, err
+		}
+		webp, err := encode(resized, encodeWebP)
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants = append(processed.Variants, Variant{
+			Rendition: rendition,
+			Image:     File{Name: rendition.Name + ext, Data: data},
+			WebP:      File{Name: rendition.Name + ".webp", Data: webp},
+		})
+	}
+	return processed, nil
+}
+
+// sniff recognises the formats we accept from their magic bytes.
+func sniff(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case len(data) >= 12 && bytes.Equal(data[0:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "webp"
+	}
+	return ""
+}
+
+// fit scales img down so its longest side is at most maxSize. Smaller images
+// are copied as they are.
+func fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			height = max(1, height*maxSize/width)
+			width = maxSize
+		} else {
+			width = max(1, width*maxSize/height)
+			height = maxSize
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+func encode(img image.Image, encoder func(io.Writer, image.Image) error) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encoder(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
+
+func encodeWebP(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
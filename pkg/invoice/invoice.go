@@ -0,0 +1,308 @@
+// Package invoice builds the PDF invoice of an order.
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/jung-kurt/gofpdf"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ForOrder loads an order with its customer, items and products and builds
+// its invoice.
+func ForOrder(db *gorm.DB, orderID uint) (*gofpdf.Fpdf, models.Order, error) {
+	var order models.Order
+	if err := db.Preload("ShippingAddress").First(&order, orderID).Error; err != nil {
+		return nil, order, err
+	}
+
+	var user models.UserAuth
+	if err := db.Unscoped().First(&user, order.UserID).Error; err != nil {
+		return nil, order, fmt.Errorf("load user: %v", err)
+	}
+
+	var orderItems []models.OrderItem
+	if err := db.Where("order_id = ?", orderID).Find(&orderItems).Error; err != nil {
+		return nil, order, fmt.Errorf("load order items: %v", err)
+	}
+
+	productIds := make([]uint, len(orderItems))
+	for i, item := range orderItems {
+		productIds[i] = item.ProductVariantID
+	}
+
+	var products []models.ProductVariantDetails
+	if err := db.Unscoped().Where("id IN ?", productIds).Find(&products).Error; err != nil {
+		return nil, order, fmt.Errorf("load products: %v", err)
+	}
+
+	productMap := make(map[uint]models.ProductVariantDetails)
+	for _, product := range products {
+		productMap[product.ID] = product
+	}
+
+	pdf, err := Generate(order, user, orderItems, productMap)
+	return pdf, order, err
+}
+
+// PDF renders the invoice of an order to bytes, for attaching to an email.
+func PDF(db *gorm.DB, orderID uint) ([]byte, string, error) {
+	pdf, order, err := ForOrder(db, orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), FileName(order), nil
+}
+
+func FileName(order models.Order) string {
+	return fmt.Sprintf("invoice_%s.pdf", order.OrderUID)
+}
+
+func Generate(order models.Order, user models.UserAuth, orderItems []models.OrderItem, products map[uint]models.ProductVariantDetails) (*gofpdf.Fpdf, error) {
+	logger.Log.Info("Generating invoice PDF", zap.Uint("orderID", order.ID))
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+
+	pageWidth := 210.0
+
+	if _, err := os.Stat(config.CompanyConfig.LogoFilePath); err == nil {
+		pdf.Image(config.CompanyConfig.LogoFilePath, 10, 10, 40, 0, false, "", 0, "")
+		logger.Log.Debug("Added company logo to invoice",
+			zap.String("logoPath", config.CompanyConfig.LogoFilePath))
+	} else {
+		logger.Log.Warn("Company logo file not found",
+			zap.String("logoPath", config.CompanyConfig.LogoFilePath),
+			zap.Error(err))
+	}
+
+	pdf.SetFont("Arial", "B", 20)
+	title := "INVOICE"
+	titleWidth := pdf.GetStringWidth(title)
+	pdf.SetXY((pageWidth-titleWidth)/2, 20)
+	pdf.Cell(titleWidth, 10, title)
+	pdf.Ln(20)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetXY(10, 40)
+	pdf.Cell(80, 10, "ORDER DETAILS")
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetX(10)
+	pdf.Cell(80, 6, fmt.Sprintf("Order #: %s", order.OrderUID))
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(80, 6, fmt.Sprintf("Date: %s", order.CreatedAt.Format("2006-01-02")))
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetX(10)
+	pdf.Cell(90, 10, "COMPANY DETAILS")
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, config.CompanyConfig.Name)
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, config.CompanyConfig.Address1)
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, config.CompanyConfig.Address2)
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, "Email: "+config.CompanyConfig.Email)
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, fmt.Sprintf("GSTIN: %s (%s)", config.CompanyConfig.GSTIN, config.CompanyConfig.State))
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetX(10)
+	pdf.Cell(90, 10, "SHIPPING ADDRESS")
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, user.FullName)
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, fmt.Sprintf("Email: %s", user.Email))
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, fmt.Sprintf("Phone: %s", order.ShippingAddress.Mobile))
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, order.ShippingAddress.Address)
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, fmt.Sprintf("%s", order.ShippingAddress.State))
+	pdf.Ln(6)
+	pdf.SetX(10)
+	pdf.Cell(90, 6, fmt.Sprintf("%s - %s", order.ShippingAddress.Country, order.ShippingAddress.PinCode))
+	pdf.Ln(6)
+	if order.ShippingAddress.Landmark != "" {
+		pdf.SetX(10)
+		pdf.Cell(90, 6, fmt.Sprintf("Landmark: %s", order.ShippingAddress.Landmark))
+		pdf.Ln(6)
+	}
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(190, 10, "PRODUCT DETAILS")
+	pdf.Ln(10)
+
+	pdf.SetFillColor(240, 240, 240)
+	pdf.SetFont("Arial", "B", 10)
+
+	pdf.CellFormat(52, 8, "Product", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(16, 8, "HSN", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(10, 8, "Qty", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(22, 8, "Price", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(22, 8, "Discount", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(24, 8, "Taxable", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(22, 8, "GST", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(22, 8, "Total", "1", 1, "R", true, 0, "")
+
+	pdf.SetFont("Arial", "", 9)
+	var subtotal float64
+	var totalDiscount float64
+	var totalTax float64
+	var totalCGST float64
+	var totalSGST float64
+	var totalIGST float64
+	var totalTaxable float64
+
+	for _, item := range orderItems {
+		product := item.ProductName
+		regularPrice := item.ProductRegularPrice
+		discountPrice := item.ProductSalePrice
+		discount := regularPrice - discountPrice
+		quantity := float64(item.Quantity)
+
+		gstAmount := item.CGST + item.SGST + item.IGST
+		lineTotal := discountPrice*quantity + item.Tax
+
+		subtotal += regularPrice * quantity
+		totalDiscount += discount * quantity
+		totalTax += gstAmount
+		totalCGST += item.CGST
+		totalSGST += item.SGST
+		totalIGST += item.IGST
+		totalTaxable += item.TaxableValue
+
+		gstLabel := fmt.Sprintf("%.2f", gstAmount)
+		if item.TaxRate > 0 {
+			gstLabel = fmt.Sprintf("%.2f (%g%%)", gstAmount, item.TaxRate)
+		}
+
+		pdf.CellFormat(52, 8, product, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(16, 8, item.HSNCode, "1", 0, "C", false, 0, "")
+		pdf.CellFormat(10, 8, strconv.Itoa(item.Quantity), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.2f", regularPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.2f", discount*quantity), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(24, 8, fmt.Sprintf("%.2f", item.TaxableValue), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(22, 8, gstLabel, "1", 0, "R", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.2f", lineTotal), "1", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(190, 10, "TAX SUMMARY")
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(50, 8, "Taxable Value", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(35, 8, "CGST", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(35, 8, "SGST", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(35, 8, "IGST", "1", 0, "R", true, 0, "")
+	pdf.CellFormat(35, 8, "Total GST", "1", 1, "R", true, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(50, 8, fmt.Sprintf("%.2f", totalTaxable), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", totalCGST), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", totalSGST), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", totalIGST), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", totalTax), "1", 1, "R", false, 0, "")
+
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(190, 10, "ORDER SUMMARY")
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	leftColWidth := 150.0
+	rightColWidth := 40.0
+
+	pdf.CellFormat(leftColWidth, 6, "Subtotal:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(rightColWidth, 6, fmt.Sprintf("%.2f", subtotal), "", 1, "R", false, 0, "")
+
+	pdf.CellFormat(leftColWidth, 6, "Product Discount:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(rightColWidth, 6, fmt.Sprintf("%.2f", totalDiscount), "", 1, "R", false, 0, "")
+
+	pdf.CellFormat(leftColWidth, 6, "Tax:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(rightColWidth, 6, fmt.Sprintf("%.2f", order.Tax), "", 1, "R", false, 0, "")
+
+	if order.CouponDiscountAmount > 0 {
+		pdf.CellFormat(leftColWidth, 6, "Coupon Discount:", "", 0, "R", false, 0, "")
+		pdf.CellFormat(rightColWidth, 6, fmt.Sprintf("%.2f", order.CouponDiscountAmount), "", 1, "R", false, 0, "")
+	}
+
+	pdf.CellFormat(leftColWidth, 6, "Shipping Charge:", "", 0, "R", false, 0, "")
+	if order.ShippingCharge == 0 {
+		pdf.CellFormat(rightColWidth, 6, "FREE", "", 1, "R", false, 0, "")
+	} else {
+		pdf.CellFormat(rightColWidth, 6, fmt.Sprintf("%.2f", order.ShippingCharge), "", 1, "R", false, 0, "")
+	}
+	totalAllDiscounts := totalDiscount + order.CouponDiscountAmount + order.ShippingDiscount
+	pdf.CellFormat(leftColWidth, 6, "Total Discount:", "", 0, "R", false, 0, "")
+	pdf.CellFormat(rightColWidth, 6, fmt.Sprintf("%.2f", totalAllDiscounts), "", 1, "R", false, 0, "")
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(leftColWidth, 8, "Total Amount:", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(rightColWidth, 8, fmt.Sprintf("%.2f", order.TotalAmount), "T", 1, "R", false, 0, "")
+
+	// Amounts above are in rupees. An order shown in another currency also
+	// gets its total there, at the rate recorded when it was placed.
+	if display := currency.ForOrder(order); !display.IsBase() {
+		pdf.SetFont("Arial", "", 10)
+		pdf.CellFormat(leftColWidth, 6, fmt.Sprintf("Total in %s (1 %s = INR %g):", display.Code, display.Code, display.Rate), "", 0, "R", false, 0, "")
+		pdf.CellFormat(rightColWidth, 6, display.Convert(order.TotalAmount).String(), "", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(15)
+
+	pdf.SetFont("Arial", "B", 12)
+	thankYouMsg := "Thank You For Shopping With Us!"
+	msgWidth := pdf.GetStringWidth(thankYouMsg)
+	pdf.SetX((pageWidth - msgWidth) / 2)
+	pdf.Cell(msgWidth, 10, thankYouMsg)
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	contactMsg := "Contact Us At " + config.CompanyConfig.Email + " for any queries."
+	contactWidth := pdf.GetStringWidth(contactMsg)
+	pdf.SetX((pageWidth - contactWidth) / 2)
+	pdf.Cell(contactWidth, 6, contactMsg)
+
+	logger.Log.Info("Invoice PDF generated successfully",
+		zap.Uint("orderID", order.ID),
+		zap.Int("itemCount", len(orderItems)),
+		zap.Float64("totalAmount", order.TotalAmount))
+	return pdf, nil
+}
@@ -0,0 +1,213 @@
+// Package jobs runs named background jobs so that each job runs on only one
+// replica at a time. A run holds a PostgreSQL transaction-level advisory lock
+// keyed by the job name, and the shared scheduled_jobs row decides whether
+// the job is due, so replicas polling at the same moment run it once.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pollInterval bounds how long a manual trigger waits to be picked up.
+const pollInterval = 15 * time.Second
+
+var ErrUnknownJob = errors.New("unknown job")
+
+type Job struct {
+	Name        string
+	Description string
+	Interval    time.Duration
+	Run         func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	db    *gorm.DB
+	jobs  []Job
+	owner string
+	wg    sync.WaitGroup
+}
+
+func NewScheduler(db *gorm.DB) *Scheduler {
+	owner, err := os.Hostname()
+	if err != nil || owner == "" {
+		owner = "unknown"
+	}
+	return &Scheduler{db: db, owner: fmt.Sprintf("%s/%d", owner, os.Getpid())}
+}
+
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start registers the jobs in the database and runs each one in its own
+// goroutine until ctx is cancelled. Use Wait to let running jobs finish.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		row := models.ScheduledJob{
+			Name:            job.Name,
+			Description:     job.Description,
+			IntervalSeconds: int(job.Interval / time.Second),
+		}
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"description", "interval_seconds", "updated_at"}),
+		}).Create(&row).Error; err != nil {
+			logger.Log.Error("Failed to register job", zap.String("job", job.Name), zap.Error(err))
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+	logger.Log.Info("Job scheduler started", zap.Int("jobs", len(s.jobs)), zap.String("owner", s.owner))
+}
+
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+	logger.Log.Info("Job scheduler stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	tick := pollInterval
+	if job.Interval < tick {
+		tick = job.Interval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.runIfDue(ctx, job); err != nil {
+				logger.Log.Error("Job run failed", zap.String("job", job.Name), zap.Error(err))
+			}
+		}
+	}
+}
+
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("jobs:" + name))
+	return int64(hash.Sum64())
+}
+
+func isDue(row models.ScheduledJob, now time.Time) bool {
+	if row.RunRequestedAt != nil && (row.LastStartedAt == nil || row.RunRequestedAt.After(*row.LastStartedAt)) {
+		return true
+	}
+	if row.IsPaused {
+		return false
+	}
+	return row.LastStartedAt == nil || now.Sub(*row.LastStartedAt) >= time.Duration(row.IntervalSeconds)*time.Second
+}
+
+// runIfDue runs the job when no other replica holds its lock and it is due
+// by interval or by a manual trigger. The lock is held by an open
+// transaction for the whole run and released when it ends, including when
+// the process dies.
+func (s *Scheduler) runIfDue(ctx context.Context, job Job) error {
+	lock := s.db.WithContext(ctx).Begin()
+	if lock.Error != nil {
+		return lock.Error
+	}
+	defer lock.Rollback()
+
+	var locked bool
+	if err := lock.Raw("SELECT pg_try_advisory_xact_lock(?)", lockKey(job.Name)).Scan(&locked).Error; err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	var row models.ScheduledJob
+	if err := lock.First(&row, "name = ?", job.Name).Error; err != nil {
+		return err
+	}
+	startedAt := time.Now()
+	if !isDue(row, startedAt) {
+		return nil
+	}
+
+	if err := s.db.Model(&models.ScheduledJob{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+		"last_started_at": startedAt,
+		"last_run_on":     s.owner,
+	}).Error; err != nil {
+		return err
+	}
+
+	runErr := runSafely(ctx, job)
+	duration := time.Since(startedAt)
+
+	updates := map[string]interface{}{
+		"last_finished_at": time.Now(),
+		"last_duration_ms": duration.Milliseconds(),
+		"last_error":       "",
+		"run_count":        gorm.Expr("run_count + 1"),
+	}
+	if runErr != nil {
+		updates["last_error"] = runErr.Error()
+		updates["failure_count"] = gorm.Expr("failure_count + 1")
+	}
+	if err := s.db.Model(&models.ScheduledJob{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		logger.Log.Error("Failed to record job run", zap.String("job", job.Name), zap.Error(err))
+	}
+
+	logger.Log.Info("Job finished",
+		zap.String("job", job.Name),
+		zap.Duration("duration", duration),
+		zap.Error(runErr))
+	return runErr
+}
+
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// Trigger asks whichever replica picks the job up next to run it, even if
+// it is paused.
+func Trigger(db *gorm.DB, name string) error {
+	return updateJob(db, name, map[string]interface{}{"run_requested_at": time.Now()})
+}
+
+func SetPaused(db *gorm.DB, name string, paused bool) error {
+	return updateJob(db, name, map[string]interface{}{"is_paused": paused})
+}
+
+func updateJob(db *gorm.DB, name string, updates map[string]interface{}) error {
+	result := db.Model(&models.ScheduledJob{}).Where("name = ?", name).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUnknownJob
+	}
+	return nil
+}
+
+func List(db *gorm.DB) ([]models.ScheduledJob, error) {
+	var jobs []models.ScheduledJob
+	err := db.Order("name ASC").Find(&jobs).Error
+	return jobs, err
+}
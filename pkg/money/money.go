@@ -0,0 +1,230 @@
+// Package money does arithmetic on rupee amounts in whole paise, so that
+// line totals, discounts, tax and refunds add up exactly and the shop, the
+// invoice and the wallet all agree to the paisa. Amounts are still stored and
+// shown in rupees; convert at the edges with FromRupees and Rupees.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is an amount of money in paise.
+type Amount int64
+
+var errInvalidAmount = errors.New("money: invalid amount")
+
+// FromRupees converts rupees to paise, rounding half away from zero.
+func FromRupees(rupees float64) Amount {
+	return Amount(math.Round(rupees * 100))
+}
+
+// Round rounds rupees to the nearest paisa.
+func Round(rupees float64) float64 {
+	return FromRupees(rupees).Rupees()
+}
+
+// Rupees converts the amount back to rupees for storing and showing.
+func (a Amount) Rupees() float64 {
+	return float64(a) / 100
+}
+
+// Times is the amount for quantity units.
+func (a Amount) Times(quantity int) Amount {
+	return a * Amount(quantity)
+}
+
+// Percent is rate percent of the amount, rounded to the paisa.
+func (a Amount) Percent(rate float64) Amount {
+	return Amount(math.Round(float64(a) * rate / 100))
+}
+
+// WithoutPercent takes an amount that includes rate percent on top and
+// returns the part before it was added, rounded to the paisa.
+func (a Amount) WithoutPercent(rate float64) Amount {
+	return Amount(math.Round(float64(a) * 100 / (100 + rate)))
+}
+
+// Ratio is the amount scaled by part/whole, rounded to the paisa. It is zero
+// when whole is zero.
+func (a Amount) Ratio(part, whole Amount) Amount {
+	if whole == 0 {
+		return 0
+	}
+	return Amount(math.Round(float64(a) * float64(part) / float64(whole)))
+}
+
+// Part is the share of the amount that part out of whole units is worth,
+// rounded to the paisa. It is zero when whole is zero.
+func (a Amount) Part(part, whole int) Amount {
+	if whole == 0 {
+		return 0
+	}
+	return Amount(math.Round(float64(a) * float64(part) / float64(whole)))
+}
+
+// Min returns the smaller of a and b.
+func Min(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Sum adds up amounts.
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, amount := range amounts {
+		total += amount
+	}
+	return total
+}
+
+// Allocate splits total over weights in proportion, so that the shares add
+// up to total exactly. Paise left over from rounding go to the largest
+// remainders first. With no positive weights it is split evenly.
+func Allocate(total Amount, weights []Amount) []Amount {
+	shares := make([]Amount, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	var weightSum Amount
+	for _, weight := range weights {
+		if weight > 0 {
+			weightSum += weight
+		}
+	}
+	if weightSum == 0 {
+		even := make([]Amount, len(weights))
+		for i := range even {
+			even[i] = 1
+		}
+		return Allocate(total, even)
+	}
+
+	sign := Amount(1)
+	if total < 0 {
+		sign, total = -1, -total
+	}
+	remainders := make([]int64, len(weights))
+	var allocated Amount
+	for i, weight := range weights {
+		if weight <= 0 {
+			continue
+		}
+		// total*weight can overflow for very large amounts; big enough for a
+		// shop, where both stay well under 10^9 paise.
+		product := int64(total) * int64(weight)
+		shares[i] = Amount(product / int64(weightSum))
+		remainders[i] = product % int64(weightSum)
+		allocated += shares[i]
+	}
+	for left := total - allocated; left > 0; left-- {
+		largest := -1
+		for i, remainder := range remainders {
+			if weights[i] > 0 && (largest < 0 || remainder > remainders[largest]) {
+				largest = i
+			}
+		}
+		shares[largest]++
+		remainders[largest] = -1
+	}
+	for i := range shares {
+		shares[i] *= sign
+	}
+	return shares
+}
+
+// String formats the amount in rupees with two decimals, like "1499.50".
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign, a = "-", -a
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, a/100, a%100)
+}
+
+// Parse reads an amount in rupees such as "1499.5" exactly, without going
+// through float64. More than two decimals are rounded half away from zero.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return 0, errInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	// ParseInt would accept a second sign, as in "--1".
+	if strings.ContainsAny(whole, "+-") {
+		return 0, errInvalidAmount
+	}
+	rupees, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, errInvalidAmount
+	}
+
+	var paise int64
+	for i, digit := range fraction {
+		if digit < '0' || digit > '9' {
+			return 0, errInvalidAmount
+		}
+		switch {
+		case i < 2:
+			paise = paise*10 + int64(digit-'0')
+		case i == 2 && digit >= '5':
+			paise++
+		}
+	}
+	if len(fraction) == 1 {
+		paise *= 10
+	}
+
+	amount := Amount(rupees*100 + paise)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// Scan reads a numeric column, such as the result of SUM(total_amount),
+// without losing paise to float64.
+func (a *Amount) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = 0
+	case []byte:
+		parsed, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		*a = parsed
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+	case float64:
+		*a = FromRupees(v)
+	case int64:
+		*a = Amount(v * 100)
+	default:
+		return fmt.Errorf("money: cannot scan %T", value)
+	}
+	return nil
+}
+
+// Value writes the amount in rupees for a numeric column.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
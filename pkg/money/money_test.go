@@ -0,0 +1,244 @@
+package money
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"0", 0},
+		{"1499", 149900},
+		{"1499.5", 149950},
+		{"1499.50", 149950},
+		{"1499.05", 149905},
+		{".5", 50},
+		{"5.", 500},
+		{" 12.34 ", 1234},
+		{"+12.34", 1234},
+		{"-12.34", -1234},
+		{"0.10", 10},
+		// More than two decimals round half away from zero.
+		{"1.004", 100},
+		{"1.005", 101},
+		{"1.0049", 100},
+		{"1.0099", 101},
+		{"0.995", 100},
+		{"99.999", 10000},
+		{"-1.005", -101},
+		{"-0.004", 0},
+		// Values float64 can't hold exactly.
+		{"0.29", 29},
+		{"1.15", 115},
+		{"99999999.99", 9999999999},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, in := range []string{"", " ", "-", ".", "abc", "1.2x", "1,499.00", "1e3", "12.3.4", "--1", "-+1", "+-1", "1.-5"} {
+		if got, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestStringRoundTrips(t *testing.T) {
+	for _, amount := range []Amount{0, 1, 9, 10, 99, 100, 149950, -1, -50, -149905} {
+		s := amount.String()
+		back, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", s, err)
+		}
+		if back != amount {
+			t.Errorf("Parse(%d.String() = %q) = %d", amount, s, back)
+		}
+	}
+	if got := Amount(-5).String(); got != "-0.05" {
+		t.Errorf("String(-5) = %q, want -0.05", got)
+	}
+}
+
+func TestFromRupees(t *testing.T) {
+	tests := []struct {
+		rupees float64
+		want   Amount
+	}{
+		{0.1 + 0.2, 30},
+		{1.005, 100}, // 1.005 is stored as 1.00499999..., so it rounds down.
+		{0.125, 13},  // Exact in binary, so it shows the half-away-from-zero rounding.
+		{-2.5, -250},
+		{-0.005, -1},
+		{1499.995, 150000},
+	}
+	for _, tt := range tests {
+		if got := FromRupees(tt.rupees); got != tt.want {
+			t.Errorf("FromRupees(%v) = %d, want %d", tt.rupees, got, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   Amount
+		weights []Amount
+		want    []Amount
+	}{
+		{"even thirds", 100, []Amount{1, 1, 1}, []Amount{34, 33, 33}},
+		{"proportional", 1000, []Amount{100, 300}, []Amount{250, 750}},
+		{"largest remainder gets the paisa", 10, []Amount{1, 2, 3}, []Amount{2, 3, 5}},
+		{"negative total", -100, []Amount{1, 1, 1}, []Amount{-34, -33, -33}},
+		{"negative total proportional", -10, []Amount{1, 2, 3}, []Amount{-2, -3, -5}},
+		{"zero and negative weights get nothing", 100, []Amount{0, 3, -5, 1}, []Amount{0, 75, 0, 25}},
+		{"no positive weights split evenly", 100, []Amount{0, 0, 0}, []Amount{34, 33, 33}},
+		{"zero total", 0, []Amount{5, 7}, []Amount{0, 0}},
+		{"single weight", 12345, []Amount{9}, []Amount{12345}},
+		{"no weights", 100, nil, []Amount{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.total, tt.weights)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+		})
+	}
+}
+
+// Whatever the weights, the shares must add up to the total exactly, and
+// with a negative total every share must be the negation of the positive
+// split.
+func TestAllocateSumsToTotal(t *testing.T) {
+	weightSets := [][]Amount{
+		{1, 1, 1},
+		{149900, 79900, 1299},
+		{7, 11, 13, 17, 19},
+		{1, 0, 1},
+		{0, 0},
+		{3, -2, 5},
+		{99999, 1},
+	}
+	for _, weights := range weightSets {
+		for _, total := range []Amount{0, 1, 2, 99, 100, 101, 9999, 123457, 49999999} {
+			shares := Allocate(total, weights)
+			if sum := Sum(shares...); sum != total {
+				t.Errorf("Allocate(%d, %v) = %v sums to %d", total, weights, shares, sum)
+			}
+			negative := Allocate(-total, weights)
+			if sum := Sum(negative...); sum != -total {
+				t.Errorf("Allocate(%d, %v) = %v sums to %d", -total, weights, negative, sum)
+			}
+			for i := range shares {
+				if negative[i] != -shares[i] {
+					t.Errorf("Allocate(%d, %v)[%d] = %d, want %d", -total, weights, i, negative[i], -shares[i])
+				}
+			}
+		}
+	}
+}
+
+func TestPartAndRatio(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Amount
+		want Amount
+	}{
+		{"Part one of three", Amount(1000).Part(1, 3), 333},
+		{"Part two of three", Amount(1000).Part(2, 3), 667},
+		{"Part all", Amount(1000).Part(3, 3), 1000},
+		{"Part of zero units", Amount(1000).Part(1, 0), 0},
+		{"Part of negative", Amount(-1000).Part(2, 3), -667},
+		{"Part rounds half away from zero", Amount(5).Part(1, 2), 3},
+		{"Ratio", Amount(10000).Ratio(2500, 10000), 2500},
+		{"Ratio rounds", Amount(100).Ratio(1, 3), 33},
+		{"Ratio of zero whole", Amount(100).Ratio(1, 0), 0},
+		{"Ratio of negative", Amount(-100).Ratio(2, 3), -67},
+		{"Percent", Amount(149900).Percent(18), 26982},
+		{"Percent rounds", Amount(999).Percent(18), 180},
+		{"WithoutPercent", Amount(118000).WithoutPercent(18), 100000},
+		{"WithoutPercent rounds", Amount(100).WithoutPercent(18), 85},
+		{"Times", Amount(149950).Times(3), 449850},
+		{"Min", Min(5, -5), -5},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// Splitting a coupon over order lines with Part loses or gains a paisa to
+// rounding; Allocate must not.
+func TestPartCanDriftWhereAllocateDoesNot(t *testing.T) {
+	coupon := Amount(100)
+	var parts Amount
+	for i := 0; i < 3; i++ {
+		parts += coupon.Part(1, 3)
+	}
+	if parts == coupon {
+		t.Fatalf("expected Part to drift, got %d", parts)
+	}
+	if sum := Sum(Allocate(coupon, []Amount{1, 1, 1})...); sum != coupon {
+		t.Errorf("Allocate sums to %d, want %d", sum, coupon)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  Amount
+	}{
+		{"nil", nil, 0},
+		{"numeric bytes", []byte("1499.50"), 149950},
+		{"numeric string", "0.29", 29},
+		{"sum with more decimals", []byte("10.125"), 1013},
+		{"negative", []byte("-12.30"), -1230},
+		{"float", 1.15, 115},
+		{"integer rupees", int64(42), 4200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Amount(999)
+			if err := a.Scan(tt.value); err != nil {
+				t.Fatalf("Scan(%v): %v", tt.value, err)
+			}
+			if a != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.value, a, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanRejects(t *testing.T) {
+	var a Amount
+	if err := a.Scan([]byte("not a number")); err == nil {
+		t.Error("Scan accepted text that is not a number")
+	}
+	if err := a.Scan(true); err == nil {
+		t.Error("Scan accepted a bool")
+	}
+}
+
+func TestValue(t *testing.T) {
+	value, err := Amount(-1205).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "-12.05" {
+		t.Errorf("Value = %v, want -12.05", value)
+	}
+}
@@ -0,0 +1,109 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/emails"
+	"github.com/anfastk/E-Commerce-Website/pkg/invoice"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/utils"
+	"go.uber.org/zap"
+)
+
+// orderStatusEmails are the item statuses customers are emailed about.
+// Payment confirmations are covered by the order placed email.
+var orderStatusEmails = map[string]bool{
+	"Confirmed":        true,
+	"Shipped":          true,
+	"Out For Delivery": true,
+	"Delivered":        true,
+	"Cancelled":        true,
+	"Returned":         true,
+}
+
+// EmailNotifier emails customers through the configured mailer.
+type EmailNotifier struct{}
+
+func NewEmailNotifier() *EmailNotifier {
+	return &EmailNotifier{}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, event string, payload []byte) error {
+	switch event {
+	case EventOTPEmail:
+		var otp OTPEmail
+		if err := json.Unmarshal(payload, &otp); err != nil {
+			return err
+		}
+		return utils.SendOTPToEmail(otp.Email, otp.OTP)
+
+	case EventGiftCardEmail:
+		var gift GiftCardEmail
+		if err := json.Unmarshal(payload, &gift); err != nil {
+			return err
+		}
+		return utils.SendGiftCardToEmail(gift.SenderName, gift.SenderProfile, gift.Message, gift.Email, gift.Amount, gift.GiftCode, gift.ExpDate)
+
+	case EventOrderPlaced:
+		var order OrderPlaced
+		if err := json.Unmarshal(payload, &order); err != nil {
+			return err
+		}
+		return send("order_placed", order.Name, order.Email, order, nil)
+
+	case EventOrderStatusChanged:
+		var order OrderStatusChanged
+		if err := json.Unmarshal(payload, &order); err != nil {
+			return err
+		}
+		if order.FromStatus == order.ToStatus || !orderStatusEmails[order.ToStatus] ||
+			(order.ToStatus == "Confirmed" && order.ActorType == "System") {
+			return nil
+		}
+		var attachments []emails.Attachment
+		if order.ToStatus == "Delivered" {
+			pdf, fileName, err := invoice.PDF(config.DB, order.OrderID)
+			if err != nil {
+				return fmt.Errorf("generate invoice: %w", err)
+			}
+			attachments = append(attachments, emails.Attachment{Filename: fileName, ContentType: "application/pdf", Content: pdf})
+		}
+		return send("order_status", order.Name, order.Email, order, attachments)
+
+	case EventRefundIssued:
+		var refund RefundIssued
+		if err := json.Unmarshal(payload, &refund); err != nil {
+			return err
+		}
+		return send("order_refunded", refund.Name, refund.Email, refund, nil)
+
+	case EventWalletTransaction:
+		var txn WalletTransaction
+		if err := json.Unmarshal(payload, &txn); err != nil {
+			return err
+		}
+		// Refunds get the order refund email instead.
+		if txn.Type == "Refund" {
+			return nil
+		}
+		return send("wallet_transaction", txn.Name, txn.Email, txn, nil)
+	}
+
+	logger.Log.Warn("No email for notification event", zap.String("event", event))
+	return nil
+}
+
+func send(template, name, email string, data interface{}, attachments []emails.Attachment) error {
+	if email == "" {
+		return nil
+	}
+	message, err := emails.New(template, name, email, data)
+	if err != nil {
+		return err
+	}
+	message.Attachments = attachments
+	return emails.Default.Send(message)
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// LogNotifier writes notifications to the application log instead of
+// sending them, for development.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, event string, payload []byte) error {
+	logger.Log.Info("Notification", zap.String("event", event), zap.ByteString("payload", payload))
+	return nil
+}
+
+// FileNotifier appends each notification to a file as a line of JSON.
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	if path == "" {
+		path = "notifications.log"
+	}
+	return &FileNotifier{Path: path}
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, event string, payload []byte) error {
+	line, err := json.Marshal(struct {
+		Time    time.Time       `json:"time"`
+		Event   string          `json:"event"`
+		Payload json.RawMessage `json:"payload"`
+	}{time.Now(), event, payload})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	file, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,145 @@
+// Package notifier delivers notifications, such as emails, for events that
+// were recorded in the outbox.
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	EventOTPEmail           = "email.otp"
+	EventGiftCardEmail      = "email.gift_card"
+	EventOrderPlaced        = "order.placed"
+	EventOrderStatusChanged = "order.status_changed"
+	EventRefundIssued       = "order.refunded"
+	EventWalletTransaction  = "wallet.transaction"
+)
+
+type OTPEmail struct {
+	Email string `json:"email"`
+	OTP   string `json:"otp"`
+}
+
+type GiftCardEmail struct {
+	SenderName    string `json:"sender_name"`
+	SenderProfile string `json:"sender_profile"`
+	Message       string `json:"message"`
+	Email         string `json:"email"`
+	Amount        string `json:"amount"`
+	GiftCode      string `json:"gift_code"`
+	ExpDate       string `json:"exp_date"`
+}
+
+type OrderPlacedItem struct {
+	OrderItemID uint    `json:"order_item_id"`
+	ProductName string  `json:"product_name"`
+	Quantity    int     `json:"quantity"`
+	Total       float64 `json:"total"`
+}
+
+type OrderPlaced struct {
+	OrderID       uint              `json:"order_id"`
+	OrderUID      string            `json:"order_uid"`
+	UserID        uint              `json:"user_id"`
+	Name          string            `json:"name"`
+	Email         string            `json:"email"`
+	PaymentMethod string            `json:"payment_method"`
+	Items         []OrderPlacedItem `json:"items"`
+	Total         float64           `json:"total"`
+}
+
+type OrderStatusChanged struct {
+	OrderID     uint   `json:"order_id"`
+	OrderItemID uint   `json:"order_item_id"`
+	OrderUID    string `json:"order_uid"`
+	ProductName string `json:"product_name"`
+	UserID      uint   `json:"user_id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	FromStatus  string `json:"from_status"`
+	ToStatus    string `json:"to_status"`
+	ActorType   string `json:"actor_type"`
+	Note        string `json:"note"`
+}
+
+type RefundIssued struct {
+	RefundUID   string  `json:"refund_uid"`
+	OrderItemID uint    `json:"order_item_id"`
+	OrderUID    string  `json:"order_uid"`
+	ProductName string  `json:"product_name"`
+	Quantity    int     `json:"quantity"`
+	Amount      float64 `json:"amount"`
+	Destination string  `json:"destination"`
+	UserID      uint    `json:"user_id"`
+	Name        string  `json:"name"`
+	Email       string  `json:"email"`
+}
+
+type WalletTransaction struct {
+	UserID        uint    `json:"user_id"`
+	Name          string  `json:"name"`
+	Email         string  `json:"email"`
+	Type          string  `json:"type"`
+	Amount        float64 `json:"amount"`
+	Description   string  `json:"description"`
+	TransactionID string  `json:"transaction_id"`
+}
+
+// secretFields are the payload fields that let whoever reads them sign in or
+// spend money. They are needed to deliver the event and must not be shown
+// anywhere else.
+var secretFields = map[string][]string{
+	EventOTPEmail:      {"otp"},
+	EventGiftCardEmail: {"gift_code"},
+}
+
+const redacted = "[redacted]"
+
+// Redact returns the payload of an event with its secret fields blanked out,
+// for showing it to admins.
+func Redact(event, payload string) string {
+	fields := secretFields[event]
+	if len(fields) == 0 {
+		return payload
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &values); err != nil {
+		return redacted
+	}
+	for _, field := range fields {
+		if _, ok := values[field]; ok {
+			values[field] = redacted
+		}
+	}
+	body, err := json.Marshal(values)
+	if err != nil {
+		return redacted
+	}
+	return string(body)
+}
+
+// Notifier delivers one event. The payload is the JSON encoding of the
+// event's struct above. Returning an error makes the outbox retry it later.
+type Notifier interface {
+	Notify(ctx context.Context, event string, payload []byte) error
+}
+
+var Default Notifier = NewLogNotifier()
+
+// Init selects the notifier from the NOTIFIER setting.
+func Init() {
+	switch config.NOTIFIER {
+	case "log":
+		Default = NewLogNotifier()
+	case "file":
+		Default = NewFileNotifier(config.NOTIFIER_FILE)
+	default:
+		Default = NewEmailNotifier()
+	}
+	logger.Log.Info("Notifier configured", zap.String("notifier", config.NOTIFIER))
+}
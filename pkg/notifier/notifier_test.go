@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	otp, _ := json.Marshal(OTPEmail{Email: "user@example.com", OTP: "482913"})
+	gift, _ := json.Marshal(GiftCardEmail{Email: "friend@example.com", Amount: "500", GiftCode: "GIFT-7Q2K-93XA"})
+	order, _ := json.Marshal(OrderPlaced{OrderUID: "ORD-1", Email: "user@example.com", Total: 1499})
+
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		hidden  string
+		kept    string
+	}{
+		{"otp", EventOTPEmail, string(otp), "482913", "user@example.com"},
+		{"gift card", EventGiftCardEmail, string(gift), "GIFT-7Q2K-93XA", "friend@example.com"},
+		{"unreadable secret payload", EventOTPEmail, `{"otp":"482913"`, "482913", ""},
+		{"no secrets", EventOrderPlaced, string(order), "", "ORD-1"},
+	}
+	for _, tt := range tests {
+		got := Redact(tt.event, tt.payload)
+		if tt.hidden != "" && strings.Contains(got, tt.hidden) {
+			t.Errorf("%s: Redact = %s, still shows %s", tt.name, got, tt.hidden)
+		}
+		if tt.kept != "" && !strings.Contains(got, tt.kept) {
+			t.Errorf("%s: Redact = %s, want %s kept", tt.name, got, tt.kept)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Package openapi builds an OpenAPI 3 document from the registered routes.
+// Every route is listed; routes whose handler has been described with
+// Describe also get their request and response bodies, generated from the
+// types the description names. The contract test in routes checks those are
+// the types the handlers bind.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+// Security schemes used by the shop.
+const (
+	BearerAuth  = "bearerAuth"
+	UserCookie  = "userCookie"
+	AdminCookie = "adminCookie"
+)
+
+// Operation describes what a handler takes and returns. Request and
+// Response are zero values of the bound and returned types.
+type Operation struct {
+	Summary string
+	// Request is bound from the JSON body, or from a form when Form is set.
+	Request interface{}
+	Form    bool
+	// Query is bound from the query string.
+	Query    interface{}
+	Response interface{}
+	// Status is the success status, 200 when left out.
+	Status int
+	// HTML marks handlers that render a page instead of JSON.
+	HTML     bool
+	Error    interface{}
+	Security string
+}
+
+// Description ties an Operation to its handler.
+type Description struct {
+	handler   string
+	operation Operation
+}
+
+// Describe documents handler. The handler is referenced directly, so
+// renaming or removing it breaks the build instead of the document.
+func Describe(handler interface{}, operation Operation) Description {
+	return Description{handler: FuncName(handler), operation: operation}
+}
+
+// Handler is the name of the described handler, as FuncName reports it.
+func (d Description) Handler() string {
+	return d.handler
+}
+
+// Operation is what the handler was described as taking and returning.
+func (d Description) Operation() Operation {
+	return d.operation
+}
+
+// FuncName is the name Go gives a function, as reported for gin handlers.
+func FuncName(handler interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+}
+
+// Route is a registered route and the name of its final handler.
+type Route struct {
+	Method  string
+	Path    string
+	Handler string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]*PathItem `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// PathItem is a single operation of a path; the document keys them by method.
+type PathItem struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Build documents routes with the descriptions given for their handlers.
+func Build(info Info, routes []Route, descriptions ...[]Description) *Document {
+	described := make(map[string]Operation)
+	for _, list := range descriptions {
+		for _, description := range list {
+			described[description.handler] = description.operation
+		}
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				BearerAuth:  {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				UserCookie:  {Type: "apiKey", In: "cookie", Name: "jwtTokensUser"},
+				AdminCookie: {Type: "apiKey", In: "cookie", Name: "jwtTokensAdmin"},
+			},
+		},
+	}
+	schemas := newSchemaBuilder(doc.Components.Schemas)
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	operationIDs := make(map[string]int)
+	for _, route := range routes {
+		path, params := convertPath(route.Path)
+		operation, ok := described[route.Handler]
+		item := &PathItem{
+			OperationID: operationID(route, operationIDs),
+			Summary:     operation.Summary,
+			Tags:        []string{tag(route.Path)},
+			Parameters:  params,
+			Responses:   make(map[string]*Response),
+		}
+		if !ok {
+			item.Responses["default"] = &Response{Description: "Not described yet"}
+			addItem(doc, path, route.Method, item)
+			continue
+		}
+
+		if operation.Query != nil {
+			item.Parameters = append(item.Parameters, schemas.queryParameters(operation.Query)...)
+		}
+		if operation.Request != nil {
+			mediaType := "application/json"
+			if operation.Form {
+				mediaType = "application/x-www-form-urlencoded"
+			}
+			item.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]*MediaType{mediaType: {Schema: schemas.schemaFor(operation.Request, operation.Form)}},
+			}
+		}
+
+		status := operation.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &Response{Description: http.StatusText(status)}
+		switch {
+		case operation.HTML:
+			success.Content = map[string]*MediaType{"text/html": {}}
+		case operation.Response != nil:
+			success.Content = map[string]*MediaType{"application/json": {Schema: schemas.schemaFor(operation.Response, false)}}
+		}
+		item.Responses[strconv.Itoa(status)] = success
+		if operation.Error != nil {
+			item.Responses["default"] = &Response{
+				Description: "Error",
+				Content:     map[string]*MediaType{"application/json": {Schema: schemas.schemaFor(operation.Error, false)}},
+			}
+		}
+		if operation.Security != "" {
+			item.Security = []map[string][]string{{operation.Security: {}}}
+		}
+		addItem(doc, path, route.Method, item)
+	}
+	return doc
+}
+
+func addItem(doc *Document, path, method string, item *PathItem) {
+	if doc.Paths[path] == nil {
+		doc.Paths[path] = make(map[string]*PathItem)
+	}
+	doc.Paths[path][strings.ToLower(method)] = item
+}
+
+// convertPath turns gin's :id and *path parameters into OpenAPI's {id}.
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID names an operation after its handler, such as
+// "admin.ListUsers". A handler used by several routes gets a number after
+// the first.
+func operationID(route Route, seen map[string]int) string {
+	name := route.Handler
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	seen[name]++
+	if seen[name] > 1 {
+		name += strconv.Itoa(seen[name])
+	}
+	return name
+}
+
+func tag(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/"):
+		return "api"
+	case strings.HasPrefix(path, "/admin"):
+		return "admin"
+	default:
+		return "storefront"
+	}
+}
@@ -0,0 +1,238 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaBuilder turns Go types into schemas. Named structs go into the
+// components once and are referenced from everywhere else, which also
+// keeps models that refer to each other from recursing forever.
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+func newSchemaBuilder(components map[string]*Schema) *schemaBuilder {
+	return &schemaBuilder{components: components}
+}
+
+// schemaFor documents v. Form structs are named by their form tags, which
+// is what gin binds them by.
+func (b *schemaBuilder) schemaFor(v interface{}, form bool) *Schema {
+	return b.schema(reflect.TypeOf(v), form)
+}
+
+func (b *schemaBuilder) schema(t reflect.Type, form bool) *Schema {
+	if t.Kind() == reflect.Ptr {
+		schema := b.schema(t.Elem(), form)
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem(), form)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem(), form)}
+	case reflect.Struct:
+		return b.structSchema(t, form)
+	default:
+		// interface{} and anything else JSON can hold.
+		return &Schema{}
+	}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type, form bool) *Schema {
+	name := componentName(t, form)
+	if name == "" {
+		return b.properties(t, form)
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := b.components[name]; ok {
+		return ref
+	}
+	b.components[name] = &Schema{}
+	*b.components[name] = *b.properties(t, form)
+	return ref
+}
+
+// componentName names a struct after its package, such as "api.CartResponse".
+// Anonymous and generic structs are written out in place instead.
+func componentName(t reflect.Type, form bool) string {
+	if t.Name() == "" || strings.Contains(t.Name(), "[") {
+		return ""
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name := pkg + "." + t.Name()
+	if form {
+		name += "Form"
+	}
+	return name
+}
+
+func (b *schemaBuilder) properties(t reflect.Type, form bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(schema, t, form)
+	return schema
+}
+
+func (b *schemaBuilder) addFields(schema *Schema, t reflect.Type, form bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := fieldName(field, form)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(schema, embedded, form)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := b.schema(field.Type, form)
+		if applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// fieldName is the name a field is bound and written by. An empty name on
+// an embedded struct means its fields are promoted.
+func fieldName(field reflect.StructField, form bool) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if form {
+		tag = field.Tag.Get("form")
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" && !field.Anonymous {
+		name = field.Name
+	}
+	return name, true
+}
+
+// applyBinding copies gin's binding rules onto the schema and reports
+// whether the field is required.
+func applyBinding(schema *Schema, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			schema.Enum = strings.Fields(value)
+		case "gte", "min":
+			setBound(schema, value, true)
+		case "lte", "max":
+			setBound(schema, value, false)
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, value string, lower bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	if schema.Type == "string" {
+		length := int(n)
+		if lower {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+		return
+	}
+	if lower {
+		schema.Minimum = &n
+	} else {
+		schema.Maximum = &n
+	}
+}
+
+// queryParameters lists the fields of a query struct as parameters.
+func (b *schemaBuilder) queryParameters(v interface{}) []Parameter {
+	t := reflect.TypeOf(v)
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := fieldName(field, true)
+		if !ok || name == "" {
+			continue
+		}
+		schema := b.schema(field.Type, true)
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: applyBinding(schema, field.Tag.Get("binding")),
+			Schema:   schema,
+		})
+	}
+	return params
+}
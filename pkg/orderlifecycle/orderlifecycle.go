@@ -0,0 +1,177 @@
+// Package orderlifecycle defines the statuses an order item moves through,
+// which moves are allowed and who may make them, and keeps the status
+// history of every item.
+package orderlifecycle
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"github.com/anfastk/E-Commerce-Website/pkg/outbox"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusOrderNotPlaced = "Order Not Placed"
+	StatusPending        = "Pending"
+	StatusConfirmed      = "Confirmed"
+	StatusShipped        = "Shipped"
+	StatusOutForDelivery = "Out For Delivery"
+	StatusDelivered      = "Delivered"
+	StatusCancelled      = "Cancelled"
+	StatusReturned       = "Returned"
+	StatusFailed         = "Failed"
+)
+
+const (
+	ActorUser   = "User"
+	ActorAdmin  = "Admin"
+	ActorSystem = "System"
+)
+
+var (
+	ErrInvalidTransition = errors.New("order status transition not allowed")
+	ErrNotPermitted      = errors.New("order status change not permitted")
+	ErrStatusChanged     = errors.New("order status was changed by another request")
+)
+
+type Actor struct {
+	Type string
+	ID   uint
+}
+
+func UserActor(userID uint) Actor {
+	return Actor{Type: ActorUser, ID: userID}
+}
+
+func AdminActor(adminID uint) Actor {
+	return Actor{Type: ActorAdmin, ID: adminID}
+}
+
+func SystemActor() Actor {
+	return Actor{Type: ActorSystem}
+}
+
+var transitions = map[string][]string{
+	StatusOrderNotPlaced: {StatusConfirmed, StatusFailed, StatusCancelled},
+	StatusPending:        {StatusConfirmed, StatusOrderNotPlaced, StatusFailed, StatusCancelled},
+	StatusConfirmed:      {StatusShipped, StatusCancelled},
+	StatusShipped:        {StatusOutForDelivery, StatusDelivered, StatusCancelled},
+	StatusOutForDelivery: {StatusDelivered, StatusCancelled},
+	StatusDelivered:      {StatusReturned},
+}
+
+// Users can only cancel before delivery; returns go through a return request
+// that an admin approves. Payment outcomes are recorded by the system.
+var actorTargets = map[string][]string{
+	ActorUser:   {StatusCancelled},
+	ActorAdmin:  {StatusConfirmed, StatusShipped, StatusOutForDelivery, StatusDelivered, StatusCancelled, StatusReturned},
+	ActorSystem: {StatusOrderNotPlaced, StatusConfirmed, StatusFailed},
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func CanTransition(from, to string) bool {
+	return contains(transitions[from], to)
+}
+
+// AllowedStatuses lists the statuses the actor may move an item to.
+func AllowedStatuses(actor Actor, from string) []string {
+	var allowed []string
+	for _, to := range transitions[from] {
+		if contains(actorTargets[actor.Type], to) {
+			allowed = append(allowed, to)
+		}
+	}
+	return allowed
+}
+
+func Validate(actor Actor, from, to string) error {
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	if !contains(actorTargets[actor.Type], to) {
+		return fmt.Errorf("%w: %s cannot mark an order %s", ErrNotPermitted, actor.Type, to)
+	}
+	return nil
+}
+
+// Transition moves the item to the given status, applying any extra column
+// updates in the same statement, and records the change. The update only
+// matches while the item is still in the status it was read with, so two
+// concurrent requests cannot both move it.
+func Transition(tx *gorm.DB, item *models.OrderItem, to string, actor Actor, note string, updates map[string]interface{}) error {
+	from := item.OrderStatus
+	if err := Validate(actor, from, to); err != nil {
+		return err
+	}
+
+	columns := map[string]interface{}{"order_status": to}
+	for column, value := range updates {
+		columns[column] = value
+	}
+
+	result := tx.Model(&models.OrderItem{}).
+		Where("id = ? AND order_status = ?", item.ID, from).
+		Updates(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStatusChanged
+	}
+
+	if err := Record(tx, item.ID, from, to, actor, note); err != nil {
+		return err
+	}
+	item.OrderStatus = to
+	return nil
+}
+
+// Record appends a history row without changing the item, for the initial
+// status of a new item. The customer notification is queued in the same
+// transaction.
+func Record(tx *gorm.DB, orderItemID uint, from, to string, actor Actor, note string) error {
+	if err := tx.Create(&models.OrderStatusHistory{
+		OrderItemID: orderItemID,
+		FromStatus:  from,
+		ToStatus:    to,
+		ActorType:   actor.Type,
+		ActorID:     actor.ID,
+		Note:        note,
+		CreatedAt:   time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	var event notifier.OrderStatusChanged
+	if err := tx.Table("order_items").
+		Select("order_items.order_id, order_items.id AS order_item_id, order_items.order_uid, order_items.product_name, order_items.user_id, user_auths.full_name AS name, user_auths.email").
+		Joins("JOIN user_auths ON user_auths.id = order_items.user_id").
+		Where("order_items.id = ?", orderItemID).
+		Scan(&event).Error; err != nil {
+		return err
+	}
+	event.FromStatus = from
+	event.ToStatus = to
+	event.ActorType = actor.Type
+	event.Note = note
+	return outbox.Enqueue(tx, notifier.EventOrderStatusChanged, event)
+}
+
+func FetchHistory(orderItemID uint) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	err := config.DB.Where("order_item_id = ?", orderItemID).Order("created_at ASC, id ASC").Find(&history).Error
+	return history, err
+}
@@ -0,0 +1,139 @@
+package orderlifecycle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var allStatuses = []string{
+	StatusOrderNotPlaced, StatusPending, StatusConfirmed, StatusShipped, StatusOutForDelivery,
+	StatusDelivered, StatusCancelled, StatusReturned, StatusFailed,
+}
+
+func TestValidate(t *testing.T) {
+	user, admin, system := UserActor(1), AdminActor(1), SystemActor()
+
+	tests := []struct {
+		name    string
+		actor   Actor
+		from    string
+		to      string
+		wantErr error
+	}{
+		// Illegal jumps, whoever makes them.
+		{"delivered back to shipped", admin, StatusDelivered, StatusShipped, ErrInvalidTransition},
+		{"cancelled back to confirmed", admin, StatusCancelled, StatusConfirmed, ErrInvalidTransition},
+		{"cancelled back to confirmed by system", system, StatusCancelled, StatusConfirmed, ErrInvalidTransition},
+		{"returned back to delivered", admin, StatusReturned, StatusDelivered, ErrInvalidTransition},
+		{"failed to confirmed", system, StatusFailed, StatusConfirmed, ErrInvalidTransition},
+		{"confirmed straight to delivered", admin, StatusConfirmed, StatusDelivered, ErrInvalidTransition},
+		{"pending straight to shipped", admin, StatusPending, StatusShipped, ErrInvalidTransition},
+		{"delivered to cancelled", user, StatusDelivered, StatusCancelled, ErrInvalidTransition},
+		{"same status", admin, StatusShipped, StatusShipped, ErrInvalidTransition},
+		{"unknown status", admin, "Lost", StatusCancelled, ErrInvalidTransition},
+
+		// Legal moves by the wrong actor.
+		{"user returns an item", user, StatusDelivered, StatusReturned, ErrNotPermitted},
+		{"user confirms an order", user, StatusPending, StatusConfirmed, ErrNotPermitted},
+		{"user ships an order", user, StatusConfirmed, StatusShipped, ErrNotPermitted},
+		{"system ships an order", system, StatusConfirmed, StatusShipped, ErrNotPermitted},
+		{"system delivers an order", system, StatusOutForDelivery, StatusDelivered, ErrNotPermitted},
+		{"system cancels an order", system, StatusConfirmed, StatusCancelled, ErrNotPermitted},
+		{"system returns an item", system, StatusDelivered, StatusReturned, ErrNotPermitted},
+		{"admin fails a payment", admin, StatusPending, StatusFailed, ErrNotPermitted},
+		{"unknown actor", Actor{Type: "Courier"}, StatusConfirmed, StatusShipped, ErrNotPermitted},
+
+		// Allowed paths.
+		{"system confirms a paid order", system, StatusPending, StatusConfirmed, nil},
+		{"system records a failed payment", system, StatusPending, StatusFailed, nil},
+		{"system records an unplaced order", system, StatusPending, StatusOrderNotPlaced, nil},
+		{"system confirms a retried payment", system, StatusOrderNotPlaced, StatusConfirmed, nil},
+		{"admin confirms", admin, StatusPending, StatusConfirmed, nil},
+		{"admin ships", admin, StatusConfirmed, StatusShipped, nil},
+		{"admin sends out for delivery", admin, StatusShipped, StatusOutForDelivery, nil},
+		{"admin delivers from shipped", admin, StatusShipped, StatusDelivered, nil},
+		{"admin delivers", admin, StatusOutForDelivery, StatusDelivered, nil},
+		{"admin approves a return", admin, StatusDelivered, StatusReturned, nil},
+		{"admin cancels a shipped item", admin, StatusShipped, StatusCancelled, nil},
+		{"user cancels a pending item", user, StatusPending, StatusCancelled, nil},
+		{"user cancels a confirmed item", user, StatusConfirmed, StatusCancelled, nil},
+		{"user cancels an item out for delivery", user, StatusOutForDelivery, StatusCancelled, nil},
+		{"user cancels an unplaced order", user, StatusOrderNotPlaced, StatusCancelled, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.actor, tt.from, tt.to)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate(%s, %s -> %s) = %v, want nil", tt.actor.Type, tt.from, tt.to, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate(%s, %s -> %s) = %v, want %v", tt.actor.Type, tt.from, tt.to, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowedStatuses(t *testing.T) {
+	user, admin, system := UserActor(1), AdminActor(1), SystemActor()
+
+	tests := []struct {
+		actor Actor
+		from  string
+		want  []string
+	}{
+		{user, StatusPending, []string{StatusCancelled}},
+		{user, StatusShipped, []string{StatusCancelled}},
+		{user, StatusDelivered, nil},
+		{user, StatusCancelled, nil},
+		{admin, StatusPending, []string{StatusConfirmed, StatusCancelled}},
+		{admin, StatusConfirmed, []string{StatusShipped, StatusCancelled}},
+		{admin, StatusShipped, []string{StatusOutForDelivery, StatusDelivered, StatusCancelled}},
+		{admin, StatusOutForDelivery, []string{StatusDelivered, StatusCancelled}},
+		{admin, StatusDelivered, []string{StatusReturned}},
+		{admin, StatusCancelled, nil},
+		{admin, StatusReturned, nil},
+		{admin, StatusFailed, nil},
+		{system, StatusPending, []string{StatusConfirmed, StatusOrderNotPlaced, StatusFailed}},
+		{system, StatusOrderNotPlaced, []string{StatusConfirmed, StatusFailed}},
+		{system, StatusConfirmed, nil},
+		{system, StatusDelivered, nil},
+	}
+	for _, tt := range tests {
+		got := AllowedStatuses(tt.actor, tt.from)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AllowedStatuses(%s, %s) = %v, want %v", tt.actor.Type, tt.from, got, tt.want)
+		}
+	}
+}
+
+// Every status AllowedStatuses offers must pass Validate, and every status
+// it leaves out must fail, so the admin dropdown never shows a move the
+// server then rejects.
+func TestAllowedStatusesAgreesWithValidate(t *testing.T) {
+	for _, actor := range []Actor{UserActor(1), AdminActor(1), SystemActor()} {
+		for _, from := range allStatuses {
+			allowed := AllowedStatuses(actor, from)
+			for _, to := range allStatuses {
+				err := Validate(actor, from, to)
+				if contains(allowed, to) != (err == nil) {
+					t.Errorf("%s %s -> %s: offered %v, Validate error %v", actor.Type, from, to, contains(allowed, to), err)
+				}
+			}
+		}
+	}
+}
+
+// Finished items stay finished.
+func TestTerminalStatuses(t *testing.T) {
+	for _, from := range []string{StatusCancelled, StatusReturned, StatusFailed} {
+		for _, to := range allStatuses {
+			if CanTransition(from, to) {
+				t.Errorf("%s can move to %s", from, to)
+			}
+		}
+	}
+}
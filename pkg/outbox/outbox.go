@@ -0,0 +1,213 @@
+// Package outbox records side effects in the same transaction as the change
+// that causes them and delivers them afterwards through the notifier,
+// retrying failures with exponential backoff. Messages that keep failing are
+// marked Dead and can be requeued by an admin. Delivered messages are deleted
+// after a week.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPending = "Pending"
+	StatusSent    = "Sent"
+	StatusDead    = "Dead"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	batchSize          = 50
+	baseBackoff        = 30 * time.Second
+	maxBackoff         = 6 * time.Hour
+	// claimLease is how long a claimed message is hidden from other
+	// dispatchers. A dispatcher that dies mid-delivery leaves the message to
+	// be picked up again once the lease runs out.
+	claimLease = 5 * time.Minute
+	// sentRetention is how long a delivered message is kept. Payloads carry
+	// OTPs and gift card codes, so they are not kept for good.
+	sentRetention = 7 * 24 * time.Hour
+)
+
+var ErrNotRequeueable = errors.New("only dead messages can be requeued")
+
+// Enqueue records an event for delivery once tx commits.
+func Enqueue(tx *gorm.DB, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxMessage{
+		EventType:     event,
+		Payload:       string(body),
+		Status:        StatusPending,
+		MaxAttempts:   DefaultMaxAttempts,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Backoff is the wait before the next attempt after the given number of
+// failed attempts: 30s, 1m, 2m, ... capped at six hours.
+func Backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// claim leases a batch of due messages. SKIP LOCKED lets several
+// dispatchers run at once without delivering a message twice.
+func claim(db *gorm.DB, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := db.Raw(`UPDATE outbox_messages SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id
+			LIMIT ? FOR UPDATE SKIP LOCKED
+		) RETURNING *`,
+		time.Now().Add(claimLease), time.Now(), StatusPending, time.Now(), limit).Scan(&messages).Error
+	return messages, err
+}
+
+// Dispatch delivers the messages that are due and returns how many were
+// sent.
+func Dispatch(ctx context.Context, db *gorm.DB) (int, error) {
+	messages, err := claim(db, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+		if deliver(ctx, db, &message) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func deliver(ctx context.Context, db *gorm.DB, message *models.OutboxMessage) bool {
+	err := notifier.Default.Notify(ctx, message.EventType, []byte(message.Payload))
+	now := time.Now()
+	if err == nil {
+		if updateErr := db.Model(&models.OutboxMessage{}).Where("id = ?", message.ID).Updates(map[string]interface{}{
+			"status":     StatusSent,
+			"sent_at":    now,
+			"last_error": "",
+		}).Error; updateErr != nil {
+			logger.Log.Error("Failed to mark outbox message sent", zap.Uint("messageID", message.ID), zap.Error(updateErr))
+		}
+		return true
+	}
+
+	updates := map[string]interface{}{
+		"last_error":      err.Error(),
+		"next_attempt_at": now.Add(Backoff(message.Attempts)),
+	}
+	if message.Attempts >= message.MaxAttempts {
+		updates["status"] = StatusDead
+		logger.Log.Error("Outbox message moved to dead letters",
+			zap.Uint("messageID", message.ID),
+			zap.String("event", message.EventType),
+			zap.Int("attempts", message.Attempts),
+			zap.Error(err))
+	} else {
+		logger.Log.Warn("Outbox delivery failed, will retry",
+			zap.Uint("messageID", message.ID),
+			zap.String("event", message.EventType),
+			zap.Int("attempts", message.Attempts),
+			zap.Error(err))
+	}
+	if updateErr := db.Model(&models.OutboxMessage{}).Where("id = ?", message.ID).Updates(updates).Error; updateErr != nil {
+		logger.Log.Error("Failed to record outbox delivery failure", zap.Uint("messageID", message.ID), zap.Error(updateErr))
+	}
+	return false
+}
+
+// Kick delivers due messages in the background without waiting for the
+// next dispatcher run. Call it after committing time-sensitive messages
+// such as OTPs.
+func Kick(db *gorm.DB) {
+	go func() {
+		if _, err := Dispatch(context.Background(), db); err != nil {
+			logger.Log.Error("Outbox dispatch failed", zap.Error(err))
+		}
+	}()
+}
+
+// Requeue gives a dead message a fresh set of attempts.
+func Requeue(db *gorm.DB, id uint) error {
+	result := db.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", id, StatusDead).
+		Updates(map[string]interface{}{
+			"status":          StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotRequeueable
+	}
+	return nil
+}
+
+// DispatchJob retries messages that could not be delivered straight away.
+func DispatchJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "outbox-dispatch",
+		Description: "Delivers pending emails and notifications from the outbox",
+		Interval:    15 * time.Second,
+		Run: func(ctx context.Context) error {
+			sent, err := Dispatch(ctx, db)
+			if err != nil {
+				return fmt.Errorf("dispatch outbox: %w", err)
+			}
+			if sent > 0 {
+				logger.Log.Info("Outbox messages delivered", zap.Int("sent", sent))
+			}
+			return nil
+		},
+	}
+}
+
+// CleanupJob deletes messages delivered more than a week ago.
+func CleanupJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "outbox-cleanup",
+		Description: "Deletes outbox messages delivered over 7 days ago",
+		Interval:    6 * time.Hour,
+		Run: func(ctx context.Context) error {
+			result := db.WithContext(ctx).
+				Where("status = ? AND sent_at < ?", StatusSent, time.Now().Add(-sentRetention)).
+				Delete(&models.OutboxMessage{})
+			if result.Error != nil {
+				return fmt.Errorf("delete sent outbox messages: %w", result.Error)
+			}
+			if result.RowsAffected > 0 {
+				logger.Log.Info("Sent outbox messages deleted", zap.Int64("count", result.RowsAffected))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,64 @@
+// Package paymentgateway sends refunds back to the payment provider and looks
+// them up again. Client is an interface so local setups can run against a
+// stub instead of Razorpay.
+package paymentgateway
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type RefundRequest struct {
+	PaymentID string
+	Amount    float64
+	Receipt   string
+	Notes     map[string]interface{}
+}
+
+type RefundResult struct {
+	RefundID string
+	Status   string
+}
+
+// RejectedError is a refund the gateway answered and turned down, so it is
+// known not to have been made. Any other error from Refund may have come
+// after the gateway made the refund, and has to be looked up with FindRefund
+// before the refund is sent again.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "refund rejected: " + e.Reason
+}
+
+// IsRejected reports whether err is a refund the gateway turned down.
+func IsRejected(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected)
+}
+
+type Client interface {
+	Refund(request RefundRequest) (RefundResult, error)
+	// FindRefund looks for a refund of the payment created with receipt. The
+	// gateway has no idempotency key for refunds, so this is how a refund
+	// whose outcome was lost is told apart from one that was never sent.
+	FindRefund(paymentID, receipt string) (RefundResult, bool, error)
+}
+
+// Default is the client used for refunds; Init picks it from PAYMENT_GATEWAY.
+var Default Client = NewStubClient(false)
+
+func Init() {
+	switch strings.ToLower(config.PAYMENT_GATEWAY) {
+	case "stub":
+		Default = NewStubClient(config.PAYMENT_GATEWAY_STUB_FAIL)
+	default:
+		Default = NewRazorpayClient(config.RAZORPAY_KEY_ID, config.RAZORPAY_KEY_SECRET)
+	}
+	logger.Log.Info("Payment gateway initialised", zap.String("gateway", config.PAYMENT_GATEWAY))
+}
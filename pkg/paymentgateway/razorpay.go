@@ -0,0 +1,96 @@
+package paymentgateway
+
+import (
+	"errors"
+	"math"
+
+	"github.com/razorpay/razorpay-go"
+	rzperrors "github.com/razorpay/razorpay-go/errors"
+)
+
+type RazorpayClient struct {
+	client *razorpay.Client
+}
+
+func NewRazorpayClient(keyID, keySecret string) *RazorpayClient {
+	return &RazorpayClient{client: razorpay.NewClient(keyID, keySecret)}
+}
+
+func (r *RazorpayClient) Refund(request RefundRequest) (RefundResult, error) {
+	if request.PaymentID == "" {
+		return RefundResult{}, &RejectedError{Reason: "payment id is required for a gateway refund"}
+	}
+
+	data := map[string]interface{}{
+		"receipt": request.Receipt,
+		"speed":   "normal",
+	}
+	if len(request.Notes) > 0 {
+		data["notes"] = request.Notes
+	}
+	refund, err := r.client.Payment.Refund(request.PaymentID, int(math.Round(request.Amount*100)), data, nil)
+	if err != nil {
+		return RefundResult{}, refundError(err)
+	}
+
+	refundID, _ := refund["id"].(string)
+	status, _ := refund["status"].(string)
+	return RefundResult{RefundID: refundID, Status: status}, nil
+}
+
+// refundError tells a refund Razorpay turned down from one whose outcome is
+// unknown. The client reports a 4xx answer as a BadRequestError with the
+// gateway's description; an error page without one, a 5xx or a timeout
+// may have come after the refund was made.
+func refundError(err error) error {
+	var badRequest *rzperrors.BadRequestError
+	if errors.As(err, &badRequest) && badRequest.Message != "" {
+		return &RejectedError{Reason: badRequest.Message}
+	}
+	return err
+}
+
+// refundPageSize is the most refunds Razorpay returns for a payment in one
+// page.
+const refundPageSize = 100
+
+func (r *RazorpayClient) FindRefund(paymentID, receipt string) (RefundResult, bool, error) {
+	if paymentID == "" {
+		return RefundResult{}, false, errors.New("payment id is required to look up a gateway refund")
+	}
+
+	for skip := 0; ; skip += refundPageSize {
+		page, err := r.client.Payment.FetchMultipleRefund(paymentID, map[string]interface{}{
+			"count": refundPageSize,
+			"skip":  skip,
+		}, nil)
+		if err != nil {
+			return RefundResult{}, false, err
+		}
+
+		items, _ := page["items"].([]interface{})
+		for _, item := range items {
+			refund, ok := item.(map[string]interface{})
+			if !ok || !refundMatches(refund, receipt) {
+				continue
+			}
+			refundID, _ := refund["id"].(string)
+			status, _ := refund["status"].(string)
+			return RefundResult{RefundID: refundID, Status: status}, true, nil
+		}
+		if len(items) < refundPageSize {
+			return RefundResult{}, false, nil
+		}
+	}
+}
+
+// refundMatches matches on the receipt, falling back to the refund_uid note
+// for refunds whose receipt the gateway did not keep.
+func refundMatches(refund map[string]interface{}, receipt string) bool {
+	if value, _ := refund["receipt"].(string); value == receipt {
+		return true
+	}
+	notes, _ := refund["notes"].(map[string]interface{})
+	value, _ := notes["refund_uid"].(string)
+	return value == receipt
+}
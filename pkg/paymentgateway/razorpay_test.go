@@ -0,0 +1,34 @@
+package paymentgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	rzperrors "github.com/razorpay/razorpay-go/errors"
+)
+
+func TestRefundError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{"bad request", &rzperrors.BadRequestError{Message: "The refund amount exceeds the payment"}, true},
+		// An error page the client could not decode has no description.
+		{"undecoded error page", &rzperrors.BadRequestError{}, false},
+		{"server error", &rzperrors.ServerError{Message: "Internal error"}, false},
+		{"gateway error", &rzperrors.GatewayError{Message: "Bank did not respond"}, false},
+		{"timeout", fmt.Errorf("Post \"https://api.razorpay.com/v1/payments/pay_1/refund\": %w", context.DeadlineExceeded), false},
+	}
+	for _, tt := range tests {
+		err := refundError(tt.err)
+		if got := IsRejected(err); got != tt.rejected {
+			t.Errorf("%s: IsRejected = %v, want %v", tt.name, got, tt.rejected)
+		}
+		if !tt.rejected && !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want it returned as is", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package paymentgateway
+
+import (
+	"sync"
+
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// StubClient accepts every refund without calling a provider. With
+// FailRefunds set it rejects them instead, to exercise the retry path. The
+// refunds it accepted are kept in memory for FindRefund.
+type StubClient struct {
+	FailRefunds bool
+
+	mu      sync.Mutex
+	refunds map[string]RefundResult
+}
+
+func NewStubClient(failRefunds bool) *StubClient {
+	return &StubClient{FailRefunds: failRefunds}
+}
+
+func (s *StubClient) Refund(request RefundRequest) (RefundResult, error) {
+	if s.FailRefunds {
+		return RefundResult{}, &RejectedError{Reason: "stub gateway configured to reject refunds"}
+	}
+	refundID := "rfnd_stub_" + uuid.New().String()[:14]
+	logger.Log.Info("Stub gateway refund",
+		zap.String("paymentID", request.PaymentID),
+		zap.Float64("amount", request.Amount),
+		zap.String("refundID", refundID))
+	result := RefundResult{RefundID: refundID, Status: "processed"}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refunds == nil {
+		s.refunds = make(map[string]RefundResult)
+	}
+	s.refunds[request.PaymentID+"/"+request.Receipt] = result
+	return result, nil
+}
+
+func (s *StubClient) FindRefund(paymentID, receipt string) (RefundResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.refunds[paymentID+"/"+receipt]
+	return result, ok, nil
+}
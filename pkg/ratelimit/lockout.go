@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ScopeUserLogin  = "user_login"
+	ScopeAdminLogin = "admin_login"
+	ScopeOTP        = "otp"
+	ScopeGiftCard   = "gift_card"
+)
+
+// Policy locks an account for LockFor once it fails MaxFailures times within
+// Window. Each lock in a row doubles LockFor, up to MaxLockFor.
+type Policy struct {
+	Label       string
+	MaxFailures int
+	Window      time.Duration
+	LockFor     time.Duration
+	MaxLockFor  time.Duration
+}
+
+var Policies = map[string]Policy{
+	ScopeUserLogin:  {Label: "Customer login", MaxFailures: 5, Window: 15 * time.Minute, LockFor: 15 * time.Minute, MaxLockFor: 24 * time.Hour},
+	ScopeAdminLogin: {Label: "Admin login", MaxFailures: 5, Window: 15 * time.Minute, LockFor: 30 * time.Minute, MaxLockFor: 24 * time.Hour},
+	ScopeOTP:        {Label: "Email OTP", MaxFailures: 5, Window: 10 * time.Minute, LockFor: 15 * time.Minute, MaxLockFor: 6 * time.Hour},
+	ScopeGiftCard:   {Label: "Gift card redeem", MaxFailures: 5, Window: 30 * time.Minute, LockFor: time.Hour, MaxLockFor: 24 * time.Hour},
+}
+
+// lockoutRetention is how long failures are remembered after the last one.
+// A lock that follows within this time doubles the previous one.
+const lockoutRetention = 24 * time.Hour
+
+// NormalizeAccount makes "User@Example.com " and "user@example.com" count as
+// the same account.
+func NormalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// LockedUntil returns when the account's lock ends, or the zero time when it
+// is not locked.
+func LockedUntil(db *gorm.DB, scope, account string) (time.Time, error) {
+	var lockout models.AccountLockout
+	err := db.Where("scope = ? AND account = ? AND locked_until > ?", scope, NormalizeAccount(account), time.Now()).
+		First(&lockout).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *lockout.LockedUntil, nil
+}
+
+// RecordFailure counts a failed attempt and returns when the account's lock
+// ends if this attempt locked it, or the zero time otherwise.
+func RecordFailure(db *gorm.DB, scope, account, ip string) (time.Time, error) {
+	policy, ok := Policies[scope]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown lockout scope %q", scope)
+	}
+	account = NormalizeAccount(account)
+	now := time.Now()
+
+	tx := db.Begin()
+	fresh := models.AccountLockout{Scope: scope, Account: account, FirstFailedAt: now, LastFailedAt: now}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh).Error; err != nil {
+		tx.Rollback()
+		return time.Time{}, err
+	}
+
+	var lockout models.AccountLockout
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&lockout, "scope = ? AND account = ?", scope, account).Error; err != nil {
+		tx.Rollback()
+		return time.Time{}, err
+	}
+
+	if now.Sub(lockout.LastFailedAt) > lockoutRetention {
+		lockout.LockCount = 0
+	}
+	if now.Sub(lockout.FirstFailedAt) > policy.Window {
+		lockout.Failures = 0
+		lockout.FirstFailedAt = now
+	}
+	lockout.Failures++
+	lockout.LastFailedAt = now
+	lockout.LastIP = ip
+
+	var lockedUntil time.Time
+	if lockout.Failures >= policy.MaxFailures {
+		lockout.LockCount++
+		lockFor := policy.LockFor
+		for i := 1; i < lockout.LockCount && lockFor < policy.MaxLockFor; i++ {
+			lockFor *= 2
+		}
+		if lockFor > policy.MaxLockFor {
+			lockFor = policy.MaxLockFor
+		}
+		lockedUntil = now.Add(lockFor)
+		lockout.LockedUntil = &lockedUntil
+		lockout.Failures = 0
+		lockout.FirstFailedAt = now
+	}
+
+	if err := tx.Model(&lockout).Select("failures", "first_failed_at", "last_failed_at", "last_ip", "locked_until", "lock_count").
+		Updates(&lockout).Error; err != nil {
+		tx.Rollback()
+		return time.Time{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return time.Time{}, err
+	}
+
+	if !lockedUntil.IsZero() {
+		logger.Log.Warn("Account locked after repeated failures",
+			zap.String("scope", scope),
+			zap.String("account", account),
+			zap.String("ip", ip),
+			zap.Int("lockCount", lockout.LockCount),
+			zap.Time("lockedUntil", lockedUntil))
+	}
+	return lockedUntil, nil
+}
+
+// RecordSuccess forgets the failed attempts once the account gets in.
+func RecordSuccess(db *gorm.DB, scope, account string) error {
+	return db.Where("scope = ? AND account = ? AND (locked_until IS NULL OR locked_until <= ?)", scope, NormalizeAccount(account), time.Now()).
+		Delete(&models.AccountLockout{}).Error
+}
+
+// Locked lists the accounts that are locked right now, newest lock first.
+func Locked(db *gorm.DB) ([]models.AccountLockout, error) {
+	var lockouts []models.AccountLockout
+	err := db.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&lockouts).Error
+	return lockouts, err
+}
+
+// Unlock lifts a lock early and returns the lockout as it was.
+func Unlock(db *gorm.DB, id uint) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	if err := db.First(&lockout, id).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Delete(&lockout).Error; err != nil {
+		return nil, err
+	}
+	return &lockout, nil
+}
+
+// CleanupJob deletes old failure counts and rate limit buckets that have
+// filled up again.
+func CleanupJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "ratelimit-cleanup",
+		Description: "Deletes expired account lockouts and idle rate limit buckets",
+		Interval:    time.Hour,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-lockoutRetention)
+			result := db.WithContext(ctx).
+				Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", cutoff, time.Now()).
+				Delete(&models.AccountLockout{})
+			if result.Error != nil {
+				return fmt.Errorf("delete old lockouts: %w", result.Error)
+			}
+			if result.RowsAffected > 0 {
+				logger.Log.Info("Old account lockouts deleted", zap.Int64("count", result.RowsAffected))
+			}
+
+			if err := db.WithContext(ctx).Where("refilled_at < ?", cutoff).Delete(&models.RateLimitBucket{}).Error; err != nil {
+				return fmt.Errorf("delete idle rate limit buckets: %w", err)
+			}
+			return nil
+		},
+	}
+}
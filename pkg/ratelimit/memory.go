@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have filled up again are dropped.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens     float64
+	refilledAt time.Time
+	per        time.Duration
+}
+
+// MemoryStore keeps buckets in the process. Limits are not shared between
+// instances and reset on restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), refilledAt: now}
+		s.buckets[key] = b
+	}
+	b.per = limit.Per
+
+	tokens, result := refill(b.tokens, b.refilledAt, limit, now)
+	b.tokens = tokens
+	b.refilledAt = now
+	return result, nil
+}
+
+// sweep drops buckets that would be full by now, since a new bucket starts
+// out full anyway.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.refilledAt) >= b.per {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that limits
+// hold across instances and restarts.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	tx := s.db.WithContext(ctx).Begin()
+	fresh := models.RateLimitBucket{BucketKey: key, Tokens: float64(limit.Burst), RefilledAt: now}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh).Error; err != nil {
+		tx.Rollback()
+		return Result{}, err
+	}
+
+	var b models.RateLimitBucket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, "bucket_key = ?", key).Error; err != nil {
+		tx.Rollback()
+		return Result{}, err
+	}
+
+	tokens, result := refill(b.Tokens, b.RefilledAt, limit, now)
+	if err := tx.Model(&b).Updates(map[string]interface{}{"tokens": tokens, "refilled_at": now}).Error; err != nil {
+		tx.Rollback()
+		return Result{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
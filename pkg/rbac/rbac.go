@@ -0,0 +1,103 @@
+// Package rbac defines the staff roles of the admin panel and the areas each
+// role may work in. Route groups ask for a permission and the signed-in
+// admin's role decides whether they get it.
+package rbac
+
+type Permission string
+
+const (
+	PermDashboard Permission = "dashboard"
+	// PermCatalog covers products, variants, prices, offers, categories,
+	// filters and reviews.
+	PermCatalog Permission = "catalog"
+	PermCoupons Permission = "coupons"
+	// PermOrders covers orders, returns, shipping rules and pin codes.
+	PermOrders    Permission = "orders"
+	PermCustomers Permission = "customers"
+	// PermFinance covers wallets, refunds, sales reports and tax rules.
+	PermFinance Permission = "finance"
+	// PermSystem covers background jobs and the outbox.
+	PermSystem Permission = "system"
+	// PermStaff covers admin users and the audit log.
+	PermStaff Permission = "staff"
+)
+
+const (
+	RoleSuperAdmin      = "super_admin"
+	RoleCatalogEditor   = "catalog_editor"
+	RoleOrderFulfilment = "order_fulfilment"
+	RoleFinance         = "finance"
+)
+
+type Role struct {
+	Name        string
+	Label       string
+	Description string
+	Permissions []Permission
+}
+
+// Roles lists every role in the order they are offered on the admin users
+// screen. The super admin may do everything, including managing staff.
+var Roles = []Role{
+	{
+		Name:        RoleSuperAdmin,
+		Label:       "Super Admin",
+		Description: "Full access, including staff accounts and the audit log",
+		Permissions: []Permission{PermDashboard, PermCatalog, PermCoupons, PermOrders, PermCustomers, PermFinance, PermSystem, PermStaff},
+	},
+	{
+		Name:        RoleCatalogEditor,
+		Label:       "Catalog Editor",
+		Description: "Products, prices, offers, categories, coupons and reviews",
+		Permissions: []Permission{PermDashboard, PermCatalog, PermCoupons},
+	},
+	{
+		Name:        RoleOrderFulfilment,
+		Label:       "Order Fulfilment",
+		Description: "Orders, returns, shipping, pin codes and customers",
+		Permissions: []Permission{PermDashboard, PermOrders, PermCustomers},
+	},
+	{
+		Name:        RoleFinance,
+		Label:       "Finance",
+		Description: "Wallets, refunds, sales reports, tax rules and coupons",
+		Permissions: []Permission{PermDashboard, PermFinance, PermCoupons},
+	},
+}
+
+func Lookup(name string) (Role, bool) {
+	for _, role := range Roles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+func Valid(name string) bool {
+	_, ok := Lookup(name)
+	return ok
+}
+
+// Label returns the display name of a role, or the raw name when it is
+// unknown.
+func Label(name string) string {
+	if role, ok := Lookup(name); ok {
+		return role.Label
+	}
+	return name
+}
+
+// Can reports whether role grants perm. Unknown roles grant nothing.
+func Can(role string, perm Permission) bool {
+	r, ok := Lookup(role)
+	if !ok {
+		return false
+	}
+	for _, p := range r.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
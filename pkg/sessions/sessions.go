@@ -0,0 +1,254 @@
+// Package sessions keeps a server-side record of every signed-in device.
+// Each session hands out short-lived access tokens and a refresh token that
+// is replaced on every use, so a session can be listed and revoked at any
+// time and a stolen refresh token stops working once either copy is reused.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AccessTokenTTL = 15 * time.Minute
+	// RotateGrace lets requests that were already in flight with the old
+	// refresh token through, instead of treating them as a replay.
+	RotateGrace = 30 * time.Second
+	// retention is how long ended sessions are kept before cleanup.
+	retention = 30 * 24 * time.Hour
+)
+
+const (
+	ReasonLogout      = "Logged out"
+	ReasonLogoutAll   = "Logged out everywhere"
+	ReasonRevoked     = "Signed out from another device"
+	ReasonBlocked     = "Account blocked"
+	ReasonPassword    = "Password changed"
+	ReasonTokenReused = "Refresh token reused"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid refresh token")
+	ErrExpired      = errors.New("session expired")
+	ErrRevoked      = errors.New("session revoked")
+	ErrTokenReused  = errors.New("refresh token was already used")
+)
+
+// RefreshTokenTTL is how long a session lasts without being used. Admin
+// sessions are kept short.
+func RefreshTokenTTL(role string) time.Duration {
+	if role == "Admin" {
+		return 12 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Create starts a session and returns it with its first refresh token.
+func Create(db *gorm.DB, userID uint, email, role, userAgent, ip string) (*models.UserSession, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := models.UserSession{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		Role:             role,
+		Email:            email,
+		RefreshTokenHash: HashToken(token),
+		UserAgent:        userAgent,
+		IPAddress:        ip,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(RefreshTokenTTL(role)),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, token, nil
+}
+
+// Rotate exchanges a refresh token for a new one. The returned token is
+// empty when the old token was presented again within RotateGrace; the
+// caller should keep the token it already handed out. Presenting an old token
+// after that revokes the whole session.
+func Rotate(db *gorm.DB, refreshToken, role, userAgent, ip string) (*models.UserSession, string, error) {
+	hash := HashToken(refreshToken)
+	now := time.Now()
+
+	tx := db.Begin()
+	var session models.UserSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND (refresh_token_hash = ? OR previous_token_hash = ?)", role, hash, hash).
+		First(&session).Error
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrInvalidToken
+		}
+		return nil, "", err
+	}
+
+	if session.RevokedAt != nil {
+		tx.Rollback()
+		return nil, "", ErrRevoked
+	}
+	if now.After(session.ExpiresAt) {
+		tx.Rollback()
+		return nil, "", ErrExpired
+	}
+
+	if session.RefreshTokenHash != hash {
+		if session.RotatedAt != nil && now.Sub(*session.RotatedAt) <= RotateGrace {
+			tx.Rollback()
+			return &session, "", nil
+		}
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoke_reason": ReasonTokenReused,
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, "", err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return nil, "", err
+		}
+		logger.Log.Warn("Refresh token reuse detected, session revoked",
+			zap.String("sessionID", session.ID),
+			zap.Uint("userID", session.UserID),
+			zap.String("ip", ip))
+		return nil, "", ErrTokenReused
+	}
+
+	token, err := newToken()
+	if err != nil {
+		tx.Rollback()
+		return nil, "", err
+	}
+	updates := map[string]interface{}{
+		"refresh_token_hash":  HashToken(token),
+		"previous_token_hash": hash,
+		"rotated_at":          now,
+		"last_used_at":        now,
+		"expires_at":          now.Add(RefreshTokenTTL(role)),
+		"user_agent":          userAgent,
+		"ip_address":          ip,
+	}
+	if err := tx.Model(&session).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, "", err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, "", err
+	}
+	return &session, token, nil
+}
+
+// Active reports whether the session is neither revoked nor expired.
+func Active(db *gorm.DB, sessionID string) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := db.First(&session, "id = ?", sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevoked
+		}
+		return nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return &session, nil
+}
+
+// List returns the user's active sessions, most recently used first.
+func List(db *gorm.DB, userID uint, role string) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := db.Where("user_id = ? AND role = ? AND revoked_at IS NULL AND expires_at > ?", userID, role, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke ends one of the user's sessions.
+func Revoke(db *gorm.DB, userID uint, role, sessionID, reason string) error {
+	result := db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND role = ? AND revoked_at IS NULL", sessionID, userID, role).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeByToken ends the session a refresh token belongs to, for logging out
+// when the access token is already gone.
+func RevokeByToken(db *gorm.DB, refreshToken, role, reason string) error {
+	return db.Model(&models.UserSession{}).
+		Where("refresh_token_hash = ? AND role = ? AND revoked_at IS NULL", HashToken(refreshToken), role).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+// RevokeAll ends every session of the user except keepSessionID, which may be
+// empty.
+func RevokeAll(db *gorm.DB, userID uint, role, keepSessionID, reason string) (int64, error) {
+	query := db.Model(&models.UserSession{}).
+		Where("user_id = ? AND role = ? AND revoked_at IS NULL", userID, role)
+	if keepSessionID != "" {
+		query = query.Where("id <> ?", keepSessionID)
+	}
+	result := query.Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason})
+	return result.RowsAffected, result.Error
+}
+
+// CleanupJob deletes sessions that ended more than a month ago.
+func CleanupJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "session-cleanup",
+		Description: "Deletes sign-in sessions that expired or were revoked over 30 days ago",
+		Interval:    6 * time.Hour,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-retention)
+			result := db.WithContext(ctx).
+				Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).
+				Delete(&models.UserSession{})
+			if result.Error != nil {
+				return fmt.Errorf("delete old sessions: %w", result.Error)
+			}
+			if result.RowsAffected > 0 {
+				logger.Log.Info("Old sessions deleted", zap.Int64("count", result.RowsAffected))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+type CloudinaryStore struct {
+	Client    *cloudinary.Cloudinary
+	CloudName string
+}
+
+func NewCloudinaryStore(client *cloudinary.Cloudinary, cloudName string) *CloudinaryStore {
+	return &CloudinaryStore{Client: client, CloudName: cloudName}
+}
+
+func (s *CloudinaryStore) Save(ctx context.Context, folder, filename string, image io.Reader) (string, error) {
+	if s.Client == nil {
+		return "", errors.New("cloudinary is not configured")
+	}
+	result, err := s.Client.Upload.Upload(ctx, image, uploader.UploadParams{Folder: folder})
+	if err != nil {
+		return "", errors.New("upload failed: " + err.Error())
+	}
+	if result.Error.Message != "" {
+		return "", errors.New("upload failed: " + result.Error.Message)
+	}
+	return result.SecureURL, nil
+}
+
+func (s *CloudinaryStore) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	return Fetch(ctx, url)
+}
+
+func (s *CloudinaryStore) Owns(url string) bool {
+	for _, scheme := range []string{"https://", "http://"} {
+		if strings.HasPrefix(url, scheme+"res.cloudinary.com/"+s.CloudName+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CloudinaryStore) Delete(ctx context.Context, url string) error {
+	if !s.Owns(url) {
+		return ErrNotOwned
+	}
+	if s.Client == nil {
+		return errors.New("cloudinary is not configured")
+	}
+	publicID, err := cloudinaryPublicID(url)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Client.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: publicID}); err != nil {
+		return errors.New("delete from Cloudinary failed: " + err.Error())
+	}
+	return nil
+}
+
+// cloudinaryPublicID takes the folder and file name after the version in
+// .../upload/v123/folder/name.jpg.
+func cloudinaryPublicID(url string) (string, error) {
+	urlParts := strings.Split(url, "/")
+	uploadIndex := -1
+	for i, part := range urlParts {
+		if part == "upload" {
+			uploadIndex = i
+			break
+		}
+	}
+	if uploadIndex == -1 || uploadIndex+2 >= len(urlParts) {
+		return "", ErrInvalidURL
+	}
+
+	fullPublicID := strings.Join(urlParts[uploadIndex+2:], "/")
+	return strings.TrimSuffix(fullPublicID, filepath.Ext(fullPublicID)), nil
+}
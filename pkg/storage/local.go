@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// LocalStore keeps images in a directory that the application serves under
+// URLPrefix.
+type LocalStore struct {
+	Dir       string
+	URLPrefix string
+}
+
+func NewLocalStore(dir, urlPrefix string) *LocalStore {
+	if dir == "" {
+		dir = "uploads"
+	}
+	if urlPrefix == "" {
+		urlPrefix = "/uploads"
+	}
+	return &LocalStore{Dir: dir, URLPrefix: strings.TrimSuffix(urlPrefix, "/")}
+}
+
+func (s *LocalStore) Save(ctx context.Context, folder, filename string, image io.Reader) (string, error) {
+	folder = path.Clean("/" + folder)[1:]
+	ext := strings.ToLower(path.Ext(filename))
+	if ext == "" || len(ext) > 5 {
+		ext = ".jpg"
+	}
+	name := uuid.New().String() + ext
+
+	dir := filepath.Join(s.Dir, filepath.FromSlash(folder))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, image); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return path.Join(s.URLPrefix, folder, name), nil
+}
+
+func (s *LocalStore) Owns(url string) bool {
+	return strings.HasPrefix(url, s.URLPrefix+"/")
+}
+
+// filePath maps a URL back to the file it was saved as, refusing anything
+// that would leave the store directory.
+func (s *LocalStore) filePath(url string) (string, error) {
+	if !s.Owns(url) {
+		return "", ErrNotOwned
+	}
+	relative := path.Clean("/" + strings.TrimPrefix(url, s.URLPrefix+"/"))
+	if relative == "/" {
+		return "", ErrInvalidURL
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(relative)), nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	filePath, err := s.filePath(url)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, url string) error {
+	filePath, err := s.filePath(url)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Package storage keeps uploaded images in a pluggable ImageStore, either
+// Cloudinary or a local directory served by the application.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	FolderProducts        = "products"
+	FolderProductVariants = "ProductVariants"
+	FolderProfilePictures = "ProfilePicture"
+)
+
+var (
+	ErrNotOwned   = errors.New("image is not held by this store")
+	ErrInvalidURL = errors.New("invalid image URL")
+)
+
+// ImageStore saves images and hands back the URL they are served from.
+type ImageStore interface {
+	Save(ctx context.Context, folder, filename string, image io.Reader) (string, error)
+	// Open reads back an image this store saved.
+	Open(ctx context.Context, url string) (io.ReadCloser, error)
+	// Delete removes an image this store saved; other URLs return ErrNotOwned.
+	Delete(ctx context.Context, url string) error
+	Owns(url string) bool
+}
+
+var Default ImageStore = NewLocalStore("uploads", "/uploads")
+
+// Init selects the image store from the IMAGE_STORE setting.
+func Init() {
+	switch config.IMAGE_STORE {
+	case "local":
+		Default = NewLocalStore(config.IMAGE_STORE_DIR, config.IMAGE_STORE_URL)
+	default:
+		Default = NewCloudinaryStore(config.InitCloudinary(), config.CLOUDINARY_CLOUD_NAME)
+	}
+	logger.Log.Info("Image store configured", zap.String("store", config.IMAGE_STORE))
+}
+
+// SaveUpload stores an uploaded form file.
+func SaveUpload(ctx context.Context, folder string, fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return Default.Save(ctx, folder, fileHeader.Filename, file)
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Fetch downloads an image from a remote URL.
+func Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, ErrInvalidURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: status %d", url, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// Open reads an image from the active store, or over HTTP for images held
+// elsewhere.
+func Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	if Default.Owns(url) {
+		return Default.Open(ctx, url)
+	}
+	return Fetch(ctx, url)
+}
+
+// SaveFromURL copies a remote image, such as a Google profile picture, into
+// the store.
+func SaveFromURL(ctx context.Context, folder, url string) (string, error) {
+	body, err := Fetch(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	return Default.Save(ctx, folder, path.Base(strings.SplitN(url, "?", 2)[0]), body)
+}
+
+// Delete removes an image from the store. Images left behind in another
+// store, for example before a migration, are only logged.
+func Delete(ctx context.Context, url string) error {
+	err := Default.Delete(ctx, url)
+	if errors.Is(err, ErrNotOwned) {
+		logger.Log.Warn("Skipping delete of image held by another store", zap.String("url", url))
+		return nil
+	}
+	return err
+}
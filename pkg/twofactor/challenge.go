@@ -0,0 +1,114 @@
+package twofactor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ChallengeTTL = 5 * time.Minute
+	// MaxAttempts wrong codes end the challenge and the password has to be
+	// entered again.
+	MaxAttempts = 5
+)
+
+var (
+	ErrChallengeExpired = errors.New("two-factor challenge expired")
+	ErrTooManyAttempts  = errors.New("too many invalid authentication codes")
+)
+
+// Result describes a successfully answered challenge.
+type Result struct {
+	// RecoveryCodes is set when the account enrolled while answering.
+	RecoveryCodes    []string
+	UsedRecoveryCode bool
+}
+
+func NewChallenge(db *gorm.DB, userID uint, email, role string) (*models.TwoFactorChallenge, error) {
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.TwoFactorChallenge{}).Error; err != nil {
+		return nil, err
+	}
+	challenge := models.TwoFactorChallenge{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Role:      role,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ChallengeTTL),
+	}
+	if err := db.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func FindChallenge(db *gorm.DB, id, role string) (*models.TwoFactorChallenge, error) {
+	var challenge models.TwoFactorChallenge
+	err := db.Where("id = ? AND role = ?", id, role).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChallengeExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrChallengeExpired
+	}
+	if challenge.Attempts >= MaxAttempts {
+		return nil, ErrTooManyAttempts
+	}
+	return &challenge, nil
+}
+
+// Answer checks code for a pending login. An account that has not finished
+// setting up 2FA, which only happens for admins, is enrolled by its first
+// valid code. The challenge is used up on success or after MaxAttempts
+// wrong codes.
+func Answer(db *gorm.DB, challenge *models.TwoFactorChallenge, code string) (*Result, error) {
+	// The attempt is counted before the code is checked, in the same
+	// statement as the limit, so concurrent guesses cannot get past it.
+	var claimed models.TwoFactorChallenge
+	claim := db.Model(&claimed).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Where("id = ? AND attempts < ?", challenge.ID, MaxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if claim.Error != nil {
+		return nil, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil, ErrTooManyAttempts
+	}
+	challenge.Attempts = claimed.Attempts
+
+	enabled, err := IsEnabled(db, challenge.UserID, challenge.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	if enabled {
+		result.UsedRecoveryCode, err = Verify(db, challenge.UserID, challenge.Role, code)
+	} else {
+		result.RecoveryCodes, err = Enable(db, challenge.UserID, challenge.Role, code)
+	}
+
+	if errors.Is(err, ErrInvalidCode) {
+		if challenge.Attempts >= MaxAttempts {
+			db.Delete(challenge)
+			return nil, ErrTooManyAttempts
+		}
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Delete(challenge).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,19 @@
+package twofactor
+
+import (
+	"encoding/base64"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const qrCodeSize = 240
+
+// QRCodeDataURI renders a provisioning URI as a PNG data URI that can be
+// put straight into an <img> tag.
+func QRCodeDataURI(uri string) (string, error) {
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
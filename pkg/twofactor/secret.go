@@ -0,0 +1,56 @@
+package twofactor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+)
+
+// Secrets are stored encrypted with AES-GCM under a key derived from
+// TWO_FACTOR_KEY, so a copy of the database alone cannot generate codes.
+
+func newCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(config.TWO_FACTOR_KEY))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealSecret(secret string) (string, error) {
+	aead, err := newCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openSecret(stored string) (string, error) {
+	aead, err := newCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("two-factor secret is too short")
+	}
+	secret, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("two-factor secret cannot be decrypted, was TWO_FACTOR_KEY changed?")
+	}
+	return string(secret), nil
+}
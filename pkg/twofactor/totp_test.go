@@ -0,0 +1,115 @@
+package twofactor
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of RFC 6238 Appendix B, "12345678901234567890",
+// in the base32 form users see.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// RFC 6238 Appendix B gives eight digit codes; the last six are the code for
+// six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	for _, vector := range rfcVectors {
+		want := vector.code[len(vector.code)-Digits:]
+		got, err := Code(rfcSecret, time.Unix(vector.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", vector.unix, err)
+		}
+		if got != want {
+			t.Errorf("Code(%d) = %s, want %s", vector.unix, got, want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseAndSpacedSecrets(t *testing.T) {
+	spaced := strings.ToLower(rfcSecret[:8] + " " + rfcSecret[8:])
+	got, err := Code(spaced, time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	if got != "287082" {
+		t.Errorf("Code = %s, want 287082", got)
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / Period
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(key, current), 0, current, true},
+		{"previous step within skew", codeAt(key, current-1), 0, current - 1, true},
+		{"next step within skew", codeAt(key, current+1), 0, current + 1, true},
+		{"two steps old", codeAt(key, current-2), 0, 0, false},
+		{"two steps ahead", codeAt(key, current+2), 0, 0, false},
+		{"replay of the last used step", codeAt(key, current), current, 0, false},
+		{"step before the last used one", codeAt(key, current-1), current, 0, false},
+		{"newer step after an older one was used", codeAt(key, current), current - 1, current, true},
+		{"wrong code", "000000", 0, 0, false},
+		{"too short", codeAt(key, current)[:Digits-1], 0, 0, false},
+		{"too long", codeAt(key, current) + "0", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateCode(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("validateCode(%q, lastStep %d) = %d, %v; want %d, %v",
+					tt.code, tt.lastStep, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateCodeRejectsBadSecret(t *testing.T) {
+	if _, ok := validateCode("not base32!", "123456", time.Now(), 0); ok {
+		t.Error("validateCode accepted a code for an invalid secret")
+	}
+}
+
+func TestGenerateSecretRoundTrips(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		t.Fatalf("decodeSecret(%q): %v", secret, err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret has %d bytes, want %d", len(key), secretSize)
+	}
+	code, err := Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := validateCode(secret, code, time.Now(), 0); !ok {
+		t.Error("a freshly generated code was refused")
+	}
+}
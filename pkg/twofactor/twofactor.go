@@ -0,0 +1,290 @@
+// Package twofactor implements TOTP two-factor sign-in (RFC 6238) with
+// one-time recovery codes. Admins must use it; customers can turn it on
+// from their settings. A login that passes the password check is parked as
+// a challenge until the second factor is given.
+package twofactor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const RecoveryCodeCount = 10
+
+var (
+	ErrNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrInvalidCode    = errors.New("invalid authentication code")
+)
+
+// Issuer is the account name shown in authenticator apps.
+func Issuer() string {
+	return config.CompanyConfig.Name
+}
+
+// Get returns the two-factor record of an account, or nil when it has never
+// started setting it up.
+func Get(db *gorm.DB, userID uint, role string) (*models.TwoFactorAuth, error) {
+	var record models.TwoFactorAuth
+	err := db.Where("user_id = ? AND role = ?", userID, role).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func IsEnabled(db *gorm.DB, userID uint, role string) (bool, error) {
+	record, err := Get(db, userID, role)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && record.EnabledAt != nil, nil
+}
+
+// Setup returns the secret to enrol with. A pending secret is reused, so
+// reloading the setup page keeps the QR code that may already be scanned.
+func Setup(db *gorm.DB, userID uint, role string) (string, error) {
+	record, err := Get(db, userID, role)
+	if err != nil {
+		return "", err
+	}
+	if record != nil {
+		if record.EnabledAt != nil {
+			return "", ErrAlreadyEnabled
+		}
+		return openSecret(record.Secret)
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	record = &models.TwoFactorAuth{UserID: userID, Role: role, Secret: sealed}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
+		return "", err
+	}
+	if record.ID == 0 {
+		// Another request created the record first; use its secret.
+		return Setup(db, userID, role)
+	}
+	return secret, nil
+}
+
+// Enable turns two-factor sign-in on once code proves the secret was set up
+// correctly, and returns the recovery codes to show the user once.
+func Enable(db *gorm.DB, userID uint, role, code string) ([]string, error) {
+	tx := db.Begin()
+	record, err := lockRecord(tx, userID, role)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if record.EnabledAt != nil {
+		tx.Rollback()
+		return nil, ErrAlreadyEnabled
+	}
+	if err := checkTOTP(tx, record, code); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Model(record).Update("enabled_at", time.Now()).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	codes, err := replaceRecoveryCodes(tx, record.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks an authenticator code or an unused recovery code. It
+// reports whether a recovery code was spent.
+func Verify(db *gorm.DB, userID uint, role, code string) (bool, error) {
+	tx := db.Begin()
+	record, err := lockRecord(tx, userID, role)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if record.EnabledAt == nil {
+		tx.Rollback()
+		return false, ErrNotEnabled
+	}
+
+	code = normalizeCode(code)
+	usedRecovery := len(code) != Digits
+	if usedRecovery {
+		err = useRecoveryCode(tx, record.ID, code)
+	} else {
+		err = checkTOTP(tx, record, code)
+	}
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	return usedRecovery, nil
+}
+
+// Disable removes the secret and recovery codes. For admins this means they
+// set up a new authenticator at their next login.
+func Disable(db *gorm.DB, userID uint, role string) error {
+	record, err := Get(db, userID, role)
+	if err != nil || record == nil {
+		return err
+	}
+
+	tx := db.Begin()
+	if err := tx.Where("two_factor_auth_id = ?", record.ID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(record).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not.
+func RegenerateRecoveryCodes(db *gorm.DB, userID uint, role string) ([]string, error) {
+	tx := db.Begin()
+	record, err := lockRecord(tx, userID, role)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if record.EnabledAt == nil {
+		tx.Rollback()
+		return nil, ErrNotEnabled
+	}
+	codes, err := replaceRecoveryCodes(tx, record.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func RecoveryCodesLeft(db *gorm.DB, userID uint, role string) (int64, error) {
+	var count int64
+	err := db.Model(&models.TwoFactorRecoveryCode{}).
+		Joins("JOIN two_factor_auths ON two_factor_auths.id = two_factor_recovery_codes.two_factor_auth_id").
+		Where("two_factor_auths.user_id = ? AND two_factor_auths.role = ? AND two_factor_recovery_codes.used_at IS NULL", userID, role).
+		Count(&count).Error
+	return count, err
+}
+
+func lockRecord(tx *gorm.DB, userID uint, role string) (*models.TwoFactorAuth, error) {
+	var record models.TwoFactorAuth
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND role = ?", userID, role).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// checkTOTP accepts a code once; the matched step is stored so the same
+// code cannot be used again while it is still valid.
+func checkTOTP(tx *gorm.DB, record *models.TwoFactorAuth, code string) error {
+	secret, err := openSecret(record.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := validateCode(secret, normalizeCode(code), time.Now(), record.LastUsedStep)
+	if !ok {
+		return ErrInvalidCode
+	}
+	return tx.Model(record).Update("last_used_step", step).Error
+}
+
+func useRecoveryCode(tx *gorm.DB, recordID uint, code string) error {
+	result := tx.Model(&models.TwoFactorRecoveryCode{}).
+		Where("two_factor_auth_id = ? AND code_hash = ? AND used_at IS NULL", recordID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, recordID uint) ([]string, error) {
+	if err := tx.Where("two_factor_auth_id = ?", recordID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, RecoveryCodeCount)
+	rows := make([]models.TwoFactorRecoveryCode, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.TwoFactorRecoveryCode{TwoFactorAuthID: recordID, CodeHash: hashRecoveryCode(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// recoveryAlphabet leaves out characters that are easy to misread.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// newRecoveryCode returns a code like "k7m2q-x9fhd".
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = recoveryAlphabet[int(b)%len(recoveryAlphabet)]
+	}
+	return fmt.Sprintf("%s-%s", buf[:5], buf[5:]), nil
+}
+
+// normalizeCode drops spaces and dashes and lowercases, so codes can be
+// typed the way they were shown or copied.
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,6 +3,8 @@ package routes
 import (
 	controllers "github.com/anfastk/E-Commerce-Website/controllers/admin"
 	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/anfastk/E-Commerce-Website/pkg/rbac"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,13 +14,15 @@ func AdminRoutes(r *gin.Engine) {
 	admin := r.Group("/admin")
 	{
 		admin.GET("/login", controllers.ShowLoginPage)
-		admin.POST("/login", controllers.AdminLoginHandler)
+		admin.POST("/login", middleware.RateLimit(ratelimit.RuleAdminLogin, middleware.AccountFromField("email")), controllers.AdminLoginHandler)
+		admin.GET("/login/2fa", controllers.ShowAdminTwoFactor)
+		admin.POST("/login/2fa", middleware.RateLimit(ratelimit.RuleTwoFactor, nil), controllers.VerifyAdminTwoFactor)
 		admin.GET("/settings", controllers.ShowSettings)
 		admin.POST("/logout", controllers.AdminLogoutHandler)
 	}
 	// Admin Product Managemant
 	product := r.Group("/admin/products")
-	product.Use(middleware.AuthMiddleware(RoleAdmin))
+	product.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermCatalog))
 	{
 		product.GET("/", controllers.ShowProductsAdmin)
 		product.GET("/main/add", controllers.ShowAddMainProduct)
@@ -43,10 +47,13 @@ func AdminRoutes(r *gin.Engine) {
 		product.DELETE("/variant/specification/delete/:id", controllers.DeleteSpecification)
 		product.DELETE("/variant/description/delete/:id", controllers.DeleteDescription)
 		product.PATCH("/variant/update/specification/:id", controllers.UpdateProductSpecification)
+		product.GET("/filters", controllers.ShowProductFilters)
+		product.POST("/filters/add", controllers.AddProductFilter)
+		product.POST("/filters/:id/delete", controllers.DeleteProductFilter)
 	}
 	// Admin User Managemant
 	adminUser := r.Group("/admin/users")
-	adminUser.Use(middleware.AuthMiddleware(RoleAdmin))
+	adminUser.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermCustomers))
 	{
 		adminUser.GET("/", controllers.ListUsers)
 		adminUser.GET("/search", controllers.SearchUsers)
@@ -55,7 +62,7 @@ func AdminRoutes(r *gin.Engine) {
 	}
 	// Admin Category Managemant
 	category := r.Group("/admin/category")
-	category.Use(middleware.AuthMiddleware(RoleAdmin))
+	category.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermCatalog))
 	{
 		category.GET("/", controllers.ListCategory)
 		category.PATCH("/:id/edit", controllers.EditCategory)
@@ -67,7 +74,7 @@ func AdminRoutes(r *gin.Engine) {
 		category.POST("/delete/offer", controllers.DeleteCategoryOffer)
 	}
 	OrderList := r.Group("/admin/orderlist")
-	OrderList.Use(middleware.AuthMiddleware(RoleAdmin))
+	OrderList.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermOrders))
 	{
 		OrderList.GET("/", controllers.ShowOrderManagement)
 		OrderList.GET("/search", controllers.SearchOrders)
@@ -77,7 +84,7 @@ func AdminRoutes(r *gin.Engine) {
 	}
 	// Admin Coupon Management
 	coupon := r.Group("/admin/coupon")
-	coupon.Use(middleware.AuthMiddleware(RoleAdmin))
+	coupon.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermCoupons))
 	{
 		coupon.GET("/", controllers.ShowCoupon)
 		coupon.POST("/add", controllers.AddCoupon)
@@ -87,7 +94,7 @@ func AdminRoutes(r *gin.Engine) {
 	}
 	//Admin Sales
 	sales := r.Group("/sales")
-	sales.Use(middleware.AuthMiddleware(RoleAdmin))
+	sales.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermFinance))
 	{
 		sales.GET("/", controllers.GetSalesDashboard)
 		sales.GET("/filter", controllers.GetSalesData)
@@ -96,15 +103,106 @@ func AdminRoutes(r *gin.Engine) {
 	}
 	// Admin Wallet Management
 	wallet := r.Group("/admin/wallet")
-	wallet.Use(middleware.AuthMiddleware(RoleAdmin))
+	wallet.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermFinance))
 	{
 		wallet.GET("/management/search", controllers.SearchWalletTransactions)
 		wallet.GET("/management", controllers.ShowWalletManagement)
 		wallet.GET("/management/details/:id", controllers.ShowTransactionDetails)
 	}
 
+	review := r.Group("/admin/reviews")
+	review.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermCatalog))
+	{
+		review.GET("/", controllers.ShowReviewModeration)
+		review.POST("/:id/hide", controllers.HideReview)
+	}
+
+	taxRule := r.Group("/admin/tax-rules")
+	taxRule.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermFinance))
+	{
+		taxRule.GET("/", controllers.ShowTaxRules)
+		taxRule.POST("/add", controllers.AddTaxRule)
+		taxRule.POST("/:id/toggle", controllers.ToggleTaxRule)
+		taxRule.POST("/:id/delete", controllers.DeleteTaxRule)
+	}
+
+	exchangeRate := r.Group("/admin/currencies")
+	exchangeRate.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermFinance))
+	{
+		exchangeRate.GET("/", controllers.ShowCurrencies)
+		exchangeRate.POST("/rate", controllers.SetExchangeRate)
+		exchangeRate.POST("/import", controllers.ImportExchangeRates)
+	}
+
+	shipping := r.Group("/admin/shipping")
+	shipping.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermOrders))
+	{
+		shipping.GET("/", controllers.ShowShippingRules)
+		shipping.POST("/zones/add", controllers.AddShippingZone)
+		shipping.POST("/zones/:id/toggle", controllers.ToggleShippingZone)
+		shipping.POST("/zones/:id/delete", controllers.DeleteShippingZone)
+		shipping.POST("/zones/:id/slabs/add", controllers.AddShippingSlab)
+		shipping.POST("/slabs/:id/delete", controllers.DeleteShippingSlab)
+	}
+
+	pinCode := r.Group("/admin/pincodes")
+	pinCode.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermOrders))
+	{
+		pinCode.GET("/", controllers.ShowPinCodes)
+		pinCode.POST("/add", controllers.AddPinCode)
+		pinCode.POST("/import", controllers.ImportPinCodes)
+		pinCode.POST("/:id/delete", controllers.DeletePinCode)
+	}
+
+	refund := r.Group("/admin/refunds")
+	refund.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermFinance))
+	{
+		refund.GET("/", controllers.ShowRefunds)
+		refund.POST("/:id/retry", controllers.RetryRefund)
+		refund.POST("/:id/reconcile", controllers.ReconcileRefund)
+		refund.POST("/:id/release", controllers.ReleaseRefund)
+	}
+
+	job := r.Group("/admin/jobs")
+	job.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermSystem))
+	{
+		job.GET("/", controllers.ShowJobs)
+		job.POST("/:name/trigger", controllers.TriggerJob)
+		job.POST("/:name/pause", controllers.ToggleJobPause)
+	}
+
+	outboxMessages := r.Group("/admin/outbox")
+	outboxMessages.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermSystem))
+	{
+		outboxMessages.GET("/", controllers.ShowOutbox)
+		outboxMessages.POST("/:id/requeue", controllers.RequeueOutboxMessage)
+	}
+
+	staff := r.Group("/admin/staff")
+	staff.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermStaff))
+	{
+		staff.GET("/", controllers.ShowAdminUsers)
+		staff.POST("/add", controllers.AddAdminUser)
+		staff.POST("/:id/role", controllers.ChangeAdminRole)
+		staff.POST("/:id/toggle", controllers.ToggleAdminActive)
+		staff.POST("/:id/reset-2fa", controllers.ResetAdminTwoFactor)
+	}
+
+	lockedAccounts := r.Group("/admin/locked-accounts")
+	lockedAccounts.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermCustomers))
+	{
+		lockedAccounts.GET("/", controllers.ShowLockedAccounts)
+		lockedAccounts.POST("/:id/unlock", controllers.UnlockAccount)
+	}
+
+	auditLog := r.Group("/admin/audit-log")
+	auditLog.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermStaff))
+	{
+		auditLog.GET("/", controllers.ShowAuditLog)
+	}
+
 	adminDashboard := r.Group("/admin/dashboard")
-	adminDashboard.Use(middleware.AuthMiddleware(RoleAdmin))
+	adminDashboard.Use(middleware.AuthMiddleware(RoleAdmin), middleware.RequirePermission(rbac.PermDashboard))
 	{
 		adminDashboard.GET("/", controllers.DashboardHandler)
 		adminDashboard.GET("/stats", controllers.StatsHandler)
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	api "github.com/anfastk/E-Commerce-Website/controllers/api"
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// APIRoutes registers the JSON API for mobile and other clients. It signs in
+// with bearer tokens instead of cookies and answers in api.Envelope.
+func APIRoutes(r *gin.Engine) {
+	v1 := r.Group("/api/v1")
+
+	auth := v1.Group("/auth")
+	{
+		auth.POST("/login", api.RateLimit(ratelimit.RuleUserLogin, middleware.AccountFromField("email")), api.Login)
+		auth.POST("/login/2fa", api.RateLimit(ratelimit.RuleTwoFactor, nil), api.LoginTwoFactor)
+		auth.POST("/refresh", api.Refresh)
+		auth.POST("/logout", api.RequireUser(), api.Logout)
+	}
+
+	v1.GET("/products", api.ListProducts)
+	v1.GET("/products/:id", api.GetProduct)
+	v1.GET("/categories", api.ListCategories)
+
+	user := v1.Group("")
+	user.Use(api.RequireUser())
+	{
+		user.GET("/cart", api.GetCart)
+		user.POST("/cart/items", api.AddCartItem)
+		user.PATCH("/cart/items/:id", api.UpdateCartItem)
+		user.DELETE("/cart/items/:id", api.RemoveCartItem)
+		user.GET("/addresses", api.ListAddresses)
+		user.POST("/checkout", api.StartCheckout)
+		user.GET("/checkout/quote", api.GetQuote)
+		user.POST("/orders", api.PlaceOrder)
+		user.GET("/orders", api.ListOrders)
+		user.GET("/orders/:id", api.GetOrder)
+	}
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	admin "github.com/anfastk/E-Commerce-Website/controllers/admin"
+	api "github.com/anfastk/E-Commerce-Website/controllers/api"
+	user "github.com/anfastk/E-Commerce-Website/controllers/user"
+	"github.com/anfastk/E-Commerce-Website/pkg/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// DocsRoutes serves the OpenAPI document for every registered route. It is
+// built on the first request, once all routes are in place.
+func DocsRoutes(r *gin.Engine) {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	r.GET(docsPath, func(c *gin.Context) {
+		once.Do(func() {
+			doc = buildDocument(r.Routes())
+		})
+		c.JSON(http.StatusOK, doc)
+	})
+}
+
+const docsPath = "/api/openapi.json"
+
+// buildDocument documents the shop's routes with the descriptions each
+// controller package keeps in its Operations list.
+func buildDocument(registered gin.RoutesInfo) *openapi.Document {
+	return openapi.Build(openapi.Info{
+		Title:       "E-Commerce Website",
+		Version:     "1.0",
+		Description: "Storefront, admin panel and JSON API.",
+	}, documentedRoutes(registered), user.Operations, admin.Operations, api.Operations)
+}
+
+func documentedRoutes(registered gin.RoutesInfo) []openapi.Route {
+	var routes []openapi.Route
+	for _, route := range registered {
+		// Static file handlers belong to gin, not to the shop.
+		if strings.HasPrefix(route.Handler, "github.com/gin-gonic/") || route.Path == docsPath {
+			continue
+		}
+		routes = append(routes, openapi.Route{Method: route.Method, Path: route.Path, Handler: route.Handler})
+	}
+	return routes
+}
@@ -0,0 +1,308 @@
+package routes
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	admin "github.com/anfastk/E-Commerce-Website/controllers/admin"
+	api "github.com/anfastk/E-Commerce-Website/controllers/api"
+	user "github.com/anfastk/E-Commerce-Website/controllers/user"
+	"github.com/anfastk/E-Commerce-Website/pkg/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+const modulePath = "github.com/anfastk/E-Commerce-Website"
+
+// bindKind is how a gin binding call reads its input.
+type bindKind int
+
+const (
+	bindJSON  bindKind = iota // ShouldBindJSON, BindJSON
+	bindQuery                 // ShouldBindQuery, BindQuery
+	bindAny                   // ShouldBind, Bind: JSON or form by Content-Type
+)
+
+var bindMethods = map[string]bindKind{
+	"ShouldBindJSON":  bindJSON,
+	"BindJSON":        bindJSON,
+	"ShouldBindQuery": bindQuery,
+	"BindQuery":       bindQuery,
+	"ShouldBind":      bindAny,
+	"Bind":            bindAny,
+}
+
+// binding is one struct a handler binds its input into.
+type binding struct {
+	kind bindKind
+	// typeName is the bound type as "import/path.Name".
+	typeName string
+	pos      token.Position
+}
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	AdminRoutes(r)
+	UserRouter(r)
+	APIRoutes(r)
+	DocsRoutes(r)
+	return r
+}
+
+// TestDocumentCoversRoutes fails when a registered route is missing from the
+// document or a description names a handler no route uses.
+func TestDocumentCoversRoutes(t *testing.T) {
+	r := newRouter()
+	routes := documentedRoutes(r.Routes())
+	doc := buildDocument(r.Routes())
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		registered[route.Handler] = true
+		path := openapiPath(route.Path)
+		if doc.Paths[path][strings.ToLower(route.Method)] == nil {
+			t.Errorf("%s %s is registered but not in the document", route.Method, route.Path)
+		}
+	}
+
+	documented := 0
+	for _, methods := range doc.Paths {
+		documented += len(methods)
+	}
+	if documented != len(routes) {
+		t.Errorf("document has %d operations for %d registered routes", documented, len(routes))
+	}
+
+	for _, description := range allDescriptions() {
+		if !registered[description.Handler()] {
+			t.Errorf("%s is described but no route uses it", description.Handler())
+		}
+	}
+}
+
+// TestDescriptionsMatchBindings fails when a handler binds a type its
+// description does not name, or a description names a type the handler
+// never binds. Every handler that binds its input has to be described.
+func TestDescriptionsMatchBindings(t *testing.T) {
+	r := newRouter()
+	described := make(map[string]openapi.Operation)
+	for _, description := range allDescriptions() {
+		described[description.Handler()] = description.Operation()
+	}
+
+	checked := make(map[string]bool)
+	for _, route := range documentedRoutes(r.Routes()) {
+		if checked[route.Handler] {
+			continue
+		}
+		checked[route.Handler] = true
+
+		bindings, ok := handlerBindings(t, route.Handler)
+		if !ok {
+			continue
+		}
+		operation, isDescribed := described[route.Handler]
+		if !isDescribed {
+			for _, b := range bindings {
+				t.Errorf("%s binds %s (%s) but is not described", route.Handler, b.typeName, b.pos)
+			}
+			continue
+		}
+		checkBindings(t, route.Handler, operation, bindings)
+	}
+}
+
+func checkBindings(t *testing.T, handler string, operation openapi.Operation, bindings []binding) {
+	t.Helper()
+	request, query := typeName(operation.Request), typeName(operation.Query)
+	var boundRequest, boundQuery bool
+	for _, b := range bindings {
+		switch {
+		case b.kind == bindQuery && b.typeName == query:
+			boundQuery = true
+		case b.kind == bindJSON && b.typeName == request && !operation.Form:
+			boundRequest = true
+		case b.kind == bindAny && b.typeName == request:
+			boundRequest = true
+		case b.kind == bindJSON && b.typeName == request:
+			t.Errorf("%s binds %s from JSON but is described as a form (%s)", handler, b.typeName, b.pos)
+		default:
+			t.Errorf("%s binds %s (%s) but is described with Request %q and Query %q", handler, b.typeName, b.pos, request, query)
+		}
+	}
+	if request != "" && !boundRequest {
+		t.Errorf("%s is described with Request %s but does not bind it", handler, request)
+	}
+	if query != "" && !boundQuery {
+		t.Errorf("%s is described with Query %s but does not bind it", handler, query)
+	}
+}
+
+func allDescriptions() []openapi.Description {
+	var all []openapi.Description
+	for _, list := range [][]openapi.Description{user.Operations, admin.Operations, api.Operations} {
+		all = append(all, list...)
+	}
+	return all
+}
+
+func typeName(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// openapiPath writes gin's :id and *path parameters the way the document
+// does.
+func openapiPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// parsedPackages caches the controller packages by import path.
+var parsedPackages = map[string]*parsedPackage{}
+
+type parsedPackage struct {
+	fset  *token.FileSet
+	funcs map[string]*ast.FuncDecl
+	files map[*ast.FuncDecl]*ast.File
+}
+
+// handlerBindings finds the binding calls in the handler's source. Handlers
+// outside this module, such as gin's, report false.
+func handlerBindings(t *testing.T, handler string) ([]binding, bool) {
+	t.Helper()
+	if !strings.HasPrefix(handler, modulePath+"/") {
+		return nil, false
+	}
+	// "…/controllers/admin.ShowRefunds", or "….NewHandler.func1" for a
+	// closure, whose body is inside NewHandler.
+	slash := strings.LastIndex(handler, "/")
+	dot := slash + strings.Index(handler[slash:], ".")
+	importPath, name := handler[:dot], handler[dot+1:]
+	name = strings.TrimSuffix(strings.SplitN(name, ".", 2)[0], "-fm")
+
+	pkg := parsePackage(t, importPath)
+	decl := pkg.funcs[name]
+	if decl == nil || decl.Body == nil {
+		t.Errorf("cannot find the source of %s", handler)
+		return nil, false
+	}
+	file := pkg.files[decl]
+
+	vars := make(map[string]ast.Expr)
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		if spec, ok := node.(*ast.ValueSpec); ok && spec.Type != nil {
+			for _, ident := range spec.Names {
+				vars[ident.Name] = spec.Type
+			}
+		}
+		return true
+	})
+
+	var bindings []binding
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		kind, ok := bindMethods[selector.Sel.Name]
+		if !ok {
+			return true
+		}
+		pos := pkg.fset.Position(call.Pos())
+		var variable *ast.Ident
+		if target, ok := call.Args[0].(*ast.UnaryExpr); ok && target.Op == token.AND {
+			variable, _ = target.X.(*ast.Ident)
+		}
+		if variable == nil || vars[variable.Name] == nil {
+			t.Errorf("%s: cannot tell what %s binds into; bind into a declared variable", pos, handler)
+			return true
+		}
+		bindings = append(bindings, binding{
+			kind:     kind,
+			typeName: resolveType(file, importPath, vars[variable.Name]),
+			pos:      pos,
+		})
+		return true
+	})
+	return bindings, true
+}
+
+func parsePackage(t *testing.T, importPath string) *parsedPackage {
+	t.Helper()
+	if pkg, ok := parsedPackages[importPath]; ok {
+		return pkg
+	}
+	dir := filepath.Join("..", filepath.FromSlash(strings.TrimPrefix(importPath, modulePath+"/")))
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatalf("list %s: %v", dir, err)
+	}
+	pkg := &parsedPackage{fset: fset, funcs: make(map[string]*ast.FuncDecl), files: make(map[*ast.FuncDecl]*ast.File)}
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, d := range file.Decls {
+			if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil {
+				pkg.funcs[fn.Name.Name] = fn
+				pkg.files[fn] = file
+			}
+		}
+	}
+	parsedPackages[importPath] = pkg
+	return pkg
+}
+
+// resolveType names a type expression as "import/path.Name", looking up
+// package qualifiers in the file's imports.
+func resolveType(file *ast.File, importPath string, expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return resolveType(file, importPath, e.X)
+	case *ast.Ident:
+		return importPath + "." + e.Name
+	case *ast.SelectorExpr:
+		qualifier, ok := e.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			if name == qualifier.Name {
+				return path + "." + e.Sel.Name
+			}
+		}
+	}
+	return "unknown"
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	controllers "github.com/anfastk/E-Commerce-Website/controllers/user"
 	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,19 +19,23 @@ func UserRouter(r *gin.Engine) {
 		auth.POST("/signup", controllers.SignUp)
 		auth.GET("/signup/otp", controllers.SendOtp)
 		auth.GET("/signup/verifyotp", controllers.ShowOtpVerifyPage)
-		auth.POST("/signup/verifyotp", controllers.VerifyOtp)
-		auth.POST("/signup/otp/resend", controllers.ResendOTP)
+		auth.POST("/signup/verifyotp", middleware.RateLimit(ratelimit.RuleOTPVerify, middleware.AccountFromField("email")), controllers.VerifyOtp)
+		auth.POST("/signup/otp/resend", middleware.RateLimit(ratelimit.RuleOTPResend, controllers.OTPSessionEmail), controllers.ResendOTP)
 		auth.GET("/login", controllers.ShowLogin)
-		auth.POST("/login", controllers.UserLoginHandler)
+		auth.POST("/login", middleware.RateLimit(ratelimit.RuleUserLogin, middleware.AccountFromField("email")), controllers.UserLoginHandler)
+		auth.GET("/login/2fa", controllers.ShowTwoFactorLogin)
+		auth.POST("/login/2fa", middleware.RateLimit(ratelimit.RuleTwoFactor, nil), controllers.VerifyTwoFactorLogin)
 		auth.GET("/forgot/password", controllers.ForgotPasswordEmail)
-		auth.POST("/forgot/password", controllers.ForgotUserEmail)
-		auth.POST("/reset/password", controllers.PasswordReset)
+		auth.POST("/forgot/password", middleware.RateLimit(ratelimit.RuleForgotPassword, middleware.AccountFromField("email")), controllers.ForgotUserEmail)
+		auth.POST("/reset/password", middleware.RateLimit(ratelimit.RuleForgotPassword, middleware.AccountFromField("email")), controllers.PasswordReset)
 		auth.POST("/logout", middleware.AuthMiddleware(RoleUser), controllers.UserLogoutHandler)
 	}
 	r.GET("/", controllers.UserHome)
 	r.GET("/products", controllers.ShowProducts)
 	r.GET("/products/details/:id", controllers.ShowProductDetail)
 	r.GET("/products/filter", controllers.FilterProducts)
+	r.GET("/products/search/suggestions", controllers.SearchSuggestions)
+	r.GET("/products/delivery/check", controllers.CheckProductDelivery)
 	r.POST("/checkout/payment/verify", middleware.AuthMiddleware(RoleUser), controllers.VerifyRazorpayPayment)
 	r.POST("/order/failed", middleware.AuthMiddleware(RoleUser), controllers.PaymentFailureHandler)
 	r.GET("/contactUs", controllers.ShowContactUs)
@@ -49,6 +54,13 @@ func UserRouter(r *gin.Engine) {
 		userProfile.POST("/address/:id/default", controllers.SetAsDefaultAddress)
 		userProfile.DELETE("/delete/address/:id", controllers.DeleteAddress)
 		userProfile.GET("/settings", controllers.Settings)
+		userProfile.POST("/settings/currency", controllers.UpdateCurrency)
+		userProfile.POST("/sessions/:id/revoke", controllers.RevokeSession)
+		userProfile.POST("/sessions/logout/all", controllers.LogoutEverywhere)
+		userProfile.POST("/2fa/setup", controllers.SetupTwoFactor)
+		userProfile.POST("/2fa/enable", controllers.EnableTwoFactor)
+		userProfile.POST("/2fa/disable", controllers.DisableTwoFactor)
+		userProfile.POST("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
 		userProfile.GET("/change/password", controllers.ShowChangePassword)
 		userProfile.POST("/change/password", controllers.ChangePassword)
 		userProfile.GET("/order/details", controllers.OrderDetails)
@@ -85,11 +97,12 @@ func UserRouter(r *gin.Engine) {
 	{
 		checkout.POST("/", controllers.ShowCheckoutPage)
 		checkout.POST("/addresses", controllers.ShippingAddress)
+		checkout.GET("/shipping/:id", controllers.CheckoutShippingQuote)
 		checkout.POST("/payment", controllers.PaymentPage)
 		checkout.POST("/payment/proceed", controllers.ProceedToPayment)
-		checkout.POST("/check/coupon", controllers.CheckCoupon)
+		checkout.POST("/check/coupon", middleware.RateLimit(ratelimit.RuleCoupon, middleware.AccountFromUser(RoleUser)), controllers.CheckCoupon)
 		checkout.GET("/check/wallet/balance", controllers.FetchWalletBalance)
-		checkout.POST("/redeem/gift/code", controllers.RedeemGiftCard)
+		checkout.POST("/redeem/gift/code", middleware.RateLimit(ratelimit.RuleGiftCard, middleware.AccountFromUser(RoleUser)), controllers.RedeemGiftCard)
 	}
 
 	wishlist := r.Group("/wishlist")
@@ -102,5 +115,13 @@ func UserRouter(r *gin.Engine) {
 		wishlist.POST("/remove/:id", controllers.RemoveFromWishlist)
 	}
 
+	review := r.Group("/review")
+	review.Use(middleware.AuthMiddleware(RoleUser))
+	{
+		review.POST("/add/:id", controllers.SubmitReview)
+		review.PATCH("/edit/:id", controllers.EditReview)
+		review.DELETE("/delete/:id", controllers.DeleteReview)
+	}
+
 	r.NoRoute(controllers.Handle404Error)
 }
@@ -1,34 +1,23 @@
 package services
 
 import (
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
 	"github.com/anfastk/E-Commerce-Website/utils/helper"
 )
- 
-func CalculateCartPrices(cartItems []CartItemDetailWithDiscount) (float64, float64, float64, float64, float64, int) {
-	var (
-		regularPrice    float64
-		salePrice       float64
-		productDiscount float64
-		tax             float64
-		totalDiscount   float64
-	)
-	shippingCharge := 100
 
+func CalculateCartPrices(cartItems []CartItemDetailWithDiscount, pinCode string, state string) (float64, float64, float64, float64, float64, ShippingQuote) {
+	var regularPrice, salePrice, tax money.Amount
 	for _, item := range cartItems {
 		discountAmount, _, _ := helper.DiscountCalculation(item.CartItem.ProductID, item.ProductDetails.CategoryID, item.ProductDetails.RegularPrice, item.ProductDetails.SalePrice)
-		regularPrice += item.ProductDetails.RegularPrice * float64(item.CartItem.Quantity)
-		salePrice += (item.ProductDetails.SalePrice - discountAmount) * float64(item.CartItem.Quantity)
+		regularPrice += money.FromRupees(item.ProductDetails.RegularPrice).Times(item.CartItem.Quantity)
+		lineSalePrice := money.FromRupees(item.ProductDetails.SalePrice - discountAmount).Times(item.CartItem.Quantity)
+		salePrice += lineSalePrice
+		tax += money.FromRupees(CalculateProductTax(item.ProductDetails.ProductID, item.ProductDetails.CategoryID, lineSalePrice.Rupees(), "").ChargedTax)
 	}
 
-	tax = (salePrice * 18) / 100
-	productDiscount = regularPrice - salePrice
+	productDiscount := regularPrice - salePrice
+	shipping := CalculateShipping(CartShippingItems(cartItems), pinCode, state)
+	totalDiscount := productDiscount + money.FromRupees(shipping.WaivedCharge)
 
-	if salePrice < 1000 {
-		totalDiscount = productDiscount
-	} else {
-		totalDiscount = productDiscount + float64(shippingCharge)
-		shippingCharge = 0
-	}
-
-	return regularPrice, salePrice, tax, productDiscount, totalDiscount, shippingCharge
+	return regularPrice.Rupees(), salePrice.Rupees(), tax.Rupees(), productDiscount.Rupees(), totalDiscount.Rupees(), shipping
 }
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"gorm.io/gorm"
+)
+
+// MaxCartQuantity is the most units of one variant a cart can hold.
+const MaxCartQuantity = 3
+
+var (
+	ErrProductNotFound  = errors.New("product not found")
+	ErrOutOfStock       = errors.New("product out of stock")
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrCartEmpty        = errors.New("cart is empty")
+	ErrMaxCartQuantity  = errors.New("maximum quantity exceeded")
+	ErrNotEnoughStock   = errors.New("not enough stock available")
+)
+
+// AddCartItem puts one unit of the variant in the user's cart, creating the
+// cart on first use. Adding a variant already at MaxCartQuantity leaves it
+// as it is.
+func AddCartItem(db *gorm.DB, userID, variantID uint) (models.CartItem, error) {
+	tx := db.Begin()
+
+	var cart models.Cart
+	if err := tx.First(&cart, "user_id = ?", userID).Error; err != nil {
+		cart = models.Cart{UserID: userID}
+		if err := tx.Create(&cart).Error; err != nil {
+			tx.Rollback()
+			return models.CartItem{}, err
+		}
+	}
+
+	var product models.ProductVariantDetails
+	if err := tx.First(&product, variantID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.CartItem{}, ErrProductNotFound
+		}
+		return models.CartItem{}, err
+	}
+	if product.StockQuantity == 0 {
+		tx.Rollback()
+		return models.CartItem{}, ErrOutOfStock
+	}
+
+	var item models.CartItem
+	if err := tx.First(&item, "product_id = ? AND product_variant_id = ? AND cart_id = ?", product.ProductID, product.ID, cart.ID).Error; err != nil {
+		item = models.CartItem{
+			CartID:           cart.ID,
+			ProductID:        product.ProductID,
+			ProductVariantID: product.ID,
+			Quantity:         1,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			tx.Rollback()
+			return models.CartItem{}, err
+		}
+	} else if item.Quantity < MaxCartQuantity {
+		item.Quantity++
+		if err := tx.Model(&item).Update("quantity", item.Quantity).Error; err != nil {
+			tx.Rollback()
+			return models.CartItem{}, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return models.CartItem{}, err
+	}
+	return item, nil
+}
+
+// FindCartItem returns an item of the user's own cart.
+func FindCartItem(db *gorm.DB, userID, itemID uint) (models.CartItem, error) {
+	var item models.CartItem
+	err := db.Where("id = ? AND cart_id = (SELECT id FROM carts WHERE user_id = ?)", itemID, userID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, ErrCartItemNotFound
+	}
+	return item, err
+}
+
+// SetCartItemQuantity changes how many units of an item the user wants.
+// A quantity of zero or less removes the item; the returned item then has
+// a quantity of zero.
+func SetCartItemQuantity(db *gorm.DB, userID, itemID uint, quantity int) (models.CartItem, error) {
+	item, err := FindCartItem(db, userID, itemID)
+	if err != nil {
+		return item, err
+	}
+
+	if quantity <= 0 {
+		if err := db.Unscoped().Delete(&item).Error; err != nil {
+			return item, err
+		}
+		item.Quantity = 0
+		return item, nil
+	}
+
+	if quantity > item.Quantity {
+		if quantity > MaxCartQuantity {
+			return item, ErrMaxCartQuantity
+		}
+		var product models.ProductVariantDetails
+		if err := db.First(&product, item.ProductVariantID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return item, ErrProductNotFound
+			}
+			return item, err
+		}
+		if quantity > product.StockQuantity {
+			return item, ErrNotEnoughStock
+		}
+	}
+
+	item.Quantity = quantity
+	if err := db.Model(&item).Update("quantity", quantity).Error; err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// RemoveCartItem deletes an item from the user's cart.
+func RemoveCartItem(db *gorm.DB, userID, itemID uint) error {
+	item, err := FindCartItem(db, userID, itemID)
+	if err != nil {
+		return err
+	}
+	return db.Unscoped().Delete(&item).Error
+}
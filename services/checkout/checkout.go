@@ -0,0 +1,386 @@
+// Package checkout turns a user's reserved cart into an order. It owns the
+// transaction and reports problems as errors, so the HTML pages and the JSON
+// API place orders the same way and only differ in how they answer.
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/anfastk/E-Commerce-Website/pkg/orderlifecycle"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MethodCOD      = "COD"
+	MethodWallet   = "Wallet"
+	MethodRazorpay = "Razorpay"
+
+	// CODLimit is the largest order that can be paid on delivery.
+	CODLimit = 75000
+)
+
+var (
+	ErrCartChanged         = errors.New("cart changed since checkout started")
+	ErrReservationExpired  = errors.New("checkout expired")
+	ErrProductUnavailable  = errors.New("product unavailable")
+	ErrAddressNotFound     = errors.New("address not found")
+	ErrNotServiceable      = errors.New("delivery not available to this address")
+	ErrCODUnavailable      = errors.New("cash on delivery not available")
+	ErrInsufficientBalance = errors.New("insufficient wallet balance")
+	ErrUnsupportedPayment  = errors.New("payment method not supported")
+	ErrPaymentNotFound     = errors.New("checkout payment not found")
+	ErrAmountMismatch      = errors.New("amount paid does not match the order total")
+	// ErrPaymentRefunded wraps the reason a captured gateway payment could
+	// not be placed as an order. The payment is refunded instead.
+	ErrPaymentRefunded = errors.New("order not placed, payment refunded")
+)
+
+// Request is what the user picked on the payment step.
+type Request struct {
+	PaymentMethod string
+	AddressID     uint
+	CouponCode    string
+}
+
+// Result is the placed order.
+type Result struct {
+	Order            models.Order
+	Items            []models.OrderItem
+	PaymentMethod    string
+	ExpectedDelivery time.Time
+}
+
+// PaymentMethodName is how a payment method is shown to the user.
+func PaymentMethodName(method string) string {
+	if method == MethodCOD {
+		return "Cash On Delivery"
+	}
+	return method
+}
+
+// pending is a reserved checkout that still matches the cart, priced for
+// delivery to the chosen address.
+type pending struct {
+	cartItems    []services.CartItemDetailWithDiscount
+	reservations []models.ReservedStock
+	address      models.UserAddress
+	delivery     services.DeliveryEstimate
+	quote        *Quote
+	display      currency.Display
+}
+
+// loadCheckout fetches the user's reservations and checks them against the
+// cart and the address. Expired reservations are refused when checkExpiry
+// is set.
+func loadCheckout(db *gorm.DB, userID, addressID uint, checkExpiry bool) (*pending, error) {
+	_, cartItems, err := services.FetchCartItems(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cartItems) == 0 {
+		return nil, services.ErrCartEmpty
+	}
+
+	reservations, err := FetchReservations(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if checkExpiry {
+		now := time.Now()
+		for _, reservation := range reservations {
+			if reservation.ReserveTill.Before(now) {
+				return nil, ErrReservationExpired
+			}
+		}
+	}
+
+	var address models.UserAddress
+	if err := db.First(&address, "id = ? AND user_id = ?", addressID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	delivery := services.CheckPinCodeDelivery(address.PinCode)
+	if !delivery.IsServiceable {
+		return nil, ErrNotServiceable
+	}
+
+	quote, err := PriceReservations(reservations, cartItems, &address)
+	if err != nil {
+		return nil, err
+	}
+	display, err := currency.ForUser(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &pending{
+		cartItems:    cartItems,
+		reservations: reservations,
+		address:      address,
+		delivery:     delivery,
+		quote:        quote,
+		display:      display,
+	}, nil
+}
+
+// PlaceOrder places an order for the stock the user reserved at checkout and
+// pays for it on delivery or from the wallet. Razorpay payments are placed
+// by CompleteGatewayPayment once the gateway confirms them. The coupon, if
+// any, is checked again here and its discount worked out on the server.
+func PlaceOrder(ctx context.Context, userID uint, request Request) (*Result, error) {
+	if request.PaymentMethod != MethodCOD && request.PaymentMethod != MethodWallet {
+		return nil, ErrUnsupportedPayment
+	}
+	return placeOrder(ctx, userID, request, nil)
+}
+
+func placeOrder(ctx context.Context, userID uint, request Request, gateway *gatewayAnswer) (*Result, error) {
+	db := config.DB.WithContext(ctx)
+
+	// The gateway has already taken the money, so a reservation that ran out
+	// while the user was paying is still honoured as long as it is there.
+	p, err := loadCheckout(db, userID, request.AddressID, gateway == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.PaymentMethod == MethodCOD {
+		available, err := CODAvailable(db, p.cartItems)
+		if err != nil {
+			return nil, err
+		}
+		if !available || !p.delivery.IsCODAvailable {
+			return nil, ErrCODUnavailable
+		}
+	}
+
+	now := time.Now()
+	tx := db.Begin()
+	coupon, couponDiscount, err := redeemCoupon(tx, p.reservations[0].ReservedCouponID, request.CouponCode, p.cartItems, p.quote.SalePrice)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	total := (money.FromRupees(p.quote.Total) - money.FromRupees(couponDiscount)).Rupees()
+	if request.PaymentMethod == MethodCOD && total > CODLimit {
+		tx.Rollback()
+		return nil, ErrCODUnavailable
+	}
+	// A failed payment is paid again from the order page at the order's own
+	// total, but money already taken for a different total is not kept
+	// against an order it does not match; placeGatewayOrder refunds it.
+	if gateway != nil && gateway.paid && money.FromRupees(total) != money.FromRupees(gateway.amount) {
+		tx.Rollback()
+		return nil, ErrAmountMismatch
+	}
+
+	order := models.Order{
+		OrderUID:             helper.GenerateOrderID(),
+		UserID:               userID,
+		SubTotal:             p.quote.RegularPrice,
+		TotalProductDiscount: p.quote.ProductDiscount,
+		TotalDiscount:        (money.FromRupees(p.quote.TotalDiscount) + money.FromRupees(couponDiscount)).Rupees(),
+		Tax:                  p.quote.Tax,
+		ShippingCharge:       p.quote.ShippingCharge,
+		ShippingDiscount:     p.quote.ShippingDiscount,
+		TotalAmount:          total,
+		OrderDate:            now,
+		Currency:             p.display.Code,
+		ExchangeRate:         p.display.Rate,
+	}
+	if coupon != nil {
+		order.IsCouponApplied = couponDiscount > 0
+		order.CouponCode = coupon.CouponCode
+		order.CouponDiscountAmount = couponDiscount
+		order.CouponDiscription = coupon.Discription
+		order.CouponValue = coupon.DiscountValue
+		order.IsCouponFixed = coupon.IsFixedCoupon
+	}
+	if err := tx.Create(&order).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	shippingAddress, err := saveShippingAddress(tx, order.ID, &p.address)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	items, err := createOrderItems(tx, p.reservations, p.quote.ItemShipping, order.ID, userID, now, shippingAddress)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if difference := services.OrderTotalDifference(&order, items); difference != 0 {
+		logger.Log.Error("Order items do not add up to the order total",
+			zap.String("orderUID", order.OrderUID),
+			zap.String("difference", difference.String()))
+	}
+
+	switch request.PaymentMethod {
+	case MethodCOD:
+		err = payOnDelivery(tx, userID, order.OrderUID, items)
+	case MethodWallet:
+		err = payFromWallet(tx, userID, &order, items)
+	case MethodRazorpay:
+		err = payByGateway(tx, userID, gateway, items)
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.ReservedStock{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := clearOrderedItems(tx, userID, p.quote.ReservedMap); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if gateway == nil || gateway.paid {
+		if err := services.NotifyOrderPlaced(tx, order.ID, PaymentMethodName(request.PaymentMethod)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("Order placed",
+		zap.Uint("userID", userID),
+		zap.String("orderUID", order.OrderUID),
+		zap.String("paymentMethod", request.PaymentMethod),
+		zap.Float64("total", total))
+	return &Result{
+		Order:            order,
+		Items:            items,
+		PaymentMethod:    PaymentMethodName(request.PaymentMethod),
+		ExpectedDelivery: services.FetchOrderExpectedDelivery(order.ID),
+	}, nil
+}
+
+// redeemCoupon swaps any coupon held for this checkout for the one asked
+// for, taking one of its uses. The coupon is nil when none is asked for.
+func redeemCoupon(tx *gorm.DB, reservedCouponID uint, code string, cartItems []services.CartItemDetailWithDiscount, purchaseAmount float64) (*models.Coupon, float64, error) {
+	if err := releaseCoupon(tx, reservedCouponID); err != nil {
+		return nil, 0, err
+	}
+	if code == "" {
+		return nil, 0, nil
+	}
+
+	coupon, discount, err := services.ApplyCoupon(tx, code, cartItems, purchaseAmount)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := tx.Exec("UPDATE coupons SET users_used_count = users_used_count + 1 WHERE id = ? AND users_used_count < max_use_count", coupon.ID)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, 0, services.ErrCouponExpired
+	}
+	return &coupon, discount, nil
+}
+
+// CODAvailable reports whether every product in the cart can be paid for on
+// delivery. The delivery address and CODLimit are checked separately.
+func CODAvailable(db *gorm.DB, cartItems []services.CartItemDetailWithDiscount) (bool, error) {
+	for _, item := range cartItems {
+		var product models.ProductDetail
+		if err := db.Unscoped().Select("id", "is_cod_available").First(&product, item.CartItem.ProductID).Error; err != nil {
+			return false, err
+		}
+		if !product.IsCODAvailable {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func payOnDelivery(tx *gorm.DB, userID uint, orderUID string, items []models.OrderItem) error {
+	for _, item := range items {
+		payment := models.PaymentDetail{
+			UserID:        userID,
+			OrderItemID:   item.ID,
+			OrderId:       orderUID,
+			PaymentStatus: "Pending",
+			PaymentAmount: item.Total,
+			PaymentMethod: PaymentMethodName(MethodCOD),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func payFromWallet(tx *gorm.DB, userID uint, order *models.Order, items []models.OrderItem) error {
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInsufficientBalance
+		}
+		return err
+	}
+	if money.FromRupees(wallet.Balance) < money.FromRupees(order.TotalAmount) {
+		return ErrInsufficientBalance
+	}
+
+	for i := range items {
+		payment := models.PaymentDetail{
+			UserID:        userID,
+			OrderItemID:   items[i].ID,
+			PaymentStatus: "Completed",
+			PaymentAmount: items[i].Total,
+			PaymentMethod: MethodWallet,
+			OrderId:       order.OrderUID,
+			TransactionID: fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Intn(10000)),
+			Receipt:       "rcpt-" + uuid.New().String(),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if err := orderlifecycle.Transition(tx, &items[i], orderlifecycle.StatusConfirmed, orderlifecycle.SystemActor(), "Payment received from wallet", nil); err != nil {
+			return err
+		}
+	}
+
+	lastBalance := wallet.Balance
+	if err := tx.Model(&wallet).Update("balance", gorm.Expr("balance - ?", order.TotalAmount)).Error; err != nil {
+		return err
+	}
+	walletHistory := models.WalletTransaction{
+		UserID:        userID,
+		WalletID:      wallet.ID,
+		Amount:        order.TotalAmount,
+		LastBalance:   lastBalance,
+		Description:   "Product Purchase ORD ID" + order.OrderUID,
+		Type:          "Debited",
+		Receipt:       "rcpt-" + uuid.New().String(),
+		OrderId:       order.OrderUID,
+		TransactionID: fmt.Sprintf("TXN-%d-%d", time.Now().UnixNano(), rand.Intn(10000)),
+		PaymentMethod: MethodWallet,
+	}
+	if err := tx.Create(&walletHistory).Error; err != nil {
+		return err
+	}
+	return services.NotifyWalletTransaction(tx, &walletHistory)
+}
@@ -0,0 +1,124 @@
+package checkout
+
+import (
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/anfastk/E-Commerce-Website/pkg/orderlifecycle"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// saveShippingAddress copies the address onto the order, so later edits to
+// the address book do not change where the order goes.
+func saveShippingAddress(tx *gorm.DB, orderID uint, address *models.UserAddress) (*models.ShippingAddress, error) {
+	shippingAddress := models.ShippingAddress{
+		UserID:    address.UserID,
+		OrderID:   orderID,
+		FirstName: address.FirstName,
+		LastName:  address.LastName,
+		Mobile:    address.Mobile,
+		Address:   address.Address,
+		Landmark:  address.Landmark,
+		Country:   address.Country,
+		State:     address.State,
+		City:      address.City,
+		PinCode:   address.PinCode,
+	}
+	if err := tx.Create(&shippingAddress).Error; err != nil {
+		return nil, err
+	}
+	return &shippingAddress, nil
+}
+
+// createOrderItems adds an item to the order for every reservation, priced
+// and taxed as it is now.
+func createOrderItems(tx *gorm.DB, reservations []models.ReservedStock, itemShipping map[uint]float64, orderID, userID uint, now time.Time, shippingAddress *models.ShippingAddress) ([]models.OrderItem, error) {
+	var items []models.OrderItem
+	for _, reservation := range reservations {
+		variant := reservation.ProductVariant
+		discountAmount, _, _ := helper.DiscountCalculation(variant.ProductID, variant.CategoryID, variant.RegularPrice, variant.SalePrice)
+		unitPrice := money.FromRupees(variant.SalePrice - discountAmount)
+		salePrice := unitPrice.Times(reservation.Quantity)
+		taxBreakdown := services.CalculateProductTax(variant.ProductID, variant.CategoryID, salePrice.Rupees(), shippingAddress.State)
+		total := salePrice + money.FromRupees(taxBreakdown.ChargedTax) + money.FromRupees(itemShipping[reservation.ProductVariantID])
+
+		var image string
+		var firstImage models.ProductVariantsImage
+		if err := tx.Unscoped().Where("product_variant_id = ?", variant.ID).Order("id ASC").First(&firstImage).Error; err == nil {
+			image = helper.ListingImage(firstImage.ProductVariantsImages, firstImage.ImageRenditions)
+		} else {
+			logger.Log.Warn("Failed to fetch first variant image",
+				zap.Uint("productVariantID", variant.ID),
+				zap.Error(err))
+		}
+
+		var product models.ProductDetail
+		if err := tx.Unscoped().First(&product, "id = ?", variant.ProductID).Error; err != nil {
+			return nil, err
+		}
+		var category models.Categories
+		if err := tx.First(&category, "id = ?", product.CategoryID).Error; err != nil {
+			return nil, err
+		}
+
+		delivery := services.EstimateDelivery(shippingAddress.PinCode, product.DispatchDays, now)
+		if !delivery.IsServiceable {
+			return nil, ErrNotServiceable
+		}
+
+		item := models.OrderItem{
+			OrderID:              orderID,
+			UserID:               userID,
+			OrderUID:             helper.GenerateOrderID(),
+			ProductName:          variant.ProductName,
+			ProductSummary:       variant.ProductSummary,
+			ProductCategory:      category.Name,
+			ProductImage:         image,
+			ProductRegularPrice:  variant.RegularPrice,
+			ProductSalePrice:     unitPrice.Rupees(),
+			ProductVariantID:     reservation.ProductVariantID,
+			Quantity:             reservation.Quantity,
+			ActiveQuantity:       reservation.Quantity,
+			SubTotal:             money.FromRupees(variant.RegularPrice).Times(reservation.Quantity).Rupees(),
+			Tax:                  taxBreakdown.ChargedTax,
+			HSNCode:              taxBreakdown.HSNCode,
+			TaxRate:              taxBreakdown.Rate,
+			IsTaxInclusive:       taxBreakdown.IsInclusive,
+			TaxableValue:         taxBreakdown.TaxableValue,
+			CGST:                 taxBreakdown.CGST,
+			SGST:                 taxBreakdown.SGST,
+			IGST:                 taxBreakdown.IGST,
+			Total:                total.Rupees(),
+			OrderStatus:          orderlifecycle.StatusPending,
+			ExpectedDeliveryDate: delivery.ExpectedDate,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return nil, err
+		}
+		if err := orderlifecycle.Record(tx, item.ID, "", orderlifecycle.StatusPending, orderlifecycle.UserActor(userID), "Order placed"); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// clearOrderedItems takes the ordered variants out of the user's cart.
+func clearOrderedItems(tx *gorm.DB, userID uint, ordered map[uint]int) error {
+	var cart models.Cart
+	if err := tx.First(&cart, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	variantIDs := make([]uint, 0, len(ordered))
+	for variantID := range ordered {
+		variantIDs = append(variantIDs, variantID)
+	}
+	return tx.Unscoped().
+		Where("cart_id = ? AND product_variant_id IN ?", cart.ID, variantIDs).
+		Delete(&models.CartItem{}).Error
+}
@@ -0,0 +1,240 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/anfastk/E-Commerce-Website/pkg/orderlifecycle"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Summary is a checkout priced with its coupon, as the payment step shows it.
+type Summary struct {
+	Address        models.UserAddress
+	CartItems      []services.CartItemDetailWithDiscount
+	Quote          Quote
+	Coupon         *models.Coupon
+	CouponDiscount float64
+	Total          float64
+	IsCODAvailable bool
+	// Currency is what the customer sees prices in; the order records it.
+	Currency currency.Display
+}
+
+// GatewayPayment is the gateway's answer for a Razorpay order opened by
+// RecordGatewayOrder.
+type GatewayPayment struct {
+	OrderID   string
+	PaymentID string
+}
+
+// gatewayAnswer carries a gateway payment into placeOrder.
+type gatewayAnswer struct {
+	payment GatewayPayment
+	paid    bool
+	amount  float64
+	record  *models.CheckoutPayment
+}
+
+// PreparePayment prices the reserved checkout for the payment step and holds
+// one use of the coupon, if one is given, until the order is placed or the
+// reservation runs out. A coupon held by an earlier visit is given back
+// first. The discount is worked out here, not taken from the client.
+func PreparePayment(ctx context.Context, userID, addressID uint, couponCode string) (*Summary, error) {
+	db := config.DB.WithContext(ctx)
+	p, err := loadCheckout(db, userID, addressID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.Begin()
+	coupon, discount, err := redeemCoupon(tx, p.reservations[0].ReservedCouponID, couponCode, p.cartItems, p.quote.SalePrice)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	var reservedCouponID uint
+	if coupon != nil {
+		reserved := models.ReservedCoupon{
+			CouponCode:           coupon.CouponCode,
+			Discription:          coupon.Discription,
+			CouponDiscountAmount: discount,
+			CouponID:             coupon.ID,
+		}
+		if err := tx.Create(&reserved).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		reservedCouponID = reserved.ID
+	}
+	if err := tx.Model(&models.ReservedStock{}).Where("user_id = ?", userID).Update("reserved_coupon_id", reservedCouponID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return summarize(db, p, coupon, discount)
+}
+
+// PriceCheckout prices the reserved checkout with the coupon asked for,
+// without holding anything. It is the amount to charge through the gateway.
+func PriceCheckout(ctx context.Context, userID uint, request Request) (*Summary, error) {
+	db := config.DB.WithContext(ctx)
+	p, err := loadCheckout(db, userID, request.AddressID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var coupon *models.Coupon
+	var discount float64
+	if request.CouponCode != "" {
+		applied, amount, err := services.ApplyCoupon(db, request.CouponCode, p.cartItems, p.quote.SalePrice)
+		if err != nil {
+			return nil, err
+		}
+		coupon, discount = &applied, amount
+	}
+	return summarize(db, p, coupon, discount)
+}
+
+func summarize(db *gorm.DB, p *pending, coupon *models.Coupon, discount float64) (*Summary, error) {
+	summary := &Summary{
+		Address:        p.address,
+		CartItems:      p.cartItems,
+		Quote:          *p.quote,
+		Coupon:         coupon,
+		CouponDiscount: discount,
+		Total:          (money.FromRupees(p.quote.Total) - money.FromRupees(discount)).Rupees(),
+		Currency:       p.display,
+	}
+	codAvailable, err := CODAvailable(db, p.cartItems)
+	if err != nil {
+		return nil, err
+	}
+	summary.IsCODAvailable = codAvailable && p.delivery.IsCODAvailable && summary.Total <= CODLimit
+	return summary, nil
+}
+
+// RecordGatewayOrder remembers what a Razorpay order was opened for, so
+// CompleteGatewayPayment and FailGatewayPayment place the order the user
+// paid for.
+func RecordGatewayOrder(ctx context.Context, userID uint, request Request, gatewayOrderID string, amount float64) error {
+	record := models.CheckoutPayment{
+		UserID:         userID,
+		GatewayOrderID: gatewayOrderID,
+		AddressID:      request.AddressID,
+		CouponCode:     request.CouponCode,
+		Amount:         amount,
+	}
+	return config.DB.WithContext(ctx).Create(&record).Error
+}
+
+// CompleteGatewayPayment places the order for a Razorpay payment the gateway
+// has confirmed. The caller checks the payment signature first.
+func CompleteGatewayPayment(ctx context.Context, userID uint, payment GatewayPayment) (*Result, error) {
+	return placeGatewayOrder(ctx, userID, payment, true)
+}
+
+// FailGatewayPayment places the order for a Razorpay payment that failed,
+// waiting for payment, so the user can pay for it from the order page. The
+// reservation cleanup job fails it if nobody does.
+func FailGatewayPayment(ctx context.Context, userID uint, payment GatewayPayment) (*Result, error) {
+	return placeGatewayOrder(ctx, userID, payment, false)
+}
+
+func placeGatewayOrder(ctx context.Context, userID uint, payment GatewayPayment, paid bool) (*Result, error) {
+	db := config.DB.WithContext(ctx)
+	var record models.CheckoutPayment
+	if err := db.First(&record, "gateway_order_id = ? AND user_id = ?", payment.OrderID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	if record.FlaggedAt != nil {
+		return nil, ErrPaymentRefunded
+	}
+
+	if paid {
+		// Once the payment is on the record it is never purged as an
+		// abandoned checkout, and CheckoutRefundJob refunds it if this
+		// request dies before the order is placed.
+		recorded := db.Model(&models.CheckoutPayment{}).
+			Where("id = ? AND flagged_at IS NULL", record.ID).
+			Update("payment_id", payment.PaymentID)
+		if recorded.Error != nil {
+			return nil, recorded.Error
+		}
+		if recorded.RowsAffected == 0 {
+			return nil, ErrPaymentNotFound
+		}
+		record.PaymentID = payment.PaymentID
+	}
+
+	answer := &gatewayAnswer{payment: payment, paid: paid, amount: record.Amount, record: &record}
+	result, err := placeOrder(ctx, userID, Request{
+		PaymentMethod: MethodRazorpay,
+		AddressID:     record.AddressID,
+		CouponCode:    record.CouponCode,
+	}, answer)
+	if err != nil && paid {
+		// The money has been taken and no order holds it, whatever the
+		// reason. The refund must outlive a request whose client has gone.
+		if refundUnplacedPayment(config.DB.WithContext(context.WithoutCancel(ctx)), userID, &record, err) {
+			return nil, fmt.Errorf("%w: %w", ErrPaymentRefunded, err)
+		}
+	}
+	return result, err
+}
+
+func payByGateway(tx *gorm.DB, userID uint, gateway *gatewayAnswer, items []models.OrderItem) error {
+	status, next, note := "Completed", orderlifecycle.StatusConfirmed, "Payment received via Razorpay"
+	if !gateway.paid {
+		status, next, note = "Failed", orderlifecycle.StatusOrderNotPlaced, "Razorpay payment failed"
+	}
+
+	for i := range items {
+		payment := models.PaymentDetail{
+			UserID:        userID,
+			OrderItemID:   items[i].ID,
+			PaymentStatus: status,
+			PaymentAmount: items[i].Total,
+			PaymentMethod: MethodRazorpay,
+			OrderId:       gateway.payment.OrderID,
+			TransactionID: gateway.payment.PaymentID,
+			Receipt:       "rcpt_" + uuid.New().String(),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if err := orderlifecycle.Transition(tx, &items[i], next, orderlifecycle.SystemActor(), note, nil); err != nil {
+			return err
+		}
+	}
+
+	// The Razorpay order is answered; a second answer must not place it
+	// again, and one that has been flagged for a refund must not be placed.
+	result := tx.Unscoped().Where("flagged_at IS NULL").Delete(gateway.record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPaymentNotFound
+	}
+	logger.Log.Info("Razorpay payment recorded",
+		zap.Uint("userID", userID),
+		zap.String("gatewayOrderID", gateway.payment.OrderID),
+		zap.String("status", status))
+	return nil
+}
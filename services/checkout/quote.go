@@ -0,0 +1,79 @@
+package checkout
+
+import (
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"gorm.io/gorm"
+)
+
+// Quote prices the reserved stock of a checkout before any coupon.
+type Quote struct {
+	// ReservedMap holds the reserved quantity of each product variant.
+	ReservedMap      map[uint]int
+	RegularPrice     float64
+	SalePrice        float64
+	ProductDiscount  float64
+	TotalDiscount    float64
+	ShippingCharge   float64
+	ShippingDiscount float64
+	ItemShipping     map[uint]float64
+	Tax              float64
+	Total            float64
+}
+
+// FetchReservations returns the user's reserved stock with its variants.
+func FetchReservations(db *gorm.DB, userID uint) ([]models.ReservedStock, error) {
+	var reservations []models.ReservedStock
+	err := db.Unscoped().Preload("ProductVariant").Find(&reservations, "user_id = ?", userID).Error
+	return reservations, err
+}
+
+// PriceReservations prices the reserved stock for delivery to address. It
+// fails with ErrCartChanged when the cart no longer matches what was
+// reserved, so the user has to start checkout again.
+func PriceReservations(reservations []models.ReservedStock, cartItems []services.CartItemDetailWithDiscount, address *models.UserAddress) (*Quote, error) {
+	if len(reservations) == 0 || len(reservations) != len(cartItems) {
+		return nil, ErrCartChanged
+	}
+
+	quote := &Quote{ReservedMap: make(map[uint]int)}
+	var regularPrice, salePrice, tax money.Amount
+	var shippingItems []services.ShippingItem
+	for _, r := range reservations {
+		discountAmount, _, _ := helper.DiscountCalculation(r.ProductVariant.ProductID, r.ProductVariant.CategoryID, r.ProductVariant.RegularPrice, r.ProductVariant.SalePrice)
+		quote.ReservedMap[r.ProductVariantID] = r.Quantity
+		regularPrice += money.FromRupees(r.ProductVariant.RegularPrice).Times(r.Quantity)
+		lineSalePrice := money.FromRupees(r.ProductVariant.SalePrice - discountAmount).Times(r.Quantity)
+		salePrice += lineSalePrice
+		tax += money.FromRupees(services.CalculateProductTax(r.ProductVariant.ProductID, r.ProductVariant.CategoryID, lineSalePrice.Rupees(), "").ChargedTax)
+		shippingItems = append(shippingItems, services.ShippingItem{
+			ProductVariantID: r.ProductVariantID,
+			ProductID:        r.ProductVariant.ProductID,
+			Quantity:         r.Quantity,
+			Amount:           lineSalePrice.Rupees(),
+		})
+	}
+
+	for _, item := range cartItems {
+		if reserved, ok := quote.ReservedMap[item.CartItem.ProductVariantID]; !ok || reserved != item.CartItem.Quantity {
+			return nil, ErrCartChanged
+		}
+	}
+
+	// Summed in paise, the quote total is exactly the sum of the order item
+	// totals createOrderItems will write.
+	shipping := services.CalculateShipping(shippingItems, address.PinCode, address.State)
+	productDiscount := regularPrice - salePrice
+	quote.RegularPrice = regularPrice.Rupees()
+	quote.SalePrice = salePrice.Rupees()
+	quote.Tax = tax.Rupees()
+	quote.ProductDiscount = productDiscount.Rupees()
+	quote.TotalDiscount = (productDiscount + money.FromRupees(shipping.WaivedCharge)).Rupees()
+	quote.ShippingCharge = shipping.Charge
+	quote.ShippingDiscount = shipping.WaivedCharge
+	quote.ItemShipping = shipping.ItemCharges
+	quote.Total = (salePrice + tax + money.FromRupees(shipping.Charge)).Rupees()
+	return quote, nil
+}
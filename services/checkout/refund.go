@@ -0,0 +1,191 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/paymentgateway"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Refund statuses of a flagged checkout payment.
+const (
+	paymentRefundPending  = "Pending"
+	paymentRefundDone     = "Refunded"
+	paymentRefundRejected = "Rejected"
+)
+
+// refundRetryAfter is how long a checkout payment is left alone after it was
+// last touched before CheckoutRefundJob picks it up. It keeps the job clear of
+// requests still placing or refunding it.
+const refundRetryAfter = 15 * time.Minute
+
+// refundUnplacedPayment flags a captured payment that could not be placed as
+// an order and refunds it. The flag is claimed first, so a second answer for
+// the same Razorpay order neither places it nor refunds it again. It reports
+// false when the payment was placed or flagged by another request, in which
+// case nothing is refunded. A refund that does not go through is left to
+// CheckoutRefundJob.
+func refundUnplacedPayment(db *gorm.DB, userID uint, record *models.CheckoutPayment, cause error) bool {
+	now := time.Now()
+	reason := "Order not placed: " + cause.Error()
+	claim := db.Model(&models.CheckoutPayment{}).
+		Where("id = ? AND flagged_at IS NULL", record.ID).
+		Updates(map[string]interface{}{
+			"flag_reason":   reason,
+			"flagged_at":    now,
+			"refund_status": paymentRefundPending,
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		if claim.Error != nil {
+			logger.Log.Error("Failed to flag unplaced checkout payment",
+				zap.String("gatewayOrderID", record.GatewayOrderID),
+				zap.Error(claim.Error))
+		}
+		return false
+	}
+	record.FlagReason = reason
+	record.FlaggedAt = &now
+	record.RefundStatus = paymentRefundPending
+
+	logger.Log.Warn("Refunding checkout payment that could not be placed",
+		zap.Uint("userID", userID),
+		zap.String("gatewayOrderID", record.GatewayOrderID),
+		zap.String("paymentID", record.PaymentID),
+		zap.Float64("amount", record.Amount),
+		zap.Error(cause))
+	if err := refundCheckoutPayment(db, record, false); err != nil {
+		logger.Log.Error("Checkout payment refund failed",
+			zap.String("gatewayOrderID", record.GatewayOrderID),
+			zap.Error(err))
+	}
+	return true
+}
+
+// refundCheckoutPayment sends the refund of a flagged checkout payment. One
+// that may have been sent before is looked up at the gateway first and only
+// sent again when the gateway has no record of it. An answer that is lost on
+// the way back leaves the refund pending; a refund the gateway turns down is
+// left for the finance team.
+func refundCheckoutPayment(db *gorm.DB, record *models.CheckoutPayment, sentBefore bool) error {
+	receipt := "CHK-" + record.GatewayOrderID
+	if sentBefore {
+		result, found, err := paymentgateway.Default.FindRefund(record.PaymentID, receipt)
+		if err != nil {
+			setCheckoutRefund(db, record, paymentRefundPending, "", "Gateway lookup failed: "+err.Error())
+			return err
+		}
+		if found {
+			setCheckoutRefund(db, record, paymentRefundDone, result.RefundID, "")
+			return nil
+		}
+	}
+
+	result, err := paymentgateway.Default.Refund(paymentgateway.RefundRequest{
+		PaymentID: record.PaymentID,
+		Amount:    record.Amount,
+		Receipt:   receipt,
+		Notes: map[string]interface{}{
+			"gateway_order_id": record.GatewayOrderID,
+			"reason":           record.FlagReason,
+		},
+	})
+	if err != nil {
+		status := paymentRefundPending
+		if paymentgateway.IsRejected(err) {
+			status = paymentRefundRejected
+		}
+		setCheckoutRefund(db, record, status, "", err.Error())
+		return err
+	}
+	setCheckoutRefund(db, record, paymentRefundDone, result.RefundID, "")
+	logger.Log.Info("Checkout payment refunded",
+		zap.String("gatewayOrderID", record.GatewayOrderID),
+		zap.String("gatewayRefundID", result.RefundID),
+		zap.Float64("amount", record.Amount))
+	return nil
+}
+
+// setCheckoutRefund records a refund outcome. The gateway has already
+// answered, so a failed update is only logged; the next run of
+// CheckoutRefundJob looks the refund up before sending it again.
+func setCheckoutRefund(db *gorm.DB, record *models.CheckoutPayment, status, gatewayRefundID, refundError string) {
+	record.RefundStatus = status
+	record.GatewayRefundID = gatewayRefundID
+	record.RefundError = refundError
+	if err := db.Model(&models.CheckoutPayment{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"refund_status":     status,
+		"gateway_refund_id": gatewayRefundID,
+		"refund_error":      refundError,
+	}).Error; err != nil {
+		logger.Log.Error("Failed to record checkout payment refund",
+			zap.String("gatewayOrderID", record.GatewayOrderID),
+			zap.String("status", status),
+			zap.Error(err))
+	}
+}
+
+// RefundCheckoutPayments refunds captured payments that have no order: ones
+// whose request died before the order was placed, and flagged ones whose
+// refund has not gone through yet.
+func RefundCheckoutPayments(db *gorm.DB) error {
+	cutoff := time.Now().Add(-refundRetryAfter)
+
+	var stranded []models.CheckoutPayment
+	if err := db.Where("payment_id <> '' AND flagged_at IS NULL AND updated_at < ?", cutoff).
+		Find(&stranded).Error; err != nil {
+		return err
+	}
+	for i := range stranded {
+		// An order placed for the payment deletes the record, so one that
+		// is still here was never placed.
+		refundUnplacedPayment(db, stranded[i].UserID, &stranded[i], errors.New("payment captured but the order was never placed"))
+	}
+
+	var pending []models.CheckoutPayment
+	if err := db.Where("refund_status = ? AND updated_at < ?", paymentRefundPending, cutoff).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+	failed := 0
+	for i := range pending {
+		record := &pending[i]
+		claim := db.Model(&models.CheckoutPayment{}).
+			Where("id = ? AND refund_status = ? AND updated_at < ?", record.ID, paymentRefundPending, cutoff).
+			Update("updated_at", time.Now())
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		if err := refundCheckoutPayment(db, record, true); err != nil {
+			failed++
+			logger.Log.Error("Checkout payment refund retry failed",
+				zap.String("gatewayOrderID", record.GatewayOrderID),
+				zap.Error(err))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checkout payment refunds did not go through", failed)
+	}
+	return nil
+}
+
+// CheckoutRefundJob refunds gateway payments that never became orders.
+func CheckoutRefundJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "checkout-payment-refunds",
+		Description: "Refund gateway payments that could not be placed as orders",
+		Interval:    refundRetryAfter,
+		Run: func(ctx context.Context) error {
+			return RefundCheckoutPayments(db.WithContext(ctx))
+		},
+	}
+}
@@ -0,0 +1,122 @@
+package checkout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/services"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ReservationTTL is how long stock is held for a checkout before the
+// reservation cleanup job puts it back.
+const ReservationTTL = 15 * time.Minute
+
+// ReserveStock starts a checkout: it checks every item in the cart is still
+// available and holds the stock for ReservationTTL. Reservations left over
+// from an earlier checkout by the same user are released first.
+func ReserveStock(ctx context.Context, userID uint) ([]services.CartItemDetailWithDiscount, error) {
+	_, cartItems, err := services.FetchCartItems(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cartItems) == 0 {
+		return nil, services.ErrCartEmpty
+	}
+	for _, item := range cartItems {
+		if item.ProductDetails.IsDeleted {
+			return nil, ErrProductUnavailable
+		}
+		if item.CartItem.Quantity == 0 || item.ProductDetails.StockQuantity < item.CartItem.Quantity {
+			return nil, services.ErrOutOfStock
+		}
+	}
+
+	tx := config.DB.WithContext(ctx).Begin()
+	if err := releaseReservations(tx, userID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, item := range cartItems {
+		result := tx.Model(&models.ProductVariantDetails{}).
+			Where("id = ? AND stock_quantity >= ?", item.CartItem.ProductVariantID, item.CartItem.Quantity).
+			Update("stock_quantity", gorm.Expr("stock_quantity - ?", item.CartItem.Quantity))
+		if result.Error != nil {
+			tx.Rollback()
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return nil, services.ErrOutOfStock
+		}
+
+		reservation := models.ReservedStock{
+			UserID:           userID,
+			ProductVariantID: item.CartItem.ProductVariantID,
+			Quantity:         item.CartItem.Quantity,
+			ReservedAt:       now,
+			ReserveTill:      now.Add(ReservationTTL),
+		}
+		if err := tx.Create(&reservation).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	logger.Log.Info("Stock reserved for checkout",
+		zap.Uint("userID", userID),
+		zap.Int("itemCount", len(cartItems)))
+	return cartItems, nil
+}
+
+// releaseReservations puts back the stock and coupon held by the user's
+// unconfirmed reservations.
+func releaseReservations(tx *gorm.DB, userID uint) error {
+	var reservations []models.ReservedStock
+	if err := tx.Where("user_id = ? AND is_confirmed = ?", userID, false).Find(&reservations).Error; err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		if err := tx.Exec(
+			"UPDATE product_variant_details SET stock_quantity = stock_quantity + ? WHERE id = ?",
+			reservation.Quantity, reservation.ProductVariantID,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&reservation).Error; err != nil {
+			return err
+		}
+	}
+	if len(reservations) > 0 {
+		return releaseCoupon(tx, reservations[0].ReservedCouponID)
+	}
+	return nil
+}
+
+// releaseCoupon gives back the use a reserved coupon was holding.
+func releaseCoupon(tx *gorm.DB, reservedCouponID uint) error {
+	if reservedCouponID == 0 {
+		return nil
+	}
+	var reserved models.ReservedCoupon
+	if err := tx.Unscoped().First(&reserved, reservedCouponID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := tx.Exec("UPDATE coupons SET users_used_count = users_used_count - 1 WHERE id = ? AND users_used_count > 0", reserved.CouponID).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&reserved).Error
+}
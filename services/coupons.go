@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponExpired       = errors.New("coupon expired")
+	ErrCouponNotStarted    = errors.New("coupon not available yet")
+	ErrCouponNotApplicable = errors.New("coupon not applicable")
+)
+
+// ApplyCoupon checks a coupon code against the cart and returns the coupon
+// with the discount it gives on purchaseAmount, the cart total after product
+// discounts. Coupons for a category only apply when every item in the cart
+// belongs to that category.
+func ApplyCoupon(db *gorm.DB, code string, cartItems []CartItemDetailWithDiscount, purchaseAmount float64) (models.Coupon, float64, error) {
+	var coupon models.Coupon
+	code = strings.TrimSpace(strings.ToUpper(code))
+	if err := db.First(&coupon, "UPPER(coupon_code) = ?", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return coupon, 0, ErrCouponNotFound
+		}
+		return coupon, 0, err
+	}
+
+	if coupon.Status == "Deleted" {
+		return coupon, 0, ErrCouponNotFound
+	}
+	if coupon.Status == "Expired" || coupon.UsersUsedCount >= coupon.MaxUseCount {
+		return coupon, 0, ErrCouponExpired
+	}
+	if coupon.ExpirationDate.Before(time.Now().Truncate(24 * time.Hour)) {
+		return coupon, 0, ErrCouponExpired
+	}
+	if time.Now().Before(coupon.ValidFrom) {
+		return coupon, 0, ErrCouponNotStarted
+	}
+
+	if coupon.ApplicableFor != "AllProducts" {
+		if len(cartItems) == 0 {
+			return coupon, 0, ErrCartEmpty
+		}
+		categoryID := cartItems[0].ProductDetails.CategoryID
+		for _, item := range cartItems {
+			if item.ProductDetails.CategoryID != categoryID {
+				return coupon, 0, ErrCouponNotApplicable
+			}
+		}
+		if name := cartItems[0].ProductDetails.Category.Name; name != "" && name != coupon.ApplicableFor {
+			return coupon, 0, ErrCouponNotApplicable
+		}
+	}
+
+	if purchaseAmount < coupon.MinOrderValue {
+		return coupon, 0, ErrCouponNotApplicable
+	}
+
+	discount := CouponDiscount(coupon, purchaseAmount)
+	if discount > purchaseAmount {
+		return coupon, 0, ErrCouponNotApplicable
+	}
+	return coupon, discount, nil
+}
+
+// CouponDiscount is what the coupon takes off purchaseAmount.
+func CouponDiscount(coupon models.Coupon, purchaseAmount float64) float64 {
+	if coupon.IsFixedCoupon {
+		return money.Round(coupon.MaxDiscountValue)
+	}
+	discount := money.FromRupees(purchaseAmount).Percent(coupon.DiscountValue)
+	return money.Min(discount, money.FromRupees(coupon.MaxDiscountValue)).Rupees()
+}
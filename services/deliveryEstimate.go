@@ -0,0 +1,92 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+)
+
+// DefaultTransitDays is used while the serviceability table is empty, so a
+// store that has not imported pin codes yet keeps delivering everywhere.
+const DefaultTransitDays = 6
+
+type DeliveryEstimate struct {
+	PinCode        string
+	City           string
+	State          string
+	IsServiceable  bool
+	IsCODAvailable bool
+	TransitDays    int
+	ExpectedDate   time.Time
+}
+
+// AddDeliveryDays counts working days forward from the given time, skipping
+// Sundays when nothing is picked up or delivered.
+func AddDeliveryDays(from time.Time, days int) time.Time {
+	date := from
+	for days > 0 {
+		date = date.AddDate(0, 0, 1)
+		if date.Weekday() != time.Sunday {
+			days--
+		}
+	}
+	return date
+}
+
+func CheckPinCodeDelivery(pinCode string) DeliveryEstimate {
+	pinCode = strings.TrimSpace(pinCode)
+	estimate := DeliveryEstimate{PinCode: pinCode}
+
+	var entry models.PinCodeServiceability
+	if err := config.DB.First(&entry, "pin_code = ?", pinCode).Error; err == nil {
+		estimate.City = entry.City
+		estimate.State = entry.State
+		estimate.IsServiceable = entry.IsServiceable
+		estimate.IsCODAvailable = entry.IsServiceable && entry.IsCODAvailable
+		estimate.TransitDays = entry.TransitDays
+		return estimate
+	}
+
+	var count int64
+	config.DB.Model(&models.PinCodeServiceability{}).Count(&count)
+	if count == 0 && pinCode != "" {
+		estimate.IsServiceable = true
+		estimate.IsCODAvailable = true
+		estimate.TransitDays = DefaultTransitDays
+	}
+	return estimate
+}
+
+// EstimateDelivery adds the product's dispatch days to the pin code's transit
+// days. dispatchDays below one are treated as next-day dispatch.
+func EstimateDelivery(pinCode string, dispatchDays int, from time.Time) DeliveryEstimate {
+	estimate := CheckPinCodeDelivery(pinCode)
+	if dispatchDays < 1 {
+		dispatchDays = 1
+	}
+	if estimate.IsServiceable {
+		estimate.ExpectedDate = AddDeliveryDays(from, dispatchDays+estimate.TransitDays)
+	}
+	return estimate
+}
+
+func ProductDispatchDays(productID uint) int {
+	var product models.ProductDetail
+	config.DB.Unscoped().Select("id, dispatch_days").First(&product, productID)
+	return product.DispatchDays
+}
+
+func FetchOrderExpectedDelivery(orderID uint) time.Time {
+	var orderItems []models.OrderItem
+	config.DB.Select("expected_delivery_date").Find(&orderItems, "order_id = ?", orderID)
+
+	var latest time.Time
+	for _, item := range orderItems {
+		if item.ExpectedDeliveryDate.After(latest) {
+			latest = item.ExpectedDeliveryDate
+		}
+	}
+	return latest
+}
@@ -34,7 +34,7 @@ func FetchCartItems(userID uint) (models.Cart, []CartItemDetailWithDiscount, err
 			return cart, []CartItemDetailWithDiscount{}, errors.New("Error fetching cart items")
 		}
 		var productImage []models.ProductVariantsImage
-		if err := config.DB.Select("product_variants_images").
+		if err := config.DB.Select("product_variants_images", "listing_url").
 			Where("product_variant_id = ?", item.ProductVariantID).
 			Find(&productImage).Error; err != nil {
 			return cart, []CartItemDetailWithDiscount{}, errors.New("Product Image Not Found")
@@ -43,7 +43,7 @@ func FetchCartItems(userID uint) (models.Cart, []CartItemDetailWithDiscount, err
 		cartItemsDetails = append(cartItemsDetails, CartItemDetailWithDiscount{
 			CartItem:       item,
 			ProductDetails: productDetail,
-			ProductImage:   productImage[0].ProductVariantsImages,
+			ProductImage:   helper.ListingImage(productImage[0].ProductVariantsImages, productImage[0].ImageRenditions),
 			DiscountPrice:  productDetail.SalePrice - discountAmount,
 		})
 	}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/notifier"
+	"github.com/anfastk/E-Commerce-Website/pkg/outbox"
+	"gorm.io/gorm"
+)
+
+// NotifyWalletTransaction queues the customer's email for a wallet
+// transaction in the transaction that created it.
+func NotifyWalletTransaction(tx *gorm.DB, txn *models.WalletTransaction) error {
+	var user models.UserAuth
+	if err := tx.Unscoped().Select("id", "full_name", "email").First(&user, txn.UserID).Error; err != nil {
+		return err
+	}
+	return outbox.Enqueue(tx, notifier.EventWalletTransaction, notifier.WalletTransaction{
+		UserID:        user.ID,
+		Name:          user.FullName,
+		Email:         user.Email,
+		Type:          txn.Type,
+		Amount:        txn.Amount,
+		Description:   txn.Description,
+		TransactionID: txn.TransactionID,
+	})
+}
+
+// NotifyOrderPlaced queues the order confirmation email once the order has
+// been paid for, or placed as cash on delivery.
+func NotifyOrderPlaced(tx *gorm.DB, orderID uint, paymentMethod string) error {
+	var order models.Order
+	if err := tx.First(&order, orderID).Error; err != nil {
+		return err
+	}
+	var user models.UserAuth
+	if err := tx.Unscoped().Select("id", "full_name", "email").First(&user, order.UserID).Error; err != nil {
+		return err
+	}
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Order("id ASC").Find(&items).Error; err != nil {
+		return err
+	}
+
+	placed := notifier.OrderPlaced{
+		OrderID:       order.ID,
+		OrderUID:      order.OrderUID,
+		UserID:        user.ID,
+		Name:          user.FullName,
+		Email:         user.Email,
+		PaymentMethod: paymentMethod,
+		Total:         order.TotalAmount,
+	}
+	for _, item := range items {
+		placed.Items = append(placed.Items, notifier.OrderPlacedItem{
+			OrderItemID: item.ID,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			Total:       item.Total,
+		})
+	}
+	return outbox.Enqueue(tx, notifier.EventOrderPlaced, placed)
+}
+
+// notifyRefundIssued queues the refund email for a refund that has reached
+// the customer.
+func notifyRefundIssued(tx *gorm.DB, refund *models.Refund) error {
+	var item models.OrderItem
+	if err := tx.Unscoped().First(&item, refund.OrderItemID).Error; err != nil {
+		return err
+	}
+	var user models.UserAuth
+	if err := tx.Unscoped().Select("id", "full_name", "email").First(&user, refund.UserID).Error; err != nil {
+		return err
+	}
+	return outbox.Enqueue(tx, notifier.EventRefundIssued, notifier.RefundIssued{
+		RefundUID:   refund.RefundUID,
+		OrderItemID: item.ID,
+		OrderUID:    item.OrderUID,
+		ProductName: item.ProductName,
+		Quantity:    refund.Quantity,
+		Amount:      refund.Amount,
+		Destination: refund.Destination,
+		UserID:      user.ID,
+		Name:        user.FullName,
+		Email:       user.Email,
+	})
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ReleaseCancelled = "Cancelled"
+	ReleaseReturned  = "Returned"
+)
+
+var ErrInvalidReleaseQuantity = errors.New("quantity must be between 1 and the active quantity of the item")
+
+// ItemRelease is what taking part of an order item back is worth. Amount and
+// Tax are the prorated share of the item; CouponDiscount is the part of the
+// order coupon the customer no longer qualifies for.
+type ItemRelease struct {
+	Quantity       int
+	Amount         float64
+	Tax            float64
+	CouponDiscount float64
+	RefundAmount   float64
+	RemovesCoupon  bool
+	IsFullItem     bool
+}
+
+// prorate splits value by quantity so that the shares of successive releases
+// always add up to value once the whole item is released.
+func prorate(value float64, released, quantity, total int) float64 {
+	amount := money.FromRupees(value)
+	return (amount.Part(released+quantity, total) - amount.Part(released, total)).Rupees()
+}
+
+// CalculateItemRelease prices releasing quantity units of item without
+// changing anything.
+func CalculateItemRelease(tx *gorm.DB, order *models.Order, item *models.OrderItem, quantity int) (ItemRelease, error) {
+	if quantity < 1 || quantity > item.ActiveQuantity || item.Quantity < 1 {
+		return ItemRelease{}, ErrInvalidReleaseQuantity
+	}
+
+	released := item.Quantity - item.ActiveQuantity
+	release := ItemRelease{
+		Quantity:   quantity,
+		Amount:     prorate(item.Total, released, quantity, item.Quantity),
+		Tax:        prorate(item.Tax, released, quantity, item.Quantity),
+		IsFullItem: quantity == item.ActiveQuantity,
+	}
+
+	if order.IsCouponApplied && order.CouponDiscountAmount > 0 {
+		var coupon models.Coupon
+		if err := tx.Unscoped().First(&coupon, "coupon_code = ?", order.CouponCode).Error; err != nil {
+			return ItemRelease{}, err
+		}
+
+		var activeItems []models.OrderItem
+		if err := tx.Where("order_id = ? AND active_quantity > 0 AND order_status NOT IN ?", order.ID, []string{"Cancelled", "Returned", "Order Not Placed", "Failed"}).
+			Find(&activeItems).Error; err != nil {
+			return ItemRelease{}, err
+		}
+		var activeValue money.Amount
+		for _, active := range activeItems {
+			activeValue += money.FromRupees(active.ProductSalePrice).Times(active.ActiveQuantity)
+		}
+		releasedValue := money.FromRupees(item.ProductSalePrice).Times(quantity)
+		remainingValue := activeValue - releasedValue
+
+		if remainingValue <= 0 || remainingValue < money.FromRupees(coupon.MinOrderValue) {
+			release.CouponDiscount = order.CouponDiscountAmount
+			release.RemovesCoupon = true
+		} else if activeValue > 0 {
+			release.CouponDiscount = money.FromRupees(order.CouponDiscountAmount).Ratio(releasedValue, activeValue).Rupees()
+		}
+	}
+
+	release.RefundAmount = (money.FromRupees(release.Amount) - money.FromRupees(release.CouponDiscount)).Rupees()
+	return release, nil
+}
+
+// ReleaseItemQuantity cancels or returns quantity units of item: the stock is
+// put back, the item's active quantity is reduced, and the order coupon and
+// shipping are re-evaluated for what is left. The status change and any
+// refund are left to the caller.
+func ReleaseItemQuantity(tx *gorm.DB, order *models.Order, item *models.OrderItem, quantity int, kind string) (ItemRelease, error) {
+	release, err := CalculateItemRelease(tx, order, item, quantity)
+	if err != nil {
+		return ItemRelease{}, err
+	}
+
+	if err := tx.Exec("UPDATE product_variant_details SET stock_quantity = stock_quantity + ? WHERE id = ?",
+		quantity, item.ProductVariantID).Error; err != nil {
+		return ItemRelease{}, err
+	}
+
+	counter := "cancelled_quantity"
+	if kind == ReleaseReturned {
+		counter = "returned_quantity"
+	}
+	result := tx.Model(&models.OrderItem{}).
+		Where("id = ? AND active_quantity = ?", item.ID, item.ActiveQuantity).
+		Updates(map[string]interface{}{
+			"active_quantity": gorm.Expr("active_quantity - ?", quantity),
+			counter:           gorm.Expr(counter+" + ?", quantity),
+		})
+	if result.Error != nil {
+		return ItemRelease{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ItemRelease{}, errors.New("order item was changed by another request")
+	}
+	item.ActiveQuantity -= quantity
+	if kind == ReleaseReturned {
+		item.ReturnedQuantity += quantity
+	} else {
+		item.CancelledQuantity += quantity
+	}
+
+	shipping := RecalculateOrderShipping(tx, order.ID)
+	updates := map[string]interface{}{
+		"shipping_charge":   shipping.Charge,
+		"shipping_discount": shipping.WaivedCharge,
+	}
+	if release.RemovesCoupon {
+		if err := tx.Model(&models.Coupon{}).Unscoped().Where("coupon_code = ?", order.CouponCode).
+			Update("users_used_count", gorm.Expr("users_used_count - 1")).Error; err != nil {
+			return ItemRelease{}, err
+		}
+		updates["coupon_code"] = gorm.Expr("NULL")
+		updates["coupon_discount_amount"] = gorm.Expr("NULL")
+		updates["is_coupon_applied"] = false
+	} else if release.CouponDiscount > 0 {
+		updates["coupon_discount_amount"] = (money.FromRupees(order.CouponDiscountAmount) - money.FromRupees(release.CouponDiscount)).Rupees()
+	}
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
+		return ItemRelease{}, err
+	}
+
+	order.ShippingCharge = shipping.Charge
+	order.ShippingDiscount = shipping.WaivedCharge
+	if release.RemovesCoupon {
+		order.IsCouponApplied = false
+		order.CouponCode = ""
+		order.CouponDiscountAmount = 0
+	} else {
+		order.CouponDiscountAmount = (money.FromRupees(order.CouponDiscountAmount) - money.FromRupees(release.CouponDiscount)).Rupees()
+	}
+	return release, nil
+}
+
+// CreditWallet moves amount into the user's wallet, or out of it when amount
+// is negative, and records the transaction.
+func CreditWallet(tx *gorm.DB, userID uint, amount float64, orderUID, description, paymentMethod string) error {
+	if amount == 0 {
+		return nil
+	}
+
+	var wallet models.Wallet
+	if err := tx.First(&wallet, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	transactionType := "Refund"
+	if amount < 0 {
+		transactionType = "Deduct"
+	}
+	lastBalance := wallet.Balance
+	wallet.Balance = (money.FromRupees(wallet.Balance) + money.FromRupees(amount)).Rupees()
+	if err := tx.Save(&wallet).Error; err != nil {
+		return err
+	}
+
+	transactionID := fmt.Sprintf("TXN-%d-%d", time.Now().UnixNano(), rand.Intn(10000))
+	walletHistory := models.WalletTransaction{
+		UserID:        userID,
+		WalletID:      wallet.ID,
+		Amount:        money.Round(math.Abs(amount)),
+		Description:   description,
+		Type:          transactionType,
+		Receipt:       "rcpt_" + uuid.New().String(),
+		OrderId:       orderUID,
+		LastBalance:   lastBalance,
+		TransactionID: strings.ToUpper(transactionID),
+		PaymentMethod: paymentMethod,
+	}
+	if err := tx.Create(&walletHistory).Error; err != nil {
+		return err
+	}
+	return NotifyWalletTransaction(tx, &walletHistory)
+}
+
+// refundReleaseToWallet credits the refund for a release. When the coupon clawback is
+// larger than the refund, the deduction and the refund are recorded as two
+// transactions so the customer can see both.
+func refundReleaseToWallet(tx *gorm.DB, userID uint, item *models.OrderItem, release ItemRelease, paymentMethod string) error {
+	if release.RefundAmount >= 0 {
+		return CreditWallet(tx, userID, release.RefundAmount, item.OrderUID,
+			fmt.Sprintf("Order Refund ORD ID %s (Qty %d)", item.OrderUID, release.Quantity), paymentMethod)
+	}
+
+	if err := CreditWallet(tx, userID, -release.CouponDiscount, item.OrderUID,
+		fmt.Sprintf("Coupon discount adjustment as the order no longer qualifies. ORD ID %s", item.OrderUID), paymentMethod); err != nil {
+		return err
+	}
+	return CreditWallet(tx, userID, release.Amount, item.OrderUID,
+		fmt.Sprintf("Order Refund ORD ID %s (Qty %d)", item.OrderUID, release.Quantity), paymentMethod)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"gorm.io/gorm"
+)
+
+type ProductFilter struct {
+	Search            string
+	Categories        []string
+	Brands            []string
+	PriceRanges       []string
+	MinDiscount       int
+	MinRating         float64
+	IncludeOutOfStock bool
+	Attributes        map[string][]string
+	Specifications    map[string][]string
+}
+
+type FacetValue struct {
+	Value    string `json:"value"`
+	Count    int    `json:"count"`
+	Selected bool   `json:"selected"`
+}
+
+type Facet struct {
+	Param   string       `json:"param"`
+	SpecKey string       `json:"spec_key,omitempty"`
+	Label   string       `json:"label"`
+	Values  []FacetValue `json:"values"`
+}
+
+type attributeFacet struct {
+	Param  string
+	Column string
+	Label  string
+}
+
+var attributeFacets = []attributeFacet{
+	{Param: "size", Column: "product_variant_details.size", Label: "Size"},
+	{Param: "colour", Column: "product_variant_details.colour", Label: "Colour"},
+	{Param: "ram", Column: "product_variant_details.ram", Label: "RAM"},
+	{Param: "storage", Column: "product_variant_details.storage", Label: "Storage"},
+}
+
+// ParseProductFilter reads the filter from the shop's query parameters.
+func ParseProductFilter(query url.Values) ProductFilter {
+	includeOutOfStock, _ := strconv.ParseBool(query.Get("includeOutOfStock"))
+	minRating, _ := strconv.ParseFloat(query.Get("minRating"), 64)
+
+	filter := ProductFilter{
+		Search:            query.Get("search"),
+		Categories:        query["categories"],
+		Brands:            query["brands"],
+		PriceRanges:       query["priceRanges"],
+		MinRating:         minRating,
+		IncludeOutOfStock: includeOutOfStock,
+		Attributes:        make(map[string][]string),
+		Specifications:    ParseSpecificationFilters(query["spec"]),
+	}
+
+	for _, d := range query["discounts"] {
+		if val, err := strconv.Atoi(d); err == nil {
+			filter.MinDiscount = val
+		}
+	}
+
+	for _, facet := range attributeFacets {
+		if values := query[facet.Param]; len(values) > 0 {
+			filter.Attributes[facet.Param] = values
+		}
+	}
+	return filter
+}
+
+// ParseSpecificationFilters splits "key:value" pairs from the spec query
+// parameter into values grouped by specification key.
+func ParseSpecificationFilters(pairs []string) map[string][]string {
+	specifications := make(map[string][]string)
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, ":")
+		if !found || key == "" || value == "" {
+			continue
+		}
+		specifications[key] = append(specifications[key], value)
+	}
+	return specifications
+}
+
+// ApplyProductFilters adds every active filter to a product_variant_details
+// query. skipFacet names one facet ("ram", "spec:Processor", ...) to leave
+// out, which is how facet counts are computed under the other filters.
+func ApplyProductFilters(query *gorm.DB, filter ProductFilter, skipFacet string) *gorm.DB {
+	query = query.Joins("JOIN product_details ON product_variant_details.product_id = product_details.id").
+		Where("product_variant_details.is_deleted = ?", false)
+
+	if filter.Search != "" {
+		query = ApplyProductSearch(query, filter.Search)
+	}
+
+	if len(filter.Categories) > 0 {
+		query = query.Joins("JOIN categories ON product_variant_details.category_id = categories.id").
+			Where("categories.name IN ?", filter.Categories)
+	}
+
+	if len(filter.Brands) > 0 {
+		query = query.Where("product_details.brand_name IN ?", filter.Brands)
+	}
+
+	if len(filter.PriceRanges) > 0 {
+		priceConditions := []string{}
+		for _, pr := range filter.PriceRanges {
+			switch pr {
+			case "1":
+				priceConditions = append(priceConditions, "(product_variant_details.sale_price BETWEEN 1000 AND 50000)")
+			case "2":
+				priceConditions = append(priceConditions, "(product_variant_details.sale_price BETWEEN 50000 AND 100000)")
+			case "3":
+				priceConditions = append(priceConditions, "(product_variant_details.sale_price BETWEEN 100000 AND 500000)")
+			}
+		}
+		if len(priceConditions) > 0 {
+			query = query.Where(strings.Join(priceConditions, " OR "))
+		}
+	}
+
+	if filter.MinDiscount > 0 {
+		query = query.Where("((product_variant_details.regular_price - product_variant_details.sale_price) / product_variant_details.regular_price * 100) >= ?",
+			filter.MinDiscount)
+	}
+
+	if !filter.IncludeOutOfStock {
+		query = query.Where("product_variant_details.stock_quantity > 0")
+	}
+
+	if filter.MinRating > 0 {
+		query = query.Where("product_details.average_rating >= ?", filter.MinRating)
+	}
+
+	for _, facet := range attributeFacets {
+		values := filter.Attributes[facet.Param]
+		if len(values) > 0 && skipFacet != facet.Param {
+			query = query.Where(facet.Column+" IN ?", values)
+		}
+	}
+
+	for key, values := range filter.Specifications {
+		if len(values) > 0 && skipFacet != "spec:"+key {
+			query = query.Where(`EXISTS (SELECT 1 FROM product_specifications
+				WHERE product_specifications.product_variant_id = product_variant_details.id
+				AND product_specifications.is_deleted = false AND product_specifications.deleted_at IS NULL
+				AND product_specifications.specification_key = ? AND product_specifications.specification_value IN ?)`,
+				key, values)
+		}
+	}
+
+	return query
+}
+
+type facetRow struct {
+	Value string
+	Total int
+}
+
+func FetchProductFacets(filter ProductFilter) ([]Facet, error) {
+	facets := []Facet{}
+
+	for _, attribute := range attributeFacets {
+		var rows []facetRow
+		query := ApplyProductFilters(config.DB.Model(&models.ProductVariantDetails{}), filter, attribute.Param)
+		if err := query.Select(attribute.Column + " AS value, COUNT(DISTINCT product_variant_details.id) AS total").
+			Where(attribute.Column + " <> ''").
+			Group(attribute.Column).
+			Order(attribute.Column).
+			Scan(&rows).Error; err != nil {
+			return nil, errors.New("Failed to fetch attribute facets")
+		}
+		if facet := buildFacet(attribute.Param, "", attribute.Label, rows, filter.Attributes[attribute.Param]); len(facet.Values) > 0 {
+			facets = append(facets, facet)
+		}
+	}
+
+	var specFilters []models.FilterableSpecification
+	if err := config.DB.Order("sort_order ASC, specification_key ASC").Find(&specFilters).Error; err != nil {
+		return nil, errors.New("Failed to fetch filterable specifications")
+	}
+
+	for _, spec := range specFilters {
+		var rows []facetRow
+		query := ApplyProductFilters(config.DB.Model(&models.ProductVariantDetails{}), filter, "spec:"+spec.SpecificationKey)
+		if err := query.Joins(`JOIN product_specifications facet_specs ON facet_specs.product_variant_id = product_variant_details.id
+				AND facet_specs.is_deleted = false AND facet_specs.deleted_at IS NULL AND facet_specs.specification_key = ?`, spec.SpecificationKey).
+			Select("facet_specs.specification_value AS value, COUNT(DISTINCT product_variant_details.id) AS total").
+			Group("facet_specs.specification_value").
+			Order("facet_specs.specification_value").
+			Scan(&rows).Error; err != nil {
+			return nil, errors.New("Failed to fetch specification facets")
+		}
+		label := spec.Label
+		if label == "" {
+			label = spec.SpecificationKey
+		}
+		if facet := buildFacet("spec", spec.SpecificationKey, label, rows, filter.Specifications[spec.SpecificationKey]); len(facet.Values) > 0 {
+			facets = append(facets, facet)
+		}
+	}
+
+	return facets, nil
+}
+
+func buildFacet(param, specKey, label string, rows []facetRow, selected []string) Facet {
+	isSelected := make(map[string]bool)
+	for _, value := range selected {
+		isSelected[value] = true
+	}
+
+	facet := Facet{Param: param, SpecKey: specKey, Label: label, Values: []FacetValue{}}
+	for _, row := range rows {
+		facet.Values = append(facet.Values, FacetValue{
+			Value:    row.Value,
+			Count:    row.Total,
+			Selected: isSelected[row.Value],
+		})
+		delete(isSelected, row.Value)
+	}
+	// Keep selections visible even when the other filters leave no matches.
+	for _, value := range selected {
+		if isSelected[value] {
+			facet.Values = append(facet.Values, FacetValue{Value: value, Selected: true})
+			delete(isSelected, value)
+		}
+	}
+	return facet
+}
+
+// SortProducts orders a query built by ApplyProductFilters. Without a sort,
+// search results come by relevance and everything else newest first.
+func SortProducts(query *gorm.DB, sort string, search string) *gorm.DB {
+	switch sort {
+	case "price-low":
+		return query.Order("product_variant_details.sale_price ASC")
+	case "price-high":
+		return query.Order("product_variant_details.sale_price DESC")
+	case "newest":
+		return query.Order("product_variant_details.created_at DESC")
+	case "rating":
+		return query.Order("product_details.average_rating DESC").
+			Order("product_details.rating_count DESC").
+			Order("product_variant_details.created_at DESC")
+	case "popularity":
+		return query.Order("product_details.rating_count DESC").
+			Order("product_details.average_rating DESC").
+			Order("product_variant_details.created_at DESC")
+	default:
+		if search != "" {
+			query = OrderBySearchRank(query, search)
+		}
+		return query.Order("product_variant_details.created_at DESC")
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/imageproc"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// StoreProductImage runs an uploaded product photo through the image
+// pipeline and stores the cleaned original with all of its renditions.
+func StoreProductImage(ctx context.Context, folder string, fileHeader *multipart.FileHeader) (string, models.ImageRenditions, error) {
+	processed, err := imageproc.ProcessUpload(fileHeader)
+	if err != nil {
+		return "", models.ImageRenditions{}, err
+	}
+	return storeProcessed(ctx, folder, processed, true)
+}
+
+// GenerateRenditions stores renditions for an image that is already stored,
+// leaving the original as it is.
+func GenerateRenditions(ctx context.Context, folder string, image io.Reader) (models.ImageRenditions, error) {
+	processed, err := imageproc.Process(image)
+	if err != nil {
+		return models.ImageRenditions{}, err
+	}
+	_, renditions, err := storeProcessed(ctx, folder, processed, false)
+	return renditions, err
+}
+
+// storeProcessed saves the files of a processed image. If any file fails to
+// store, the ones already stored are removed again.
+func storeProcessed(ctx context.Context, folder string, processed *imageproc.Processed, withOriginal bool) (string, models.ImageRenditions, error) {
+	var stored []string
+	save := func(file imageproc.File) (string, error) {
+		url, err := storage.Default.Save(ctx, folder, file.Name, bytes.NewReader(file.Data))
+		if err != nil {
+			for _, url := range stored {
+				if delErr := storage.Delete(ctx, url); delErr != nil {
+					logger.Log.Warn("Failed to remove partially stored image", zap.String("url", url), zap.Error(delErr))
+				}
+			}
+			return "", err
+		}
+		stored = append(stored, url)
+		return url, nil
+	}
+
+	var original string
+	if withOriginal {
+		var err error
+		if original, err = save(processed.Original); err != nil {
+			return "", models.ImageRenditions{}, err
+		}
+	}
+
+	var renditions models.ImageRenditions
+	for _, variant := range processed.Variants {
+		url, err := save(variant.Image)
+		if err != nil {
+			return "", models.ImageRenditions{}, err
+		}
+		webpURL, err := save(variant.WebP)
+		if err != nil {
+			return "", models.ImageRenditions{}, err
+		}
+		switch variant.Rendition {
+		case imageproc.Thumbnail:
+			renditions.ThumbnailURL, renditions.ThumbnailWebPURL = url, webpURL
+		case imageproc.Listing:
+			renditions.ListingURL, renditions.ListingWebPURL = url, webpURL
+		case imageproc.Zoom:
+			renditions.ZoomURL, renditions.ZoomWebPURL = url, webpURL
+		}
+	}
+	return original, renditions, nil
+}
+
+// DeleteProductImage removes an original and whichever renditions it has.
+// Only a failure to remove the original is returned.
+func DeleteProductImage(ctx context.Context, original string, renditions models.ImageRenditions) error {
+	if err := storage.Delete(ctx, original); err != nil {
+		return err
+	}
+	for _, url := range []string{
+		renditions.ThumbnailURL, renditions.ThumbnailWebPURL,
+		renditions.ListingURL, renditions.ListingWebPURL,
+		renditions.ZoomURL, renditions.ZoomWebPURL,
+	} {
+		if url == "" {
+			continue
+		}
+		if err := storage.Delete(ctx, url); err != nil {
+			logger.Log.Warn("Failed to delete image rendition", zap.String("url", url), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"math"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type RatingBreakdown struct {
+	Star    int
+	Count   int
+	Percent int
+}
+
+type RatingSummary struct {
+	Average   float64
+	Count     int
+	Stars     []bool
+	Breakdown []RatingBreakdown
+}
+
+type ratingRow struct {
+	ProductID uint
+	Value     float64
+	Total     int
+}
+
+func FetchRatingSummary(productID uint) (RatingSummary, error) {
+	summaries, err := FetchRatingSummaries([]uint{productID})
+	if err != nil {
+		return RatingSummary{}, err
+	}
+	return summaries[productID], nil
+}
+
+func FetchRatingSummaries(productIDs []uint) (map[uint]RatingSummary, error) {
+	summaries := make(map[uint]RatingSummary)
+	if len(productIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []ratingRow
+	if err := config.DB.Model(&models.Rating{}).
+		Select("product_id, value, COUNT(*) AS total").
+		Where("product_id IN ? AND is_hidden = ?", productIDs, false).
+		Group("product_id, value").
+		Scan(&rows).Error; err != nil {
+		return summaries, errors.New("Failed to fetch ratings")
+	}
+
+	counts := make(map[uint]map[int]int)
+	for _, row := range rows {
+		if counts[row.ProductID] == nil {
+			counts[row.ProductID] = make(map[int]int)
+		}
+		counts[row.ProductID][int(math.Round(row.Value))] += row.Total
+	}
+
+	for _, productID := range productIDs {
+		summaries[productID] = buildRatingSummary(counts[productID])
+	}
+	return summaries, nil
+}
+
+func buildRatingSummary(counts map[int]int) RatingSummary {
+	var summary RatingSummary
+	var sum int
+	for star, count := range counts {
+		summary.Count += count
+		sum += star * count
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.Count)*10) / 10
+	}
+
+	summary.Stars = make([]bool, 5)
+	for i := range summary.Stars {
+		summary.Stars[i] = float64(i) < math.Round(summary.Average)
+	}
+
+	for star := 5; star >= 1; star-- {
+		breakdown := RatingBreakdown{Star: star, Count: counts[star]}
+		if summary.Count > 0 {
+			breakdown.Percent = int(math.Round(float64(counts[star]) * 100 / float64(summary.Count)))
+		}
+		summary.Breakdown = append(summary.Breakdown, breakdown)
+	}
+	return summary
+}
+
+const refreshProductRatingSQL = `UPDATE product_details SET
+	average_rating = COALESCE((SELECT ROUND(AVG(ratings.value)::numeric, 1) FROM ratings
+		WHERE ratings.product_id = product_details.id AND ratings.is_hidden = false AND ratings.deleted_at IS NULL), 0),
+	rating_count = (SELECT COUNT(*) FROM ratings
+		WHERE ratings.product_id = product_details.id AND ratings.is_hidden = false AND ratings.deleted_at IS NULL)`
+
+// RefreshProductRating recomputes the rating aggregate stored on the product
+// so the listing can filter and sort on it in SQL. Call it inside the same
+// transaction that changes the product's ratings.
+func RefreshProductRating(tx *gorm.DB, productID uint) error {
+	if err := tx.Exec(refreshProductRatingSQL+" WHERE product_details.id = ?", productID).Error; err != nil {
+		return errors.New("Failed to update product rating")
+	}
+	return nil
+}
+
+func RefreshAllProductRatings(db *gorm.DB) {
+	if err := db.Exec(refreshProductRatingSQL).Error; err != nil {
+		logger.Log.Error("Failed to refresh product ratings", zap.Error(err))
+		return
+	}
+	logger.Log.Info("Product ratings refreshed")
+}
+
+func HasDeliveredProduct(userID uint, productID uint) bool {
+	var count int64
+	config.DB.Model(&models.OrderItem{}).
+		Joins("JOIN product_variant_details ON product_variant_details.id = order_items.product_variant_id").
+		Where("order_items.user_id = ? AND product_variant_details.product_id = ? AND order_items.order_status = ?", userID, productID, "Delivered").
+		Count(&count)
+	return count > 0
+}
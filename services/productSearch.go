@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/utils/helper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Search documents are built per variant. Name and brand carry the most
+// weight, then category and variant attributes, then specifications.
+const productSearchDocumentSQL = `
+INSERT INTO product_search_documents (product_variant_id, document, search_text, updated_at)
+SELECT v.id,
+	setweight(to_tsvector('english', COALESCE(v.product_name, '') || ' ' || COALESCE(p.product_name, '')), 'A') ||
+	setweight(to_tsvector('simple', COALESCE(p.brand_name, '')), 'A') ||
+	setweight(to_tsvector('english', COALESCE(c.name, '')), 'B') ||
+	setweight(to_tsvector('simple', concat_ws(' ', v.ram, v.storage, v.colour, v.size)), 'B') ||
+	setweight(to_tsvector('english', COALESCE(s.specs, '')), 'C') ||
+	setweight(to_tsvector('english', COALESCE(v.product_summary, '')), 'D'),
+	lower(concat_ws(' ', v.product_name, p.brand_name, c.name, v.ram, v.storage, v.colour, v.size, s.specs)),
+	NOW()
+FROM product_variant_details v
+JOIN product_details p ON p.id = v.product_id
+LEFT JOIN categories c ON c.id = v.category_id
+LEFT JOIN (
+	SELECT product_variant_id, string_agg(specification_key || ' ' || specification_value, ' ') AS specs
+	FROM product_specifications
+	WHERE is_deleted = false AND deleted_at IS NULL
+	GROUP BY product_variant_id
+) s ON s.product_variant_id = v.id
+WHERE %s
+ON CONFLICT (product_variant_id) DO UPDATE
+SET document = EXCLUDED.document, search_text = EXCLUDED.search_text, updated_at = EXCLUDED.updated_at`
+
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+func SetupProductSearch(db *gorm.DB) {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_product_search_documents_document ON product_search_documents USING GIN (document)",
+		"CREATE INDEX IF NOT EXISTS idx_product_search_documents_search_text ON product_search_documents USING GIN (search_text gin_trgm_ops)",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			logger.Log.Error("Failed to set up product search", zap.String("statement", statement), zap.Error(err))
+			return
+		}
+	}
+
+	if err := refreshSearchDocuments(db, "TRUE"); err != nil {
+		logger.Log.Error("Failed to build product search documents", zap.Error(err))
+		return
+	}
+	logger.Log.Info("Product search documents built")
+}
+
+func refreshSearchDocuments(db *gorm.DB, where string, args ...interface{}) error {
+	sql := strings.Replace(productSearchDocumentSQL, "%s", where, 1)
+	if err := db.Exec(sql, args...).Error; err != nil {
+		return errors.New("Failed to refresh search documents")
+	}
+	return nil
+}
+
+func RefreshVariantSearch(variantIDs ...uint) error {
+	if len(variantIDs) == 0 {
+		return nil
+	}
+	return refreshSearchDocuments(config.DB, "v.id IN ?", variantIDs)
+}
+
+func RefreshProductSearch(productID uint) error {
+	return refreshSearchDocuments(config.DB, "v.product_id = ?", productID)
+}
+
+func RefreshCategorySearch(categoryID uint) error {
+	return refreshSearchDocuments(config.DB, "v.category_id = ?", categoryID)
+}
+
+// prefixTSQuery turns free text into an AND of prefix terms so partially
+// typed words still match, e.g. "leno think" -> "leno:* & think:*".
+func prefixTSQuery(term string) string {
+	words := searchTermPattern.FindAllString(strings.ToLower(term), -1)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// ApplyProductSearch restricts a product_variant_details query to variants
+// matching term either through the full-text document or, for misspellings,
+// through trigram word similarity.
+func ApplyProductSearch(query *gorm.DB, term string) *gorm.DB {
+	term = strings.TrimSpace(strings.ToLower(term))
+	tsQuery := prefixTSQuery(term)
+	if tsQuery == "" {
+		return query
+	}
+	return query.Joins("JOIN product_search_documents ON product_search_documents.product_variant_id = product_variant_details.id").
+		Where("product_search_documents.document @@ to_tsquery('english', ?) OR ? <% product_search_documents.search_text",
+			tsQuery, term)
+}
+
+// OrderBySearchRank sorts a query prepared by ApplyProductSearch by relevance.
+func OrderBySearchRank(query *gorm.DB, term string) *gorm.DB {
+	term = strings.TrimSpace(strings.ToLower(term))
+	tsQuery := prefixTSQuery(term)
+	if tsQuery == "" {
+		return query
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL: "ts_rank(product_search_documents.document, to_tsquery('english', ?)) + word_similarity(?, product_search_documents.search_text) DESC",
+		Vars: []interface{}{tsQuery, term},
+	}})
+}
+
+type SearchSuggestion struct {
+	ID          uint   `json:"id"`
+	ProductName string `json:"product_name"`
+	BrandName   string `json:"brand_name"`
+	Category    string `json:"category"`
+	Image       string `json:"image"`
+}
+
+func FetchSearchSuggestions(term string, limit int) ([]SearchSuggestion, error) {
+	query := config.DB.Model(&models.ProductVariantDetails{}).
+		Preload("VariantsImages", "is_deleted = ?", false).
+		Preload("Category").
+		Preload("Product").
+		Where("product_variant_details.is_deleted = ?", false)
+	query = OrderBySearchRank(ApplyProductSearch(query, term), term)
+
+	var variants []models.ProductVariantDetails
+	if err := query.Limit(limit).Find(&variants).Error; err != nil {
+		return nil, errors.New("Failed to fetch search suggestions")
+	}
+
+	suggestions := []SearchSuggestion{}
+	for _, variant := range variants {
+		suggestion := SearchSuggestion{
+			ID:          variant.ID,
+			ProductName: variant.ProductName,
+			BrandName:   variant.Product.BrandName,
+			Category:    variant.Category.Name,
+		}
+		if len(variant.VariantsImages) > 0 {
+			suggestion.Image = helper.ThumbnailImage(variant.VariantsImages[0].ProductVariantsImages, variant.VariantsImages[0].ImageRenditions)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// OrderMismatch is an order whose stored amounts do not add up.
+type OrderMismatch struct {
+	OrderID  uint
+	OrderUID string
+	// ItemsDifference is the item totals less the coupon, less the order
+	// total.
+	ItemsDifference money.Amount
+	// HeaderDifference is the subtotal less discounts plus tax and shipping,
+	// less the order total.
+	HeaderDifference money.Amount
+	// SubtotalDifference is the item subtotals less each item's regular
+	// price times its quantity.
+	SubtotalDifference money.Amount
+	// PartlyChecked is set for orders with cancelled or returned units, where
+	// only the item subtotals can be checked.
+	PartlyChecked bool
+}
+
+// Mismatched reports whether any of the checked amounts is off.
+func (m OrderMismatch) Mismatched() bool {
+	return m.ItemsDifference != 0 || m.HeaderDifference != 0 || m.SubtotalDifference != 0
+}
+
+// OrderTotalDifference is how far the item totals, less the order coupon,
+// are from the order total. It is zero for an order as checkout writes it.
+func OrderTotalDifference(order *models.Order, items []models.OrderItem) money.Amount {
+	var itemsTotal money.Amount
+	for _, item := range items {
+		itemsTotal += money.FromRupees(item.Total)
+	}
+	return itemsTotal - money.FromRupees(order.CouponDiscountAmount) - money.FromRupees(order.TotalAmount)
+}
+
+// orderHeaderDifference is how far the order's subtotal, discounts, tax and
+// shipping are from its total.
+func orderHeaderDifference(order *models.Order) money.Amount {
+	expected := money.FromRupees(order.SubTotal) -
+		money.FromRupees(order.TotalProductDiscount) -
+		money.FromRupees(order.CouponDiscountAmount) +
+		money.FromRupees(order.Tax) +
+		money.FromRupees(order.ShippingCharge)
+	return expected - money.FromRupees(order.TotalAmount)
+}
+
+// itemSubtotalDifference is how far the items' subtotals are from their
+// regular prices times their quantities. Releasing units leaves both alone,
+// so it holds for every order.
+func itemSubtotalDifference(items []models.OrderItem) money.Amount {
+	var difference money.Amount
+	for _, item := range items {
+		difference += money.FromRupees(item.SubTotal) - money.FromRupees(item.ProductRegularPrice).Times(item.Quantity)
+	}
+	return difference
+}
+
+// reconcileOrder checks one order with its items. Releasing units re-prices
+// the coupon and shipping but not the stored totals, so orders with
+// cancelled or returned units only have their item subtotals checked.
+func reconcileOrder(order *models.Order) OrderMismatch {
+	mismatch := OrderMismatch{
+		OrderID:            order.ID,
+		OrderUID:           order.OrderUID,
+		SubtotalDifference: itemSubtotalDifference(order.OrderItem),
+	}
+	for _, item := range order.OrderItem {
+		if item.ActiveQuantity < item.Quantity {
+			mismatch.PartlyChecked = true
+			return mismatch
+		}
+	}
+	mismatch.ItemsDifference = OrderTotalDifference(order, order.OrderItem)
+	mismatch.HeaderDifference = orderHeaderDifference(order)
+	return mismatch
+}
+
+// reconcileBatchSize keeps a reconciliation of the whole order history from
+// loading every order at once.
+const reconcileBatchSize = 500
+
+// ReconcileOrders checks, to the paisa, that orders placed since the given
+// time add up: the item totals less the coupon make the order total, and so
+// do the subtotal, discounts, tax and shipping. A zero time checks every
+// order.
+func ReconcileOrders(db *gorm.DB, since time.Time) ([]OrderMismatch, error) {
+	var mismatches []OrderMismatch
+	var checked, partlyChecked int
+	var orders []models.Order
+	result := db.Preload("OrderItem").Where("created_at >= ?", since).
+		FindInBatches(&orders, reconcileBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range orders {
+				order := &orders[i]
+				if len(order.OrderItem) == 0 {
+					continue
+				}
+				checked++
+				mismatch := reconcileOrder(order)
+				if mismatch.PartlyChecked {
+					partlyChecked++
+				}
+				if !mismatch.Mismatched() {
+					continue
+				}
+				logger.Log.Warn("Order totals do not reconcile",
+					zap.Uint("orderID", order.ID),
+					zap.String("orderUID", order.OrderUID),
+					zap.String("itemsDifference", mismatch.ItemsDifference.String()),
+					zap.String("headerDifference", mismatch.HeaderDifference.String()),
+					zap.String("subtotalDifference", mismatch.SubtotalDifference.String()),
+					zap.Bool("partlyChecked", mismatch.PartlyChecked))
+				mismatches = append(mismatches, mismatch)
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	logger.Log.Info("Order reconciliation finished",
+		zap.Time("since", since),
+		zap.Int("ordersChecked", checked),
+		zap.Int("ordersPartlyChecked", partlyChecked),
+		zap.Int("mismatches", len(mismatches)))
+	return mismatches, nil
+}
+
+// ReconcileHistory checks every order ever placed, for after the money
+// columns are converted, and logs whether the history still adds up.
+func ReconcileHistory(db *gorm.DB) {
+	logger.Log.Info("Reconciling the full order history")
+	mismatches, err := ReconcileOrders(db, time.Time{})
+	if err != nil {
+		logger.Log.Error("Failed to reconcile order history", zap.Error(err))
+		return
+	}
+	if len(mismatches) > 0 {
+		logger.Log.Error("Order history does not reconcile", zap.Int("mismatches", len(mismatches)))
+		return
+	}
+	logger.Log.Info("Order history reconciles")
+}
+
+// ReconciliationJob reconciles the last two days of orders once a day, so
+// each order is checked at least once after it is placed. It fails when any
+// order does not add up, which shows on the admin jobs page.
+func ReconciliationJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "order-reconciliation",
+		Description: "Check that order item totals add up to the order total",
+		Interval:    24 * time.Hour,
+		Run: func(ctx context.Context) error {
+			mismatches, err := ReconcileOrders(db.WithContext(ctx), time.Now().Add(-48*time.Hour))
+			if err != nil {
+				return err
+			}
+			if len(mismatches) > 0 {
+				return fmt.Errorf("%d orders do not reconcile", len(mismatches))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+)
+
+// reconciledOrder is two items, one of them two units, with a coupon and
+// shipping, written the way checkout writes them.
+func reconciledOrder() models.Order {
+	return models.Order{
+		OrderUID:             "ORD1",
+		SubTotal:             3497,
+		TotalProductDiscount: 500,
+		CouponDiscountAmount: 100.01,
+		Tax:                  269.46,
+		ShippingCharge:       40,
+		TotalAmount:          3206.45,
+		OrderItem: []models.OrderItem{
+			{ProductRegularPrice: 1499, ProductSalePrice: 1249, Quantity: 2, ActiveQuantity: 2, SubTotal: 2998, Tax: 224.82, Total: 2742.82},
+			{ProductRegularPrice: 499, ProductSalePrice: 499, Quantity: 1, ActiveQuantity: 1, SubTotal: 499, Tax: 44.64, Total: 563.64},
+		},
+	}
+}
+
+func TestReconcileOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(*models.Order)
+		items    money.Amount
+		header   money.Amount
+		subtotal money.Amount
+		partly   bool
+	}{
+		{"order as placed", func(*models.Order) {}, 0, 0, 0, false},
+		{"total a paisa short", func(o *models.Order) { o.TotalAmount = 3206.44 }, 1, 1, 0, false},
+		{"item total off", func(o *models.Order) { o.OrderItem[1].Total = 563.65 }, 1, 0, 0, false},
+		{"tax off", func(o *models.Order) { o.Tax = 269.47 }, 0, 1, 0, false},
+		{"item subtotal off", func(o *models.Order) { o.OrderItem[0].SubTotal = 2997.99 }, 0, 0, -1, false},
+		{
+			"cancelled units only check subtotals",
+			func(o *models.Order) {
+				o.OrderItem[0].ActiveQuantity = 1
+				o.CouponDiscountAmount = 0
+				o.ShippingCharge = 0
+			},
+			0, 0, 0, true,
+		},
+		{
+			"cancelled units with a subtotal off",
+			func(o *models.Order) {
+				o.OrderItem[1].ActiveQuantity = 0
+				o.OrderItem[1].SubTotal = 500
+			},
+			0, 0, 100, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := reconciledOrder()
+			tt.change(&order)
+			got := reconcileOrder(&order)
+			if got.ItemsDifference != tt.items || got.HeaderDifference != tt.header ||
+				got.SubtotalDifference != tt.subtotal || got.PartlyChecked != tt.partly {
+				t.Errorf("reconcileOrder = items %s, header %s, subtotal %s, partly %v; want %s, %s, %s, %v",
+					got.ItemsDifference, got.HeaderDifference, got.SubtotalDifference, got.PartlyChecked,
+					tt.items, tt.header, tt.subtotal, tt.partly)
+			}
+			wantMismatch := tt.items != 0 || tt.header != 0 || tt.subtotal != 0
+			if got.Mismatched() != wantMismatch {
+				t.Errorf("Mismatched = %v, want %v", got.Mismatched(), wantMismatch)
+			}
+		})
+	}
+}
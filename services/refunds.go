@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/paymentgateway"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	RefundToWallet   = "Wallet"
+	RefundToOriginal = "Original"
+
+	RefundPending    = "Pending"
+	RefundProcessing = "Processing"
+	RefundProcessed  = "Processed"
+	RefundFailed     = "Failed"
+	// RefundReconcile is a refund whose gateway outcome was lost, or that
+	// the gateway had no record of when it was looked up. It may still have
+	// gone through, so it is only sent again after an admin has checked the
+	// gateway dashboard.
+	RefundReconcile = "Reconcile"
+)
+
+// refundClaimLease is how long a refund stays claimed by the request that
+// sent it to the gateway. A request that dies mid-refund leaves it
+// Processing, and it is looked up at the gateway once the lease runs out.
+const refundClaimLease = 15 * time.Minute
+
+const refundNotFoundReason = "The gateway has no refund with this receipt. Check the gateway dashboard before releasing it for retry."
+
+var (
+	ErrRefundNotRetryable    = errors.New("refund is not pending or failed")
+	ErrRefundNotReconcilable = errors.New("refund is not stuck processing or awaiting reconciliation")
+	ErrRefundNotReleasable   = errors.New("refund is not awaiting reconciliation")
+)
+
+// RefundRetryable reports whether ProcessRefund would claim the refund.
+func RefundRetryable(refund *models.Refund) bool {
+	return refund.Destination == RefundToOriginal &&
+		(refund.Status == RefundPending || refund.Status == RefundFailed)
+}
+
+// RefundReconcilable reports whether ReconcileRefund would claim the refund.
+func RefundReconcilable(refund *models.Refund) bool {
+	if refund.Destination != RefundToOriginal {
+		return false
+	}
+	switch refund.Status {
+	case RefundReconcile:
+		return true
+	case RefundProcessing:
+		return refund.ClaimedAt == nil || refund.ClaimedAt.Before(time.Now().Add(-refundClaimLease))
+	}
+	return false
+}
+
+// refundDestination sends gateway payments back to the original method and
+// everything else, including wallet and cash on delivery payments, to the
+// wallet. A coupon clawback larger than the refund can only be taken from
+// the wallet.
+func refundDestination(payment *models.PaymentDetail, release ItemRelease) string {
+	if payment.PaymentMethod == "Razorpay" && payment.TransactionID != "" && release.RefundAmount > 0 {
+		return RefundToOriginal
+	}
+	return RefundToWallet
+}
+
+// RecordRefund records the refund for a release of a paid item. Wallet refunds
+// are credited inside tx; refunds to the original payment are left Pending
+// and must be passed to ProcessRefund once tx has committed. It returns nil
+// when nothing is owed back.
+func RecordRefund(tx *gorm.DB, payment *models.PaymentDetail, item *models.OrderItem, release ItemRelease, reason string) (*models.Refund, error) {
+	destination := refundDestination(payment, release)
+	if destination == RefundToWallet {
+		if err := refundReleaseToWallet(tx, payment.UserID, item, release, payment.PaymentMethod); err != nil {
+			return nil, err
+		}
+	}
+	if release.RefundAmount <= 0 {
+		return nil, nil
+	}
+
+	refund := models.Refund{
+		RefundUID:       "RFD-" + uuid.New().String(),
+		PaymentDetailID: payment.ID,
+		OrderItemID:     item.ID,
+		UserID:          payment.UserID,
+		Amount:          release.RefundAmount,
+		Quantity:        release.Quantity,
+		Reason:          reason,
+		Destination:     destination,
+		Status:          RefundPending,
+	}
+	if destination == RefundToOriginal {
+		refund.GatewayPaymentID = payment.TransactionID
+	} else {
+		now := time.Now()
+		refund.Status = RefundProcessed
+		refund.ProcessedAt = &now
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return nil, err
+	}
+	if refund.Status == RefundProcessed {
+		if err := notifyRefundIssued(tx, &refund); err != nil {
+			return nil, err
+		}
+	}
+	return &refund, nil
+}
+
+// ProcessRefund sends a pending or failed refund to the gateway. The refund
+// is claimed first so two retries cannot refund the same payment twice. A
+// refund that was sent before is looked up at the gateway first and only sent
+// again when the gateway has no record of it. A refund whose outcome is lost
+// on the way back is left for reconciliation rather than marked Failed,
+// since the gateway may have made it.
+func ProcessRefund(db *gorm.DB, refund *models.Refund) error {
+	if refund.Destination != RefundToOriginal {
+		return nil
+	}
+
+	now := time.Now()
+	claim := db.Model(&models.Refund{}).
+		Where("id = ? AND status IN ?", refund.ID, []string{RefundPending, RefundFailed}).
+		Updates(map[string]interface{}{
+			"status":     RefundProcessing,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"claimed_at": now,
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return ErrRefundNotRetryable
+	}
+	sentBefore := refund.Attempts > 0
+	refund.Status = RefundProcessing
+	refund.Attempts++
+	refund.ClaimedAt = &now
+
+	if sentBefore {
+		result, found, err := paymentgateway.Default.FindRefund(refund.GatewayPaymentID, refund.RefundUID)
+		if err != nil {
+			// Nothing was sent this time, so the refund can be retried as it
+			// was.
+			setRefundStatus(db, refund, RefundFailed, "Gateway lookup before retry failed: "+err.Error())
+			return err
+		}
+		if found {
+			return markRefundProcessed(db, refund, result.RefundID)
+		}
+	}
+
+	result, err := paymentgateway.Default.Refund(paymentgateway.RefundRequest{
+		PaymentID: refund.GatewayPaymentID,
+		Amount:    refund.Amount,
+		Receipt:   refund.RefundUID,
+		Notes: map[string]interface{}{
+			"order_item_id": fmt.Sprint(refund.OrderItemID),
+			"refund_uid":    refund.RefundUID,
+			"reason":        refund.Reason,
+		},
+	})
+	if err != nil {
+		if paymentgateway.IsRejected(err) {
+			setRefundStatus(db, refund, RefundFailed, err.Error())
+		} else {
+			setRefundStatus(db, refund, RefundReconcile, "Gateway did not answer: "+err.Error())
+		}
+		return err
+	}
+	return markRefundProcessed(db, refund, result.RefundID)
+}
+
+// ReconcileRefund settles a refund whose gateway outcome was lost, either
+// because it has been Processing for longer than refundClaimLease or because
+// an admin asks for it to be checked again. It never sends the refund: it
+// looks for it at the gateway and marks it Processed if found, and leaves it
+// for manual reconciliation otherwise.
+func ReconcileRefund(db *gorm.DB, refund *models.Refund) error {
+	if refund.Destination != RefundToOriginal {
+		return ErrRefundNotReconcilable
+	}
+
+	now := time.Now()
+	claim := db.Model(&models.Refund{}).
+		Where("id = ? AND (status = ? OR (status = ? AND (claimed_at IS NULL OR claimed_at < ?)))",
+			refund.ID, RefundReconcile, RefundProcessing, now.Add(-refundClaimLease)).
+		Updates(map[string]interface{}{
+			"status":     RefundProcessing,
+			"claimed_at": now,
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return ErrRefundNotReconcilable
+	}
+	refund.Status = RefundProcessing
+	refund.ClaimedAt = &now
+
+	result, found, err := paymentgateway.Default.FindRefund(refund.GatewayPaymentID, refund.RefundUID)
+	if err != nil {
+		setRefundStatus(db, refund, RefundReconcile, "Gateway lookup failed: "+err.Error())
+		return err
+	}
+	if !found {
+		setRefundStatus(db, refund, RefundReconcile, refundNotFoundReason)
+		return nil
+	}
+	return markRefundProcessed(db, refund, result.RefundID)
+}
+
+// ReleaseRefund moves a refund awaiting reconciliation to Failed once an
+// admin has confirmed at the gateway that it was never made, so it can be
+// retried.
+func ReleaseRefund(db *gorm.DB, refund *models.Refund) error {
+	reason := "Released for retry after manual reconciliation"
+	release := db.Model(&models.Refund{}).
+		Where("id = ? AND status = ?", refund.ID, RefundReconcile).
+		Updates(map[string]interface{}{
+			"status":         RefundFailed,
+			"failure_reason": reason,
+		})
+	if release.Error != nil {
+		return release.Error
+	}
+	if release.RowsAffected == 0 {
+		return ErrRefundNotReleasable
+	}
+	refund.Status = RefundFailed
+	refund.FailureReason = reason
+	return nil
+}
+
+// setRefundStatus records the outcome of a claimed refund that did not end
+// in a gateway refund. The caller already has an error to report, so a
+// failed update is only logged.
+func setRefundStatus(db *gorm.DB, refund *models.Refund, status, reason string) {
+	refund.Status = status
+	refund.FailureReason = reason
+	if err := db.Model(&models.Refund{}).Where("id = ?", refund.ID).Updates(map[string]interface{}{
+		"status":         status,
+		"failure_reason": reason,
+	}).Error; err != nil {
+		logger.Log.Error("Failed to update refund status",
+			zap.Uint("refundID", refund.ID), zap.String("status", status), zap.Error(err))
+	}
+}
+
+func markRefundProcessed(db *gorm.DB, refund *models.Refund, gatewayRefundID string) error {
+	now := time.Now()
+	refund.Status = RefundProcessed
+	refund.GatewayRefundID = gatewayRefundID
+	refund.FailureReason = ""
+	refund.ProcessedAt = &now
+	tx := db.Begin()
+	if err := tx.Model(&models.Refund{}).Where("id = ?", refund.ID).Updates(map[string]interface{}{
+		"status":            RefundProcessed,
+		"gateway_refund_id": gatewayRefundID,
+		"failure_reason":    "",
+		"processed_at":      now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := notifyRefundIssued(tx, refund); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// ProcessRefunds sends refunds recorded by a committed transaction. Failures
+// are left for an admin to retry and do not fail the caller's request.
+func ProcessRefunds(db *gorm.DB, refunds []*models.Refund) {
+	for _, refund := range refunds {
+		if refund == nil || refund.Destination != RefundToOriginal {
+			continue
+		}
+		if err := ProcessRefund(db, refund); err != nil {
+			logger.Log.Error("Gateway refund failed",
+				zap.String("refundUID", refund.RefundUID),
+				zap.Float64("amount", refund.Amount),
+				zap.Error(err))
+			continue
+		}
+		logger.Log.Info("Gateway refund processed",
+			zap.String("refundUID", refund.RefundUID),
+			zap.String("gatewayRefundID", refund.GatewayRefundID))
+	}
+}
+
+// RefundReconcileJob looks up refunds left Processing past their claim lease
+// at the gateway, so a crashed request neither strands a refund nor gets it
+// sent twice.
+func RefundReconcileJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "refund-reconciliation",
+		Description: "Look up refunds stuck in Processing at the payment gateway",
+		Interval:    refundClaimLease,
+		Run: func(ctx context.Context) error {
+			var refunds []models.Refund
+			if err := db.WithContext(ctx).
+				Where("destination = ? AND status = ? AND (claimed_at IS NULL OR claimed_at < ?)",
+					RefundToOriginal, RefundProcessing, time.Now().Add(-refundClaimLease)).
+				Find(&refunds).Error; err != nil {
+				return err
+			}
+
+			failed := 0
+			for i := range refunds {
+				refund := &refunds[i]
+				if err := ReconcileRefund(db.WithContext(ctx), refund); err != nil {
+					if errors.Is(err, ErrRefundNotReconcilable) {
+						continue
+					}
+					failed++
+					logger.Log.Error("Refund reconciliation failed", zap.String("refundUID", refund.RefundUID), zap.Error(err))
+					continue
+				}
+				logger.Log.Info("Stuck refund reconciled",
+					zap.String("refundUID", refund.RefundUID),
+					zap.String("status", refund.Status))
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d refunds could not be looked up at the gateway", failed)
+			}
+			return nil
+		},
+	}
+}
@@ -1,20 +1,22 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
-	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/jobs"
 	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/orderlifecycle"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 ) 
 
-func ReleaseExpiredReservations(db *gorm.DB) {
+func ReleaseExpiredReservations(db *gorm.DB) error {
 	logger.Log.Info("Starting expired reservations cleanup")
 
-	tx := config.DB.Begin()
+	tx := db.Begin()
 
 	var expiredReservations []models.ReservedStock
 	err := tx.Where("is_confirmed = ? AND reserve_till < ?", false, time.Now()).
@@ -23,7 +25,7 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 		logger.Log.Error("Error finding expired reservations",
 			zap.Error(err))
 		tx.Rollback()
-		return
+		return err
 	}
 
 	for _, reservation := range expiredReservations {
@@ -42,14 +44,14 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 					zap.Uint("couponID", coupon.CouponID),
 					zap.Error(err))
 				tx.Rollback()
-				return
+				return err
 			}
 			if err := tx.Unscoped().Delete(&coupon).Error; err != nil {
 				logger.Log.Error("Failed to delete reserved coupon",
 					zap.Uint("reservedCouponID", coupon.ID),
 					zap.Error(err))
 				tx.Rollback()
-				return
+				return err
 			}
 		}
 
@@ -62,7 +64,7 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 				zap.Int("quantity", reservation.Quantity),
 				zap.Error(err))
 			tx.Rollback()
-			return
+			return err
 		}
 
 		if err := tx.Unscoped().Delete(&reservation).Error; err != nil {
@@ -70,7 +72,7 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 				zap.Uint("reservationID", reservation.ID),
 				zap.Error(err))
 			tx.Rollback()
-			return
+			return err
 		}
 	}
 
@@ -80,7 +82,7 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 		logger.Log.Error("Error finding failed order items",
 			zap.Error(err))
 		tx.Rollback()
-		return
+		return err
 	}
 
 	for _, item := range failedOrderItems {
@@ -88,25 +90,28 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 			zap.Uint("orderItemID", item.ID),
 			zap.Uint("productVariantID", item.ProductVariantID))
 
-		if err := tx.Exec(
-			"UPDATE product_variant_details SET stock_quantity = stock_quantity + ? WHERE id = ?",
-			item.Quantity, item.ProductVariantID,
-		).Error; err != nil {
-			logger.Log.Error("Failed to update stock quantity for failed order",
-				zap.Uint("productVariantID", item.ProductVariantID),
-				zap.Int("quantity", item.Quantity),
-				zap.Error(err))
-			tx.Rollback()
-			return
+		// Units cancelled while the item waited for payment were put back
+		// in stock when they were cancelled.
+		if item.ActiveQuantity > 0 {
+			if err := tx.Exec(
+				"UPDATE product_variant_details SET stock_quantity = stock_quantity + ? WHERE id = ?",
+				item.ActiveQuantity, item.ProductVariantID,
+			).Error; err != nil {
+				logger.Log.Error("Failed to update stock quantity for failed order",
+					zap.Uint("productVariantID", item.ProductVariantID),
+					zap.Int("quantity", item.ActiveQuantity),
+					zap.Error(err))
+				tx.Rollback()
+				return err
+			}
 		}
 
-		item.OrderStatus = "Failed"
-		if err := tx.Save(&item).Error; err != nil {
+		if err := orderlifecycle.Transition(tx, &item, orderlifecycle.StatusFailed, orderlifecycle.SystemActor(), "Payment not completed in time", nil); err != nil {
 			logger.Log.Error("Failed to update order status to Failed",
 				zap.Uint("orderItemID", item.ID),
 				zap.Error(err))
 			tx.Rollback()
-			return
+			return err
 		}
 
 		var paymentDetail models.PaymentDetail
@@ -120,7 +125,7 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 				zap.Uint("orderItemID", item.ID),
 				zap.Error(err))
 			tx.Rollback()
-			return
+			return err
 		}
 
 		paymentDetail.PaymentStatus = "Cancelled"
@@ -129,27 +134,42 @@ func ReleaseExpiredReservations(db *gorm.DB) {
 				zap.Uint("paymentDetailID", paymentDetail.ID),
 				zap.Error(err))
 			tx.Rollback()
-			return
+			return err
 		}
 	}
 
+	// Razorpay orders the user walked away from are never answered. Paid and
+	// flagged ones are refunded by the checkout refund job, and are kept for
+	// the finance team.
+	if err := tx.Unscoped().Where("created_at < ? AND flagged_at IS NULL AND COALESCE(payment_id, '') = ''", time.Now().Add(-24*time.Hour)).
+		Delete(&models.CheckoutPayment{}).Error; err != nil {
+		logger.Log.Error("Failed to delete abandoned checkout payments",
+			zap.Error(err))
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		logger.Log.Error("Failed to commit transaction",
 			zap.Error(err))
-		return
+		return err
 	}
 
 	logger.Log.Info("Expired reservations released",
 		zap.Int("reservationCount", len(expiredReservations)),
 		zap.Int("failedOrderCount", len(failedOrderItems)))
+	return nil
 }
 
-func StartReservationCleanupTask(db *gorm.DB) {
-	logger.Log.Info("Starting reservation cleanup task")
-	go func() {
-		for {
-			time.Sleep(1 * time.Minute)
-			ReleaseExpiredReservations(db)
-		}
-	}()
+// ReservationCleanupJob releases expired stock reservations and fails orders
+// whose payment was never completed.
+func ReservationCleanupJob(db *gorm.DB) jobs.Job {
+	return jobs.Job{
+		Name:        "reservation-cleanup",
+		Description: "Release expired stock reservations and fail unpaid orders",
+		Interval:    time.Minute,
+		Run: func(ctx context.Context) error {
+			return ReleaseExpiredReservations(db.WithContext(ctx))
+		},
+	}
 }
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+	"gorm.io/gorm"
+)
+
+// Used when no active zone matches the address and no default zone exists.
+const (
+	DefaultShippingCharge        = 100.0
+	DefaultFreeShippingThreshold = 1000.0
+)
+
+const (
+	SlabBasisQuantity = "Quantity"
+	SlabBasisWeight   = "Weight"
+)
+
+type ShippingItem struct {
+	ProductVariantID uint
+	ProductID        uint
+	Quantity         int
+	Amount           float64
+}
+
+type ShippingQuote struct {
+	ZoneName     string
+	BaseCharge   float64
+	Surcharge    float64
+	Charge       float64
+	WaivedCharge float64
+	ItemCharges  map[uint]float64
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// ResolveShippingZone returns the active zone whose pin-code prefix matches
+// the address, preferring the longest prefix, then a zone listing the state,
+// then the default zone.
+func ResolveShippingZone(pinCode string, state string) (models.ShippingZone, bool) {
+	var zones []models.ShippingZone
+	if err := config.DB.Preload("Slabs", func(db *gorm.DB) *gorm.DB {
+		return db.Order("min_value ASC")
+	}).Where("is_active = ?", true).Order("priority DESC, id ASC").Find(&zones).Error; err != nil {
+		return models.ShippingZone{}, false
+	}
+
+	pinCode = strings.TrimSpace(pinCode)
+	state = strings.TrimSpace(state)
+
+	matchIndex, matchLength := -1, 0
+	for i, zone := range zones {
+		for _, prefix := range splitList(zone.PinCodePrefixes) {
+			if pinCode != "" && strings.HasPrefix(pinCode, prefix) && len(prefix) > matchLength {
+				matchIndex, matchLength = i, len(prefix)
+			}
+		}
+	}
+	if matchIndex >= 0 {
+		return zones[matchIndex], true
+	}
+
+	if state != "" {
+		for _, zone := range zones {
+			for _, zoneState := range splitList(zone.States) {
+				if strings.EqualFold(zoneState, state) {
+					return zone, true
+				}
+			}
+		}
+	}
+
+	for _, zone := range zones {
+		if zone.IsDefault {
+			return zone, true
+		}
+	}
+	return models.ShippingZone{}, false
+}
+
+func slabCharge(slabs []models.ShippingSlab, value float64) float64 {
+	if len(slabs) == 0 {
+		return 0
+	}
+	for _, slab := range slabs {
+		if value >= slab.MinValue && (slab.MaxValue == 0 || value <= slab.MaxValue) {
+			return slab.Charge
+		}
+	}
+	return slabs[len(slabs)-1].Charge
+}
+
+// CalculateShipping prices a shipment to the given address. The zone's free
+// shipping threshold waives the slab charge only; heavy item surcharges set
+// on the product are always charged. ItemCharges spreads the total over the
+// items by value so order items can carry their share.
+func CalculateShipping(items []ShippingItem, pinCode string, state string) ShippingQuote {
+	quote := ShippingQuote{ItemCharges: make(map[uint]float64)}
+	if len(items) == 0 {
+		return quote
+	}
+
+	productIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	var products []models.ProductDetail
+	config.DB.Unscoped().Select("id, weight, shipping_surcharge").Where("id IN ?", productIDs).Find(&products)
+	productMap := make(map[uint]models.ProductDetail)
+	for _, product := range products {
+		productMap[product.ID] = product
+	}
+
+	var orderAmount, surcharge money.Amount
+	var totalWeight float64
+	var totalQuantity int
+	surcharges := make(map[uint]money.Amount)
+	for _, item := range items {
+		product := productMap[item.ProductID]
+		orderAmount += money.FromRupees(item.Amount)
+		totalQuantity += item.Quantity
+		totalWeight += product.Weight * float64(item.Quantity)
+		surcharges[item.ProductVariantID] = money.FromRupees(product.ShippingSurcharge).Times(item.Quantity)
+		surcharge += surcharges[item.ProductVariantID]
+	}
+	quote.Surcharge = surcharge.Rupees()
+
+	zone, found := ResolveShippingZone(pinCode, state)
+	freeThreshold := DefaultFreeShippingThreshold
+	if found {
+		quote.ZoneName = zone.Name
+		freeThreshold = zone.FreeShippingThreshold
+		if zone.SlabBasis == SlabBasisWeight {
+			quote.BaseCharge = slabCharge(zone.Slabs, totalWeight)
+		} else {
+			quote.BaseCharge = slabCharge(zone.Slabs, float64(totalQuantity))
+		}
+	} else {
+		quote.ZoneName = "Standard"
+		quote.BaseCharge = DefaultShippingCharge
+	}
+
+	if freeThreshold > 0 && orderAmount >= money.FromRupees(freeThreshold) {
+		quote.WaivedCharge = quote.BaseCharge
+		quote.BaseCharge = 0
+	}
+	base := money.FromRupees(quote.BaseCharge)
+	quote.Charge = (base + surcharge).Rupees()
+
+	// Allocate keeps the item shares adding up to Charge to the paisa.
+	weights := make([]money.Amount, len(items))
+	for i, item := range items {
+		weights[i] = money.FromRupees(item.Amount)
+	}
+	for i, share := range money.Allocate(base, weights) {
+		variantID := items[i].ProductVariantID
+		quote.ItemCharges[variantID] = (share + surcharges[variantID]).Rupees()
+	}
+	return quote
+}
+
+// RecalculateOrderShipping prices the quantities of an order that are still
+// active, so cancellations and returns can re-evaluate the free shipping
+// threshold.
+func RecalculateOrderShipping(tx *gorm.DB, orderID uint) ShippingQuote {
+	var orderItems []models.OrderItem
+	tx.Preload("ProductVariantDetails", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("order_id = ? AND active_quantity > 0 AND order_status NOT IN ?", orderID, []string{"Cancelled", "Returned", "Order Not Placed"}).
+		Find(&orderItems)
+
+	var address models.ShippingAddress
+	tx.First(&address, "order_id = ?", orderID)
+
+	var items []ShippingItem
+	for _, orderItem := range orderItems {
+		items = append(items, ShippingItem{
+			ProductVariantID: orderItem.ProductVariantID,
+			ProductID:        orderItem.ProductVariantDetails.ProductID,
+			Quantity:         orderItem.ActiveQuantity,
+			Amount:           orderItem.ProductSalePrice * float64(orderItem.ActiveQuantity),
+		})
+	}
+	return CalculateShipping(items, address.PinCode, address.State)
+}
+
+func CartShippingItems(cartItems []CartItemDetailWithDiscount) []ShippingItem {
+	var items []ShippingItem
+	for _, item := range cartItems {
+		items = append(items, ShippingItem{
+			ProductVariantID: item.ProductDetails.ID,
+			ProductID:        item.ProductDetails.ProductID,
+			Quantity:         item.CartItem.Quantity,
+			Amount:           item.DiscountPrice * float64(item.CartItem.Quantity),
+		})
+	}
+	return items
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
+)
+
+// DefaultTaxRate applies, exclusive of price, when neither the product's HSN
+// code nor its category has an active tax rule.
+const DefaultTaxRate = 18.0
+
+type TaxBreakdown struct {
+	HSNCode      string
+	Rate         float64
+	IsInclusive  bool
+	TaxableValue float64
+	TaxAmount    float64
+	ChargedTax   float64
+	CGST         float64
+	SGST         float64
+	IGST         float64
+}
+
+// ResolveTaxRule picks the rule for a product, preferring an HSN code match
+// over a category match. The product's HSN code is returned with the rule.
+func ResolveTaxRule(productID uint, categoryID uint) (models.TaxRule, string) {
+	var product models.ProductDetail
+	config.DB.Unscoped().Select("id, category_id, hsn_code").First(&product, productID)
+	if categoryID == 0 {
+		categoryID = product.CategoryID
+	}
+
+	var rule models.TaxRule
+	if product.HSNCode != "" {
+		if err := config.DB.Where("hsn_code = ? AND is_active = ?", product.HSNCode, true).
+			Order("updated_at DESC").First(&rule).Error; err == nil {
+			return rule, product.HSNCode
+		}
+	}
+	if err := config.DB.Where("category_id = ? AND (hsn_code = '' OR hsn_code IS NULL) AND is_active = ?", categoryID, true).
+		Order("updated_at DESC").First(&rule).Error; err == nil {
+		return rule, product.HSNCode
+	}
+	return models.TaxRule{Name: "Default GST", Rate: DefaultTaxRate}, product.HSNCode
+}
+
+func IsIntraStateSupply(destinationState string) bool {
+	destinationState = strings.TrimSpace(destinationState)
+	if destinationState == "" {
+		return true
+	}
+	return strings.EqualFold(destinationState, strings.TrimSpace(config.CompanyConfig.State))
+}
+
+// CalculateTax splits the tax on a line amount into CGST+SGST for supplies
+// within the company's state and IGST otherwise. For inclusive rules the
+// tax is carved out of the amount, so nothing extra is charged.
+func CalculateTax(rule models.TaxRule, hsnCode string, lineAmount float64, destinationState string) TaxBreakdown {
+	breakdown := TaxBreakdown{
+		HSNCode:     hsnCode,
+		Rate:        rule.Rate,
+		IsInclusive: rule.IsInclusive,
+	}
+
+	line := money.FromRupees(lineAmount)
+	var taxable, tax money.Amount
+	if rule.IsInclusive {
+		taxable = line.WithoutPercent(rule.Rate)
+		tax = line - taxable
+	} else {
+		taxable = line
+		tax = line.Percent(rule.Rate)
+		breakdown.ChargedTax = tax.Rupees()
+	}
+	breakdown.TaxableValue = taxable.Rupees()
+	breakdown.TaxAmount = tax.Rupees()
+
+	if IsIntraStateSupply(destinationState) {
+		cgst := tax.Percent(50)
+		breakdown.CGST = cgst.Rupees()
+		breakdown.SGST = (tax - cgst).Rupees()
+	} else {
+		breakdown.IGST = breakdown.TaxAmount
+	}
+	return breakdown
+}
+
+func CalculateProductTax(productID uint, categoryID uint, lineAmount float64, destinationState string) TaxBreakdown {
+	rule, hsnCode := ResolveTaxRule(productID, categoryID)
+	return CalculateTax(rule, hsnCode, lineAmount, destinationState)
+}
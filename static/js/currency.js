@@ -0,0 +1,10 @@
+// formatPrice shows a rupee amount in the shopper's currency, the same way the
+// server's price template function does. Pages that load this script set
+// window.displayCurrency to { symbol, rate }, where rate is the rupees one
+// unit of the currency is worth; without it amounts are shown in rupees.
+function formatPrice(rupees) {
+    const currency = window.displayCurrency || { symbol: '₹', rate: 1 };
+    const amount = Math.round(Number(rupees) * 100) / 100 / currency.rate;
+    const formatted = Math.abs(amount).toFixed(2);
+    return (amount < 0 ? '-' : '') + currency.symbol + formatted;
+}
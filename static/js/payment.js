@@ -57,6 +57,8 @@ document.addEventListener('DOMContentLoaded', () => {
     // Fetch wallet balance initially
     fetchWalletBalance();
 
+    let walletBalanceAmount = 0;
+
     function fetchWalletBalance() {
         fetch('/checkout/check/wallet/balance', {
             method: 'GET',
@@ -75,7 +77,8 @@ document.addEventListener('DOMContentLoaded', () => {
     }
 
     function updateWalletDisplay(balance) {
-        walletBalanceDisplay.textContent = `Wallet Balance ₹ ${balance.toFixed(2)}${balance < total ? ' Unavailable' : ''}`;
+        walletBalanceAmount = balance;
+        walletBalanceDisplay.textContent = `Wallet Balance ${formatPrice(balance)}${balance < total ? ' Unavailable' : ''}`;
 
         const isInsufficient = balance < total;
 
@@ -181,7 +184,7 @@ document.addEventListener('DOMContentLoaded', () => {
             this.querySelector('.custom-radio').classList.add('selected');
             selectedPaymentMethod = this.getAttribute('data-value');
 
-            const walletBalance = parseFloat(walletBalanceDisplay.textContent.match(/₹\s+([\d.]+)/)[1]) || 0;
+            const walletBalance = walletBalanceAmount;
 
             // Handle wallet input visibility
             if (this.id === 'walletPayment') {
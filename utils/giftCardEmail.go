@@ -0,0 +1,18 @@
+package utils
+
+import "github.com/anfastk/E-Commerce-Website/pkg/emails"
+
+func SendGiftCardToEmail(senderName, senderProfile, message, email, amount, giftCode, expDate string) error {
+	giftCard, err := emails.New("gift_card", "Recipient", email, struct {
+		SenderName    string
+		SenderProfile string
+		Message       string
+		Amount        string
+		GiftCode      string
+		ExpDate       string
+	}{senderName, senderProfile, message, amount, giftCode, expDate})
+	if err != nil {
+		return err
+	}
+	return emails.Default.Send(giftCard)
+}
@@ -0,0 +1,17 @@
+package helper
+
+import (
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditActor describes the signed-in admin for audit log entries.
+func AuditActor(c *gin.Context) audit.Actor {
+	actor := audit.Actor{AdminID: c.GetUint("userid"), IPAddress: c.ClientIP()}
+	if admin, err := middleware.CurrentAdmin(c); err == nil {
+		actor.Email = admin.Email
+		actor.Role = admin.Role
+	}
+	return actor
+}
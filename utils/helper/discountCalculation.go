@@ -5,6 +5,7 @@ import (
 
 	"github.com/anfastk/E-Commerce-Website/config"
 	"github.com/anfastk/E-Commerce-Website/models"
+	"github.com/anfastk/E-Commerce-Website/pkg/money"
 )
 
 func DiscountCalculation(productID uint, categoryID uint, regularPrice float64, salePrice float64) (float64, float64, error) {
@@ -34,7 +35,9 @@ func DiscountCalculation(productID uint, categoryID uint, regularPrice float64,
 		discountPercentage = 100
 	}
 
-	discountAmount := (regularPrice * discountPercentage / 100) - difference
+	// Worked out in paise so the discounted price is always a whole paisa.
+	regular := money.FromRupees(regularPrice)
+	discountAmount := regular.Percent(discountPercentage) - (regular - money.FromRupees(salePrice))
 
-	return discountAmount, discountPercentage, nil
+	return discountAmount.Rupees(), discountPercentage, nil
 }
@@ -0,0 +1,22 @@
+package helper
+
+import (
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/pkg/currency"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DisplayCurrency is the currency to show the signed-in user's prices in, or
+// rupees for guests. Pages pass it to templates as "Currency".
+func DisplayCurrency(c *gin.Context) currency.Display {
+	userID := FetchUserID(c)
+	display, err := currency.ForUser(config.DB, userID)
+	if err != nil {
+		logger.Log.Warn("Failed to load display currency, showing rupees",
+			zap.Uint("userID", userID),
+			zap.Error(err))
+	}
+	return display
+}
@@ -0,0 +1,58 @@
+package helper
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anfastk/E-Commerce-Website/config"
+	"github.com/anfastk/E-Commerce-Website/middleware"
+	"github.com/anfastk/E-Commerce-Website/pkg/logger"
+	"github.com/anfastk/E-Commerce-Website/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AccountLocked responds with 429 and returns true while the account is
+// locked for scope.
+func AccountLocked(c *gin.Context, scope, account string) bool {
+	until, err := ratelimit.LockedUntil(config.DB, scope, account)
+	if err != nil {
+		logger.Log.Error("Failed to check account lockout", zap.String("scope", scope), zap.Error(err))
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+	respondLocked(c, until)
+	return true
+}
+
+// RecordFailedAttempt counts a failed attempt. When it locks the account it
+// responds with 429 and returns true; otherwise the caller sends its own error.
+func RecordFailedAttempt(c *gin.Context, scope, account string) bool {
+	until, err := ratelimit.RecordFailure(config.DB, scope, account, c.ClientIP())
+	if err != nil {
+		logger.Log.Error("Failed to record failed attempt", zap.String("scope", scope), zap.Error(err))
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+	respondLocked(c, until)
+	return true
+}
+
+// ClearFailedAttempts forgets the failures once the account gets in.
+func ClearFailedAttempts(scope, account string) {
+	if err := ratelimit.RecordSuccess(config.DB, scope, account); err != nil {
+		logger.Log.Error("Failed to clear failed attempts", zap.String("scope", scope), zap.Error(err))
+	}
+}
+
+func respondLocked(c *gin.Context, until time.Time) {
+	wait := time.Until(until)
+	c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	RespondWithError(c, http.StatusTooManyRequests, "Account locked",
+		"Too many failed attempts, please try again in "+middleware.RetryAfterText(wait), "")
+}
@@ -0,0 +1,28 @@
+package helper
+
+import "github.com/anfastk/E-Commerce-Website/models"
+
+// ListingImage is the image to show on product cards, falling back to the
+// original for images uploaded before renditions were generated.
+func ListingImage(original string, renditions models.ImageRenditions) string {
+	if renditions.ListingURL != "" {
+		return renditions.ListingURL
+	}
+	return original
+}
+
+// ThumbnailImage is the small image for suggestions and variant pickers.
+func ThumbnailImage(original string, renditions models.ImageRenditions) string {
+	if renditions.ThumbnailURL != "" {
+		return renditions.ThumbnailURL
+	}
+	return original
+}
+
+// ZoomImage is the large image for product pages, with the same fallback.
+func ZoomImage(original string, renditions models.ImageRenditions) string {
+	if renditions.ZoomURL != "" {
+		return renditions.ZoomURL
+	}
+	return original
+}
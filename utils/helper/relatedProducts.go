@@ -13,6 +13,7 @@ type productsResponse struct {
 	RegularPrice    float64 `json:"regular_price"`
 	OfferPercentage int     `json:"offer_percentage"`
 	Images          string  `json:"images"`
+	ImagesWebP      string  `json:"images_webp,omitempty"`
 	IsInCart        bool    `json:"is_in_cart"`
 	IsInWishlist    bool    `json:"is_in_wishlist"`
 }
@@ -36,7 +37,8 @@ func RelatedProducts(categoryID uint) ([]productsResponse, error) {
 			SalePrice:       product.SalePrice - discountAmount,
 			RegularPrice:    product.RegularPrice,
 			OfferPercentage: int(TotalPercentage),
-			Images:          product.VariantsImages[0].ProductVariantsImages,
+			Images:          ListingImage(product.VariantsImages[0].ProductVariantsImages, product.VariantsImages[0].ImageRenditions),
+			ImagesWebP:      product.VariantsImages[0].ListingWebPURL,
 		})
 	}
 	return response, nil
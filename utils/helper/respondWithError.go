@@ -6,13 +6,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ErrorResponse is the body of every error from the shop and admin panel.
+type ErrorResponse struct {
+	Status   string `json:"status"`
+	Error    string `json:"error"`
+	Message  string `json:"message"`
+	Code     int    `json:"code"`
+	Redirect string `json:"redirect"`
+}
+
 func RespondWithError(c *gin.Context, status int, error string, message string, redirect string) {
-	c.JSON(status, gin.H{
-		"status":   http.StatusText(status),
-		"error":    error,
-		"message":  message,
-		"code":     status,
-		"redirect": redirect,
+	c.JSON(status, ErrorResponse{
+		Status:   http.StatusText(status),
+		Error:    error,
+		Message:  message,
+		Code:     status,
+		Redirect: redirect,
 	})
 }
- 
\ No newline at end of file
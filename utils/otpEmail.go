@@ -0,0 +1,11 @@
+package utils
+
+import "github.com/anfastk/E-Commerce-Website/pkg/emails"
+
+func SendOTPToEmail(email, otp string) error {
+	message, err := emails.New("otp", "User", email, struct{ OTP string }{otp})
+	if err != nil {
+		return err
+	}
+	return emails.Default.Send(message)
+}